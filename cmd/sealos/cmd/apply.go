@@ -18,6 +18,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/labring/sealos/pkg/apply"
+	"github.com/labring/sealos/pkg/apply/processor"
 	"github.com/labring/sealos/pkg/utils/logger"
 )
 
@@ -45,5 +46,6 @@ func newApplyCmd() *cobra.Command {
 	setRequireBuildahAnnotation(applyCmd)
 	applyCmd.Flags().StringVarP(&clusterFile, "Clusterfile", "f", "Clusterfile", "apply a kubernetes cluster")
 	applyArgs.RegisterFlags(applyCmd.Flags())
+	applyCmd.Flags().BoolVar(&processor.ForceUpgrade, "force-upgrade", false, "bypass the failed upgrade preflight checks")
 	return applyCmd
 }
@@ -0,0 +1,115 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/apply"
+	"github.com/labring/sealos/pkg/bundle"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+var exampleBundle = `
+create a bundle with the images of the Clusterfile for amd64 and arm64 hosts:
+	sealos bundle create -f Clusterfile -o cluster.tar.gz --arch amd64,arm64
+
+install the cluster from the bundle on the air-gapped site:
+	sealos bundle install cluster.tar.gz
+`
+
+func newBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "bundle",
+		Short:   "Create and install offline bundles for air-gapped sites",
+		Example: exampleBundle,
+	}
+	cmd.AddCommand(newBundleCreateCmd())
+	cmd.AddCommand(newBundleInstallCmd())
+	return cmd
+}
+
+func newBundleCreateCmd() *cobra.Command {
+	opts := &bundle.CreateOptions{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Pack the Clusterfile, its images and the sealos binaries into one archive",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := bundle.Create(opts); err != nil {
+				return err
+			}
+			logger.Info("bundle %s is created", opts.Output)
+			return nil
+		},
+	}
+	setRequireBuildahAnnotation(cmd)
+	cmd.Flags().StringVarP(&opts.Clusterfile, "Clusterfile", "f", "Clusterfile", "Clusterfile of the cluster, the Config objects in it are kept")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "sealos-bundle.tar.gz", "path of the bundle")
+	cmd.Flags().StringSliceVar(&opts.Arches, "arch", nil, "architectures of the images, default is the architectures of the hosts in the Clusterfile")
+	cmd.Flags().StringVar(&opts.TmpDir, "tmp-dir", os.TempDir(), "directory to stage the bundle in, it needs space for all images")
+	return cmd
+}
+
+func newBundleInstallCmd() *cobra.Command {
+	var (
+		applyArgs       = &apply.Args{}
+		tmpDir          string
+		installBinaries bool
+		binDir          string
+	)
+	cmd := &cobra.Command{
+		Use:   "install BUNDLE",
+		Short: "Load the images of a bundle and apply its Clusterfile without network access",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := os.MkdirTemp(tmpDir, "sealos-bundle-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			m, err := bundle.Extract(args[0], dir)
+			if err != nil {
+				return err
+			}
+			logger.Info("bundle of cluster %s is created by sealos %s at %s", m.Cluster, m.SealosVersion, m.Created)
+			if installBinaries {
+				if err = bundle.InstallBinaries(dir, binDir); err != nil {
+					return err
+				}
+			}
+			if err = bundle.LoadImages(dir, m); err != nil {
+				return err
+			}
+			applier, err := apply.NewApplierFromFile(cmd, filepath.Join(dir, bundle.ClusterfileName), applyArgs)
+			if err != nil {
+				return err
+			}
+			return applier.Apply()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			logger.Info(getContact())
+		},
+	}
+	setRequireBuildahAnnotation(cmd)
+	applyArgs.RegisterFlags(cmd.Flags())
+	cmd.Flags().StringVar(&tmpDir, "tmp-dir", os.TempDir(), "directory to extract the bundle in")
+	cmd.Flags().BoolVar(&installBinaries, "install-binaries", false, "install the sealos and sealctl binaries of the bundle")
+	cmd.Flags().StringVar(&binDir, "bin-dir", "/usr/bin", "directory to install the binaries in")
+	return cmd
+}
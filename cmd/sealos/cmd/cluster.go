@@ -0,0 +1,296 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/runtime"
+	"github.com/labring/sealos/pkg/runtime/factory"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/yaml"
+)
+
+var exampleCluster = `
+list the clusters managed on this host:
+	sealos cluster list
+
+show the details of a cluster:
+	sealos cluster show prod
+
+use a cluster for the commands that are run without -c/--cluster:
+	sealos cluster use prod
+
+move the management of a cluster to another host:
+	sealos cluster export prod -o prod.tar.gz
+	sealos cluster import prod.tar.gz
+
+move the control plane endpoint of the current cluster to a new VIP and API server domain:
+	sealos cluster set-endpoint --vip 10.103.97.100 --domain apiserver.prod.local
+`
+
+func newClusterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cluster",
+		Short:   "Manage the clusters saved on this host",
+		Example: exampleCluster,
+	}
+	cmd.AddCommand(newClusterListCmd())
+	cmd.AddCommand(newClusterShowCmd())
+	cmd.AddCommand(newClusterUseCmd())
+	cmd.AddCommand(newClusterExportCmd())
+	cmd.AddCommand(newClusterImportCmd())
+	cmd.AddCommand(newClusterSetEndpointCmd())
+	return cmd
+}
+
+func clusterVersion(cluster *v2.Cluster) string {
+	if img := cluster.GetRootfsImage(); img != nil {
+		return img.KubeVersion()
+	}
+	return ""
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+func newClusterListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the clusters with their phase, masters, nodes and version",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := clusterfile.ListClusters()
+			if err != nil {
+				return err
+			}
+			current := clusterfile.GetCurrentClusterName()
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "CURRENT\tNAME\tPHASE\tMASTERS\tNODES\tVERSION")
+			for _, name := range names {
+				mark := ""
+				if name == current {
+					mark = "*"
+				}
+				cluster, err := clusterfile.GetClusterFromName(name)
+				if err != nil {
+					logger.Warn("failed to load cluster %s: %v", name, err)
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", mark, name, "<unknown>", "-", "-", "-")
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", mark, name, valueOrNone(string(cluster.Status.Phase)),
+					len(cluster.GetMasterIPList()), len(cluster.GetNodeIPList()), valueOrNone(clusterVersion(cluster)))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newClusterShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show [NAME]",
+		Short: "Show the details of a cluster, the current one if no name is given",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			cluster, err := clusterfile.GetClusterFromName(name)
+			if err != nil {
+				return err
+			}
+			return printCluster(cmd.OutOrStdout(), cluster)
+		},
+	}
+}
+
+func printCluster(out io.Writer, cluster *v2.Cluster) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", cluster.Name)
+	fmt.Fprintf(w, "Current:\t%t\n", cluster.Name == clusterfile.GetCurrentClusterName())
+	fmt.Fprintf(w, "Phase:\t%s\n", valueOrNone(string(cluster.Status.Phase)))
+	fmt.Fprintf(w, "Distribution:\t%s\n", cluster.GetDistribution())
+	fmt.Fprintf(w, "Version:\t%s\n", valueOrNone(clusterVersion(cluster)))
+	fmt.Fprintf(w, "Masters:\t%s\n", valueOrNone(strings.Join(cluster.GetMasterIPList(), ",")))
+	fmt.Fprintf(w, "Nodes:\t%s\n", valueOrNone(strings.Join(cluster.GetNodeIPList(), ",")))
+	fmt.Fprintf(w, "Images:\t%s\n", valueOrNone(strings.Join(cluster.Spec.Image, ",")))
+	fmt.Fprintf(w, "Clusterfile:\t%s\n", constants.Clusterfile(cluster.Name))
+	for _, condition := range cluster.Status.Conditions {
+		fmt.Fprintf(w, "Condition:\t%s=%s %s %s\n", condition.Type, condition.Status,
+			condition.LastHeartbeatTime.Format("2006-01-02 15:04:05"), condition.Message)
+	}
+	return w.Flush()
+}
+
+func newClusterUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use NAME",
+		Short: "Set the cluster used by the commands run without -c/--cluster",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := clusterfile.SetCurrentClusterName(args[0]); err != nil {
+				return err
+			}
+			logger.Info("switched to cluster %s", args[0])
+			return nil
+		},
+	}
+}
+
+func newClusterExportCmd() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "export [NAME]",
+		Short: "Export the Clusterfile, pki and etc dirs of a cluster into a tarball",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			if name == "" {
+				var err error
+				if name, err = clusterfile.GetDefaultClusterName(); err != nil {
+					return err
+				}
+			}
+			if output == "" {
+				output = name + ".tar.gz"
+			}
+			f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			if err = clusterfile.ExportCluster(name, f); err != nil {
+				_ = f.Close()
+				_ = os.Remove(output)
+				return err
+			}
+			if err = f.Close(); err != nil {
+				return err
+			}
+			logger.Info("cluster %s is exported to %s, it contains the certificates of the cluster, keep it safe", name, output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "path of the exported tarball, default is NAME.tar.gz")
+	return cmd
+}
+
+func newClusterImportCmd() *cobra.Command {
+	var (
+		force bool
+		use   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Import a cluster exported by `sealos cluster export`",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			name, err := clusterfile.ImportCluster(f, force)
+			if err != nil {
+				return err
+			}
+			logger.Info("cluster %s is imported", name)
+			if use {
+				if err = clusterfile.SetCurrentClusterName(name); err != nil {
+					return err
+				}
+				logger.Info("switched to cluster %s", name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite the cluster if it already exists")
+	cmd.Flags().BoolVar(&use, "use", false, "use the imported cluster as the current cluster")
+	return cmd
+}
+
+func newClusterSetEndpointCmd() *cobra.Command {
+	var vip, domain string
+	cmd := &cobra.Command{
+		Use:   "set-endpoint",
+		Short: "Change the VIP and API server domain of a running cluster",
+		Long: `Change the VIP and API server domain of a running cluster.
+The certificates and the kubeadm-config ConfigMap are updated first, then the hosts entries,
+lvscare manifests and kubeconfigs of every host, and the components are restarted one by one.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if vip == "" && domain == "" {
+				return fmt.Errorf("at least one of --vip and --domain is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cf := clusterfile.NewClusterFile(constants.Clusterfile(clusterName))
+			if err := cf.Process(); err != nil {
+				return err
+			}
+			cluster := cf.GetCluster()
+			rt, err := factory.New(cluster, cf.GetRuntimeConfig())
+			if err != nil {
+				return fmt.Errorf("create runtime failed: %v", err)
+			}
+			em, ok := rt.(runtime.EndpointManager)
+			if !ok {
+				return fmt.Errorf("changing endpoint is not supported by distribution %s", cluster.GetDistribution())
+			}
+			if err = em.SetEndpoint(vip, domain); err != nil {
+				return err
+			}
+			if err = saveClusterFile(cf); err != nil {
+				return err
+			}
+			logger.Info("endpoint of cluster %s is changed to %s(%s)", cluster.Name, cluster.GetAPIServerDomain(), cluster.GetVIP())
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to change endpoint")
+	cmd.Flags().StringVar(&vip, "vip", "", "new virtual IP of the API servers used by the nodes")
+	cmd.Flags().StringVar(&domain, "domain", "", "new domain of the API server")
+	return cmd
+}
+
+// saveClusterFile writes the cluster back to its Clusterfile together with the runtime config and configs
+func saveClusterFile(cf clusterfile.Interface) error {
+	objects := []interface{}{cf.GetCluster()}
+	if runtimeConfig := cf.GetRuntimeConfig(); runtimeConfig != nil {
+		objects = append(objects, runtimeConfig.GetComponents()...)
+	}
+	for i := range cf.GetConfigs() {
+		objects = append(objects, cf.GetConfigs()[i])
+	}
+	return yaml.MarshalFile(constants.Clusterfile(cf.GetCluster().Name), objects...)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/labring/sealos/pkg/audit"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/exec"
 	"github.com/labring/sealos/pkg/ssh"
@@ -52,8 +53,10 @@ func newExecCmd() *cobra.Command {
 		Short:   "Execute shell command or script on specified nodes",
 		Example: exampleExec,
 		Args:    cobra.MinimumNArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			targets := getTargets(cluster, ips, roles)
+			rec := audit.Begin(cluster, targets)
+			defer func() { rec.End(err) }()
 			return runCommand(cluster, targets, args)
 		},
 		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
@@ -0,0 +1,98 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/labring/sealos/pkg/audit"
+)
+
+var exampleHistory = `
+list the operations run against the current cluster:
+	sealos history
+
+list the last 5 operations of cluster prod:
+	sealos history -c prod --limit 5
+
+inspect an operation, including the changes of the Clusterfile:
+	sealos history 1bq2x5n8k7c0
+`
+
+func newHistoryCmd() *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:     "history [ID]",
+		Short:   "List or inspect the operations run against a cluster",
+		Example: exampleHistory,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				entry, err := audit.Get(clusterName, args[0])
+				if err != nil {
+					return err
+				}
+				return printAuditEntry(cmd.OutOrStdout(), entry)
+			}
+			entries, err := audit.List(clusterName)
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(entries) > limit {
+				entries = entries[len(entries)-limit:]
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tSTART\tDURATION\tOPERATOR\tRESULT\tCOMMAND")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.StartTime.Format("2006-01-02 15:04:05"),
+					e.Duration().Round(time.Second), e.Operator, e.Result, e.Command)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVarP(&clusterName, "cluster", "c", "default", "name of cluster to list operations")
+	cmd.Flags().IntVar(&limit, "limit", 0, "list the latest N operations only, 0 lists all")
+	return cmd
+}
+
+func printAuditEntry(out io.Writer, e *audit.Entry) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", e.ID)
+	fmt.Fprintf(w, "Cluster:\t%s\n", e.Cluster)
+	fmt.Fprintf(w, "Operator:\t%s\n", e.Operator)
+	fmt.Fprintf(w, "Command:\t%s\n", e.Command)
+	fmt.Fprintf(w, "Hosts:\t%s\n", valueOrNone(strings.Join(e.Hosts, ",")))
+	fmt.Fprintf(w, "Start:\t%s\n", e.StartTime.Format(time.RFC3339))
+	fmt.Fprintf(w, "End:\t%s\n", e.EndTime.Format(time.RFC3339))
+	fmt.Fprintf(w, "Result:\t%s\n", e.Result)
+	if e.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", e.Error)
+	}
+	if e.Diff == "" {
+		fmt.Fprintln(w, "Clusterfile:\tunchanged")
+		return w.Flush()
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "Clusterfile:\n%s", e.Diff)
+	return err
+}
@@ -0,0 +1,212 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/apply"
+	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/runtime"
+	"github.com/labring/sealos/pkg/runtime/factory"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/iputils"
+)
+
+var exampleNode = `
+drain the pods off a node, it stays cordoned until uncordon:
+	sealos node drain --nodes 192.168.0.3
+	sealos node uncordon --nodes 192.168.0.3
+
+reboot all workers for a kernel patch, 3 at a time:
+	sealos node reboot --roles node --batch-size 3
+
+reboot the masters one by one:
+	sealos node reboot --roles master
+
+replace a failed worker with a new host:
+	sealos node replace --old 192.168.0.3 --new 192.168.0.10
+`
+
+type nodeSelector struct {
+	masters string
+	nodes   string
+	roles   []string
+}
+
+func (s *nodeSelector) registerFlags(cmd *cobra.Command, action string) {
+	cmd.Flags().StringVar(&s.masters, "masters", "", fmt.Sprintf("masters to %s", action))
+	cmd.Flags().StringVar(&s.nodes, "nodes", "", fmt.Sprintf("nodes to %s", action))
+	cmd.Flags().StringSliceVar(&s.roles, "roles", nil, fmt.Sprintf("%s all hosts of the roles, e.g. master,node", action))
+	cmd.Flags().StringVarP(&clusterName, "cluster", "c", "default", fmt.Sprintf("name of cluster to applied %s action", action))
+}
+
+// selected returns the selected hosts as they are recorded in the Clusterfile
+func (s *nodeSelector) selected(cluster *v2.Cluster) ([]string, error) {
+	all := cluster.GetAllIPS()
+	var ret []string
+	add := func(hosts ...string) {
+		for _, host := range hosts {
+			if !slices.Contains(ret, host) {
+				ret = append(ret, host)
+			}
+		}
+	}
+	for _, list := range []string{s.masters, s.nodes} {
+		ips, err := iputils.ParseIPList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			idx := slices.IndexFunc(all, func(host string) bool {
+				return host == ip || (!strings.Contains(ip, ":") && iputils.GetHostIP(host) == ip)
+			})
+			if idx < 0 {
+				return nil, fmt.Errorf("host %s is not in cluster %s", ip, cluster.Name)
+			}
+			add(all[idx])
+		}
+	}
+	for _, role := range s.roles {
+		hosts := cluster.GetIPSByRole(role)
+		if len(hosts) == 0 {
+			return nil, fmt.Errorf("no host of role %s in cluster %s", role, cluster.Name)
+		}
+		add(hosts...)
+	}
+	if len(ret) == 0 {
+		return nil, errors.New("no host is selected, use --masters, --nodes or --roles")
+	}
+	return ret, nil
+}
+
+func newNodeMaintainer() (runtime.NodeMaintainer, *v2.Cluster, error) {
+	cf := clusterfile.NewClusterFile(constants.Clusterfile(clusterName))
+	if err := cf.Process(); err != nil {
+		return nil, nil, err
+	}
+	rt, err := factory.New(cf.GetCluster(), cf.GetRuntimeConfig())
+	if err != nil {
+		return nil, nil, fmt.Errorf("create runtime failed: %v", err)
+	}
+	m, ok := rt.(runtime.NodeMaintainer)
+	if !ok {
+		return nil, nil, fmt.Errorf("node maintenance is not supported by distribution %s", cf.GetCluster().GetDistribution())
+	}
+	return m, cf.GetCluster(), nil
+}
+
+func newNodeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "node",
+		Short:   "Cordon, drain, reboot and replace nodes of the cluster",
+		Example: exampleNode,
+	}
+	cmd.AddCommand(newNodeCordonCmd("cordon", "Mark nodes as unschedulable", runtime.NodeMaintainer.Cordon))
+	cmd.AddCommand(newNodeCordonCmd("uncordon", "Mark nodes as schedulable", runtime.NodeMaintainer.Uncordon))
+	cmd.AddCommand(newNodeMaintenanceCmd("drain", "Cordon nodes and evict their pods through the eviction API", runtime.NodeMaintainer.Drain))
+	cmd.AddCommand(newNodeMaintenanceCmd("reboot", "Drain, reboot and uncordon nodes in rolling batches", runtime.NodeMaintainer.Reboot))
+	cmd.AddCommand(newNodeReplaceCmd())
+	return cmd
+}
+
+func newNodeCordonCmd(use, short string, fn func(runtime.NodeMaintainer, []string) error) *cobra.Command {
+	selector := &nodeSelector{}
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, cluster, err := newNodeMaintainer()
+			if err != nil {
+				return err
+			}
+			nodes, err := selector.selected(cluster)
+			if err != nil {
+				return err
+			}
+			return fn(m, nodes)
+		},
+	}
+	selector.registerFlags(cmd, use)
+	return cmd
+}
+
+func newNodeMaintenanceCmd(use, short string, fn func(runtime.NodeMaintainer, []string, *runtime.MaintenanceOptions) error) *cobra.Command {
+	selector := &nodeSelector{}
+	opts := &runtime.MaintenanceOptions{}
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.BatchSize < 1 {
+				return errors.New("--batch-size must be positive")
+			}
+			m, cluster, err := newNodeMaintainer()
+			if err != nil {
+				return err
+			}
+			nodes, err := selector.selected(cluster)
+			if err != nil {
+				return err
+			}
+			return fn(m, nodes, opts)
+		},
+	}
+	selector.registerFlags(cmd, use)
+	cmd.Flags().IntVar(&opts.BatchSize, "batch-size", 1, fmt.Sprintf("number of workers to %s at the same time, masters are always handled one by one", use))
+	cmd.Flags().DurationVar(&opts.GracePeriod, "grace-period", -1, "termination grace period of the evicted pods, negative uses the pod's own")
+	cmd.Flags().DurationVar(&opts.DrainTimeout, "timeout", 5*time.Minute, "timeout to drain a node, evictions refused by PodDisruptionBudgets are retried until then")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "evict the pods not managed by a controller, they are not recreated on another node")
+	cmd.Flags().BoolVar(&opts.DeleteEmptyDirData, "delete-emptydir-data", false, "evict the pods with emptyDir volumes, the data of the volumes is lost")
+	if use == "reboot" {
+		cmd.Flags().DurationVar(&opts.ReadyTimeout, "ready-timeout", 10*time.Minute, "timeout for a rebooted node to be ready again")
+	}
+	return cmd
+}
+
+func newNodeReplaceCmd() *cobra.Command {
+	replaceArgs := &apply.ReplaceArgs{
+		ClusterName: &apply.ClusterName{},
+		SSH:         &apply.SSH{},
+	}
+	cmd := &cobra.Command{
+		Use:   "replace",
+		Short: "Replace a host of the cluster with a new one in one operation",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(replaceArgs.Old) == "" || strings.TrimSpace(replaceArgs.New) == "" {
+				return errors.New("both --old and --new must be specified")
+			}
+			if err := processor.ConfirmDeleteNodes(); err != nil {
+				return err
+			}
+			return apply.ReplaceNode(cmd, replaceArgs)
+		},
+	}
+	setRequireBuildahAnnotation(cmd)
+	replaceArgs.RegisterFlags(cmd.Flags())
+	cmd.Flags().BoolVar(&processor.ForceDelete, "force", false, "replace the host without confirmation")
+	return cmd
+}
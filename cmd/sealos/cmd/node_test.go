@@ -0,0 +1,133 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestNodeCmdFlags(t *testing.T) {
+	tests := []struct {
+		args  []string
+		flags map[string]string
+	}{
+		{
+			args:  []string{"cordon", "-c", "prod", "--nodes", "192.168.0.3"},
+			flags: map[string]string{"cluster": "prod", "nodes": "192.168.0.3"},
+		},
+		{
+			args:  []string{"drain", "--roles", "node", "--force", "--delete-emptydir-data", "--timeout", "1m"},
+			flags: map[string]string{"roles": "[node]", "force": "true", "delete-emptydir-data": "true", "timeout": "1m0s"},
+		},
+		{
+			args:  []string{"reboot", "--masters", "192.168.0.2", "--batch-size", "3", "--ready-timeout", "5m"},
+			flags: map[string]string{"masters": "192.168.0.2", "batch-size": "3", "ready-timeout": "5m0s"},
+		},
+		{
+			args:  []string{"replace", "-c", "prod", "--old", "192.168.0.3", "--new", "192.168.0.10", "-p", "s3cret", "--force"},
+			flags: map[string]string{"cluster": "prod", "old": "192.168.0.3", "new": "192.168.0.10", "passwd": "s3cret", "force": "true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			sub, args, err := newNodeCmd().Find(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := sub.ParseFlags(args); err != nil {
+				t.Fatalf("parse flag error: %v", err)
+			}
+			for name, want := range tt.flags {
+				flag := sub.Flags().Lookup(name)
+				if flag == nil {
+					t.Errorf("%s has no flag --%s", sub.Name(), name)
+					continue
+				}
+				if got := flag.Value.String(); got != want {
+					t.Errorf("%s --%s = %s, want %s", sub.Name(), name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeCmdRejectsUnknownFlags(t *testing.T) {
+	// --ready-timeout only makes sense for a reboot
+	sub, args, err := newNodeCmd().Find([]string{"drain", "--ready-timeout", "5m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.ParseFlags(args); err == nil {
+		t.Error("drain accepts --ready-timeout")
+	}
+}
+
+func TestNodeSelectorSelected(t *testing.T) {
+	cluster := &v2.Cluster{
+		Spec: v2.ClusterSpec{
+			Hosts: []v2.Host{
+				{IPS: []string{"192.168.0.2:22", "192.168.0.3:22"}, Roles: []string{v2.MASTER}},
+				{IPS: []string{"192.168.0.4:22", "192.168.0.5:2222"}, Roles: []string{v2.NODE}},
+			},
+		},
+	}
+	cluster.Name = "default"
+	tests := []struct {
+		name     string
+		selector nodeSelector
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "select by ip",
+			selector: nodeSelector{nodes: "192.168.0.5"},
+			want:     []string{"192.168.0.5:2222"},
+		},
+		{
+			name:     "select by role without duplicates",
+			selector: nodeSelector{masters: "192.168.0.3", roles: []string{v2.MASTER, v2.NODE}},
+			want:     []string{"192.168.0.3:22", "192.168.0.2:22", "192.168.0.4:22", "192.168.0.5:2222"},
+		},
+		{
+			name:     "host not in cluster",
+			selector: nodeSelector{nodes: "192.168.0.9"},
+			wantErr:  true,
+		},
+		{
+			name:     "role without hosts",
+			selector: nodeSelector{roles: []string{"gpu"}},
+			wantErr:  true,
+		},
+		{
+			name:    "nothing selected",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.selector.selected(cluster)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selected() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"k8s.io/kubectl/pkg/util/templates"
 
 	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/system"
 	"github.com/labring/sealos/pkg/utils/file"
@@ -64,7 +65,10 @@ func init() {
 			Message: "Cluster Management Commands:",
 			Commands: []*cobra.Command{
 				newApplyCmd(),
+				newBundleCmd(),
 				newCertCmd(),
+				newClusterCmd(),
+				newHistoryCmd(),
 				newRunCmd(),
 				newResetCmd(),
 				newStatusCmd(),
@@ -75,6 +79,7 @@ func init() {
 			Commands: []*cobra.Command{
 				newAddCmd(),
 				newDeleteCmd(),
+				newNodeCmd(),
 			},
 		},
 		{
@@ -123,6 +128,42 @@ func onBootOnDie() {
 
 	logger.CfgConsoleAndFileLogger(debug, constants.LogPath(), "sealos", false)
 	sreglog.CfgConsoleAndFileLogger(debug, constants.LogPath(), "sealos", false)
+
+	useCurrentCluster()
+}
+
+// useCurrentCluster replaces the default value of the -c/--cluster flag of the running command
+// with the cluster selected by `sealos cluster use`
+func useCurrentCluster() {
+	cmd, _, err := rootCmd.Find(os.Args[1:])
+	if err != nil {
+		return
+	}
+	flag := cmd.Flags().Lookup("cluster")
+	if flag == nil || flag.Changed || flag.DefValue != "default" {
+		return
+	}
+	if current := clusterfile.GetCurrentClusterName(); current != "" {
+		if isDestructiveCommand(cmd) {
+			// the cluster is not named in the command line, so it is always shown before it is changed
+			logger.Info("using current cluster %s, set -c/--cluster to choose another one", current)
+		} else {
+			logger.Debug("using current cluster %s", current)
+		}
+		_ = flag.Value.Set(current)
+	}
+}
+
+// destructiveCommands are the commands removing the clusters or their hosts
+var destructiveCommands = map[string]bool{"reset": true, "delete": true, "node": true}
+
+func isDestructiveCommand(cmd *cobra.Command) bool {
+	for ; cmd.HasParent(); cmd = cmd.Parent() {
+		if cmd.Parent() == rootCmd {
+			return destructiveCommands[cmd.Name()]
+		}
+	}
+	return false
 }
 
 func errExit(err error) {
@@ -90,6 +90,7 @@ func newRunCmd() *cobra.Command {
 		logger.Fatal(err)
 	}
 	runCmd.Flags().BoolVarP(&processor.ForceOverride, "force", "f", false, "force override app in this cluster")
+	runCmd.Flags().BoolVar(&processor.ForceUpgrade, "force-upgrade", false, "bypass the failed upgrade preflight checks")
 	runCmd.Flags().StringVarP(&transport, "transport", "t", buildah.OCIArchive,
 		fmt.Sprintf("load image transport from tar archive file.(optional value: %s, %s)", buildah.OCIArchive, buildah.DockerArchive))
 	return runCmd
@@ -22,6 +22,7 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/labring/sealos/pkg/audit"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/exec"
 	"github.com/labring/sealos/pkg/ssh"
@@ -51,8 +52,10 @@ func newScpCmd() *cobra.Command {
 		Short:   "Copy file to remote on specified nodes",
 		Example: exampleScp,
 		Args:    cobra.ExactArgs(2),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			targets := getTargets(cluster, ips, roles)
+			rec := audit.Begin(cluster, targets)
+			defer func() { rec.End(err) }()
 			return runCopy(cluster, targets, args)
 		},
 		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
@@ -53,7 +53,11 @@ type PaymentReconciler struct {
 	createDuration    time.Duration
 	accountConfig     pkgtypes.AccountConfig
 	userLock          map[uuid.UUID]*sync.Mutex
+	userLockMu        sync.Mutex
 	domain            string
+	// NotifyAddr is the address the payment providers post the payment results to, the payments are only polled if
+	// it is empty
+	NotifyAddr string
 }
 
 var (
@@ -68,6 +72,8 @@ const (
 
 	defaultReconcileDuration = 10 * time.Second
 	defaultCreateDuration    = 5 * time.Second
+	// defaultFallbackReconcileDuration is the polling duration if the payment results are notified
+	defaultFallbackReconcileDuration = time.Minute
 )
 
 //+kubebuilder:rbac:groups=account.sealos.io,resources=payments,verbs=get;list;watch;create;update;patch;delete
@@ -83,6 +89,21 @@ func (r *PaymentReconciler) SetupWithManager(mgr ctrl.Manager) (err error) {
 	r.reconcileDuration = defaultReconcileDuration
 	r.createDuration = defaultCreateDuration
 	r.userLock = make(map[uuid.UUID]*sync.Mutex)
+	var notifyServer *PaymentNotifyServer
+	if r.NotifyAddr != "" {
+		notifyServer = &PaymentNotifyServer{
+			Addr:      r.NotifyAddr,
+			Payment:   r,
+			Notifiers: newPaymentNotifiers(r.Logger),
+			Logger:    ctrl.Log.WithName("payment_notify"),
+		}
+		if len(notifyServer.Notifiers) == 0 {
+			r.Logger.Info("no payment notifier is configured, only poll the payments")
+			notifyServer = nil
+		} else {
+			r.reconcileDuration = defaultFallbackReconcileDuration
+		}
+	}
 	if duration := os.Getenv(EnvPaymentReconcileDuration); duration != "" {
 		reconcileDuration, err := time.ParseDuration(duration)
 		if err == nil {
@@ -107,6 +128,14 @@ func (r *PaymentReconciler) SetupWithManager(mgr ctrl.Manager) (err error) {
 	if err := mgr.Add(r); err != nil {
 		return fmt.Errorf("add payment controller failed: %w", err)
 	}
+	if notifyServer != nil {
+		if err := indexPaymentTradeNO(context.Background(), mgr); err != nil {
+			return fmt.Errorf("index payment trade failed: %w", err)
+		}
+		if err := mgr.Add(notifyServer); err != nil {
+			return fmt.Errorf("add payment notify server failed: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -206,44 +235,8 @@ func (r *PaymentReconciler) reconcilePayment(payment *accountv1.Payment) error {
 	}
 	switch status {
 	case pay.PaymentSuccess:
-		user, err := r.Account.AccountV2.GetUser(&pkgtypes.UserQueryOpts{ID: payment.Spec.UserID})
-		if err != nil {
-			return fmt.Errorf("get user failed: %w", err)
-		}
-		if r.userLock[user.UID] == nil {
-			r.userLock[user.UID] = &sync.Mutex{}
-		}
-		r.userLock[user.UID].Lock()
-		defer r.userLock[user.UID].Unlock()
-		userDiscount, err := r.Account.AccountV2.GetUserRechargeDiscount(&pkgtypes.UserQueryOpts{ID: payment.Spec.UserID})
-		if err != nil {
-			return fmt.Errorf("get user discount failed: %w", err)
-		}
-		//1¥ = 100WechatPayAmount; 1 WechatPayAmount = 10000 SealosAmount
-		payAmount := orderAmount * 10000
-		isFirstRecharge, gift := getFirstRechargeDiscount(payAmount, userDiscount)
-		paymentRaw := pkgtypes.PaymentRaw{
-			UserUID:         user.UID,
-			Amount:          payAmount,
-			Gift:            gift,
-			CreatedAt:       payment.CreationTimestamp.Time,
-			RegionUserOwner: getUsername(payment.Namespace),
-			Method:          payment.Spec.PaymentMethod,
-			TradeNO:         payment.Status.TradeNO,
-			CodeURL:         payment.Status.CodeURL,
-		}
-		if isFirstRecharge {
-			paymentRaw.ActivityType = pkgtypes.ActivityTypeFirstRecharge
-		}
-
-		if err = r.Account.AccountV2.Payment(&pkgtypes.Payment{
-			PaymentRaw: paymentRaw,
-		}); err != nil {
-			return fmt.Errorf("payment failed: %w", err)
-		}
-		payment.Status.Status = pay.PaymentSuccess
-		if err := r.Status().Update(context.Background(), payment); err != nil {
-			return fmt.Errorf("update payment failed: %w", err)
+		if err := r.confirmPayment(payment, orderAmount); err != nil {
+			return err
 		}
 	//case pay.PaymentFailed, pay.PaymentExpired:
 	default:
@@ -254,6 +247,73 @@ func (r *PaymentReconciler) reconcilePayment(payment *accountv1.Payment) error {
 	return nil
 }
 
+// confirmPayment credits the account of the user with a paid payment, the payment may be confirmed by both the
+// polling and the notification of the payment provider, it is credited once since the latest status is checked
+// under the lock of the user.
+func (r *PaymentReconciler) confirmPayment(payment *accountv1.Payment, orderAmount int64) error {
+	user, err := r.Account.AccountV2.GetUser(&pkgtypes.UserQueryOpts{ID: payment.Spec.UserID})
+	if err != nil {
+		return fmt.Errorf("get user failed: %w", err)
+	}
+	unlock := r.lockUser(user.UID)
+	defer unlock()
+	// the cache may not see the status updated by the other confirmation yet
+	payment = payment.DeepCopy()
+	if err := r.WatchClient.Get(context.Background(), client.ObjectKeyFromObject(payment), payment); err != nil {
+		// the payment is deleted once it is confirmed or expired
+		if client.IgnoreNotFound(err) == nil {
+			return nil
+		}
+		return fmt.Errorf("get payment failed: %w", err)
+	}
+	if payment.Status.Status == pay.PaymentSuccess {
+		return nil
+	}
+	userDiscount, err := r.Account.AccountV2.GetUserRechargeDiscount(&pkgtypes.UserQueryOpts{ID: payment.Spec.UserID})
+	if err != nil {
+		return fmt.Errorf("get user discount failed: %w", err)
+	}
+	//1¥ = 100WechatPayAmount; 1 WechatPayAmount = 10000 SealosAmount
+	payAmount := orderAmount * 10000
+	isFirstRecharge, gift := getFirstRechargeDiscount(payAmount, userDiscount)
+	paymentRaw := pkgtypes.PaymentRaw{
+		UserUID:         user.UID,
+		Amount:          payAmount,
+		Gift:            gift,
+		CreatedAt:       payment.CreationTimestamp.Time,
+		RegionUserOwner: getUsername(payment.Namespace),
+		Method:          payment.Spec.PaymentMethod,
+		TradeNO:         payment.Status.TradeNO,
+		CodeURL:         payment.Status.CodeURL,
+	}
+	if isFirstRecharge {
+		paymentRaw.ActivityType = pkgtypes.ActivityTypeFirstRecharge
+	}
+
+	if err = r.Account.AccountV2.Payment(&pkgtypes.Payment{
+		PaymentRaw: paymentRaw,
+	}); err != nil {
+		return fmt.Errorf("payment failed: %w", err)
+	}
+	payment.Status.Status = pay.PaymentSuccess
+	if err := r.Status().Update(context.Background(), payment); err != nil {
+		return fmt.Errorf("update payment failed: %w", err)
+	}
+	return nil
+}
+
+func (r *PaymentReconciler) lockUser(uid uuid.UUID) (unlock func()) {
+	r.userLockMu.Lock()
+	lock := r.userLock[uid]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		r.userLock[uid] = lock
+	}
+	r.userLockMu.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
 func (r *PaymentReconciler) expiredOvertimePayment(payment *accountv1.Payment) error {
 	if payment.CreationTimestamp.Time.Add(10 * time.Minute).After(time.Now()) {
 		return nil
@@ -0,0 +1,229 @@
+/*
+Copyright 2024 labring.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gorm.io/gorm"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	accountv1 "github.com/labring/sealos/controllers/account/api/v1"
+	"github.com/labring/sealos/controllers/pkg/pay"
+)
+
+const (
+	// seenNotificationTTL is how long a handled notification is remembered, it is longer than the time the signature
+	// of a notification is accepted in, so a replayed notification is either dropped here or rejected by the signature
+	// check
+	seenNotificationTTL = 10 * time.Minute
+
+	// paymentTradeNOField indexes the payments by the trade of the payment provider
+	paymentTradeNOField = "status.tradeNO"
+)
+
+// PaymentNotifyServer serves the endpoints the payment providers post the payment results to, so a payment is
+// credited once it is paid instead of at the next polling of the PaymentReconciler.
+// The endpoint of a payment method is /payment/<method>/notify.
+type PaymentNotifyServer struct {
+	Addr      string
+	Payment   *PaymentReconciler
+	Notifiers map[string]pay.Notifier
+	Logger    logr.Logger
+
+	// seen only saves the lookups of the notifications redelivered to this replica, the unique trade of the payment
+	// table is what keeps a payment from being credited twice, by the replicas or by the polling
+	seenLock sync.Mutex
+	seen     map[string]time.Time
+}
+
+var (
+	// Ensure PaymentNotifyServer implements the LeaderElectionRunnable and Runnable interface
+	_ manager.LeaderElectionRunnable = &PaymentNotifyServer{}
+	_ manager.Runnable               = &PaymentNotifyServer{}
+)
+
+// newPaymentNotifiers creates the notifiers of the payment methods which are configured
+func newPaymentNotifiers(logger logr.Logger) map[string]pay.Notifier {
+	notifiers := make(map[string]pay.Notifier)
+	for _, method := range []string{"stripe", "wechat"} {
+		notifier, err := pay.NewNotifier(method)
+		if err != nil {
+			logger.Info("payment notification is disabled", "method", method, "reason", err.Error())
+			continue
+		}
+		notifiers[method] = notifier
+	}
+	return notifiers
+}
+
+// NeedLeaderElection returns false since the providers may post the notifications to any replica.
+func (s *PaymentNotifyServer) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *PaymentNotifyServer) Start(ctx context.Context) error {
+	s.seen = make(map[string]time.Time)
+	mux := http.NewServeMux()
+	for method, notifier := range s.Notifiers {
+		mux.Handle(fmt.Sprintf("/payment/%s/notify", method), s.handler(method, notifier))
+	}
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			s.Logger.Error(err, "shutdown payment notify server failed")
+		}
+	}()
+	s.Logger.Info("starting payment notify server", "addr", s.Addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("payment notify server failed: %w", err)
+	}
+	return nil
+}
+
+// handler acknowledges a notification with 200 once it is handled, the provider delivers it again on any other
+// status code
+func (s *PaymentNotifyServer) handler(method string, notifier pay.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		req.Body = http.MaxBytesReader(w, req.Body, pay.MaxNotificationSize)
+		notification, err := notifier.ParseNotification(req)
+		switch {
+		case errors.Is(err, pay.ErrIgnoredNotification):
+			s.Logger.V(1).Info("ignore payment notification", "method", method, "reason", err.Error())
+			w.WriteHeader(http.StatusOK)
+			return
+		case errors.Is(err, pay.ErrInvalidNotification):
+			s.Logger.Info("reject payment notification", "method", method, "reason", err.Error())
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		case err != nil:
+			s.Logger.Error(err, "parse payment notification failed", "method", method)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		key := method + "/" + notification.ID
+		if s.isSeen(key) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if err := s.handleNotification(req.Context(), notification); err != nil {
+			s.Logger.Error(err, "handle payment notification failed", "method", method, "notification", notification.ID, "tradeNO", notification.TradeNO)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.markSeen(key)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *PaymentNotifyServer) handleNotification(ctx context.Context, notification *pay.Notification) error {
+	payment, err := s.getPayment(ctx, notification.TradeNO)
+	if err != nil {
+		return err
+	}
+	if payment == nil {
+		// the payment is deleted once it is confirmed or expired, only a payment which is not credited yet is retried
+		if notification.Status != pay.PaymentSuccess {
+			return nil
+		}
+		_, err := s.Payment.Account.AccountV2.GetPaymentWithTradeNO(notification.TradeNO)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("payment of trade %s not found", notification.TradeNO)
+		}
+		return fmt.Errorf("get credited payment of trade %s failed: %w", notification.TradeNO, err)
+	}
+	s.Logger.Info("payment notified", "payment", payment.Name, "namespace", payment.Namespace, "status", notification.Status)
+	switch notification.Status {
+	case pay.PaymentSuccess:
+		return s.Payment.confirmPayment(payment, notification.Amount)
+	default:
+		// the payment is deleted at the next polling
+		if payment.Status.Status == pay.PaymentSuccess || payment.Status.Status == notification.Status {
+			return nil
+		}
+		payment.Status.Status = notification.Status
+		if err := s.Payment.Status().Update(ctx, payment); err != nil {
+			return fmt.Errorf("update payment failed: %w", err)
+		}
+		return nil
+	}
+}
+
+func (s *PaymentNotifyServer) getPayment(ctx context.Context, tradeNO string) (*accountv1.Payment, error) {
+	paymentList := &accountv1.PaymentList{}
+	if err := s.Payment.List(ctx, paymentList, client.MatchingFields{paymentTradeNOField: tradeNO}); err != nil {
+		return nil, fmt.Errorf("list payment failed: %w", err)
+	}
+	if len(paymentList.Items) == 0 {
+		return nil, nil
+	}
+	return &paymentList.Items[0], nil
+}
+
+// indexPaymentTradeNO indexes the payments by the trade, so a notification looks up the payment without listing all
+// the payments
+func indexPaymentTradeNO(ctx context.Context, mgr ctrl.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(ctx, &accountv1.Payment{}, paymentTradeNOField, func(obj client.Object) []string {
+		tradeNO := obj.(*accountv1.Payment).Status.TradeNO
+		if tradeNO == "" {
+			return nil
+		}
+		return []string{tradeNO}
+	})
+}
+
+func (s *PaymentNotifyServer) isSeen(key string) bool {
+	s.seenLock.Lock()
+	defer s.seenLock.Unlock()
+	t, ok := s.seen[key]
+	return ok && time.Since(t) < seenNotificationTTL
+}
+
+func (s *PaymentNotifyServer) markSeen(key string) {
+	s.seenLock.Lock()
+	defer s.seenLock.Unlock()
+	now := time.Now()
+	for k, t := range s.seen {
+		if now.Sub(t) >= seenNotificationTTL {
+			delete(s.seen, k)
+		}
+	}
+	s.seen[key] = now
+}
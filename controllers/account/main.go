@@ -76,6 +76,7 @@ func main() {
 		leaseDuration        time.Duration
 		renewDeadline        time.Duration
 		retryPeriod          time.Duration
+		paymentNotifyAddr    string
 	)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -87,6 +88,7 @@ func main() {
 	flag.DurationVar(&leaseDuration, "leader-elect-lease-duration", 60*time.Second, "Duration that non-leader candidates will wait to force acquire leadership.")
 	flag.DurationVar(&renewDeadline, "leader-elect-renew-deadline", 40*time.Second, "Duration the acting master will retry refreshing leadership before giving up.")
 	flag.DurationVar(&retryPeriod, "leader-elect-retry-period", 5*time.Second, "Duration the LeaderElector clients should wait between tries of actions.")
+	flag.StringVar(&paymentNotifyAddr, "payment-notify-bind-address", "", "The address the payment notify endpoints /payment/<method>/notify bind to, e.g. :8090. The payments are only polled if it is empty, the endpoints must be exposed to the payment providers once it is set.")
 	opts := zap.Options{
 		Development: development,
 	}
@@ -254,6 +256,7 @@ func main() {
 		WatchClient: watchClient,
 		Client:      mgr.GetClient(),
 		Scheme:      mgr.GetScheme(),
+		NotifyAddr:  paymentNotifyAddr,
 	}).SetupWithManager(mgr); err != nil {
 		setupManagerError(err, "Payment")
 	}
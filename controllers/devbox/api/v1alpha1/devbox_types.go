@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -101,6 +102,37 @@ type Config struct {
 	Volumes []corev1.Volume `json:"volumes,omitempty"`
 }
 
+// AutoStopSpec stops a running devbox once it is idle for IdleTimeout. The devbox is idle if there are no ssh
+// sessions, no connections to the app ports and its cpu usage is below CPUThreshold, or only if its cpu usage is
+// below CPUThreshold with CPUOnly.
+// +kubebuilder:validation:XValidation:rule="!has(self.cpuOnly) || !self.cpuOnly || has(self.cpuThreshold)",message="cpuThreshold is required by cpuOnly"
+type AutoStopSpec struct {
+	// IdleTimeout is how long the devbox is idle before it is stopped, e.g. 30m
+	// +kubebuilder:validation:Required
+	IdleTimeout metav1.Duration `json:"idleTimeout"`
+	// CPUThreshold is the cpu usage below which the devbox is idle, the cpu usage is ignored if it is not set
+	// +kubebuilder:validation:Optional
+	CPUThreshold *resource.Quantity `json:"cpuThreshold,omitempty"`
+	// CPUOnly tells the idleness by the cpu usage alone, the ssh sessions and the app connections are ignored. The
+	// sessions and the connections are reported by an agent in the devbox, without the agent only a devbox with
+	// CPUOnly is ever stopped.
+	// +kubebuilder:validation:Optional
+	CPUOnly bool `json:"cpuOnly,omitempty"`
+}
+
+type ScheduleSpec struct {
+	// State is the state the devbox is changed to when the schedule runs
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=Running;Stopped
+	State DevboxState `json:"state"`
+	// Schedule is a cron expression of 5 fields, e.g. "0 9 * * 1-5"
+	// +kubebuilder:validation:Required
+	Schedule string `json:"schedule"`
+	// TimeZone is the IANA time zone of the schedule, e.g. Asia/Shanghai, UTC if it is not set
+	// +kubebuilder:validation:Optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
 // DevboxSpec defines the desired state of Devbox
 type DevboxSpec struct {
 	// +kubebuilder:validation:Required
@@ -113,6 +145,20 @@ type DevboxSpec struct {
 	// +kubebuilder:default=false
 	Squash bool `json:"squash"`
 
+	// CommitHistoryLimit is the number of the latest commits kept in the history, the images of the older commits
+	// are deleted from the registry unless they are released. The controller default applies if it is not set, which
+	// keeps all commits unless the controller is started with a limit.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	CommitHistoryLimit *int32 `json:"commitHistoryLimit,omitempty"`
+
+	// AutoStop stops the devbox once it is idle, the devbox runs until it is stopped if it is not set
+	// +kubebuilder:validation:Optional
+	AutoStop *AutoStopSpec `json:"autoStop,omitempty"`
+	// Schedules start and stop the devbox at the scheduled times
+	// +kubebuilder:validation:Optional
+	Schedules []ScheduleSpec `json:"schedules,omitempty"`
+
 	// +kubebuilder:validation:Required
 	Image string `json:"image"`
 
@@ -143,11 +189,22 @@ type NetworkStatus struct {
 	// +kubebuilder:validation:Optional
 	NodePort int32 `json:"nodePort"`
 
-	// todo TailNet
+	// TailNet is the address of the devbox in the tailnet
 	// +kubebuilder:validation:Optional
 	TailNet string `json:"tailnet"`
 }
 
+type AppPortStatus struct {
+	// Name is the name of the app port
+	Name string `json:"name"`
+	// Port is the port of the app service
+	Port int32 `json:"port"`
+	// Host is the generated host of the app port
+	Host string `json:"host"`
+	// URL is the public url of the app port
+	URL string `json:"url"`
+}
+
 type CommitStatus string
 
 const (
@@ -174,6 +231,28 @@ type CommitHistory struct {
 	ContainerID string `json:"containerID"`
 }
 
+type AutoTransitionReason string
+
+const (
+	// AutoTransitionReasonIdle means the devbox is stopped since it is idle
+	AutoTransitionReasonIdle AutoTransitionReason = "Idle"
+	// AutoTransitionReasonSchedule means the state of the devbox is changed by a schedule
+	AutoTransitionReasonSchedule AutoTransitionReason = "Schedule"
+)
+
+// AutoTransition is a state change made by the controller instead of the user
+type AutoTransition struct {
+	// State is the state the devbox is changed to
+	State DevboxState `json:"state"`
+	// Reason is why the state is changed
+	Reason AutoTransitionReason `json:"reason"`
+	// Message is the detail of the reason
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+	// Time is the time the state is changed
+	Time metav1.Time `json:"time"`
+}
+
 type DevboxPhase string
 
 const (
@@ -196,6 +275,8 @@ type DevboxStatus struct {
 	// +kubebuilder:validation:Optional
 	Network NetworkStatus `json:"network"`
 	// +kubebuilder:validation:Optional
+	AppPorts []AppPortStatus `json:"appPorts,omitempty"`
+	// +kubebuilder:validation:Optional
 	CommitHistory []*CommitHistory `json:"commitHistory"`
 	// +kubebuilder:validation:Optional
 	Phase DevboxPhase `json:"phase"`
@@ -204,6 +285,16 @@ type DevboxStatus struct {
 	State corev1.ContainerState `json:"state"`
 	// +kubebuilder:validation:Optional
 	LastTerminationState corev1.ContainerState `json:"lastState"`
+
+	// LastActivityTime is the last time the devbox is observed active, it is only tracked while AutoStop is set
+	// +kubebuilder:validation:Optional
+	LastActivityTime *metav1.Time `json:"lastActivityTime,omitempty"`
+	// LastScheduleTime is the last time the schedules are checked, schedules between it and now are applied
+	// +kubebuilder:validation:Optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastAutoTransition is the latest state change made by the idle policy or the schedules
+	// +kubebuilder:validation:Optional
+	LastAutoTransition *AutoTransition `json:"lastAutoTransition,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -211,6 +302,7 @@ type DevboxStatus struct {
 // +kubebuilder:printcolumn:name="State",type="string",JSONPath=".spec.state"
 // +kubebuilder:printcolumn:name="NetworkType",type="string",JSONPath=".status.network.type"
 // +kubebuilder:printcolumn:name="NodePort",type="integer",JSONPath=".status.network.nodePort"
+// +kubebuilder:printcolumn:name="TailNet",type="string",JSONPath=".status.network.tailnet"
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
 
 // Devbox is the Schema for the devboxes API
@@ -28,6 +28,18 @@ type DevBoxReleaseSpec struct {
 	NewTag string `json:"newTag"`
 	// +kubebuilder:validation:Optional
 	Notes string `json:"notes,omitempty"`
+	// Promotion copies the release into another repository once it is released
+	// +kubebuilder:validation:Optional
+	Promotion *PromotionSpec `json:"promotion,omitempty"`
+}
+
+type PromotionSpec struct {
+	// Repository is the target repository in the registry of the release, e.g. ns-user/app
+	// +kubebuilder:validation:Required
+	Repository string `json:"repository"`
+	// Tag is the target tag, the tag of the release is used if it is empty
+	// +kubebuilder:validation:Optional
+	Tag string `json:"tag,omitempty"`
 }
 
 type DevboxReleasePhase string
@@ -48,6 +60,47 @@ type DevBoxReleaseStatus struct {
 	Phase DevboxReleasePhase `json:"phase"`
 	// +kubebuilder:validation:Optional
 	OriginalImage string `json:"originalImage"`
+	// Provenance records where the release comes from
+	// +kubebuilder:validation:Optional
+	Provenance *Provenance `json:"provenance,omitempty"`
+	// +kubebuilder:validation:Optional
+	Promotion *PromotionStatus `json:"promotion,omitempty"`
+}
+
+type Provenance struct {
+	// Devbox is the name of the devbox released
+	Devbox string `json:"devbox"`
+	// Commit is the image of the commit the release is tagged from
+	Commit string `json:"commit"`
+	// Digest is the digest of the manifest of the release
+	Digest string `json:"digest"`
+}
+
+type PromotionStatus struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Pending
+	Phase DevboxReleasePhase `json:"phase"`
+	// Repository and Tag are the target of the promotion the status is of, the status is reset once the target in the
+	// spec is changed, so a failed promotion is retried by changing the target
+	// +kubebuilder:validation:Optional
+	Repository string `json:"repository,omitempty"`
+	// +kubebuilder:validation:Optional
+	Tag string `json:"tag,omitempty"`
+	// Image is the image the release is promoted to
+	// +kubebuilder:validation:Optional
+	Image string `json:"image,omitempty"`
+	// Digest is the digest of the manifest of the promoted image
+	// +kubebuilder:validation:Optional
+	Digest string `json:"digest,omitempty"`
+	// Attempts is the number of attempts to promote the release
+	// +kubebuilder:validation:Optional
+	Attempts int32 `json:"attempts,omitempty"`
+	// LastAttemptTime is the time of the last attempt
+	// +kubebuilder:validation:Optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+	// Message is the error of the last failed attempt
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -20,21 +20,67 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+type OperationAction string
+
+const (
+	// OperationActionRestart recreates the pod of a running devbox, a stopped devbox is started
+	OperationActionRestart OperationAction = "Restart"
+	// OperationActionStop stops the devbox
+	OperationActionStop OperationAction = "Stop"
+	// OperationActionStart starts the devbox
+	OperationActionStart OperationAction = "Start"
+	// OperationActionCommit commits the current state of a running devbox by recreating its pod
+	OperationActionCommit OperationAction = "Commit"
+	// OperationActionReset resets the devbox to a commit of its history, a running devbox is restarted from it
+	OperationActionReset OperationAction = "Reset"
+)
 
 // OperationRequestSpec defines the desired state of OperationRequest
 type OperationRequestSpec struct {
+	// DevboxName is the name of the devbox in the same namespace to operate
+	// +kubebuilder:validation:Required
+	DevboxName string `json:"devboxName"`
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=Restart;Stop;Start;Commit;Reset
+	Action OperationAction `json:"action"`
+	// Commit is the image of the commit to reset to, it is required by the Reset action
+	// +kubebuilder:validation:Optional
+	Commit string `json:"commit,omitempty"`
 }
 
+type OperationRequestPhase string
+
+const (
+	OperationRequestPhasePending    OperationRequestPhase = "Pending"
+	OperationRequestPhaseProcessing OperationRequestPhase = "Processing"
+	OperationRequestPhaseCompleted  OperationRequestPhase = "Completed"
+	OperationRequestPhaseFailed     OperationRequestPhase = "Failed"
+)
+
 // OperationRequestStatus defines the observed state of OperationRequest
 type OperationRequestStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
-	//+kubebuilder:default:=Pending
-	//+kubebuilder:validation:Enum=Pending;Processing;Completed;Failed
+	// Phase is the recently observed lifecycle phase of the request
+	// +kubebuilder:default:=Pending
+	// +kubebuilder:validation:Enum=Pending;Processing;Completed;Failed
+	Phase OperationRequestPhase `json:"phase,omitempty"`
+	// Pod is the pod of the devbox when the action is applied, it is used to tell when the pod is recreated
+	// +kubebuilder:validation:Optional
+	Pod string `json:"pod,omitempty"`
+	// StartTime is the time when the action is applied
+	// +kubebuilder:validation:Optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time when the request is completed or failed
+	// +kubebuilder:validation:Optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message is the reason why the request failed
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
 }
 
+// +kubebuilder:printcolumn:name="Devbox",type="string",JSONPath=".spec.devboxName"
+// +kubebuilder:printcolumn:name="Action",type="string",JSONPath=".spec.action"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 
@@ -25,6 +25,58 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppPortStatus) DeepCopyInto(out *AppPortStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppPortStatus.
+func (in *AppPortStatus) DeepCopy() *AppPortStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AppPortStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoStopSpec) DeepCopyInto(out *AutoStopSpec) {
+	*out = *in
+	out.IdleTimeout = in.IdleTimeout
+	if in.CPUThreshold != nil {
+		in, out := &in.CPUThreshold, &out.CPUThreshold
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoStopSpec.
+func (in *AutoStopSpec) DeepCopy() *AutoStopSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoStopSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoTransition) DeepCopyInto(out *AutoTransition) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoTransition.
+func (in *AutoTransition) DeepCopy() *AutoTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CommitHistory) DeepCopyInto(out *CommitHistory) {
 	*out = *in
@@ -128,8 +180,8 @@ func (in *DevBoxRelease) DeepCopyInto(out *DevBoxRelease) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevBoxRelease.
@@ -185,6 +237,11 @@ func (in *DevBoxReleaseList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DevBoxReleaseSpec) DeepCopyInto(out *DevBoxReleaseSpec) {
 	*out = *in
+	if in.Promotion != nil {
+		in, out := &in.Promotion, &out.Promotion
+		*out = new(PromotionSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevBoxReleaseSpec.
@@ -200,6 +257,16 @@ func (in *DevBoxReleaseSpec) DeepCopy() *DevBoxReleaseSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DevBoxReleaseStatus) DeepCopyInto(out *DevBoxReleaseStatus) {
 	*out = *in
+	if in.Provenance != nil {
+		in, out := &in.Provenance, &out.Provenance
+		*out = new(Provenance)
+		**out = **in
+	}
+	if in.Promotion != nil {
+		in, out := &in.Promotion, &out.Promotion
+		*out = new(PromotionStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevBoxReleaseStatus.
@@ -281,6 +348,21 @@ func (in *DevboxSpec) DeepCopyInto(out *DevboxSpec) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.CommitHistoryLimit != nil {
+		in, out := &in.CommitHistoryLimit, &out.CommitHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AutoStop != nil {
+		in, out := &in.AutoStop, &out.AutoStop
+		*out = new(AutoStopSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]ScheduleSpec, len(*in))
+		copy(*out, *in)
+	}
 	in.Config.DeepCopyInto(&out.Config)
 	in.NetworkSpec.DeepCopyInto(&out.NetworkSpec)
 	if in.NodeSelector != nil {
@@ -318,6 +400,11 @@ func (in *DevboxSpec) DeepCopy() *DevboxSpec {
 func (in *DevboxStatus) DeepCopyInto(out *DevboxStatus) {
 	*out = *in
 	out.Network = in.Network
+	if in.AppPorts != nil {
+		in, out := &in.AppPorts, &out.AppPorts
+		*out = make([]AppPortStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.CommitHistory != nil {
 		in, out := &in.CommitHistory, &out.CommitHistory
 		*out = make([]*CommitHistory, len(*in))
@@ -331,6 +418,19 @@ func (in *DevboxStatus) DeepCopyInto(out *DevboxStatus) {
 	}
 	in.State.DeepCopyInto(&out.State)
 	in.LastTerminationState.DeepCopyInto(&out.LastTerminationState)
+	if in.LastActivityTime != nil {
+		in, out := &in.LastActivityTime, &out.LastActivityTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastAutoTransition != nil {
+		in, out := &in.LastAutoTransition, &out.LastAutoTransition
+		*out = new(AutoTransition)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevboxStatus.
@@ -384,7 +484,7 @@ func (in *OperationRequest) DeepCopyInto(out *OperationRequest) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationRequest.
@@ -455,6 +555,14 @@ func (in *OperationRequestSpec) DeepCopy() *OperationRequestSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperationRequestStatus) DeepCopyInto(out *OperationRequestStatus) {
 	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationRequestStatus.
@@ -467,6 +575,55 @@ func (in *OperationRequestStatus) DeepCopy() *OperationRequestStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromotionSpec) DeepCopyInto(out *PromotionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromotionSpec.
+func (in *PromotionSpec) DeepCopy() *PromotionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PromotionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromotionStatus) DeepCopyInto(out *PromotionStatus) {
+	*out = *in
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromotionStatus.
+func (in *PromotionStatus) DeepCopy() *PromotionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PromotionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Provenance) DeepCopyInto(out *Provenance) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Provenance.
+func (in *Provenance) DeepCopy() *Provenance {
+	if in == nil {
+		return nil
+	}
+	out := new(Provenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RuntimeRef) DeepCopyInto(out *RuntimeRef) {
 	*out = *in
@@ -481,3 +638,18 @@ func (in *RuntimeRef) DeepCopy() *RuntimeRef {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleSpec) DeepCopyInto(out *ScheduleSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleSpec.
+func (in *ScheduleSpec) DeepCopy() *ScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
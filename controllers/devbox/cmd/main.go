@@ -19,7 +19,9 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"net/http"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -27,10 +29,12 @@ import (
 	"k8s.io/client-go/rest"
 
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
@@ -43,9 +47,12 @@ import (
 
 	devboxv1alpha1 "github.com/labring/sealos/controllers/devbox/api/v1alpha1"
 	"github.com/labring/sealos/controllers/devbox/internal/controller"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/helper"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/activity"
 	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/matcher"
 	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/registry"
 	utilresource "github.com/labring/sealos/controllers/devbox/internal/controller/utils/resource"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/tailnet"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -85,6 +92,22 @@ func main() {
 	var enablePodEnvMatcher bool
 	var enablePodPortMatcher bool
 	var enablePodEphemeralStorageMatcher bool
+	// commit history flag
+	var commitHistoryLimit int
+	// tailnet flag
+	var tailnetControlURL string
+	var tailnetAPIKey string
+	var tailnetUser string
+	var tailnetAgentImage string
+	// app ports flag
+	var appDomain string
+	var appIngressClassName string
+	var appTLSSecretName string
+	// idle flag
+	var idleCheckInterval time.Duration
+	// operation request flag
+	var operationRequestExpiration time.Duration
+	var operationRequestRetention time.Duration
 	// config qps and burst
 	var configQPS int
 	var configBurst int
@@ -116,6 +139,22 @@ func main() {
 	flag.BoolVar(&enablePodPortMatcher, "enable-pod-port-matcher", true, "If set, pod port matcher will be enabled")
 	flag.BoolVar(&enablePodEphemeralStorageMatcher, "enable-pod-ephemeral-storage-matcher", false, "If set, pod ephemeral storage matcher will be enabled")
 	// config qps and burst
+	flag.IntVar(&commitHistoryLimit, "commit-history-limit", 0, "The default number of commits kept in the history of a devbox, the images of the older commits are deleted from the registry. 0 keeps all commits, the images are only deleted once it is set")
+
+	flag.StringVar(&tailnetControlURL, "tailnet-control-url", "", "The url of the headscale compatible control server Tailnet devboxes join, the Tailnet network type is disabled if empty, it needs kubernetes 1.29 or later")
+	flag.StringVar(&tailnetAPIKey, "tailnet-api-key", os.Getenv("TAILNET_API_KEY"), "The api key of the tailnet control server, defaults to env TAILNET_API_KEY")
+	flag.StringVar(&tailnetUser, "tailnet-user", "devbox", "The user of the tailnet control server owning the nodes of devboxes")
+	flag.StringVar(&tailnetAgentImage, "tailnet-agent-image", "tailscale/tailscale:stable", "The image of the agent joining devboxes into the tailnet")
+
+	flag.StringVar(&appDomain, "app-domain", "", "The domain the hosts of devbox app ports are generated under, app ports are not exposed by ingress if empty")
+	flag.StringVar(&appIngressClassName, "app-ingress-class", "nginx", "The ingress class of the ingresses of devbox app ports")
+	flag.StringVar(&appTLSSecretName, "app-tls-secret", "wildcard-cert", "The tls secret of the ingresses of devbox app ports, ingresses serve http only if empty")
+
+	flag.DurationVar(&idleCheckInterval, "idle-check-interval", time.Minute, "How often the activity of a running devbox with auto stop is sampled")
+
+	flag.DurationVar(&operationRequestExpiration, "operation-request-expiration", 10*time.Minute, "The duration an operation request can take before it fails")
+	flag.DurationVar(&operationRequestRetention, "operation-request-retention", 3*time.Minute, "The duration a finished operation request is kept before it is deleted")
+
 	flag.IntVar(&configQPS, "config-qps", 50, "The qps of the config")
 	flag.IntVar(&configBurst, "config-burst", 100, "The burst of the config")
 	opts := zap.Options{
@@ -200,9 +239,10 @@ func main() {
 
 		NewCache: func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
 			opts.ByObject = map[client.Object]cache.ByObject{
-				&corev1.Service{}: {Label: cacheObjLabelSelector},
-				&corev1.Pod{}:     {Label: cacheObjLabelSelector},
-				&corev1.Secret{}:  {Label: cacheObjLabelSelector},
+				&corev1.Service{}:       {Label: cacheObjLabelSelector},
+				&corev1.Pod{}:           {Label: cacheObjLabelSelector},
+				&corev1.Secret{}:        {Label: cacheObjLabelSelector},
+				&networkingv1.Ingress{}: {Label: cacheObjLabelSelector},
 			}
 			return cache.New(config, opts)
 		},
@@ -226,10 +266,31 @@ func main() {
 		podMatchers = append(podMatchers, matcher.EphemeralStorageMatcher{})
 	}
 
+	var tailnetClient *tailnet.Client
+	if tailnetControlURL != "" {
+		if err = helper.CheckTailnetAgentSupported(discovery.NewDiscoveryClientForConfigOrDie(config)); err != nil {
+			setupLog.Error(err, "unable to enable the Tailnet network type")
+			os.Exit(1)
+		}
+		tailnetClient = &tailnet.Client{
+			ControlURL: tailnetControlURL,
+			APIKey:     tailnetAPIKey,
+			User:       tailnetUser,
+			HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	registryClient := &registry.Client{
+		Username: registryUser,
+		Password: registryPassword,
+	}
+
 	if err = (&controller.DevboxReconciler{
 		Client:              mgr.GetClient(),
 		Scheme:              mgr.GetScheme(),
 		CommitImageRegistry: registryAddr,
+		CommitHistoryLimit:  commitHistoryLimit,
+		Registry:            registryClient,
 		Recorder:            mgr.GetEventRecorderFor("devbox-controller"),
 		RequestRate: utilresource.RequestRate{
 			CPU:    requestCPURate,
@@ -240,32 +301,40 @@ func main() {
 			DefaultLimit:   resource.MustParse(limitEphemeralStorage),
 			MaximumLimit:   resource.MustParse(maximumLimitEphemeralStorage),
 		},
-		PodMatchers: podMatchers,
-		DebugMode:   debugMode,
+		PodMatchers:         podMatchers,
+		DebugMode:           debugMode,
+		Tailnet:             tailnetClient,
+		TailnetAgentImage:   tailnetAgentImage,
+		AppDomain:           appDomain,
+		AppIngressClassName: appIngressClassName,
+		AppTLSSecretName:    appTLSSecretName,
+		Activity:            &activity.PodSource{Reader: mgr.GetAPIReader()},
+		IdleCheckInterval:   idleCheckInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Devbox")
 		os.Exit(1)
 	}
 
 	if err = (&controller.DevBoxReleaseReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Registry: &registry.Client{
-			Username: registryUser,
-			Password: registryPassword,
-		},
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		Recorder:            mgr.GetEventRecorderFor("devboxrelease-controller"),
+		Registry:            registryClient,
+		CommitImageRegistry: registryAddr,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DevBoxRelease")
 		os.Exit(1)
 	}
-	//if err = (&controller.OperationRequestReconciler{
-	//	CommitImageRegistry: registryAddr,
-	//	Client:              mgr.GetClient(),
-	//	Scheme:              mgr.GetScheme(),
-	//}).SetupWithManager(mgr); err != nil {
-	//	setupLog.Error(err, "unable to create controller", "controller", "OperationRequest")
-	//	os.Exit(1)
-	//}
+	if err = (&controller.OperationRequestReconciler{
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		Recorder:   mgr.GetEventRecorderFor("operationrequest-controller"),
+		Expiration: operationRequestExpiration,
+		Retention:  operationRequestRetention,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "OperationRequest")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
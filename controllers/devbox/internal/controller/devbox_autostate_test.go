@@ -0,0 +1,212 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	devboxv1alpha1 "github.com/labring/sealos/controllers/devbox/api/v1alpha1"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/activity"
+	"github.com/labring/sealos/controllers/devbox/label"
+)
+
+type fakeActivitySource struct {
+	activity activity.Activity
+}
+
+func (s *fakeActivitySource) Sample(_ context.Context, _ *corev1.Pod) (*activity.Activity, error) {
+	a := s.activity
+	return &a, nil
+}
+
+func newAutoStateTest(devbox *devboxv1alpha1.Devbox) (*DevboxReconciler, *fakeActivitySource, map[string]string) {
+	recLabels := label.RecommendedLabels(&label.Recommended{
+		Name:      devbox.Name,
+		ManagedBy: label.DefaultManagedBy,
+		PartOf:    devboxv1alpha1.DevBoxPartOf,
+	})
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: devbox.Name + "-pod", Namespace: devbox.Namespace, Labels: recLabels},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	source := &fakeActivitySource{}
+	c := newFakeClient(devbox, pod)
+	r := &DevboxReconciler{
+		Activity:          source,
+		IdleCheckInterval: time.Minute,
+		Client:            c,
+		Scheme:            c.Scheme(),
+		Recorder:          record.NewFakeRecorder(100),
+	}
+	return r, source, recLabels
+}
+
+func TestSyncAutoStateIdle(t *testing.T) {
+	cpuThreshold := resource.MustParse("100m")
+	devbox := &devboxv1alpha1.Devbox{
+		ObjectMeta: metav1.ObjectMeta{Name: "devbox", Namespace: "default"},
+		Spec: devboxv1alpha1.DevboxSpec{
+			State:    devboxv1alpha1.DevboxStateRunning,
+			AutoStop: &devboxv1alpha1.AutoStopSpec{IdleTimeout: metav1.Duration{Duration: 30 * time.Minute}, CPUThreshold: &cpuThreshold},
+		},
+		Status: devboxv1alpha1.DevboxStatus{Phase: devboxv1alpha1.DevboxPhaseRunning},
+	}
+	r, source, recLabels := newAutoStateTest(devbox)
+	ctx := context.Background()
+
+	// the idle clock starts once the devbox is running
+	requeueAfter, err := r.syncAutoState(ctx, devbox, recLabels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if devbox.Status.LastActivityTime == nil || requeueAfter != r.IdleCheckInterval {
+		t.Fatalf("idle clock is not started, last activity %v, requeue after %s", devbox.Status.LastActivityTime, requeueAfter)
+	}
+
+	// ssh sessions keep the devbox running
+	devbox.Status.LastActivityTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	source.activity = activity.Activity{SSHSessions: ptr.To(1), AppConnections: ptr.To(0), CPU: resource.NewMilliQuantity(10, resource.DecimalSI)}
+	if _, err := r.syncAutoState(ctx, devbox, recLabels); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(devbox.Status.LastActivityTime.Time) > time.Minute || devbox.Spec.State != devboxv1alpha1.DevboxStateRunning {
+		t.Fatalf("active devbox should keep running, last activity %v", devbox.Status.LastActivityTime)
+	}
+
+	// the devbox may be in use if no agent reports the sessions and the connections
+	devbox.Status.LastActivityTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	source.activity = activity.Activity{CPU: resource.NewMilliQuantity(10, resource.DecimalSI)}
+	if _, err := r.syncAutoState(ctx, devbox, recLabels); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(devbox.Status.LastActivityTime.Time) > time.Minute || devbox.Spec.State != devboxv1alpha1.DevboxStateRunning {
+		t.Fatalf("devbox with unknown activity should keep running, last activity %v", devbox.Status.LastActivityTime)
+	}
+
+	// the devbox is stopped once it is idle for the timeout
+	devbox.Status.LastActivityTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	source.activity = activity.Activity{SSHSessions: ptr.To(0), AppConnections: ptr.To(0), CPU: resource.NewMilliQuantity(10, resource.DecimalSI)}
+	if _, err := r.syncAutoState(ctx, devbox, recLabels); err != nil {
+		t.Fatal(err)
+	}
+	latest := &devboxv1alpha1.Devbox{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), latest); err != nil {
+		t.Fatal(err)
+	}
+	if latest.Spec.State != devboxv1alpha1.DevboxStateStopped {
+		t.Fatalf("idle devbox should be stopped, got state %s", latest.Spec.State)
+	}
+	transition := latest.Status.LastAutoTransition
+	if transition == nil || transition.Reason != devboxv1alpha1.AutoTransitionReasonIdle || transition.State != devboxv1alpha1.DevboxStateStopped {
+		t.Errorf("idle transition is not recorded, got %+v", transition)
+	}
+	if latest.Status.LastActivityTime != nil {
+		t.Errorf("last activity should be cleared once the devbox is stopped, got %v", latest.Status.LastActivityTime)
+	}
+}
+
+func TestSyncAutoStateCPUOnly(t *testing.T) {
+	cpuThreshold := resource.MustParse("100m")
+	devbox := &devboxv1alpha1.Devbox{
+		ObjectMeta: metav1.ObjectMeta{Name: "devbox", Namespace: "default"},
+		Spec: devboxv1alpha1.DevboxSpec{
+			State: devboxv1alpha1.DevboxStateRunning,
+			AutoStop: &devboxv1alpha1.AutoStopSpec{
+				IdleTimeout:  metav1.Duration{Duration: 30 * time.Minute},
+				CPUThreshold: &cpuThreshold,
+				CPUOnly:      true,
+			},
+		},
+		Status: devboxv1alpha1.DevboxStatus{
+			Phase:            devboxv1alpha1.DevboxPhaseRunning,
+			LastActivityTime: &metav1.Time{Time: time.Now().Add(-time.Hour)},
+		},
+	}
+	r, source, recLabels := newAutoStateTest(devbox)
+	ctx := context.Background()
+
+	// the cpu usage keeps the devbox running without the sessions and the connections reported
+	source.activity = activity.Activity{CPU: resource.NewMilliQuantity(500, resource.DecimalSI)}
+	if _, err := r.syncAutoState(ctx, devbox, recLabels); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(devbox.Status.LastActivityTime.Time) > time.Minute || devbox.Spec.State != devboxv1alpha1.DevboxStateRunning {
+		t.Fatalf("busy devbox should keep running, last activity %v", devbox.Status.LastActivityTime)
+	}
+
+	devbox.Status.LastActivityTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	source.activity = activity.Activity{CPU: resource.NewMilliQuantity(10, resource.DecimalSI)}
+	if _, err := r.syncAutoState(ctx, devbox, recLabels); err != nil {
+		t.Fatal(err)
+	}
+	latest := &devboxv1alpha1.Devbox{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), latest); err != nil {
+		t.Fatal(err)
+	}
+	if latest.Spec.State != devboxv1alpha1.DevboxStateStopped {
+		t.Fatalf("devbox idle by the cpu usage should be stopped, got state %s", latest.Spec.State)
+	}
+}
+
+func TestSyncAutoStateSchedule(t *testing.T) {
+	devbox := &devboxv1alpha1.Devbox{
+		ObjectMeta: metav1.ObjectMeta{Name: "devbox", Namespace: "default"},
+		Spec: devboxv1alpha1.DevboxSpec{
+			State:     devboxv1alpha1.DevboxStateStopped,
+			Schedules: []devboxv1alpha1.ScheduleSpec{{State: devboxv1alpha1.DevboxStateRunning, Schedule: "* * * * *"}},
+		},
+		Status: devboxv1alpha1.DevboxStatus{Phase: devboxv1alpha1.DevboxPhaseStopped},
+	}
+	r, _, recLabels := newAutoStateTest(devbox)
+	ctx := context.Background()
+
+	// schedules run before the first check are not applied
+	requeueAfter, err := r.syncAutoState(ctx, devbox, recLabels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if devbox.Spec.State != devboxv1alpha1.DevboxStateStopped || devbox.Status.LastScheduleTime == nil {
+		t.Fatalf("schedule should not be applied in the first check, state %s", devbox.Spec.State)
+	}
+	if requeueAfter <= 0 || requeueAfter > time.Minute {
+		t.Errorf("requeue after %s, want the next minute", requeueAfter)
+	}
+
+	devbox.Status.LastScheduleTime = &metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+	if _, err := r.syncAutoState(ctx, devbox, recLabels); err != nil {
+		t.Fatal(err)
+	}
+	latest := &devboxv1alpha1.Devbox{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), latest); err != nil {
+		t.Fatal(err)
+	}
+	if latest.Spec.State != devboxv1alpha1.DevboxStateRunning {
+		t.Fatalf("scheduled devbox should be started, got state %s", latest.Spec.State)
+	}
+	if transition := latest.Status.LastAutoTransition; transition == nil || transition.Reason != devboxv1alpha1.AutoTransitionReasonSchedule {
+		t.Errorf("schedule transition is not recorded, got %+v", transition)
+	}
+}
@@ -18,16 +18,23 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	devboxv1alpha1 "github.com/labring/sealos/controllers/devbox/api/v1alpha1"
 	"github.com/labring/sealos/controllers/devbox/internal/controller/helper"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/activity"
 	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/matcher"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/registry"
 	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/resource"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/tailnet"
 	"github.com/labring/sealos/controllers/devbox/label"
 
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -48,6 +55,10 @@ import (
 // DevboxReconciler reconciles a Devbox object
 type DevboxReconciler struct {
 	CommitImageRegistry string
+	// CommitHistoryLimit is the default number of commits kept in the history of a devbox, 0 keeps all of them
+	CommitHistoryLimit int
+	// Registry deletes the images of the pruned commits
+	Registry *registry.Client
 
 	RequestRate      resource.RequestRate
 	EphemeralStorage resource.EphemeralStorage
@@ -56,6 +67,21 @@ type DevboxReconciler struct {
 
 	DebugMode bool
 
+	// Tailnet is the control server Tailnet devboxes join, nil if the Tailnet network type is not supported
+	Tailnet           *tailnet.Client
+	TailnetAgentImage string
+
+	// AppDomain is the domain the hosts of app ports are generated under, app ports are not exposed by ingress if empty
+	AppDomain           string
+	AppIngressClassName string
+	// AppTLSSecretName is the tls secret of the ingresses, usually a wildcard certificate of AppDomain
+	AppTLSSecretName string
+
+	// Activity samples the activity of the devboxes with AutoStop set, AutoStop is ignored if it is nil
+	Activity activity.Source
+	// IdleCheckInterval is how often the activity of a running devbox with AutoStop set is sampled
+	IdleCheckInterval time.Duration
+
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
@@ -64,13 +90,16 @@ type DevboxReconciler struct {
 // +kubebuilder:rbac:groups=devbox.sealos.io,resources=devboxes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=devbox.sealos.io,resources=devboxes/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=devbox.sealos.io,resources=devboxes/finalizers,verbs=update
+// +kubebuilder:rbac:groups=devbox.sealos.io,resources=devboxreleases,verbs=get;list;watch
 // +kubebuilder:rbac:groups=devbox.sealos.io,resources=runtimes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=devbox.sealos.io,resources=runtimeclasses,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=pods,verbs=*
 // +kubebuilder:rbac:groups="",resources=pods/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=services,verbs=*
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=*
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=*
 // +kubebuilder:rbac:groups="",resources=events,verbs=*
+// +kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get
 
 func (r *DevboxReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -129,8 +158,11 @@ func (r *DevboxReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	logger.Info("sync secret success")
 	r.Recorder.Eventf(devbox, corev1.EventTypeNormal, "Sync secret success", "Sync secret success")
 
-	// create service if network type is NodePort
-	if devbox.Spec.NetworkSpec.Type == devboxv1alpha1.NetworkTypeNodePort {
+	// the tailnet pre-auth key is replaced before it expires
+	var keyRenewAfter time.Duration
+	switch devbox.Spec.NetworkSpec.Type {
+	case devboxv1alpha1.NetworkTypeNodePort:
+		// create service if network type is NodePort
 		logger.Info("syncing service")
 		if err := r.Get(ctx, req.NamespacedName, devbox); err != nil {
 			return ctrl.Result{}, err
@@ -142,6 +174,40 @@ func (r *DevboxReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 		logger.Info("sync service success")
 		r.Recorder.Eventf(devbox, corev1.EventTypeNormal, "Sync service success", "Sync service success")
+	case devboxv1alpha1.NetworkTypeTailnet:
+		// the agent in the pod joins the tailnet with the pre-auth key kept in the secret
+		logger.Info("syncing tailnet auth key")
+		renewAfter, err := r.syncTailnetAuthKey(ctx, devbox)
+		if err != nil {
+			logger.Error(err, "sync tailnet auth key failed")
+			r.Recorder.Eventf(devbox, corev1.EventTypeWarning, "Sync tailnet auth key failed", "%v", err)
+			return ctrl.Result{}, err
+		}
+		keyRenewAfter = renewAfter
+		logger.Info("sync tailnet auth key success")
+	}
+
+	// create or update app service and ingresses
+	logger.Info("syncing app ports")
+	if err := r.syncAppPorts(ctx, devbox, recLabels); err != nil {
+		logger.Error(err, "sync app ports failed")
+		r.Recorder.Eventf(devbox, corev1.EventTypeWarning, "Sync app ports failed", "%v", err)
+		return ctrl.Result{}, err
+	}
+	logger.Info("sync app ports success")
+	r.Recorder.Eventf(devbox, corev1.EventTypeNormal, "Sync app ports success", "Sync app ports success")
+
+	// stop idle devboxes and apply schedules before syncing the pod, the pod follows the changed state
+	logger.Info("syncing auto state")
+	requeueAfter, err := r.syncAutoState(ctx, devbox, recLabels)
+	if err != nil {
+		logger.Error(err, "sync auto state failed")
+		r.Recorder.Eventf(devbox, corev1.EventTypeWarning, "Sync auto state failed", "%v", err)
+		return ctrl.Result{}, err
+	}
+	logger.Info("sync auto state success")
+	if keyRenewAfter > 0 && (requeueAfter == 0 || keyRenewAfter < requeueAfter) {
+		requeueAfter = keyRenewAfter
 	}
 
 	// create or update pod
@@ -159,8 +225,31 @@ func (r *DevboxReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	logger.Info("sync pod success")
 	r.Recorder.Eventf(devbox, corev1.EventTypeNormal, "Sync pod success", "Sync pod success")
 
+	logger.Info("pruning commit history")
+	if err := r.pruneCommitHistory(ctx, devbox); err != nil {
+		// the devbox works without pruning, it is retried in the next reconcile
+		logger.Error(err, "prune commit history failed")
+		r.Recorder.Eventf(devbox, corev1.EventTypeWarning, "Prune commit history failed", "%v", err)
+	}
+
+	if devbox.Spec.NetworkSpec.Type == devboxv1alpha1.NetworkTypeTailnet {
+		logger.Info("syncing tailnet node")
+		joined, err := r.syncTailnetNode(ctx, devbox)
+		if err != nil {
+			logger.Error(err, "sync tailnet node failed")
+			r.Recorder.Eventf(devbox, corev1.EventTypeWarning, "Sync tailnet node failed", "%v", err)
+			return ctrl.Result{}, err
+		}
+		if !joined {
+			// the agent joins the tailnet after the pod is started, nothing about the pod changes then
+			logger.Info("devbox has not joined the tailnet yet")
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		}
+		logger.Info("sync tailnet node success")
+	}
+
 	logger.Info("devbox reconcile success")
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *DevboxReconciler) syncSecret(ctx context.Context, devbox *devboxv1alpha1.Devbox, recLabels map[string]string) error {
@@ -380,14 +469,7 @@ func (r *DevboxReconciler) syncService(ctx context.Context, devbox *devboxv1alph
 		// only update some specific fields
 		service.Spec.Selector = expectServiceSpec.Selector
 		service.Spec.Type = expectServiceSpec.Type
-		if len(service.Spec.Ports) == 0 {
-			service.Spec.Ports = expectServiceSpec.Ports
-		} else {
-			service.Spec.Ports[0].Name = expectServiceSpec.Ports[0].Name
-			service.Spec.Ports[0].Port = expectServiceSpec.Ports[0].Port
-			service.Spec.Ports[0].TargetPort = expectServiceSpec.Ports[0].TargetPort
-			service.Spec.Ports[0].Protocol = expectServiceSpec.Ports[0].Protocol
-		}
+		service.Spec.Ports = mergeServicePorts(service.Spec.Ports, expectServiceSpec.Ports)
 		return controllerutil.SetControllerReference(devbox, service, r.Scheme)
 	}); err != nil {
 		return err
@@ -422,6 +504,477 @@ func (r *DevboxReconciler) syncService(ctx context.Context, devbox *devboxv1alph
 	return r.Status().Update(ctx, devbox)
 }
 
+// syncTailnetAuthKey keeps a valid pre-auth key in the secret, the key is replaced before it expires. It returns how
+// long the key is kept before it is replaced.
+func (r *DevboxReconciler) syncTailnetAuthKey(ctx context.Context, devbox *devboxv1alpha1.Devbox) (time.Duration, error) {
+	if r.Tailnet == nil {
+		return 0, fmt.Errorf("network type %s is not enabled, tailnet control server is not configured", devboxv1alpha1.NetworkTypeTailnet)
+	}
+	var renewAfter time.Duration
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: devbox.Namespace, Name: devbox.Name}, secret); err != nil {
+			return fmt.Errorf("failed to get secret: %w", err)
+		}
+		// a key without the expiration recorded is replaced too
+		if _, ok := secret.Data[helper.TailnetAuthKeySecretKey]; ok {
+			expiration, err := time.Parse(time.RFC3339, secret.Annotations[helper.TailnetAuthKeyExpirationAnnotation])
+			if err == nil {
+				if renewAfter = time.Until(r.Tailnet.RenewAt(expiration)); renewAfter > 0 {
+					return nil
+				}
+			}
+		}
+		key, expiration, err := r.Tailnet.CreatePreAuthKey(ctx)
+		if err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		secret.Data[helper.TailnetAuthKeySecretKey] = []byte(key)
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Annotations[helper.TailnetAuthKeyExpirationAnnotation] = expiration.Format(time.RFC3339)
+		renewAfter = time.Until(r.Tailnet.RenewAt(expiration))
+		return r.Update(ctx, secret)
+	})
+	return renewAfter, err
+}
+
+// syncTailnetNode reports the tailnet address of the devbox in its status, nodes left by the previous pods are
+// removed from the tailnet. It returns false if a running devbox has not joined the tailnet yet.
+func (r *DevboxReconciler) syncTailnetNode(ctx context.Context, devbox *devboxv1alpha1.Devbox) (bool, error) {
+	if r.Tailnet == nil {
+		return false, fmt.Errorf("network type %s is not enabled, tailnet control server is not configured", devboxv1alpha1.NetworkTypeTailnet)
+	}
+	nodes, err := r.Tailnet.ListNodes(ctx, helper.GetTailnetHostname(devbox))
+	if err != nil {
+		return false, err
+	}
+	var current *tailnet.Node
+	if devbox.Spec.State == devboxv1alpha1.DevboxStateRunning {
+		for i := range nodes {
+			if current == nil || nodes[i].CreatedAt.After(current.CreatedAt) {
+				current = &nodes[i]
+			}
+		}
+	}
+	for _, node := range nodes {
+		if current != nil && node.ID == current.ID {
+			continue
+		}
+		if err := r.Tailnet.DeleteNode(ctx, node.ID); err != nil {
+			return false, err
+		}
+	}
+
+	address := ""
+	if current != nil {
+		address = current.IPv4()
+	}
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestDevbox := &devboxv1alpha1.Devbox{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: devbox.Namespace, Name: devbox.Name}, latestDevbox); err != nil {
+			return err
+		}
+		if latestDevbox.Status.Network.TailNet == address && latestDevbox.Status.Network.Type == devboxv1alpha1.NetworkTypeTailnet {
+			return nil
+		}
+		latestDevbox.Status.Network.Type = devboxv1alpha1.NetworkTypeTailnet
+		latestDevbox.Status.Network.NodePort = 0
+		latestDevbox.Status.Network.TailNet = address
+		return r.Status().Update(ctx, latestDevbox)
+	}); err != nil {
+		return false, err
+	}
+	return devbox.Spec.State != devboxv1alpha1.DevboxStateRunning || address != "", nil
+}
+
+// mergeServicePorts returns the expected ports, the node ports already allocated to them are kept
+func mergeServicePorts(current, expected []corev1.ServicePort) []corev1.ServicePort {
+	ports := make([]corev1.ServicePort, 0, len(expected))
+	for _, port := range expected {
+		for _, c := range current {
+			if c.Port == port.Port && c.Protocol == port.Protocol {
+				port.NodePort = c.NodePort
+				break
+			}
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+func appServiceName(devbox *devboxv1alpha1.Devbox) string {
+	return devbox.Name + "-app"
+}
+
+func appIngressName(devbox *devboxv1alpha1.Devbox, port int32) string {
+	return fmt.Sprintf("%s-app-%d", devbox.Name, port)
+}
+
+// syncAppPorts exposes every app port by a ClusterIP service and an ingress with a generated host,
+// the ingresses of the removed app ports are deleted, and the public urls are reported in the status.
+func (r *DevboxReconciler) syncAppPorts(ctx context.Context, devbox *devboxv1alpha1.Devbox, recLabels map[string]string) error {
+	appPorts := helper.GetAppPorts(devbox)
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      appServiceName(devbox),
+			Namespace: devbox.Namespace,
+		},
+	}
+	if len(appPorts) == 0 {
+		if err := r.Delete(ctx, service); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete app service: %w", err)
+		}
+	} else if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		service.Labels = recLabels
+		service.Spec.Selector = recLabels
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+		service.Spec.Ports = mergeServicePorts(service.Spec.Ports, appPorts)
+		return controllerutil.SetControllerReference(devbox, service, r.Scheme)
+	}); err != nil {
+		return fmt.Errorf("failed to sync app service: %w", err)
+	}
+
+	var (
+		statuses = make([]devboxv1alpha1.AppPortStatus, 0, len(appPorts))
+		expected = make(map[string]bool)
+	)
+	for _, port := range appPorts {
+		status := devboxv1alpha1.AppPortStatus{Name: port.Name, Port: port.Port}
+		if r.AppDomain != "" {
+			status.Host = helper.GenerateAppHost(devbox, port.Port, r.AppDomain)
+			status.URL = "http://" + status.Host
+			if r.AppTLSSecretName != "" {
+				status.URL = "https://" + status.Host
+			}
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      appIngressName(devbox, port.Port),
+					Namespace: devbox.Namespace,
+				},
+			}
+			if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, ingress, func() error {
+				ingress.Labels = recLabels
+				ingress.Spec = r.generateAppIngressSpec(service.Name, port.Port, status.Host)
+				return controllerutil.SetControllerReference(devbox, ingress, r.Scheme)
+			}); err != nil {
+				return fmt.Errorf("failed to sync ingress of app port %d: %w", port.Port, err)
+			}
+			expected[ingress.Name] = true
+		}
+		statuses = append(statuses, status)
+	}
+
+	// delete the ingresses of the app ports removed, no ingress is managed without the app domain
+	if r.AppDomain != "" {
+		ingressList := &networkingv1.IngressList{}
+		if err := r.List(ctx, ingressList, client.InNamespace(devbox.Namespace), client.MatchingLabels(recLabels)); err != nil {
+			return fmt.Errorf("failed to list ingresses: %w", err)
+		}
+		for i := range ingressList.Items {
+			ingress := &ingressList.Items[i]
+			if expected[ingress.Name] || !metav1.IsControlledBy(ingress, devbox) {
+				continue
+			}
+			if err := r.Delete(ctx, ingress); client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("failed to delete ingress %s: %w", ingress.Name, err)
+			}
+		}
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestDevbox := &devboxv1alpha1.Devbox{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: devbox.Namespace, Name: devbox.Name}, latestDevbox); err != nil {
+			return err
+		}
+		if equality.Semantic.DeepEqual(latestDevbox.Status.AppPorts, statuses) {
+			return nil
+		}
+		latestDevbox.Status.AppPorts = statuses
+		return r.Status().Update(ctx, latestDevbox)
+	})
+}
+
+func (r *DevboxReconciler) generateAppIngressSpec(serviceName string, port int32, host string) networkingv1.IngressSpec {
+	spec := networkingv1.IngressSpec{
+		Rules: []networkingv1.IngressRule{
+			{
+				Host: host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{
+							{
+								Path:     "/",
+								PathType: ptr.To(networkingv1.PathTypePrefix),
+								Backend: networkingv1.IngressBackend{
+									Service: &networkingv1.IngressServiceBackend{
+										Name: serviceName,
+										Port: networkingv1.ServiceBackendPort{Number: port},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if r.AppIngressClassName != "" {
+		spec.IngressClassName = ptr.To(r.AppIngressClassName)
+	}
+	if r.AppTLSSecretName != "" {
+		spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: r.AppTLSSecretName}}
+	}
+	return spec
+}
+
+// syncAutoState changes the state of the devbox by its schedules and its idle policy, the reason of the change is
+// recorded in the status. It returns when the devbox has to be checked again, 0 if neither of them is set.
+func (r *DevboxReconciler) syncAutoState(ctx context.Context, devbox *devboxv1alpha1.Devbox, recLabels map[string]string) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	now := time.Now()
+	state := devbox.Spec.State
+	status := devbox.Status.DeepCopy()
+	var (
+		transition   *devboxv1alpha1.AutoTransition
+		requeueAfter time.Duration
+	)
+	requeue := func(d time.Duration) {
+		if d > 0 && (requeueAfter == 0 || d < requeueAfter) {
+			requeueAfter = d
+		}
+	}
+
+	status.LastScheduleTime = nil
+	if len(devbox.Spec.Schedules) > 0 {
+		// schedules run before the first check are not applied
+		after := now
+		if devbox.Status.LastScheduleTime != nil {
+			after = devbox.Status.LastScheduleTime.Time
+		}
+		schedule, next, err := helper.LastScheduled(devbox.Spec.Schedules, after, now)
+		if err != nil {
+			// the valid schedules still apply
+			logger.Error(err, "invalid schedules")
+			r.Recorder.Eventf(devbox, corev1.EventTypeWarning, "Invalid schedules", "%v", err)
+		}
+		if schedule != nil && schedule.State != state {
+			state = schedule.State
+			transition = &devboxv1alpha1.AutoTransition{
+				State:   state,
+				Reason:  devboxv1alpha1.AutoTransitionReasonSchedule,
+				Message: fmt.Sprintf("scheduled by %q", schedule.Schedule),
+				Time:    metav1.NewTime(now),
+			}
+		}
+		if !next.IsZero() {
+			requeue(next.Sub(now))
+		}
+		status.LastScheduleTime = &metav1.Time{Time: now}
+	}
+
+	idle := false
+	if devbox.Spec.AutoStop != nil && r.Activity != nil && state == devboxv1alpha1.DevboxStateRunning &&
+		devbox.Status.Phase == devboxv1alpha1.DevboxPhaseRunning {
+		active, err := r.sampleActivity(ctx, devbox, recLabels)
+		if err != nil {
+			return 0, err
+		}
+		if active || status.LastActivityTime == nil {
+			status.LastActivityTime = &metav1.Time{Time: now}
+		}
+		idleFor := now.Sub(status.LastActivityTime.Time)
+		if idleFor >= devbox.Spec.AutoStop.IdleTimeout.Duration {
+			idle = true
+			state = devboxv1alpha1.DevboxStateStopped
+			transition = &devboxv1alpha1.AutoTransition{
+				State:   state,
+				Reason:  devboxv1alpha1.AutoTransitionReasonIdle,
+				Message: fmt.Sprintf("idle for %s", idleFor.Truncate(time.Second)),
+				Time:    metav1.NewTime(now),
+			}
+		} else {
+			requeue(devbox.Spec.AutoStop.IdleTimeout.Duration - idleFor)
+			requeue(r.IdleCheckInterval)
+		}
+	}
+	if idle || devbox.Spec.AutoStop == nil || state != devboxv1alpha1.DevboxStateRunning ||
+		devbox.Status.Phase != devboxv1alpha1.DevboxPhaseRunning {
+		// the idle time is counted from the devbox is running again
+		status.LastActivityTime = nil
+	}
+
+	if state != devbox.Spec.State {
+		logger.Info("changing devbox state", "state", state, "reason", transition.Reason, "message", transition.Message)
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			latestDevbox := &devboxv1alpha1.Devbox{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), latestDevbox); err != nil {
+				return err
+			}
+			latestDevbox.Spec.State = state
+			return r.Update(ctx, latestDevbox)
+		}); err != nil {
+			return 0, err
+		}
+		devbox.Spec.State = state
+		status.LastAutoTransition = transition
+		r.Recorder.Eventf(devbox, corev1.EventTypeNormal, "Devbox state changed", "Changed devbox state to %s: %s", state, transition.Message)
+	}
+
+	if equality.Semantic.DeepEqual(status.LastActivityTime, devbox.Status.LastActivityTime) &&
+		equality.Semantic.DeepEqual(status.LastScheduleTime, devbox.Status.LastScheduleTime) &&
+		equality.Semantic.DeepEqual(status.LastAutoTransition, devbox.Status.LastAutoTransition) {
+		return requeueAfter, nil
+	}
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestDevbox := &devboxv1alpha1.Devbox{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), latestDevbox); err != nil {
+			return err
+		}
+		latestDevbox.Status.LastActivityTime = status.LastActivityTime
+		latestDevbox.Status.LastScheduleTime = status.LastScheduleTime
+		latestDevbox.Status.LastAutoTransition = status.LastAutoTransition
+		return r.Status().Update(ctx, latestDevbox)
+	}); err != nil {
+		return 0, err
+	}
+	devbox.Status.LastActivityTime = status.LastActivityTime
+	devbox.Status.LastScheduleTime = status.LastScheduleTime
+	devbox.Status.LastAutoTransition = status.LastAutoTransition
+	return requeueAfter, nil
+}
+
+// sampleActivity tells whether the devbox is in use, the devbox is active if its activity is unknown
+func (r *DevboxReconciler) sampleActivity(ctx context.Context, devbox *devboxv1alpha1.Devbox, recLabels map[string]string) (bool, error) {
+	var podList corev1.PodList
+	if err := r.List(ctx, &podList, client.InNamespace(devbox.Namespace), client.MatchingLabels(recLabels)); err != nil {
+		return false, err
+	}
+	if len(podList.Items) != 1 || podList.Items[0].Status.Phase != corev1.PodRunning {
+		return true, nil
+	}
+	a, err := r.Activity.Sample(ctx, &podList.Items[0])
+	if err != nil {
+		return false, err
+	}
+	autoStop := devbox.Spec.AutoStop
+	// the devbox may be in use if the sessions or the connections are not reported, unless only the cpu usage counts
+	if !autoStop.CPUOnly && (!a.Known() || *a.SSHSessions > 0 || *a.AppConnections > 0) {
+		return true, nil
+	}
+	if autoStop.CPUThreshold == nil {
+		return autoStop.CPUOnly, nil
+	}
+	return a.CPU == nil || a.CPU.Cmp(*autoStop.CPUThreshold) >= 0, nil
+}
+
+// pruneCommitHistory removes the commits out of the retention from the history and deletes their images from the
+// registry, the commits referenced by a DevBoxRelease are kept.
+func (r *DevboxReconciler) pruneCommitHistory(ctx context.Context, devbox *devboxv1alpha1.Devbox) error {
+	logger := log.FromContext(ctx)
+
+	limit := r.CommitHistoryLimit
+	if devbox.Spec.CommitHistoryLimit != nil {
+		limit = int(*devbox.Spec.CommitHistoryLimit)
+	}
+	latestDevbox := &devboxv1alpha1.Devbox{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), latestDevbox); err != nil {
+		return err
+	}
+	if limit <= 0 || len(latestDevbox.Status.CommitHistory) <= limit {
+		return nil
+	}
+
+	// releases record the image they are tagged from without the registry
+	releaseList := &devboxv1alpha1.DevBoxReleaseList{}
+	if err := r.List(ctx, releaseList, client.InNamespace(devbox.Namespace)); err != nil {
+		return fmt.Errorf("failed to list devbox releases: %w", err)
+	}
+	releasedImages := make(map[string]bool)
+	for _, release := range releaseList.Items {
+		if release.Spec.DevboxName == devbox.Name && release.Status.OriginalImage != "" {
+			releasedImages[release.Status.OriginalImage] = true
+		}
+	}
+	released := make(map[string]bool)
+	for _, c := range latestDevbox.Status.CommitHistory {
+		if _, repo, tag, err := helper.ParseImage(c.Image); err == nil && releasedImages[repo+":"+tag] {
+			released[c.Image] = true
+		}
+	}
+
+	kept, pruned := helper.PruneCommitHistory(latestDevbox.Status.CommitHistory, limit, released)
+	if len(pruned) == 0 {
+		return nil
+	}
+	keptImages := make(map[string]bool)
+	for _, c := range kept {
+		keptImages[c.Image] = true
+	}
+	var (
+		deleted []string
+		errs    []error
+	)
+	for _, c := range pruned {
+		// the image of a reset commit is shared with the commit it is reset to
+		if c.Image != "" && !keptImages[c.Image] && r.Registry != nil {
+			if err := r.deleteCommitImage(c.Image); err != nil {
+				// keep the commit to delete its image next time
+				logger.Error(err, "delete commit image failed", "image", c.Image)
+				errs = append(errs, err)
+				kept = append(kept, c)
+				continue
+			}
+		}
+		deleted = append(deleted, c.Image)
+	}
+	if len(deleted) == 0 {
+		return errors.Join(errs...)
+	}
+
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), latestDevbox); err != nil {
+			return err
+		}
+		prunedPods := make(map[string]bool)
+		for _, c := range pruned {
+			prunedPods[c.Pod] = true
+		}
+		for _, c := range kept {
+			delete(prunedPods, c.Pod)
+		}
+		history := make([]*devboxv1alpha1.CommitHistory, 0, len(latestDevbox.Status.CommitHistory))
+		for _, c := range latestDevbox.Status.CommitHistory {
+			if !prunedPods[c.Pod] {
+				history = append(history, c)
+			}
+		}
+		latestDevbox.Status.CommitHistory = history
+		return r.Status().Update(ctx, latestDevbox)
+	}); err != nil {
+		return err
+	}
+	devbox.Status.CommitHistory = latestDevbox.Status.CommitHistory
+	r.Recorder.Eventf(devbox, corev1.EventTypeNormal, "Commit history pruned", "Pruned %d commits beyond the limit %d: %s", len(deleted), limit, strings.Join(deleted, ", "))
+	return errors.Join(errs...)
+}
+
+func (r *DevboxReconciler) deleteCommitImage(image string) error {
+	hostName, imageName, tag, err := helper.ParseImage(image)
+	if err != nil {
+		return err
+	}
+	if err := r.Registry.DeleteImage(hostName, imageName, tag); err != nil && !errors.Is(err, registry.ErrorManifestNotFound) {
+		return fmt.Errorf("failed to delete image %s: %w", image, err)
+	}
+	return nil
+}
+
 // create a new pod, add predicated status to nextCommitHistory
 func (r *DevboxReconciler) createPod(ctx context.Context, devbox *devboxv1alpha1.Devbox, expectPod *corev1.Pod, nextCommitHistory *devboxv1alpha1.CommitHistory) error {
 	logger := log.FromContext(ctx)
@@ -502,6 +1055,18 @@ func (r *DevboxReconciler) removeAll(ctx context.Context, devbox *devboxv1alpha1
 	if err := r.deleteResourcesByLabels(ctx, &corev1.Service{}, devbox.Namespace, recLabels); err != nil {
 		return err
 	}
+	// Delete Ingress
+	if r.AppDomain != "" {
+		if err := r.deleteResourcesByLabels(ctx, &networkingv1.Ingress{}, devbox.Namespace, recLabels); err != nil {
+			return err
+		}
+	}
+	// Delete tailnet node
+	if devbox.Spec.NetworkSpec.Type == devboxv1alpha1.NetworkTypeTailnet && r.Tailnet != nil {
+		if err := r.Tailnet.DeleteNodes(ctx, helper.GetTailnetHostname(devbox)); err != nil {
+			return err
+		}
+	}
 	// Delete Secret
 	return r.deleteResourcesByLabels(ctx, &corev1.Secret{}, devbox.Namespace, recLabels)
 }
@@ -557,6 +1122,11 @@ func (r *DevboxReconciler) generateDevboxPod(devbox *devboxv1alpha1.Devbox, next
 			Resources:  helper.GenerateResourceRequirements(devbox, r.RequestRate, r.EphemeralStorage)},
 	}
 
+	var initContainers []corev1.Container
+	if devbox.Spec.NetworkSpec.Type == devboxv1alpha1.NetworkTypeTailnet && r.Tailnet != nil {
+		initContainers = append(initContainers, helper.GenerateTailnetAgentContainer(devbox, r.TailnetAgentImage, r.Tailnet.ControlURL))
+	}
+
 	terminationGracePeriodSeconds := 300
 	automountServiceAccountToken := false
 
@@ -575,9 +1145,10 @@ func (r *DevboxReconciler) generateDevboxPod(devbox *devboxv1alpha1.Devbox, next
 			AutomountServiceAccountToken:  ptr.To(automountServiceAccountToken),
 			RestartPolicy:                 corev1.RestartPolicyNever,
 
-			Hostname:   devbox.Name,
-			Containers: containers,
-			Volumes:    volumes,
+			Hostname:       devbox.Name,
+			InitContainers: initContainers,
+			Containers:     containers,
+			Volumes:        volumes,
 
 			RuntimeClassName: runtimeClassNamePtr,
 
@@ -610,11 +1181,15 @@ func (r *DevboxReconciler) generateImageName(devbox *devboxv1alpha1.Devbox) stri
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DevboxReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 10}).
 		For(&devboxv1alpha1.Devbox{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Owns(&corev1.Pod{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})). // enqueue request if pod spec/status is updated
 		Owns(&corev1.Service{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
-		Owns(&corev1.Secret{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
-		Complete(r)
+		Owns(&corev1.Secret{}, builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	// the ingresses are only managed with the app domain, so the controller runs without the ingress permissions
+	if r.AppDomain != "" {
+		b = b.Owns(&networkingv1.Ingress{}, builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+	return b.Complete(r)
 }
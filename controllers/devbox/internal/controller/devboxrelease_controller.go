@@ -22,25 +22,37 @@ import (
 	"fmt"
 	"time"
 
-	reference "github.com/google/go-containerregistry/pkg/name"
-
 	devboxv1alpha1 "github.com/labring/sealos/controllers/devbox/api/v1alpha1"
 	"github.com/labring/sealos/controllers/devbox/internal/controller/helper"
 	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/registry"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+const (
+	// promotionMaxAttempts is the number of attempts to promote a release before it is failed
+	promotionMaxAttempts = 5
+	// promotionBackoff is the delay after the first failed attempt, doubled after each further one
+	promotionBackoff = 10 * time.Second
+)
+
 // DevBoxReleaseReconciler reconciles a DevBoxRelease object
 type DevBoxReleaseReconciler struct {
+	// CommitImageRegistry is the registry of releases created before the provenance is recorded
+	CommitImageRegistry string
+
 	client.Client
 	Registry *registry.Client
 	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=devbox.sealos.io,resources=devboxreleases,verbs=get;list;watch;create;update;patch;delete
@@ -61,6 +73,14 @@ func (r *DevBoxReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			}
 		}
 	} else {
+		if !controllerutil.ContainsFinalizer(devboxRelease, devboxv1alpha1.FinalizerName) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.DeleteReleaseTag(ctx, devboxRelease); err != nil {
+			logger.Error(err, "Failed to delete release tag", "devbox", devboxRelease.Spec.DevboxName, "newTag", devboxRelease.Spec.NewTag)
+			r.Recorder.Eventf(devboxRelease, corev1.EventTypeWarning, "Delete release tag failed", "%v", err)
+			return ctrl.Result{}, err
+		}
 		if controllerutil.RemoveFinalizer(devboxRelease, devboxv1alpha1.FinalizerName) {
 			if err := r.Update(ctx, devboxRelease); err != nil {
 				return ctrl.Result{}, err
@@ -100,6 +120,10 @@ func (r *DevBoxReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			return ctrl.Result{}, err
 		}
 	}
+
+	if devboxRelease.Status.Phase == devboxv1alpha1.DevboxReleasePhaseSuccess && devboxRelease.Spec.Promotion != nil {
+		return r.PromoteRelease(ctx, devboxRelease)
+	}
 	logger.Info("Reconciliation complete", "devbox", devboxRelease.Spec.DevboxName, "newTag", devboxRelease.Spec.NewTag)
 	return ctrl.Result{}, nil
 }
@@ -124,12 +148,153 @@ func (r *DevBoxReleaseReconciler) CreateReleaseTag(ctx context.Context, devboxRe
 		logger.Error(err, "Failed to update status", "devbox", devboxRelease.Spec.DevboxName, "newTag", devboxRelease.Spec.NewTag)
 		return err
 	}
-	return r.Registry.TagImage(hostName, imageName, oldTag, devboxRelease.Spec.NewTag)
+	// the released image runs the release command of the devbox instead of the devbox itself
+	digest, err := r.Registry.MutateImage(hostName, imageName, oldTag, devboxRelease.Spec.NewTag, helper.GenerateReleaseImageConfig(devbox))
+	if err != nil {
+		return err
+	}
+	devboxRelease.Status.Provenance = &devboxv1alpha1.Provenance{
+		Devbox: devbox.Name,
+		Commit: hostName + "/" + imageName + ":" + oldTag,
+		Digest: digest,
+	}
+	return nil
 }
 
-func (r *DevBoxReleaseReconciler) DeleteReleaseTag(_ context.Context, _ *devboxv1alpha1.DevBoxRelease) error {
-	//todo only delete CR without doing any other operations
-	return nil
+// DeleteReleaseTag deletes the manifest the tag of a released release refers to by its digest, since docker
+// distribution does not support deleting tags. The manifest is kept if the tag is moved to another manifest or another
+// release refers to the same manifest. The commit image it is tagged from and the promoted image are kept.
+func (r *DevBoxReleaseReconciler) DeleteReleaseTag(ctx context.Context, devboxRelease *devboxv1alpha1.DevBoxRelease) error {
+	logger := log.FromContext(ctx)
+	if devboxRelease.Status.Phase != devboxv1alpha1.DevboxReleasePhaseSuccess {
+		return nil
+	}
+	hostName, imageName, err := r.getReleaseRepository(devboxRelease)
+	if err != nil {
+		return err
+	}
+	digest, err := r.Registry.GetManifestDigest(hostName, imageName, devboxRelease.Spec.NewTag)
+	if errors.Is(err, registry.ErrorManifestNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if provenance := devboxRelease.Status.Provenance; provenance != nil && provenance.Digest != "" && provenance.Digest != digest {
+		r.Recorder.Eventf(devboxRelease, corev1.EventTypeWarning, "Release tag kept", "tag %s refers to %s instead of the released %s", devboxRelease.Spec.NewTag, digest, provenance.Digest)
+		return nil
+	}
+	if shared, err := r.isManifestShared(ctx, devboxRelease, imageName, digest); err != nil || shared != "" {
+		if shared != "" {
+			r.Recorder.Eventf(devboxRelease, corev1.EventTypeNormal, "Release tag kept", "release %s refers to the same manifest %s", shared, digest)
+		}
+		return err
+	}
+	logger.Info("Deleting release manifest", "host", hostName, "image", imageName, "newTag", devboxRelease.Spec.NewTag, "digest", digest)
+	err = r.Registry.DeleteManifest(hostName, imageName, digest)
+	switch {
+	case errors.Is(err, registry.ErrorManifestNotFound):
+		return nil
+	case errors.Is(err, registry.ErrorUnsupported):
+		r.Recorder.Eventf(devboxRelease, corev1.EventTypeWarning, "Release tag kept", "registry does not support deleting manifests: %v", err)
+		return nil
+	}
+	return err
+}
+
+// isManifestShared returns the name of another release in the namespace referring to the manifest of the repository,
+// deleting the manifest would delete its tag too
+func (r *DevBoxReleaseReconciler) isManifestShared(ctx context.Context, devboxRelease *devboxv1alpha1.DevBoxRelease, imageName string, digest string) (string, error) {
+	releases := &devboxv1alpha1.DevBoxReleaseList{}
+	if err := r.List(ctx, releases, client.InNamespace(devboxRelease.Namespace)); err != nil {
+		return "", err
+	}
+	for _, release := range releases.Items {
+		if release.Name == devboxRelease.Name || !release.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if release.Status.Provenance == nil || release.Status.Provenance.Digest != digest {
+			continue
+		}
+		if _, otherImageName, err := r.getReleaseRepository(&release); err == nil && otherImageName == imageName {
+			return release.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// PromoteRelease copies the release into the repository of the promotion, failed attempts are retried with an
+// exponential backoff until promotionMaxAttempts is reached, the promotion is made again once its target is changed.
+func (r *DevBoxReleaseReconciler) PromoteRelease(ctx context.Context, devboxRelease *devboxv1alpha1.DevBoxRelease) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	repository, tag := devboxRelease.Spec.Promotion.Repository, devboxRelease.Spec.Promotion.Tag
+	if tag == "" {
+		tag = devboxRelease.Spec.NewTag
+	}
+	promotion := devboxRelease.Status.Promotion
+	if promotion == nil || promotion.Repository != repository || promotion.Tag != tag {
+		promotion = &devboxv1alpha1.PromotionStatus{
+			Phase:      devboxv1alpha1.DevboxReleasePhasePending,
+			Repository: repository,
+			Tag:        tag,
+		}
+		devboxRelease.Status.Promotion = promotion
+	}
+	if promotion.Phase == devboxv1alpha1.DevboxReleasePhaseSuccess || promotion.Phase == devboxv1alpha1.DevboxReleasePhaseFailed {
+		return ctrl.Result{}, nil
+	}
+	if promotion.Attempts > 0 && promotion.LastAttemptTime != nil {
+		if wait := time.Until(promotion.LastAttemptTime.Add(promotionBackoff << (promotion.Attempts - 1))); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	hostName, imageName, err := r.getReleaseRepository(devboxRelease)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	logger.Info("Promoting release", "devbox", devboxRelease.Spec.DevboxName, "newTag", devboxRelease.Spec.NewTag,
+		"repository", repository, "tag", tag, "attempt", promotion.Attempts+1)
+
+	promotion.Attempts++
+	promotion.LastAttemptTime = &metav1.Time{Time: time.Now()}
+	digest, err := r.Registry.CopyImage(hostName, imageName, devboxRelease.Spec.NewTag, repository, tag)
+	result := ctrl.Result{}
+	if err != nil {
+		logger.Error(err, "Failed to promote release", "devbox", devboxRelease.Spec.DevboxName, "newTag", devboxRelease.Spec.NewTag)
+		r.Recorder.Eventf(devboxRelease, corev1.EventTypeWarning, "Promote release failed", "%v", err)
+		promotion.Message = err.Error()
+		if promotion.Attempts >= promotionMaxAttempts {
+			promotion.Phase = devboxv1alpha1.DevboxReleasePhaseFailed
+		} else {
+			result.RequeueAfter = promotionBackoff << (promotion.Attempts - 1)
+		}
+	} else {
+		promotion.Phase = devboxv1alpha1.DevboxReleasePhaseSuccess
+		promotion.Image = hostName + "/" + repository + ":" + tag
+		promotion.Digest = digest
+		promotion.Message = ""
+	}
+	if err := r.Status().Update(ctx, devboxRelease); err != nil {
+		logger.Error(err, "Failed to update status", "devbox", devboxRelease.Spec.DevboxName, "newTag", devboxRelease.Spec.NewTag)
+		return ctrl.Result{}, err
+	}
+	return result, nil
+}
+
+// getReleaseRepository returns the registry host and the repository the release is tagged in
+func (r *DevBoxReleaseReconciler) getReleaseRepository(devboxRelease *devboxv1alpha1.DevBoxRelease) (string, string, error) {
+	image := devboxRelease.Status.OriginalImage
+	if devboxRelease.Status.Provenance != nil {
+		image = devboxRelease.Status.Provenance.Commit
+	} else if image != "" {
+		image = r.CommitImageRegistry + "/" + image
+	}
+	if image == "" {
+		return "", "", fmt.Errorf("image of the release is unknown")
+	}
+	hostName, imageName, _, err := helper.ParseImage(image)
+	return hostName, imageName, err
 }
 
 func (r *DevBoxReleaseReconciler) GetImageInfo(devbox *devboxv1alpha1.Devbox) (string, string, string, error) {
@@ -140,12 +305,7 @@ func (r *DevBoxReleaseReconciler) GetImageInfo(devbox *devboxv1alpha1.Devbox) (s
 	if commitHistory == nil {
 		return "", "", "", fmt.Errorf("no successful commit history found")
 	}
-	res, err := reference.ParseReference(commitHistory.Image)
-	if err != nil {
-		return "", "", "", err
-	}
-	repo := res.Context()
-	return repo.RegistryStr(), repo.RepositoryStr(), res.Identifier(), nil
+	return helper.ParseImage(commitHistory.Image)
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -0,0 +1,316 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	devboxv1alpha1 "github.com/labring/sealos/controllers/devbox/api/v1alpha1"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/registry"
+)
+
+// distributionDeleteHandler deletes manifests like docker distribution, deleting by tag is refused and deleting by
+// digest deletes the tags referring to the manifest too
+func distributionDeleteHandler(next http.Handler) http.Handler {
+	serve := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		next.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		repo, reference, ok := strings.Cut(strings.TrimPrefix(req.URL.Path, "/v2/"), "/manifests/")
+		if req.Method != http.MethodDelete || !ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if !strings.HasPrefix(reference, "sha256:") {
+			http.Error(w, `{"errors":[{"code":"UNSUPPORTED"}]}`, http.StatusMethodNotAllowed)
+			return
+		}
+		var tags struct {
+			Tags []string `json:"tags"`
+		}
+		_ = json.Unmarshal(serve(http.MethodGet, "/v2/"+repo+"/tags/list").Body.Bytes(), &tags)
+		for _, tag := range tags.Tags {
+			if serve(http.MethodHead, "/v2/"+repo+"/manifests/"+tag).Header().Get("Docker-Content-Digest") == reference {
+				serve(http.MethodDelete, "/v2/"+repo+"/manifests/"+tag)
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// newReleaseTestRegistry starts an in-process registry holding the commit image of a devbox and returns its host
+func newReleaseTestRegistry(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(distributionDeleteHandler(ggcrregistry.New(ggcrregistry.Logger(log.New(io.Discard, "", 0)))))
+	t.Cleanup(server.Close)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	image, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.ParseReference(host + "/default/devbox:commit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, image); err != nil {
+		t.Fatal(err)
+	}
+	return host
+}
+
+// newReleaseTest returns a devbox with a commit in the registry, a release of it and their reconciler, mutate changes
+// the release before it is created
+func newReleaseTest(host string, mutate func(release *devboxv1alpha1.DevBoxRelease)) (*DevBoxReleaseReconciler, *devboxv1alpha1.DevBoxRelease) {
+	devbox := &devboxv1alpha1.Devbox{
+		ObjectMeta: metav1.ObjectMeta{Name: "devbox", Namespace: "default"},
+		Spec: devboxv1alpha1.DevboxSpec{
+			Config: devboxv1alpha1.Config{
+				ReleaseCommand: []string{"/bin/bash", "-c"},
+				ReleaseArgs:    []string{"/home/devbox/project/entrypoint.sh"},
+				WorkingDir:     "/home/devbox/project",
+				Env:            []corev1.EnvVar{{Name: "MODE", Value: "production"}},
+				AppPorts:       []corev1.ServicePort{{Name: "web", Port: 8080}},
+			},
+		},
+		Status: devboxv1alpha1.DevboxStatus{
+			CommitHistory: []*devboxv1alpha1.CommitHistory{{
+				Image:            host + "/default/devbox:commit",
+				Pod:              "devbox-commit",
+				Time:             metav1.Now(),
+				Status:           devboxv1alpha1.CommitStatusSuccess,
+				PredicatedStatus: devboxv1alpha1.CommitStatusSuccess,
+			}},
+		},
+	}
+	release := &devboxv1alpha1.DevBoxRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "devbox-v1", Namespace: "default"},
+		Spec:       devboxv1alpha1.DevBoxReleaseSpec{DevboxName: "devbox", NewTag: "v1"},
+	}
+	if mutate != nil {
+		mutate(release)
+	}
+	c := newFakeClient(devbox, release)
+	return &DevBoxReleaseReconciler{
+		CommitImageRegistry: host,
+		Client:              c,
+		Registry:            &registry.Client{},
+		Scheme:              c.Scheme(),
+		Recorder:            record.NewFakeRecorder(100),
+	}, release
+}
+
+func reconcileDevBoxRelease(t *testing.T, r *DevBoxReleaseReconciler, release *devboxv1alpha1.DevBoxRelease) reconcile.Result {
+	t.Helper()
+	result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(release)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(release), release); err != nil && !errors.IsNotFound(err) {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestDevBoxReleaseTagAndDelete(t *testing.T) {
+	host := newReleaseTestRegistry(t)
+	r, release := newReleaseTest(host, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 3 && release.Status.Phase != devboxv1alpha1.DevboxReleasePhaseSuccess; i++ {
+		reconcileDevBoxRelease(t, r, release)
+	}
+	if release.Status.Phase != devboxv1alpha1.DevboxReleasePhaseSuccess {
+		t.Fatalf("release should be released, got phase %s", release.Status.Phase)
+	}
+	releaseDigest, err := r.Registry.GetManifestDigest(host, "default/devbox", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := devboxv1alpha1.Provenance{Devbox: "devbox", Commit: host + "/default/devbox:commit", Digest: releaseDigest}
+	if release.Status.Provenance == nil || *release.Status.Provenance != want {
+		t.Fatalf("provenance = %+v, want %+v", release.Status.Provenance, want)
+	}
+
+	// the released image runs the release command with the config of the devbox
+	ref, err := name.ParseReference(host + "/default/devbox:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := remote.Image(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := image.ConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(config.Config.Entrypoint, " ") + " " + strings.Join(config.Config.Cmd, " "); got != "/bin/bash -c /home/devbox/project/entrypoint.sh" {
+		t.Errorf("released image runs %q", got)
+	}
+	if _, ok := config.Config.ExposedPorts["8080/tcp"]; !ok || config.Config.WorkingDir != "/home/devbox/project" {
+		t.Errorf("released image config = %+v", config.Config)
+	}
+	if env := strings.Join(config.Config.Env, ","); !strings.Contains(env, "MODE=production") {
+		t.Errorf("released image env = %s", env)
+	}
+	if layers, err := image.Layers(); err != nil || len(layers) != 2 {
+		t.Errorf("released image should share the layers of the commit, got %d layers, %v", len(layers), err)
+	}
+
+	if err := r.Delete(ctx, release); err != nil {
+		t.Fatal(err)
+	}
+	reconcileDevBoxRelease(t, r, release)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(release), release); !errors.IsNotFound(err) {
+		t.Errorf("release should be deleted once the tag is deleted, got %v", err)
+	}
+	if _, err := r.Registry.GetManifestDigest(host, "default/devbox", "v1"); err != registry.ErrorManifestNotFound {
+		t.Errorf("release tag should be deleted, got %v", err)
+	}
+	if _, err := r.Registry.GetManifestDigest(host, "default/devbox", "commit"); err != nil {
+		t.Errorf("commit image should be kept, got %v", err)
+	}
+}
+
+func TestDevBoxReleasePromotion(t *testing.T) {
+	host := newReleaseTestRegistry(t)
+	r, release := newReleaseTest(host, func(release *devboxv1alpha1.DevBoxRelease) {
+		release.Spec.Promotion = &devboxv1alpha1.PromotionSpec{Repository: "default/app"}
+	})
+
+	for i := 0; i < 3 && release.Status.Promotion == nil; i++ {
+		reconcileDevBoxRelease(t, r, release)
+	}
+	promotion := release.Status.Promotion
+	if promotion == nil || promotion.Phase != devboxv1alpha1.DevboxReleasePhaseSuccess || promotion.Attempts != 1 {
+		t.Fatalf("release should be promoted, got %+v", promotion)
+	}
+	if promotion.Image != host+"/default/app:v1" || promotion.Digest != release.Status.Provenance.Digest {
+		t.Errorf("promoted image %s@%s, want %s/default/app:v1@%s", promotion.Image, promotion.Digest, host, release.Status.Provenance.Digest)
+	}
+	if digest, err := r.Registry.GetManifestDigest(host, "default/app", "v1"); err != nil || digest != promotion.Digest {
+		t.Errorf("promoted image digest = %s, %v", digest, err)
+	}
+}
+
+func TestDevBoxReleasePromotionBackoff(t *testing.T) {
+	host := newReleaseTestRegistry(t)
+	r, release := newReleaseTest(host, func(release *devboxv1alpha1.DevBoxRelease) {
+		// the promotion fails since the release tag is missing in the registry
+		release.Spec.Promotion = &devboxv1alpha1.PromotionSpec{Repository: "default/app", Tag: "latest"}
+		release.Status = devboxv1alpha1.DevBoxReleaseStatus{
+			Phase:      devboxv1alpha1.DevboxReleasePhaseSuccess,
+			Provenance: &devboxv1alpha1.Provenance{Devbox: "devbox", Commit: host + "/default/devbox:commit"},
+		}
+	})
+
+	result := reconcileDevBoxRelease(t, r, release)
+	promotion := release.Status.Promotion
+	if promotion == nil || promotion.Phase != devboxv1alpha1.DevboxReleasePhasePending || promotion.Attempts != 1 || promotion.Message == "" {
+		t.Fatalf("promotion should be retried, got %+v", promotion)
+	}
+	if result.RequeueAfter != promotionBackoff {
+		t.Errorf("requeue after %s, want %s", result.RequeueAfter, promotionBackoff)
+	}
+	// no attempt is made before the backoff expires
+	if result = reconcileDevBoxRelease(t, r, release); release.Status.Promotion.Attempts != 1 || result.RequeueAfter <= 0 {
+		t.Errorf("promotion should wait for the backoff, got %+v, %+v", release.Status.Promotion, result)
+	}
+
+	release.Status.Promotion.Attempts = promotionMaxAttempts - 1
+	release.Status.Promotion.LastAttemptTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	if err := r.Status().Update(context.Background(), release); err != nil {
+		t.Fatal(err)
+	}
+	reconcileDevBoxRelease(t, r, release)
+	if release.Status.Promotion.Phase != devboxv1alpha1.DevboxReleasePhaseFailed {
+		t.Errorf("promotion should fail after %d attempts, got %+v", promotionMaxAttempts, release.Status.Promotion)
+	}
+
+	// a failed promotion is retried once the target is changed
+	release.Spec.Promotion.Tag = "stable"
+	if err := r.Update(context.Background(), release); err != nil {
+		t.Fatal(err)
+	}
+	reconcileDevBoxRelease(t, r, release)
+	promotion = release.Status.Promotion
+	if promotion.Phase != devboxv1alpha1.DevboxReleasePhasePending || promotion.Attempts != 1 || promotion.Tag != "stable" {
+		t.Errorf("promotion should be retried for the new target, got %+v", promotion)
+	}
+}
+
+func TestDevBoxReleaseDeleteSharedManifest(t *testing.T) {
+	host := newReleaseTestRegistry(t)
+	r, release := newReleaseTest(host, nil)
+	ctx := context.Background()
+	// v2 releases the same commit with the same config, so both tags refer to the same manifest
+	other := &devboxv1alpha1.DevBoxRelease{
+		ObjectMeta: metav1.ObjectMeta{Name: "devbox-v2", Namespace: "default"},
+		Spec:       devboxv1alpha1.DevBoxReleaseSpec{DevboxName: "devbox", NewTag: "v2"},
+	}
+	if err := r.Create(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		reconcileDevBoxRelease(t, r, release)
+		reconcileDevBoxRelease(t, r, other)
+	}
+	if release.Status.Provenance == nil || other.Status.Provenance == nil || release.Status.Provenance.Digest != other.Status.Provenance.Digest {
+		t.Fatalf("releases should refer to the same manifest, got %+v and %+v", release.Status.Provenance, other.Status.Provenance)
+	}
+
+	if err := r.Delete(ctx, release); err != nil {
+		t.Fatal(err)
+	}
+	reconcileDevBoxRelease(t, r, release)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(release), release); !errors.IsNotFound(err) {
+		t.Errorf("release should be deleted, got %v", err)
+	}
+	if digest, err := r.Registry.GetManifestDigest(host, "default/devbox", "v2"); err != nil || digest != other.Status.Provenance.Digest {
+		t.Errorf("manifest of the other release should be kept, got %s, %v", digest, err)
+	}
+
+	if err := r.Delete(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	reconcileDevBoxRelease(t, r, other)
+	if _, err := r.Registry.GetManifestDigest(host, "default/devbox", "v2"); err != registry.ErrorManifestNotFound {
+		t.Errorf("manifest should be deleted with the last release referring to it, got %v", err)
+	}
+}
@@ -15,21 +15,31 @@
 package helper
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/pem"
 
+	reference "github.com/google/go-containerregistry/pkg/name"
 	"golang.org/x/crypto/ssh"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
 	"k8s.io/utils/ptr"
 
 	devboxv1alpha1 "github.com/labring/sealos/controllers/devbox/api/v1alpha1"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/cron"
+	"github.com/labring/sealos/controllers/devbox/internal/controller/utils/registry"
 	utilsresource "github.com/labring/sealos/controllers/devbox/internal/controller/utils/resource"
 	"github.com/labring/sealos/controllers/devbox/label"
 )
@@ -381,3 +391,226 @@ func GetArgs(devbox *devboxv1alpha1.Devbox) []string {
 func IsExceededQuotaError(err error) bool {
 	return strings.Contains(err.Error(), "exceeded quota")
 }
+
+const (
+	TailnetAuthKeySecretKey = "SEALOS_DEVBOX_TAILNET_AUTH_KEY"
+	// TailnetAuthKeyExpirationAnnotation records when the pre-auth key in the secret expires
+	TailnetAuthKeyExpirationAnnotation = "devbox.sealos.io/tailnet-auth-key-expiration"
+	tailnetAgentName        = "tailnet-agent"
+)
+
+// GetTailnetHostname returns the hostname of the devbox in the tailnet, it is unique across namespaces
+func GetTailnetHostname(devbox *devboxv1alpha1.Devbox) string {
+	hostname := devbox.Namespace + "-" + devbox.Name
+	if len(hostname) > 63 {
+		hostname = hostname[:63]
+	}
+	return strings.TrimRight(hostname, "-")
+}
+
+// TailnetMinKubernetesVersion is the version native sidecars are enabled by default since, kubernetes 1.28 needs the
+// SidecarContainers feature gate which can not be detected, and without it the agent blocks the pod as an init container.
+var TailnetMinKubernetesVersion = version.MajorMinor(1, 29)
+
+// CheckTailnetAgentSupported returns an error if the cluster can not run the tailnet agent as a native sidecar
+func CheckTailnetAgentSupported(d discovery.ServerVersionInterface) error {
+	info, err := d.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get the kubernetes version: %w", err)
+	}
+	v, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return fmt.Errorf("failed to parse the kubernetes version %s: %w", info.GitVersion, err)
+	}
+	if !v.AtLeast(TailnetMinKubernetesVersion) {
+		return fmt.Errorf("network type %s needs kubernetes %s or later to run the agent as a native sidecar, the cluster runs %s",
+			devboxv1alpha1.NetworkTypeTailnet, TailnetMinKubernetesVersion, info.GitVersion)
+	}
+	return nil
+}
+
+// GenerateTailnetAgentContainer generates the agent joining the devbox into the tailnet. It runs as a native sidecar,
+// so that the status of the devbox container is still the first one of the pod. The agent uses userspace networking
+// which forwards the connections to the tailnet address to the devbox container without any privilege. The cluster is checked by CheckTailnetAgentSupported.
+func GenerateTailnetAgentContainer(devbox *devboxv1alpha1.Devbox, image, controlURL string) corev1.Container {
+	return corev1.Container{
+		Name:          tailnetAgentName,
+		Image:         image,
+		RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways),
+		Env: []corev1.EnvVar{
+			{
+				Name: "TS_AUTHKEY",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: devbox.Name},
+						Key:                  TailnetAuthKeySecretKey,
+					},
+				},
+			},
+			{Name: "TS_HOSTNAME", Value: GetTailnetHostname(devbox)},
+			{Name: "TS_EXTRA_ARGS", Value: "--login-server=" + controlURL},
+			{Name: "TS_USERSPACE", Value: "true"},
+			// keep the state in memory, the node is registered again once the pod is recreated
+			{Name: "TS_KUBE_SECRET", Value: ""},
+			{Name: "TS_STATE_DIR", Value: ""},
+		},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("10m"),
+				corev1.ResourceMemory: resource.MustParse("32Mi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("200m"),
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+			},
+		},
+	}
+}
+
+// GetAppPorts returns the app ports of the devbox, the target port and the protocol are defaulted
+func GetAppPorts(devbox *devboxv1alpha1.Devbox) []corev1.ServicePort {
+	ports := make([]corev1.ServicePort, 0, len(devbox.Spec.Config.AppPorts))
+	for _, p := range devbox.Spec.Config.AppPorts {
+		port := corev1.ServicePort{
+			Name:       p.Name,
+			Port:       p.Port,
+			TargetPort: p.TargetPort,
+			Protocol:   p.Protocol,
+		}
+		if port.Name == "" {
+			port.Name = fmt.Sprintf("app-port-%d", p.Port)
+		}
+		if port.TargetPort.IntValue() == 0 && port.TargetPort.StrVal == "" {
+			port.TargetPort = intstr.FromInt32(p.Port)
+		}
+		if port.Protocol == "" {
+			port.Protocol = corev1.ProtocolTCP
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// GenerateReleaseImageConfig generates the config a released image runs the devbox as an application with, the
+// release command and args are its entrypoint and cmd, and the app ports are exposed
+func GenerateReleaseImageConfig(devbox *devboxv1alpha1.Devbox) *registry.ImageConfig {
+	config := &registry.ImageConfig{
+		Entrypoint: devbox.Spec.Config.ReleaseCommand,
+		Cmd:        devbox.Spec.Config.ReleaseArgs,
+		WorkingDir: devbox.Spec.Config.WorkingDir,
+	}
+	for _, env := range devbox.Spec.Config.Env {
+		// the values from secrets or fields of the pod are not known out of the pod
+		if env.ValueFrom == nil {
+			config.Env = append(config.Env, env.Name+"="+env.Value)
+		}
+	}
+	for _, p := range GetAppPorts(devbox) {
+		port := p.TargetPort.IntValue()
+		if p.TargetPort.Type == intstr.String {
+			port = 0
+			for _, cp := range devbox.Spec.Config.Ports {
+				if cp.Name == p.TargetPort.StrVal {
+					port = int(cp.ContainerPort)
+				}
+			}
+		}
+		if port == 0 {
+			continue
+		}
+		config.ExposedPorts = append(config.ExposedPorts, fmt.Sprintf("%d/%s", port, strings.ToLower(string(p.Protocol))))
+	}
+	return config
+}
+
+// GenerateAppHost generates the host of an app port of the devbox under the domain. The host is stable for the same
+// port and it is unique across namespaces, the hash keeps it in the length limit of a dns label.
+func GenerateAppHost(devbox *devboxv1alpha1.Devbox, port int32, domain string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", devbox.Namespace, devbox.Name, port)))
+	name := devbox.Name
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	return fmt.Sprintf("%s-%s.%s", strings.TrimRight(name, "-"), hex.EncodeToString(sum[:])[:10], domain)
+}
+
+// PruneCommitHistory splits the commit history into the commits kept and the commits pruned. The latest limit commits,
+// the pending commits, the latest successful commit the next pod starts from and the released commits are always kept.
+func PruneCommitHistory(history []*devboxv1alpha1.CommitHistory, limit int, released map[string]bool) (kept, pruned []*devboxv1alpha1.CommitHistory) {
+	if limit <= 0 || len(history) <= limit {
+		return history, nil
+	}
+	sorted := make([]*devboxv1alpha1.CommitHistory, len(history))
+	copy(sorted, history)
+	// sort commit history by time in descending order
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.After(sorted[j].Time.Time)
+	})
+	lastSuccess := false
+	for i, c := range sorted {
+		keep := i < limit || c.Status == devboxv1alpha1.CommitStatusPending || released[c.Image]
+		if !lastSuccess && c.Status == devboxv1alpha1.CommitStatusSuccess {
+			lastSuccess = true
+			keep = true
+		}
+		if keep {
+			kept = append(kept, c)
+		} else {
+			pruned = append(pruned, c)
+		}
+	}
+	return kept, pruned
+}
+
+// ParseImage splits an image into the registry host, the repository and the tag
+func ParseImage(image string) (string, string, string, error) {
+	res, err := reference.ParseReference(image)
+	if err != nil {
+		return "", "", "", err
+	}
+	repo := res.Context()
+	return repo.RegistryStr(), repo.RepositoryStr(), res.Identifier(), nil
+}
+
+// maxScheduleCatchUp bounds how far back missed schedules are looked for, e.g. after the controller is down for long
+const maxScheduleCatchUp = 24 * time.Hour
+
+// LastScheduled returns the schedule which runs last in (after, now] and the next time any of the schedules runs
+// after now. The schedule is nil if none of them runs in (after, now], invalid schedules are skipped and returned as
+// the error.
+func LastScheduled(schedules []devboxv1alpha1.ScheduleSpec, after, now time.Time) (*devboxv1alpha1.ScheduleSpec, time.Time, error) {
+	if after.Before(now.Add(-maxScheduleCatchUp)) {
+		after = now.Add(-maxScheduleCatchUp)
+	}
+	var (
+		last       *devboxv1alpha1.ScheduleSpec
+		lastTime   time.Time
+		next       time.Time
+		errs       []error
+		loadedZone = map[string]*time.Location{}
+	)
+	for i := range schedules {
+		loc, ok := loadedZone[schedules[i].TimeZone]
+		if !ok {
+			var err error
+			if loc, err = time.LoadLocation(schedules[i].TimeZone); err != nil {
+				errs = append(errs, fmt.Errorf("invalid time zone of schedule %q: %w", schedules[i].Schedule, err))
+				continue
+			}
+			loadedZone[schedules[i].TimeZone] = loc
+		}
+		s, err := cron.Parse(schedules[i].Schedule, loc)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// the later schedule wins if two of them run at the same time
+		if t := s.Prev(after, now); !t.IsZero() && !t.Before(lastTime) {
+			last, lastTime = &schedules[i], t
+		}
+		if t := s.Next(now); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return last, next, errors.Join(errs...)
+}
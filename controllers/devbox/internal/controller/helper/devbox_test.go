@@ -0,0 +1,147 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	devboxv1alpha1 "github.com/labring/sealos/controllers/devbox/api/v1alpha1"
+)
+
+func TestGetAppPorts(t *testing.T) {
+	devbox := &devboxv1alpha1.Devbox{}
+	devbox.Spec.Config.AppPorts = []corev1.ServicePort{
+		{Name: "web", Port: 80, TargetPort: intstr.FromInt32(8080), Protocol: corev1.ProtocolTCP},
+		{Port: 3000},
+	}
+	ports := GetAppPorts(devbox)
+	if len(ports) != 2 {
+		t.Fatalf("got %d ports, want 2", len(ports))
+	}
+	if ports[0].TargetPort.IntValue() != 8080 {
+		t.Errorf("target port %v should be kept", ports[0].TargetPort)
+	}
+	if ports[1].Name != "app-port-3000" || ports[1].TargetPort.IntValue() != 3000 || ports[1].Protocol != corev1.ProtocolTCP {
+		t.Errorf("port is not defaulted: %+v", ports[1])
+	}
+}
+
+func TestGenerateAppHost(t *testing.T) {
+	devbox := &devboxv1alpha1.Devbox{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 63), Namespace: "ns-user"}}
+	host := GenerateAppHost(devbox, 8080, "cloud.example.com")
+	if host != GenerateAppHost(devbox, 8080, "cloud.example.com") {
+		t.Error("host should be stable")
+	}
+	if errs := validation.IsDNS1123Subdomain(host); len(errs) != 0 {
+		t.Errorf("invalid host %s: %v", host, errs)
+	}
+	if !strings.HasSuffix(host, ".cloud.example.com") {
+		t.Errorf("host %s is not under the domain", host)
+	}
+	if host == GenerateAppHost(devbox, 3000, "cloud.example.com") {
+		t.Error("hosts of different ports should be different")
+	}
+	other := devbox.DeepCopy()
+	other.Namespace = "ns-other"
+	if host == GenerateAppHost(other, 8080, "cloud.example.com") {
+		t.Error("hosts of devboxes in different namespaces should be different")
+	}
+}
+
+func TestPruneCommitHistory(t *testing.T) {
+	now := time.Now()
+	commit := func(image string, age time.Duration, status devboxv1alpha1.CommitStatus) *devboxv1alpha1.CommitHistory {
+		return &devboxv1alpha1.CommitHistory{Image: image, Pod: image, Time: metav1.Time{Time: now.Add(-age)}, Status: status}
+	}
+	history := []*devboxv1alpha1.CommitHistory{
+		commit("released", 6*time.Hour, devboxv1alpha1.CommitStatusSuccess),
+		commit("old", 5*time.Hour, devboxv1alpha1.CommitStatusSuccess),
+		commit("success", 4*time.Hour, devboxv1alpha1.CommitStatusSuccess),
+		commit("pending", 3*time.Hour, devboxv1alpha1.CommitStatusPending),
+		commit("failed", 2*time.Hour, devboxv1alpha1.CommitStatusFailed),
+		commit("current", time.Hour, devboxv1alpha1.CommitStatusFailed),
+	}
+
+	kept, pruned := PruneCommitHistory(history, 1, map[string]bool{"released": true})
+	images := func(commits []*devboxv1alpha1.CommitHistory) string {
+		var names []string
+		for _, c := range commits {
+			names = append(names, c.Image)
+		}
+		return strings.Join(names, ",")
+	}
+	if got := images(kept); got != "current,pending,success,released" {
+		t.Errorf("kept = %s", got)
+	}
+	if got := images(pruned); got != "failed,old" {
+		t.Errorf("pruned = %s", got)
+	}
+
+	if kept, pruned = PruneCommitHistory(history, 0, nil); len(kept) != len(history) || len(pruned) != 0 {
+		t.Errorf("nothing should be pruned without a limit, pruned %s", images(pruned))
+	}
+}
+
+func TestLastScheduled(t *testing.T) {
+	schedules := []devboxv1alpha1.ScheduleSpec{
+		{State: devboxv1alpha1.DevboxStateRunning, Schedule: "0 9 * * *"},
+		{State: devboxv1alpha1.DevboxStateStopped, Schedule: "0 18 * * *"},
+		{State: devboxv1alpha1.DevboxStateStopped, Schedule: "0 20 * * *", TimeZone: "Invalid/Zone"},
+	}
+	now := time.Date(2024, 1, 1, 19, 0, 0, 0, time.UTC)
+
+	schedule, next, err := LastScheduled(schedules, now.Add(-12*time.Hour), now)
+	if err == nil {
+		t.Error("invalid time zone should be returned")
+	}
+	if schedule == nil || schedule.State != devboxv1alpha1.DevboxStateStopped {
+		t.Errorf("last schedule = %+v, want the stop schedule", schedule)
+	}
+	if want := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("next = %s, want %s", next, want)
+	}
+
+	if schedule, _, _ = LastScheduled(schedules, now.Add(-time.Minute), now); schedule != nil {
+		t.Errorf("no schedule runs in the last minute, got %+v", schedule)
+	}
+}
+
+func TestCheckTailnetAgentSupported(t *testing.T) {
+	for gitVersion, supported := range map[string]bool{
+		"v1.27.16":            false,
+		"v1.28.9":             false,
+		"v1.29.0":             true,
+		"v1.30.1+k3s1":        true,
+		"v1.31.2-eks-7f9249a": true,
+	} {
+		d := &fakediscovery.FakeDiscovery{
+			Fake:               &clienttesting.Fake{},
+			FakedServerVersion: &version.Info{GitVersion: gitVersion},
+		}
+		if err := CheckTailnetAgentSupported(d); (err == nil) != supported {
+			t.Errorf("CheckTailnetAgentSupported(%s) = %v, want supported %v", gitVersion, err, supported)
+		}
+	}
+}
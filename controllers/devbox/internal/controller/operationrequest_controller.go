@@ -0,0 +1,319 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	devboxv1alpha1 "github.com/labring/sealos/controllers/devbox/api/v1alpha1"
+	"github.com/labring/sealos/controllers/devbox/label"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// operationRequestRequeueDuration is the interval to check whether the devbox has reached the state an action expects
+const operationRequestRequeueDuration = 5 * time.Second
+
+// OperationRequestReconciler reconciles a OperationRequest object. The actions are applied to the spec or the pod
+// of the devbox, the DevboxReconciler brings the devbox to the expected state then.
+type OperationRequestReconciler struct {
+	// Expiration is how long a request can take before it fails
+	Expiration time.Duration
+	// Retention is how long a finished request is kept before it is deleted
+	Retention time.Duration
+
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=devbox.sealos.io,resources=operationrequests,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=devbox.sealos.io,resources=operationrequests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=devbox.sealos.io,resources=operationrequests/finalizers,verbs=update
+
+func (r *OperationRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	request := &devboxv1alpha1.OperationRequest{}
+	if err := r.Get(ctx, req.NamespacedName, request); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// delete the finished request once it is retained long enough
+	if isOperationRequestFinished(request) {
+		if request.Status.CompletionTime == nil {
+			return ctrl.Result{}, nil
+		}
+		retained := time.Since(request.Status.CompletionTime.Time)
+		if retained < r.Retention {
+			return ctrl.Result{RequeueAfter: r.Retention - retained}, nil
+		}
+		logger.Info("delete finished operation request", "phase", request.Status.Phase)
+		return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, request))
+	}
+
+	if time.Since(request.CreationTimestamp.Time) > r.Expiration {
+		return ctrl.Result{}, r.finish(ctx, request, fmt.Errorf("request is expired after %s", r.Expiration))
+	}
+
+	devbox := &devboxv1alpha1.Devbox{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: request.Namespace, Name: request.Spec.DevboxName}, devbox); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, r.finish(ctx, request, fmt.Errorf("devbox %s not found", request.Spec.DevboxName))
+		}
+		return ctrl.Result{}, err
+	}
+
+	switch request.Status.Phase {
+	case devboxv1alpha1.OperationRequestPhaseProcessing:
+		done, err := r.check(ctx, request, devbox)
+		if err != nil || done {
+			return ctrl.Result{}, r.finish(ctx, request, err)
+		}
+		logger.Info("waiting for devbox", "devbox", devbox.Name, "action", request.Spec.Action, "phase", devbox.Status.Phase)
+		return ctrl.Result{RequeueAfter: operationRequestRequeueDuration}, nil
+	default:
+		// the requests of a devbox are applied one by one in order of creation
+		processing, err := r.hasEarlierRequest(ctx, request)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if processing {
+			logger.Info("waiting for the earlier requests of devbox", "devbox", devbox.Name)
+			return ctrl.Result{RequeueAfter: operationRequestRequeueDuration}, nil
+		}
+		pod, done, err := r.apply(ctx, request, devbox)
+		if err != nil || done {
+			return ctrl.Result{}, r.finish(ctx, request, err)
+		}
+		r.Recorder.Eventf(request, corev1.EventTypeNormal, "Processing", "Action %s is applied to devbox %s", request.Spec.Action, devbox.Name)
+		request.Status.Phase = devboxv1alpha1.OperationRequestPhaseProcessing
+		request.Status.Pod = pod
+		request.Status.StartTime = &metav1.Time{Time: time.Now()}
+		if err := r.Status().Update(ctx, request); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: operationRequestRequeueDuration}, nil
+	}
+}
+
+// apply applies the action to the devbox, it returns the pod of the devbox before the action and
+// whether the request is done already.
+func (r *OperationRequestReconciler) apply(ctx context.Context, request *devboxv1alpha1.OperationRequest, devbox *devboxv1alpha1.Devbox) (string, bool, error) {
+	switch request.Spec.Action {
+	case devboxv1alpha1.OperationActionStop:
+		return "", false, r.setDevboxState(ctx, devbox, devboxv1alpha1.DevboxStateStopped)
+	case devboxv1alpha1.OperationActionStart:
+		return "", false, r.setDevboxState(ctx, devbox, devboxv1alpha1.DevboxStateRunning)
+	case devboxv1alpha1.OperationActionRestart:
+		if devbox.Spec.State == devboxv1alpha1.DevboxStateStopped {
+			return "", false, r.setDevboxState(ctx, devbox, devboxv1alpha1.DevboxStateRunning)
+		}
+		return r.deleteDevboxPod(ctx, devbox)
+	case devboxv1alpha1.OperationActionCommit:
+		if devbox.Spec.State == devboxv1alpha1.DevboxStateStopped {
+			return "", false, errors.New("devbox is stopped, its changes are committed already")
+		}
+		pod, _, err := r.deleteDevboxPod(ctx, devbox)
+		if err == nil && pod == "" {
+			err = errors.New("devbox has no pod to commit")
+		}
+		return pod, false, err
+	case devboxv1alpha1.OperationActionReset:
+		if err := r.resetDevboxCommit(ctx, request, devbox); err != nil {
+			return "", false, err
+		}
+		// a stopped devbox starts from the commit next time
+		if devbox.Spec.State == devboxv1alpha1.DevboxStateStopped {
+			return "", true, nil
+		}
+		return r.deleteDevboxPod(ctx, devbox)
+	}
+	return "", false, fmt.Errorf("invalid action %s", request.Spec.Action)
+}
+
+// check returns whether the devbox has reached the state the action expects
+func (r *OperationRequestReconciler) check(ctx context.Context, request *devboxv1alpha1.OperationRequest, devbox *devboxv1alpha1.Devbox) (bool, error) {
+	switch request.Spec.Action {
+	case devboxv1alpha1.OperationActionStop:
+		if devbox.Spec.State != devboxv1alpha1.DevboxStateStopped {
+			return false, fmt.Errorf("devbox state is changed to %s", devbox.Spec.State)
+		}
+		return devbox.Status.Phase == devboxv1alpha1.DevboxPhaseStopped, nil
+	case devboxv1alpha1.OperationActionCommit:
+		for _, commit := range devbox.Status.CommitHistory {
+			if commit.Pod != request.Status.Pod {
+				continue
+			}
+			switch commit.Status {
+			case devboxv1alpha1.CommitStatusSuccess:
+				return true, nil
+			case devboxv1alpha1.CommitStatusFailed:
+				return false, fmt.Errorf("failed to commit pod %s", commit.Pod)
+			}
+		}
+		return false, nil
+	default:
+		// Start, Restart and Reset wait for the devbox running in a new pod
+		if devbox.Spec.State != devboxv1alpha1.DevboxStateRunning {
+			return false, fmt.Errorf("devbox state is changed to %s", devbox.Spec.State)
+		}
+		if devbox.Status.Phase != devboxv1alpha1.DevboxPhaseRunning {
+			return false, nil
+		}
+		pod, err := r.getDevboxPod(ctx, devbox)
+		if err != nil {
+			return false, err
+		}
+		return pod != nil && pod.Name != request.Status.Pod, nil
+	}
+}
+
+// finish marks the request completed, or failed if err is not nil
+func (r *OperationRequestReconciler) finish(ctx context.Context, request *devboxv1alpha1.OperationRequest, err error) error {
+	request.Status.Phase = devboxv1alpha1.OperationRequestPhaseCompleted
+	request.Status.Message = ""
+	if err != nil {
+		request.Status.Phase = devboxv1alpha1.OperationRequestPhaseFailed
+		request.Status.Message = err.Error()
+		r.Recorder.Eventf(request, corev1.EventTypeWarning, "Failed", "Action %s failed: %v", request.Spec.Action, err)
+	} else {
+		r.Recorder.Eventf(request, corev1.EventTypeNormal, "Completed", "Action %s completed", request.Spec.Action)
+	}
+	request.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	return r.Status().Update(ctx, request)
+}
+
+func isOperationRequestFinished(request *devboxv1alpha1.OperationRequest) bool {
+	return request.Status.Phase == devboxv1alpha1.OperationRequestPhaseCompleted ||
+		request.Status.Phase == devboxv1alpha1.OperationRequestPhaseFailed
+}
+
+// hasEarlierRequest returns whether an earlier request of the same devbox is not finished yet
+func (r *OperationRequestReconciler) hasEarlierRequest(ctx context.Context, request *devboxv1alpha1.OperationRequest) (bool, error) {
+	requestList := &devboxv1alpha1.OperationRequestList{}
+	if err := r.List(ctx, requestList, client.InNamespace(request.Namespace)); err != nil {
+		return false, err
+	}
+	for _, other := range requestList.Items {
+		if other.Name == request.Name || other.Spec.DevboxName != request.Spec.DevboxName || isOperationRequestFinished(&other) {
+			continue
+		}
+		if other.Status.Phase == devboxv1alpha1.OperationRequestPhaseProcessing ||
+			other.CreationTimestamp.Before(&request.CreationTimestamp) ||
+			(other.CreationTimestamp.Equal(&request.CreationTimestamp) && other.Name < request.Name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *OperationRequestReconciler) setDevboxState(ctx context.Context, devbox *devboxv1alpha1.Devbox, state devboxv1alpha1.DevboxState) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestDevbox := &devboxv1alpha1.Devbox{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), latestDevbox); err != nil {
+			return err
+		}
+		if latestDevbox.Spec.State == state {
+			return nil
+		}
+		latestDevbox.Spec.State = state
+		return r.Update(ctx, latestDevbox)
+	})
+}
+
+func (r *OperationRequestReconciler) getDevboxPod(ctx context.Context, devbox *devboxv1alpha1.Devbox) (*corev1.Pod, error) {
+	recLabels := label.RecommendedLabels(&label.Recommended{
+		Name:      devbox.Name,
+		ManagedBy: label.DefaultManagedBy,
+		PartOf:    devboxv1alpha1.DevBoxPartOf,
+	})
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(devbox.Namespace), client.MatchingLabels(recLabels)); err != nil {
+		return nil, err
+	}
+	for i := range podList.Items {
+		if podList.Items[i].DeletionTimestamp.IsZero() {
+			return &podList.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// deleteDevboxPod deletes the pod of the devbox, the DevboxReconciler commits it and creates a new one.
+// It returns the name of the deleted pod, empty if the devbox has no pod.
+func (r *OperationRequestReconciler) deleteDevboxPod(ctx context.Context, devbox *devboxv1alpha1.Devbox) (string, bool, error) {
+	pod, err := r.getDevboxPod(ctx, devbox)
+	if err != nil || pod == nil {
+		return "", false, err
+	}
+	if err := r.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+		return "", false, fmt.Errorf("failed to delete pod %s: %w", pod.Name, err)
+	}
+	return pod.Name, false, nil
+}
+
+// resetDevboxCommit records the commit as the latest successful one, the next pod of the devbox starts from it
+func (r *OperationRequestReconciler) resetDevboxCommit(ctx context.Context, request *devboxv1alpha1.OperationRequest, devbox *devboxv1alpha1.Devbox) error {
+	if request.Spec.Commit == "" {
+		return errors.New("commit is required to reset the devbox")
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestDevbox := &devboxv1alpha1.Devbox{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), latestDevbox); err != nil {
+			return err
+		}
+		found := false
+		for _, commit := range latestDevbox.Status.CommitHistory {
+			if commit.Image == request.Spec.Commit && commit.Status == devboxv1alpha1.CommitStatusSuccess {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("commit %s is not a successful commit of devbox %s", request.Spec.Commit, devbox.Name)
+		}
+		latestDevbox.Status.CommitHistory = append(latestDevbox.Status.CommitHistory, &devboxv1alpha1.CommitHistory{
+			Image: request.Spec.Commit,
+			Time:  metav1.Time{Time: time.Now()},
+			// no pod runs for the reset, the name of the request keeps the record unique
+			Pod:              request.Name,
+			Status:           devboxv1alpha1.CommitStatusSuccess,
+			PredicatedStatus: devboxv1alpha1.CommitStatusSuccess,
+		})
+		return r.Status().Update(ctx, latestDevbox)
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OperationRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&devboxv1alpha1.OperationRequest{}).
+		Complete(r)
+}
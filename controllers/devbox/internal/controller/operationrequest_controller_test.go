@@ -0,0 +1,172 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	devboxv1alpha1 "github.com/labring/sealos/controllers/devbox/api/v1alpha1"
+	"github.com/labring/sealos/controllers/devbox/label"
+)
+
+func newOperationRequestReconciler(objs ...client.Object) *OperationRequestReconciler {
+	c := newFakeClient(objs...)
+	return &OperationRequestReconciler{
+		Expiration: time.Minute,
+		Retention:  time.Minute,
+		Client:     c,
+		Scheme:     c.Scheme(),
+		Recorder:   record.NewFakeRecorder(100),
+	}
+}
+
+func newOperationRequest(name string, action devboxv1alpha1.OperationAction) *devboxv1alpha1.OperationRequest {
+	return &devboxv1alpha1.OperationRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.Now()},
+		Spec:       devboxv1alpha1.OperationRequestSpec{DevboxName: "devbox", Action: action},
+	}
+}
+
+func reconcileOperationRequest(t *testing.T, r *OperationRequestReconciler, name string) *devboxv1alpha1.OperationRequest {
+	t.Helper()
+	key := types.NamespacedName{Namespace: "default", Name: name}
+	if _, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	request := &devboxv1alpha1.OperationRequest{}
+	if err := r.Get(context.Background(), key, request); err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
+
+func TestOperationRequestStop(t *testing.T) {
+	devbox := &devboxv1alpha1.Devbox{
+		ObjectMeta: metav1.ObjectMeta{Name: "devbox", Namespace: "default"},
+		Spec:       devboxv1alpha1.DevboxSpec{State: devboxv1alpha1.DevboxStateRunning},
+	}
+	stop := newOperationRequest("stop", devboxv1alpha1.OperationActionStop)
+	stop.CreationTimestamp = metav1.NewTime(time.Now().Add(-10 * time.Second))
+	r := newOperationRequestReconciler(devbox, stop, newOperationRequest("start", devboxv1alpha1.OperationActionStart))
+	ctx := context.Background()
+
+	request := reconcileOperationRequest(t, r, "stop")
+	if request.Status.Phase != devboxv1alpha1.OperationRequestPhaseProcessing || request.Status.StartTime == nil {
+		t.Fatalf("request should be processing, got %+v", request.Status)
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), devbox); err != nil {
+		t.Fatal(err)
+	}
+	if devbox.Spec.State != devboxv1alpha1.DevboxStateStopped {
+		t.Fatalf("devbox state is %s, want Stopped", devbox.Spec.State)
+	}
+
+	// the start request waits for the stop request of the same devbox
+	if request := reconcileOperationRequest(t, r, "start"); request.Status.Phase == devboxv1alpha1.OperationRequestPhaseProcessing {
+		t.Fatal("start request should wait for the stop request")
+	}
+
+	if request := reconcileOperationRequest(t, r, "stop"); request.Status.Phase != devboxv1alpha1.OperationRequestPhaseProcessing {
+		t.Fatalf("request should wait for the devbox stopped, got %s", request.Status.Phase)
+	}
+	devbox.Status.Phase = devboxv1alpha1.DevboxPhaseStopped
+	if err := r.Status().Update(ctx, devbox); err != nil {
+		t.Fatal(err)
+	}
+	request = reconcileOperationRequest(t, r, "stop")
+	if request.Status.Phase != devboxv1alpha1.OperationRequestPhaseCompleted || request.Status.CompletionTime == nil {
+		t.Fatalf("request should be completed, got %+v", request.Status)
+	}
+
+	// finished requests are deleted after the retention
+	request.Status.CompletionTime = &metav1.Time{Time: time.Now().Add(-2 * r.Retention)}
+	if err := r.Status().Update(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(request)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(request), request); err == nil {
+		t.Error("finished request should be deleted after the retention")
+	}
+}
+
+func TestOperationRequestReset(t *testing.T) {
+	devbox := &devboxv1alpha1.Devbox{
+		ObjectMeta: metav1.ObjectMeta{Name: "devbox", Namespace: "default"},
+		Spec:       devboxv1alpha1.DevboxSpec{State: devboxv1alpha1.DevboxStateRunning},
+		Status: devboxv1alpha1.DevboxStatus{
+			CommitHistory: []*devboxv1alpha1.CommitHistory{
+				{Image: "hub/default/devbox:old", Pod: "devbox-old", Time: metav1.Time{Time: time.Now().Add(-time.Hour)}, Status: devboxv1alpha1.CommitStatusSuccess},
+				{Image: "hub/default/devbox:new", Pod: "devbox-new", Time: metav1.Now(), Status: devboxv1alpha1.CommitStatusSuccess},
+			},
+		},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "devbox-running",
+		Namespace: "default",
+		Labels: label.RecommendedLabels(&label.Recommended{
+			Name:      devbox.Name,
+			ManagedBy: label.DefaultManagedBy,
+			PartOf:    devboxv1alpha1.DevBoxPartOf,
+		}),
+	}}
+	reset := newOperationRequest("reset", devboxv1alpha1.OperationActionReset)
+	reset.Spec.Commit = "hub/default/devbox:old"
+	invalid := newOperationRequest("invalid", devboxv1alpha1.OperationActionReset)
+	invalid.Spec.Commit = "hub/default/devbox:unknown"
+	r := newOperationRequestReconciler(devbox, pod, reset)
+	ctx := context.Background()
+
+	request := reconcileOperationRequest(t, r, "reset")
+	if request.Status.Phase != devboxv1alpha1.OperationRequestPhaseProcessing || request.Status.Pod != pod.Name {
+		t.Fatalf("request should be processing with pod %s, got %+v", pod.Name, request.Status)
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(pod), pod); err == nil {
+		t.Error("pod should be deleted to restart the devbox")
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(devbox), devbox); err != nil {
+		t.Fatal(err)
+	}
+	latest := devbox.Status.CommitHistory[len(devbox.Status.CommitHistory)-1]
+	if latest.Image != reset.Spec.Commit || latest.Status != devboxv1alpha1.CommitStatusSuccess {
+		t.Errorf("commit is not reset, the latest commit is %+v", latest)
+	}
+
+	if err := r.Create(ctx, invalid); err != nil {
+		t.Fatal(err)
+	}
+	// the reset request is still processing, finish it first
+	request.Status.Phase = devboxv1alpha1.OperationRequestPhaseCompleted
+	request.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	if err := r.Status().Update(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+	request = reconcileOperationRequest(t, r, "invalid")
+	if request.Status.Phase != devboxv1alpha1.OperationRequestPhaseFailed || request.Status.Message == "" {
+		t.Errorf("reset to an unknown commit should fail, got %+v", request.Status)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package activity samples the signals telling whether a devbox is in use.
+package activity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SSHSessionsAnnotation is the number of open ssh sessions, reported on the pod by an agent in the devbox.
+	// The sessions are unknown if no agent reports them, and the devbox is never idle then.
+	SSHSessionsAnnotation = "devbox.sealos.io/ssh-sessions"
+	// AppConnectionsAnnotation is the number of open connections to the app ports, reported on the pod by an agent
+	// in the devbox. The connections are unknown if no agent reports them, and the devbox is never idle then.
+	AppConnectionsAnnotation = "devbox.sealos.io/app-connections"
+)
+
+// podMetricsGVK is the kind metrics-server serves the resource usage of pods as
+var podMetricsGVK = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics"}
+
+// Activity is a sample of the signals of a devbox pod
+type Activity struct {
+	// SSHSessions is the number of open ssh sessions, nil if it is unknown
+	SSHSessions *int
+	// AppConnections is the number of open connections to the app ports, nil if it is unknown
+	AppConnections *int
+	// CPU is the cpu usage of the pod, nil if it is unknown, e.g. metrics-server is not installed
+	CPU *resource.Quantity
+}
+
+// Source samples the activity of devbox pods
+type Source interface {
+	Sample(ctx context.Context, pod *corev1.Pod) (*Activity, error)
+}
+
+// PodSource reads the sessions and connections from the annotations of the pod and the cpu usage from metrics-server
+type PodSource struct {
+	// Reader reads the pod metrics, it must not be a cached reader since pod metrics can not be watched
+	Reader client.Reader
+}
+
+func (s *PodSource) Sample(ctx context.Context, pod *corev1.Pod) (*Activity, error) {
+	var err error
+	a := &Activity{}
+	if a.SSHSessions, err = countAnnotation(pod, SSHSessionsAnnotation); err != nil {
+		return nil, err
+	}
+	if a.AppConnections, err = countAnnotation(pod, AppConnectionsAnnotation); err != nil {
+		return nil, err
+	}
+	if a.CPU, err = s.cpuUsage(ctx, pod); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *PodSource) cpuUsage(ctx context.Context, pod *corev1.Pod) (*resource.Quantity, error) {
+	metrics := &unstructured.Unstructured{}
+	metrics.SetGroupVersionKind(podMetricsGVK)
+	if err := s.Reader.Get(ctx, client.ObjectKeyFromObject(pod), metrics); err != nil {
+		// the metrics of a new pod are not collected yet, or metrics-server is not installed
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get metrics of pod %s: %w", pod.Name, err)
+	}
+	containers, _, err := unstructured.NestedSlice(metrics.Object, "containers")
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics of pod %s: %w", pod.Name, err)
+	}
+	total := resource.NewMilliQuantity(0, resource.DecimalSI)
+	for _, c := range containers {
+		container, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		cpu, _, err := unstructured.NestedString(container, "usage", "cpu")
+		if err != nil || cpu == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(cpu)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu usage %s of pod %s: %w", cpu, pod.Name, err)
+		}
+		total.Add(q)
+	}
+	return total, nil
+}
+
+// countAnnotation returns the count reported in the annotation of the pod, nil if it is not reported
+func countAnnotation(pod *corev1.Pod, key string) (*int, error) {
+	v, ok := pod.Annotations[key]
+	if !ok || v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation %s=%s of pod %s: %w", key, v, pod.Name, err)
+	}
+	return &n, nil
+}
+
+// Known returns whether the sessions and the connections are both reported
+func (a *Activity) Known() bool {
+	return a.SSHSessions != nil && a.AppConnections != nil
+}
@@ -0,0 +1,71 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package activity
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// noMetricsReader serves no pod metrics, as if the metrics of the pod are not collected yet
+type noMetricsReader struct {
+	client.Reader
+}
+
+func (noMetricsReader) Get(_ context.Context, key client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+	return errors.NewNotFound(schema.GroupResource{Group: "metrics.k8s.io", Resource: "pods"}, key.Name)
+}
+
+func TestPodSourceSample(t *testing.T) {
+	s := &PodSource{Reader: noMetricsReader{}}
+	newPod := func(annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "devbox", Namespace: "default", Annotations: annotations}}
+	}
+
+	a, err := s.Sample(context.Background(), newPod(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Known() || a.SSHSessions != nil || a.AppConnections != nil || a.CPU != nil {
+		t.Errorf("activity without reports should be unknown, got %+v", a)
+	}
+
+	a, err = s.Sample(context.Background(), newPod(map[string]string{SSHSessionsAnnotation: "2", AppConnectionsAnnotation: "0"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Known() || *a.SSHSessions != 2 || *a.AppConnections != 0 {
+		t.Errorf("reported activity = %+v, want 2 sessions and 0 connections", a)
+	}
+
+	// the connections are unknown while only the sessions are reported
+	a, err = s.Sample(context.Background(), newPod(map[string]string{SSHSessionsAnnotation: "0"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Known() {
+		t.Errorf("activity without the connections should be unknown, got %+v", a)
+	}
+
+	if _, err = s.Sample(context.Background(), newPod(map[string]string{SSHSessionsAnnotation: "many"})); err == nil {
+		t.Error("invalid annotation should fail the sample")
+	}
+}
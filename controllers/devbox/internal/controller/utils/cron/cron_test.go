@@ -0,0 +1,84 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// 2024-01-01 is a monday
+	from := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 18 * * 1-5", time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// either day field matches
+		{"0 0 15 * 3", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"30 8,20 * * *", time.Date(2024, 1, 1, 20, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr, nil)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next() of %q = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestNextInLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	s, err := Parse("0 9 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got, want := s.Next(from), time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() = %s, want %s", got, want)
+	}
+}
+
+func TestPrev(t *testing.T) {
+	s, err := Parse("0 * * * *", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	if got, want := s.Prev(after, after.Add(150*time.Minute)), time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Prev() = %s, want %s", got, want)
+	}
+	if got := s.Prev(after, after.Add(30*time.Minute)); !got.IsZero() {
+		t.Errorf("Prev() = %s, want zero", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := Parse(expr, nil); err == nil {
+			t.Errorf("Parse(%q) should fail", expr)
+		}
+	}
+}
@@ -16,9 +16,14 @@ package registry
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/avast/retry-go"
@@ -31,6 +36,8 @@ type Client struct {
 
 var (
 	ErrorManifestNotFound = errors.New("manifest not found")
+	// ErrorUnsupported is returned if the registry does not support the operation, e.g. deleting with deleting disabled
+	ErrorUnsupported = errors.New("operation unsupported by registry")
 )
 
 func (t *Client) TagImage(hostName string, imageName string, oldTag string, newTag string) error {
@@ -43,6 +50,204 @@ func (t *Client) TagImage(hostName string, imageName string, oldTag string, newT
 	}, retry.Delay(time.Second*5), retry.Attempts(3), retry.LastErrorOnly(true))
 }
 
+// DeleteImage deletes the manifest the tag refers to, the blobs are removed by the garbage collection of the registry.
+// Deleting an image which does not exist returns ErrorManifestNotFound.
+func (t *Client) DeleteImage(hostName string, imageName string, tag string) error {
+	return retry.Do(func() error {
+		digest, err := t.getManifestDigest(t.Username, t.Password, hostName, imageName, tag)
+		if err != nil {
+			return err
+		}
+		return t.deleteManifest(t.Username, t.Password, hostName, imageName, digest)
+	}, retry.Delay(time.Second*5), retry.Attempts(3), retry.LastErrorOnly(true),
+		retry.RetryIf(func(err error) bool { return !errors.Is(err, ErrorManifestNotFound) }))
+}
+
+// DeleteManifest deletes a manifest by its digest, the tags referring to it are deleted too. Docker distribution
+// does not support deleting a manifest by tag. Registries with deleting disabled return ErrorUnsupported.
+func (t *Client) DeleteManifest(hostName string, imageName string, digest string) error {
+	return retry.Do(func() error {
+		return t.deleteManifest(t.Username, t.Password, hostName, imageName, digest)
+	}, retry.Delay(time.Second*5), retry.Attempts(3), retry.LastErrorOnly(true),
+		retry.RetryIf(func(err error) bool {
+			return !errors.Is(err, ErrorManifestNotFound) && !errors.Is(err, ErrorUnsupported)
+		}))
+}
+
+// GetManifestDigest returns the digest of the manifest the tag refers to
+func (t *Client) GetManifestDigest(hostName string, imageName string, tag string) (string, error) {
+	var digest string
+	err := retry.Do(func() error {
+		var err error
+		digest, err = t.getManifestDigest(t.Username, t.Password, hostName, imageName, tag)
+		return err
+	}, retry.Delay(time.Second*5), retry.Attempts(3), retry.LastErrorOnly(true),
+		retry.RetryIf(func(err error) bool { return !errors.Is(err, ErrorManifestNotFound) }))
+	return digest, err
+}
+
+// CopyImage copies an image into another repository of the same registry, the blobs are mounted from the source
+// repository, or uploaded if the registry does not support mounting. It returns the digest of the copied manifest.
+func (t *Client) CopyImage(hostName string, srcImageName string, srcTag string, dstImageName string, dstTag string) (string, error) {
+	var digest string
+	err := retry.Do(func() error {
+		manifest, err := t.pullManifest(t.Username, t.Password, hostName, srcImageName, srcTag)
+		if err != nil {
+			return err
+		}
+		blobs, err := manifestBlobs(manifest)
+		if err != nil {
+			return err
+		}
+		for _, blob := range blobs {
+			if err := t.copyBlob(t.Username, t.Password, hostName, srcImageName, dstImageName, blob); err != nil {
+				return err
+			}
+		}
+		if err := t.pushManifest(t.Username, t.Password, hostName, dstImageName, dstTag, manifest); err != nil {
+			return err
+		}
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+		return nil
+	}, retry.Delay(time.Second*5), retry.Attempts(3), retry.LastErrorOnly(true),
+		retry.RetryIf(func(err error) bool { return !errors.Is(err, ErrorManifestNotFound) }))
+	return digest, err
+}
+
+// ImageConfig is the part of the config of an image a release overrides, empty fields are kept as they are
+type ImageConfig struct {
+	Entrypoint []string
+	Cmd        []string
+	// Env is a list of KEY=VALUE, the variables of the image with the same keys are replaced
+	Env []string
+	// ExposedPorts is a list of port/protocol, e.g. 8080/tcp
+	ExposedPorts []string
+	WorkingDir   string
+}
+
+// MutateImage pushes the image of srcTag with its config overridden as dstTag, the layers are shared with the source
+// image. It returns the digest of the pushed manifest.
+func (t *Client) MutateImage(hostName string, imageName string, srcTag string, dstTag string, config *ImageConfig) (string, error) {
+	var digest string
+	err := retry.Do(func() error {
+		manifest, err := t.pullManifest(t.Username, t.Password, hostName, imageName, srcTag)
+		if err != nil {
+			return err
+		}
+		var m map[string]any
+		decoder := json.NewDecoder(bytes.NewReader(manifest))
+		// keep the sizes as they are
+		decoder.UseNumber()
+		if err := decoder.Decode(&m); err != nil {
+			return err
+		}
+		manifestConfig, ok := m["config"].(map[string]any)
+		if !ok {
+			return errors.New("manifest is not an image manifest")
+		}
+		configDigest, _ := manifestConfig["digest"].(string)
+		if configDigest == "" {
+			return errors.New("manifest is not an image manifest")
+		}
+
+		blob, err := t.getBlob(t.Username, t.Password, hostName, imageName, configDigest)
+		if err != nil {
+			return err
+		}
+		configBlob, err := io.ReadAll(blob.Body)
+		blob.Body.Close()
+		if err != nil {
+			return err
+		}
+		configBlob, err = mutateConfig(configBlob, config)
+		if err != nil {
+			return err
+		}
+		configDigest = fmt.Sprintf("sha256:%x", sha256.Sum256(configBlob))
+		if err := t.uploadBlob(t.Username, t.Password, hostName, imageName, configDigest, configBlob); err != nil {
+			return err
+		}
+
+		manifestConfig["digest"] = configDigest
+		manifestConfig["size"] = len(configBlob)
+		if manifest, err = json.Marshal(m); err != nil {
+			return err
+		}
+		if err := t.pushManifest(t.Username, t.Password, hostName, imageName, dstTag, manifest); err != nil {
+			return err
+		}
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+		return nil
+	}, retry.Delay(time.Second*5), retry.Attempts(3), retry.LastErrorOnly(true),
+		retry.RetryIf(func(err error) bool { return !errors.Is(err, ErrorManifestNotFound) }))
+	return digest, err
+}
+
+// mutateConfig overrides the config of an image config blob, the other fields of the blob are kept as they are
+func mutateConfig(blob []byte, config *ImageConfig) ([]byte, error) {
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &file); err != nil {
+		return nil, fmt.Errorf("invalid image config: %w", err)
+	}
+	c := map[string]any{}
+	if raw, ok := file["config"]; ok && string(raw) != "null" {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&c); err != nil {
+			return nil, fmt.Errorf("invalid image config: %w", err)
+		}
+	}
+
+	if len(config.Entrypoint) > 0 {
+		c["Entrypoint"] = config.Entrypoint
+		// the cmd of the image are the arguments of its entrypoint
+		delete(c, "Cmd")
+	}
+	if len(config.Cmd) > 0 {
+		c["Cmd"] = config.Cmd
+	}
+	if config.WorkingDir != "" {
+		c["WorkingDir"] = config.WorkingDir
+	}
+	if len(config.ExposedPorts) > 0 {
+		ports, _ := c["ExposedPorts"].(map[string]any)
+		if ports == nil {
+			ports = map[string]any{}
+		}
+		for _, p := range config.ExposedPorts {
+			ports[p] = struct{}{}
+		}
+		c["ExposedPorts"] = ports
+	}
+	if len(config.Env) > 0 {
+		var env []string
+		overridden := map[string]bool{}
+		for _, e := range config.Env {
+			overridden[envKey(e)] = true
+		}
+		if old, ok := c["Env"].([]any); ok {
+			for _, e := range old {
+				if s, ok := e.(string); ok && !overridden[envKey(s)] {
+					env = append(env, s)
+				}
+			}
+		}
+		c["Env"] = append(env, config.Env...)
+	}
+
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	file["config"] = raw
+	return json.Marshal(file)
+}
+
+func envKey(env string) string {
+	key, _, _ := strings.Cut(env, "=")
+	return key
+}
+
 //func (t *Client) login(authPath string, username string, password string, imageName string) (string, error) {
 //	var (
 //		client = http.DefaultClient
@@ -140,3 +345,203 @@ func (t *Client) pushManifest(username string, password string, hostName string,
 
 	return nil
 }
+
+func (t *Client) getManifestDigest(username string, password string, hostName string, imageName string, tag string) (string, error) {
+	var (
+		client = http.DefaultClient
+		url    = "http://" + hostName + "/v2/" + imageName + "/manifests/" + tag
+	)
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrorManifestNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", errors.New("digest of manifest not found")
+	}
+	return digest, nil
+}
+
+func (t *Client) deleteManifest(username string, password string, hostName string, imageName string, reference string) error {
+	var (
+		client = http.DefaultClient
+		url    = "http://" + hostName + "/v2/" + imageName + "/manifests/" + reference
+	)
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(username, password)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrorManifestNotFound
+	}
+
+	// registries refuse to delete by tag or with deleting disabled with these codes
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusMethodNotAllowed {
+		return fmt.Errorf("%w: %s", ErrorUnsupported, resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		return errors.New(resp.Status)
+	}
+
+	return nil
+}
+
+// manifestBlobs returns the digests of the config and the layers of an image manifest
+func manifestBlobs(manifest []byte) ([]string, error) {
+	var m struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, err
+	}
+	if m.Config.Digest == "" {
+		return nil, errors.New("manifest is not an image manifest")
+	}
+	blobs := []string{m.Config.Digest}
+	for _, layer := range m.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+	return blobs, nil
+}
+
+func (t *Client) copyBlob(username string, password string, hostName string, srcImageName string, dstImageName string, digest string) error {
+	client := http.DefaultClient
+
+	req, err := http.NewRequest("HEAD", "http://"+hostName+"/v2/"+dstImageName+"/blobs/"+digest, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(username, password)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	// mount the blob from the source repository
+	req, err = http.NewRequest("POST", "http://"+hostName+"/v2/"+dstImageName+"/blobs/uploads/?mount="+url.QueryEscape(digest)+"&from="+url.QueryEscape(srcImageName), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(username, password)
+	resp, err = client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusCreated {
+		return nil
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return errors.New(resp.Status)
+	}
+
+	// the registry started an upload instead of mounting, upload the blob in a single request
+	location, err := resp.Location()
+	if err != nil {
+		return err
+	}
+	blob, err := t.getBlob(username, password, hostName, srcImageName, digest)
+	if err != nil {
+		return err
+	}
+	defer blob.Body.Close()
+	return t.putBlob(username, password, location, digest, blob.Body, blob.ContentLength)
+}
+
+func (t *Client) getBlob(username string, password string, hostName string, imageName string, digest string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", "http://"+hostName+"/v2/"+imageName+"/blobs/"+digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(username, password)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New(resp.Status)
+	}
+	return resp, nil
+}
+
+// uploadBlob uploads a blob in a single request
+func (t *Client) uploadBlob(username string, password string, hostName string, imageName string, digest string, blob []byte) error {
+	req, err := http.NewRequest("POST", "http://"+hostName+"/v2/"+imageName+"/blobs/uploads/", nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(username, password)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return errors.New(resp.Status)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return err
+	}
+	return t.putBlob(username, password, location, digest, bytes.NewReader(blob), int64(len(blob)))
+}
+
+// putBlob completes the upload started at location with the whole blob
+func (t *Client) putBlob(username string, password string, location *url.URL, digest string, blob io.Reader, size int64) error {
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("PUT", location.String(), blob)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(username, password)
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
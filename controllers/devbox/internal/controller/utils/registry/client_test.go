@@ -14,7 +14,14 @@
 
 package registry
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
 
 func TestClient_TagImage(t1 *testing.T) {
 	type fields struct {
@@ -59,3 +66,80 @@ func TestClient_TagImage(t1 *testing.T) {
 		})
 	}
 }
+
+func TestClient_DeleteImage(t1 *testing.T) {
+	const digest = "sha256:0123456789abcdef"
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "admin" || password != "passw0rd" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/v2/default/devbox-sample/manifests/exist" && !deleted:
+			w.Header().Set("Docker-Content-Digest", digest)
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v2/default/devbox-sample/manifests/"+digest:
+			deleted = true
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t := &Client{Username: "admin", Password: "passw0rd"}
+	hostName := strings.TrimPrefix(server.URL, "http://")
+	if err := t.DeleteImage(hostName, "default/devbox-sample", "exist"); err != nil {
+		t1.Fatalf("DeleteImage() error = %v", err)
+	}
+	if !deleted {
+		t1.Error("manifest is not deleted")
+	}
+	if err := t.DeleteImage(hostName, "default/devbox-sample", "exist"); !errors.Is(err, ErrorManifestNotFound) {
+		t1.Errorf("DeleteImage() of a deleted image error = %v, want %v", err, ErrorManifestNotFound)
+	}
+}
+
+func TestMutateConfig(t1 *testing.T) {
+	blob := []byte(`{"architecture":"amd64","config":{"Env":["PATH=/usr/bin","MODE=dev"],"Cmd":["bash"],"ExposedPorts":{"22/tcp":{}}},"rootfs":{"type":"layers","diff_ids":["sha256:1"]}}`)
+	mutated, err := mutateConfig(blob, &ImageConfig{
+		Entrypoint:   []string{"/bin/bash", "-c"},
+		Cmd:          []string{"./entrypoint.sh"},
+		Env:          []string{"MODE=production"},
+		ExposedPorts: []string{"8080/tcp"},
+		WorkingDir:   "/home/devbox/project",
+	})
+	if err != nil {
+		t1.Fatal(err)
+	}
+	var file struct {
+		Architecture string `json:"architecture"`
+		Config       struct {
+			Entrypoint   []string
+			Cmd          []string
+			Env          []string
+			ExposedPorts map[string]struct{}
+			WorkingDir   string
+		} `json:"config"`
+		RootFS struct {
+			DiffIDs []string `json:"diff_ids"`
+		} `json:"rootfs"`
+	}
+	if err := json.Unmarshal(mutated, &file); err != nil {
+		t1.Fatal(err)
+	}
+	if file.Architecture != "amd64" || len(file.RootFS.DiffIDs) != 1 {
+		t1.Errorf("fields out of the config are changed: %s", mutated)
+	}
+	c := file.Config
+	if strings.Join(c.Entrypoint, " ") != "/bin/bash -c" || strings.Join(c.Cmd, " ") != "./entrypoint.sh" || c.WorkingDir != "/home/devbox/project" {
+		t1.Errorf("config is not overridden: %+v", c)
+	}
+	if strings.Join(c.Env, ",") != "PATH=/usr/bin,MODE=production" {
+		t1.Errorf("env = %v", c.Env)
+	}
+	if _, ok := c.ExposedPorts["22/tcp"]; !ok || len(c.ExposedPorts) != 2 {
+		t1.Errorf("exposed ports = %v", c.ExposedPorts)
+	}
+}
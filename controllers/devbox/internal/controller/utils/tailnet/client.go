@@ -0,0 +1,179 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tailnet talks to a headscale compatible control server of the tailnet devboxes join.
+package tailnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client manages the pre-auth keys and nodes of one user of the control server
+type Client struct {
+	// ControlURL is the url of the control server, the agents of devboxes log in to it too
+	ControlURL string
+	// APIKey is the api key of the control server
+	APIKey string
+	// User is the user of the control server owning the nodes of devboxes
+	User string
+	// KeyExpiration is how long the pre-auth key of a devbox is valid
+	KeyExpiration time.Duration
+
+	HTTPClient *http.Client
+}
+
+// Node is a machine joined the tailnet
+type Node struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	GivenName   string    `json:"givenName"`
+	IPAddresses []string  `json:"ipAddresses"`
+	Online      bool      `json:"online"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// IPv4 returns the first ipv4 address of the node, empty if none
+func (n *Node) IPv4() string {
+	for _, addr := range n.IPAddresses {
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+			return addr
+		}
+	}
+	return ""
+}
+
+// CreatePreAuthKey creates a reusable and ephemeral pre-auth key, nodes joined with it are removed
+// by the control server once they are offline for a while. It returns the key and when it expires.
+func (c *Client) CreatePreAuthKey(ctx context.Context) (string, time.Time, error) {
+	expiration := time.Now().Add(c.keyExpiration()).UTC().Truncate(time.Second)
+	req := map[string]any{
+		"user":       c.User,
+		"reusable":   true,
+		"ephemeral":  true,
+		"expiration": expiration.Format(time.RFC3339),
+	}
+	var resp struct {
+		PreAuthKey struct {
+			Key string `json:"key"`
+		} `json:"preAuthKey"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/preauthkey", req, &resp); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create pre-auth key: %w", err)
+	}
+	if resp.PreAuthKey.Key == "" {
+		return "", time.Time{}, fmt.Errorf("failed to create pre-auth key: empty key returned")
+	}
+	return resp.PreAuthKey.Key, expiration, nil
+}
+
+func (c *Client) keyExpiration() time.Duration {
+	if c.KeyExpiration == 0 {
+		return 365 * 24 * time.Hour
+	}
+	return c.KeyExpiration
+}
+
+// RenewAt returns when a pre-auth key expiring at expiration is replaced, a third of its lifetime before it expires
+func (c *Client) RenewAt(expiration time.Time) time.Time {
+	return expiration.Add(-c.keyExpiration() / 3)
+}
+
+// ListNodes returns the nodes of the user whose hostname is name
+func (c *Client) ListNodes(ctx context.Context, name string) ([]Node, error) {
+	var resp struct {
+		Nodes []Node `json:"nodes"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/node?user="+url.QueryEscape(c.User), nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	var nodes []Node
+	for _, n := range resp.Nodes {
+		if n.Name == name {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+// DeleteNode removes a node from the tailnet
+func (c *Client) DeleteNode(ctx context.Context, id string) error {
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/node/"+url.PathEscape(id), nil, nil); err != nil {
+		return fmt.Errorf("failed to delete node %s: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteNodes removes all nodes whose hostname is name from the tailnet
+func (c *Client) DeleteNodes(ctx context.Context, name string) error {
+	nodes, err := c.ListNodes(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if err := c.DeleteNode(ctx, n.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.ControlURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,147 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailnet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeControlServer is a stand-in for the node and pre-auth key api of headscale
+type fakeControlServer struct {
+	mu     sync.Mutex
+	apiKey string
+	keys   map[string]string
+	nodes  map[string]Node
+	nextID int
+}
+
+func newFakeControlServer(apiKey string) *fakeControlServer {
+	return &fakeControlServer{apiKey: apiKey, keys: map[string]string{}, nodes: map[string]Node{}}
+}
+
+// join registers a node as the agent in a devbox pod does with a pre-auth key
+func (s *fakeControlServer) join(key, hostname string) Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[key]; !ok {
+		panic("unknown pre-auth key " + key)
+	}
+	s.nextID++
+	n := Node{
+		ID:          strconv.Itoa(s.nextID),
+		Name:        hostname,
+		GivenName:   hostname,
+		IPAddresses: []string{"fd7a:115c:a1e0::" + strconv.Itoa(s.nextID), "100.64.0." + strconv.Itoa(s.nextID)},
+		Online:      true,
+		CreatedAt:   time.Now(),
+	}
+	s.nodes[n.ID] = n
+	return n
+}
+
+func (s *fakeControlServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/preauthkey":
+		var req struct {
+			User      string `json:"user"`
+			Ephemeral bool   `json:"ephemeral"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.User == "" || !req.Ephemeral {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		key := "key-" + strconv.Itoa(len(s.keys)+1)
+		s.keys[key] = req.User
+		_ = json.NewEncoder(w).Encode(map[string]any{"preAuthKey": map[string]any{"user": req.User, "key": key}})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/node":
+		nodes := []Node{}
+		for _, n := range s.nodes {
+			nodes = append(nodes, n)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"nodes": nodes})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v1/node/"):
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/node/")
+		if _, ok := s.nodes[id]; !ok {
+			http.Error(w, "node not found", http.StatusNotFound)
+			return
+		}
+		delete(s.nodes, id)
+		_, _ = w.Write([]byte("{}"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestClient(t *testing.T) {
+	fake := newFakeControlServer("api-key")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := context.Background()
+	c := &Client{ControlURL: server.URL + "/", APIKey: "api-key", User: "devbox"}
+
+	key, expiration, err := c.CreatePreAuthKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewAt := c.RenewAt(expiration); !renewAt.After(time.Now()) || !renewAt.Before(expiration) {
+		t.Errorf("RenewAt() = %s, want between now and the expiration %s", renewAt, expiration)
+	}
+	joined := fake.join(key, "ns-devbox")
+	fake.join(key, "ns-other")
+
+	nodes, err := c.ListNodes(ctx, "ns-devbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].ID != joined.ID {
+		t.Fatalf("ListNodes() = %v, want node %s only", nodes, joined.ID)
+	}
+	if got := nodes[0].IPv4(); got != "100.64.0."+joined.ID {
+		t.Errorf("IPv4() = %s, want 100.64.0.%s", got, joined.ID)
+	}
+
+	if err := c.DeleteNodes(ctx, "ns-devbox"); err != nil {
+		t.Fatal(err)
+	}
+	if nodes, _ = c.ListNodes(ctx, "ns-devbox"); len(nodes) != 0 {
+		t.Errorf("nodes of the devbox are not deleted: %v", nodes)
+	}
+	if nodes, _ = c.ListNodes(ctx, "ns-other"); len(nodes) != 1 {
+		t.Errorf("nodes of other devboxes should be kept: %v", nodes)
+	}
+
+	if err := c.DeleteNode(ctx, joined.ID); err == nil {
+		t.Error("deleting a removed node should fail")
+	}
+	unauthorized := &Client{ControlURL: server.URL, APIKey: "wrong", User: "devbox"}
+	if _, _, err := unauthorized.CreatePreAuthKey(ctx); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("CreatePreAuthKey() with a wrong api key returns %v", err)
+	}
+}
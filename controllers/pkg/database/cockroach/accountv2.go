@@ -732,6 +732,15 @@ func (c *Cockroach) GetPaymentWithID(paymentID string) (*types.Payment, error) {
 	return &payment, nil
 }
 
+// GetPaymentWithTradeNO returns the payment of the trade, gorm.ErrRecordNotFound is wrapped if it is not credited
+func (c *Cockroach) GetPaymentWithTradeNO(tradeNO string) (*types.Payment, error) {
+	var payment types.Payment
+	if err := c.DB.Where(types.Payment{PaymentRaw: types.PaymentRaw{TradeNO: tradeNO}}).First(&payment).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return &payment, nil
+}
+
 func (c *Cockroach) GetPaymentWithLimit(ops *types.UserQueryOpts, req types.LimitReq, invoiced *bool) ([]types.Payment, types.LimitResp, error) {
 	var payment []types.Payment
 	var total int64
@@ -1014,7 +1023,7 @@ func (c *Cockroach) transferAccount(from, to *types.UserQueryOpts, amount int64,
 }
 
 func (c *Cockroach) InitTables() error {
-	err := CreateTableIfNotExist(c.DB, types.Account{}, types.Payment{}, types.Transfer{}, types.Region{}, types.Invoice{}, types.InvoicePayment{}, types.Configs{})
+	err := CreateTableIfNotExist(c.DB, types.Account{}, types.Payment{}, types.Refund{}, types.TaskLock{}, types.Transfer{}, types.Region{}, types.Invoice{}, types.InvoicePayment{}, types.Configs{})
 	if err != nil {
 		return fmt.Errorf("failed to create table: %v", err)
 	}
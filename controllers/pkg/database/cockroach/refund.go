@@ -0,0 +1,174 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cockroach
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/labring/sealos/controllers/pkg/types"
+)
+
+const (
+	AccountTransactionTypeRefund         = "Refund"
+	AccountTransactionTypeRefundReverted = "RefundReverted"
+)
+
+var (
+	ErrRefundExceedsPayment = errors.New("refund amount exceeds the refundable amount of the payment")
+	ErrRefundExceedsBalance = errors.New("refund would push the balance below the outstanding debt")
+	ErrRefundInvoiced       = errors.New("payment is invoiced and can not be refunded")
+)
+
+// CreateRefund records a refund of a payment in processing and debits the balance of the user with the amount and
+// the gift of it, before the refund is made by the payment provider so the balance can not be spent in the meantime.
+// The whole refundable amount of the payment is refunded if the amount of the refund is zero, the refund which makes
+// the payment fully refunded deducts the rest of the gift.
+func (c *Cockroach) CreateRefund(refund *types.Refund) error {
+	if refund.ID == "" {
+		id, err := gonanoid.New(12)
+		if err != nil {
+			return fmt.Errorf("failed to generate refund id: %v", err)
+		}
+		refund.ID = id
+	}
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		// the payment is locked so the refunds of it are made one by one
+		var payment types.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(&types.Payment{ID: refund.PaymentID}).First(&payment).Error; err != nil {
+			return fmt.Errorf("failed to get payment: %w", err)
+		}
+		if payment.UserUID != refund.UserUID {
+			return fmt.Errorf("payment %s does not belong to user %s", payment.ID, refund.UserUID)
+		}
+		if payment.InvoicedAt {
+			return fmt.Errorf("%w: payment %s", ErrRefundInvoiced, payment.ID)
+		}
+		var refunded struct {
+			Amount    int64
+			Deduction int64
+		}
+		if err := tx.Model(&types.Refund{}).Where("payment_id = ? AND status <> ?", payment.ID, types.RefundStatusFailed).
+			Select("COALESCE(SUM(amount), 0) AS amount, COALESCE(SUM(deduction), 0) AS deduction").Scan(&refunded).Error; err != nil {
+			return fmt.Errorf("failed to get refunded amount: %w", err)
+		}
+		if refund.Amount == 0 {
+			refund.Amount = payment.Amount - refunded.Amount
+		}
+		if refund.Amount <= 0 || refunded.Amount+refund.Amount > payment.Amount {
+			return fmt.Errorf("%w: refund amount %d, refundable amount %d", ErrRefundExceedsPayment, refund.Amount, payment.Amount-refunded.Amount)
+		}
+		refund.Deduction = refund.Amount + refundGift(payment.Amount, payment.Gift, refunded.Amount, refunded.Deduction-refunded.Amount, refund.Amount)
+
+		var account types.Account
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(&types.Account{UserUID: payment.UserUID}).First(&account).Error; err != nil {
+			return fmt.Errorf("failed to get account: %w", err)
+		}
+		if account.Balance-refund.Deduction < account.DeductionBalance {
+			return fmt.Errorf("%w: deduction %d, balance %d, deduction balance %d", ErrRefundExceedsBalance, refund.Deduction, account.Balance, account.DeductionBalance)
+		}
+
+		refund.Method = payment.Method
+		refund.TradeNO = payment.TradeNO
+		refund.Status = types.RefundStatusProcessing
+		if err := tx.Create(refund).Error; err != nil {
+			return fmt.Errorf("failed to save refund: %w", err)
+		}
+		if err := c.updateWithAccount(payment.UserUID, false, false, false, refund.Deduction, tx); err != nil {
+			return fmt.Errorf("failed to reduce balance: %w", err)
+		}
+		message := fmt.Sprintf("refund %s of payment %s, amount %d, gift %d: %s", refund.ID, payment.ID, refund.Amount, refund.Deduction-refund.Amount, refund.Reason)
+		return createAccountTransaction(tx, AccountTransactionTypeRefund, payment.UserUID, -refund.Deduction, message)
+	})
+}
+
+// FinishRefund sets the status of a refund in processing, the deduction is returned to the balance if it failed
+func (c *Cockroach) FinishRefund(refundID, status, message string) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		var refund types.Refund
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(&types.Refund{ID: refundID}).First(&refund).Error; err != nil {
+			return fmt.Errorf("failed to get refund: %w", err)
+		}
+		if refund.Status != types.RefundStatusProcessing {
+			return fmt.Errorf("refund %s is already %s", refund.ID, refund.Status)
+		}
+		if err := tx.Model(&refund).Updates(map[string]interface{}{
+			"status":     status,
+			"message":    message,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update refund: %w", err)
+		}
+		if status != types.RefundStatusFailed {
+			return nil
+		}
+		if err := c.updateWithAccount(refund.UserUID, false, true, false, refund.Deduction, tx); err != nil {
+			return fmt.Errorf("failed to add balance: %w", err)
+		}
+		return createAccountTransaction(tx, AccountTransactionTypeRefundReverted, refund.UserUID, refund.Deduction,
+			fmt.Sprintf("refund %s of payment %s failed: %s", refund.ID, refund.PaymentID, message))
+	})
+}
+
+// GetProcessingRefunds returns the refunds which are in processing since before
+func (c *Cockroach) GetProcessingRefunds(before time.Time) ([]types.Refund, error) {
+	var refunds []types.Refund
+	if err := c.DB.Where("status = ? AND updated_at < ?", types.RefundStatusProcessing, before).
+		Order("created_at").Find(&refunds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get processing refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// refundGift returns the gift deducted by a refund in proportion to the amount, the gift rounded down by the partial
+// refunds is deducted by the refund of the rest of the payment
+func refundGift(paymentAmount, paymentGift, refundedAmount, refundedGift, amount int64) int64 {
+	if refundedAmount+amount == paymentAmount {
+		return paymentGift - refundedGift
+	}
+	return paymentGift * amount / paymentAmount
+}
+
+func (c *Cockroach) GetRefunds(paymentID string) ([]types.Refund, error) {
+	var refunds []types.Refund
+	if err := c.DB.Where(&types.Refund{PaymentID: paymentID}).Order("created_at").Find(&refunds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+func createAccountTransaction(tx *gorm.DB, transactionType string, userUID uuid.UUID, balance int64, message string) error {
+	if err := tx.Create(&types.AccountTransaction{
+		ID:        uuid.New(),
+		Type:      transactionType,
+		UserUID:   userUID,
+		Balance:   balance,
+		Message:   &message,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to create account transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cockroach
+
+import "testing"
+
+func TestRefundGift(t *testing.T) {
+	const amount, gift = 30_000, 1_000
+	var refundedAmount, refundedGift int64
+	// the partial refunds round the gift down, 333 each
+	for i := 0; i < 2; i++ {
+		g := refundGift(amount, gift, refundedAmount, refundedGift, 10_000)
+		if g != 333 {
+			t.Fatalf("gift of partial refund %d = %d, want 333", i, g)
+		}
+		refundedAmount += 10_000
+		refundedGift += g
+	}
+	if g := refundGift(amount, gift, refundedAmount, refundedGift, 10_000); g != 334 {
+		t.Errorf("gift of the final refund = %d, want 334", g)
+	}
+	if g := refundGift(amount, gift, 0, 0, amount); g != gift {
+		t.Errorf("gift of the full refund = %d, want %d", g, gift)
+	}
+}
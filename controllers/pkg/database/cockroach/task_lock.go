@@ -0,0 +1,43 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cockroach
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/labring/sealos/controllers/pkg/types"
+)
+
+// TryLockTask takes the lock of a periodic task for ttl, so the task is run by only one replica at a time. It returns
+// false if the lock is held by another holder and not expired yet, the holder renews its lock by taking it again.
+func (c *Cockroach) TryLockTask(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	table := types.TaskLock{}.TableName()
+	result := c.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"holder", "expiresAt"}),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Or(
+			clause.Lt{Column: clause.Column{Table: table, Name: "expiresAt"}, Value: now},
+			clause.Eq{Column: clause.Column{Table: table, Name: "holder"}, Value: holder},
+		)}},
+	}).Create(&types.TaskLock{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to lock task %s: %w", name, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
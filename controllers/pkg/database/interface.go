@@ -108,7 +108,13 @@ type AccountV2 interface {
 	NewAccount(user *types.UserQueryOpts) (*types.Account, error)
 	Payment(payment *types.Payment) error
 	SavePayment(payment *types.Payment) error
+	GetPaymentWithTradeNO(tradeNO string) (*types.Payment, error)
 	GetUnInvoicedPaymentListWithIds(ids []string) ([]types.Payment, error)
+	CreateRefund(refund *types.Refund) error
+	FinishRefund(refundID, status, message string) error
+	GetProcessingRefunds(before time.Time) ([]types.Refund, error)
+	GetRefunds(paymentID string) ([]types.Refund, error)
+	TryLockTask(name, holder string, ttl time.Duration) (bool, error)
 	CreateAccount(ops *types.UserQueryOpts, account *types.Account) (*types.Account, error)
 	TransferAccount(from, to *types.UserQueryOpts, amount int64) error
 	TransferAccountAll(from, to *types.UserQueryOpts) error
@@ -18,6 +18,13 @@ type Interface interface {
 	CreatePayment(amount int64, user, describe string) (string, string, error)
 	GetPaymentDetails(sessionID string) (string, int64, error)
 	ExpireSession(payment string) error
+	// RefundPayment refunds the amount of the paid payment with the trade number, total is the amount of the payment.
+	// refundNO identifies the refund, a refund retried with the same refundNO is made once. The returned status is
+	// PaymentSuccess, PaymentProcessing or PaymentFailed, the refund is not made if it is PaymentFailed and the error
+	// tells why; if the error is not nil for any other status, whether the refund is made is unknown.
+	RefundPayment(tradeNO, refundNO string, amount, total int64, reason string) (string, error)
+	// GetRefundStatus returns the status of the refund made by RefundPayment, PaymentFailed if the refund is not made
+	GetRefundStatus(tradeNO, refundNO string) (string, error)
 }
 
 func NewPayHandler(paymentMethod string) (Interface, error) {
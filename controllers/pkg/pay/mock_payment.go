@@ -0,0 +1,116 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MockPayment is a payment provider in memory for tests, a payment is paid once it is created.
+type MockPayment struct {
+	// RefundStatus is the status of the refunds made, PaymentSuccess if empty
+	RefundStatus string
+	// RefundErr is returned by RefundPayment without making the refund if it is set
+	RefundErr error
+
+	lock     sync.Mutex
+	payments map[string]int64
+	// refunds is the amount of the refunds by the refund number
+	refunds map[string]int64
+	// refunded is the amount refunded of the payments by the trade number
+	refunded map[string]int64
+}
+
+var _ Interface = &MockPayment{}
+
+func (m *MockPayment) init() {
+	if m.payments == nil {
+		m.payments = make(map[string]int64)
+		m.refunds = make(map[string]int64)
+		m.refunded = make(map[string]int64)
+	}
+}
+
+func (m *MockPayment) CreatePayment(amount int64, _, _ string) (string, string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.init()
+	tradeNO := GetRandomString(32)
+	m.payments[tradeNO] = amount
+	return tradeNO, "", nil
+}
+
+func (m *MockPayment) GetPaymentDetails(tradeNO string) (string, int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.init()
+	amount, ok := m.payments[tradeNO]
+	if !ok {
+		return PaymentUnknown, 0, fmt.Errorf("payment %s not found", tradeNO)
+	}
+	return PaymentSuccess, amount, nil
+}
+
+func (m *MockPayment) ExpireSession(_ string) error {
+	return nil
+}
+
+// RefundPayment refunds the payments not created by the mock as well, which are bounded by the total
+func (m *MockPayment) RefundPayment(tradeNO, refundNO string, amount, total int64, _ string) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.init()
+	if m.RefundErr != nil {
+		return PaymentFailed, m.RefundErr
+	}
+	status := m.RefundStatus
+	if status == "" {
+		status = PaymentSuccess
+	}
+	if _, ok := m.refunds[refundNO]; ok {
+		return status, nil
+	}
+	if paid, ok := m.payments[tradeNO]; ok {
+		total = paid
+	}
+	if amount <= 0 || m.refunded[tradeNO]+amount > total {
+		return PaymentFailed, fmt.Errorf("refund amount %d exceeds the refundable amount %d", amount, total-m.refunded[tradeNO])
+	}
+	m.refunds[refundNO] = amount
+	m.refunded[tradeNO] += amount
+	return status, nil
+}
+
+// GetRefundStatus returns RefundStatus of the refunds made, the status of a refund in processing changes with it
+func (m *MockPayment) GetRefundStatus(_, refundNO string) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.init()
+	if _, ok := m.refunds[refundNO]; !ok {
+		return PaymentFailed, fmt.Errorf("refund %s not found", refundNO)
+	}
+	if m.RefundStatus == "" {
+		return PaymentSuccess, nil
+	}
+	return m.RefundStatus, nil
+}
+
+// Refunded returns the amount refunded of the payment
+func (m *MockPayment) Refunded(tradeNO string) int64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.refunded[tradeNO]
+}
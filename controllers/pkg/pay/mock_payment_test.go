@@ -0,0 +1,50 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import "testing"
+
+func TestMockPaymentRefund(t *testing.T) {
+	m := &MockPayment{}
+	tradeNO, _, err := m.CreatePayment(100, "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status, err := m.RefundPayment(tradeNO, "refund-1", 60, 100, "partial"); err != nil || status != PaymentSuccess {
+		t.Fatalf("RefundPayment() = %s, %v", status, err)
+	}
+	// a retried refund is made once
+	if status, err := m.RefundPayment(tradeNO, "refund-1", 60, 100, "partial"); err != nil || status != PaymentSuccess {
+		t.Fatalf("retried RefundPayment() = %s, %v", status, err)
+	}
+	if status, err := m.RefundPayment(tradeNO, "refund-2", 50, 100, "exceeded"); err == nil || status != PaymentFailed {
+		t.Fatalf("RefundPayment() over the paid amount = %s, %v", status, err)
+	}
+	if status, err := m.RefundPayment(tradeNO, "refund-3", 40, 100, "rest"); err != nil || status != PaymentSuccess {
+		t.Fatalf("RefundPayment() = %s, %v", status, err)
+	}
+	if got := m.Refunded(tradeNO); got != 100 {
+		t.Errorf("Refunded() = %d, want 100", got)
+	}
+
+	if status, err := m.GetRefundStatus(tradeNO, "refund-1"); err != nil || status != PaymentSuccess {
+		t.Errorf("GetRefundStatus() = %s, %v", status, err)
+	}
+	// the refund rejected is not made
+	if status, err := m.GetRefundStatus(tradeNO, "refund-2"); err == nil || status != PaymentFailed {
+		t.Errorf("GetRefundStatus() of the rejected refund = %s, %v", status, err)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MaxNotificationSize is the max size of the body of a payment notification
+const MaxNotificationSize = 64 << 10
+
+var (
+	// ErrInvalidNotification is returned if the signature of a notification can not be verified, or the notification
+	// is too old to be accepted
+	ErrInvalidNotification = errors.New("invalid payment notification")
+	// ErrIgnoredNotification is returned for the notifications which are not about the result of a payment
+	ErrIgnoredNotification = errors.New("ignored payment notification")
+)
+
+// Notification is a verified payment result a payment provider pushes to the callback endpoint
+type Notification struct {
+	// ID identifies the notification, a provider delivers the same notification again until it is acknowledged
+	ID string
+	// TradeNO is the trade number CreatePayment returned for the payment
+	TradeNO string
+	// Status is one of the payment status, the amount is only set if the status is PaymentSuccess
+	Status string
+	Amount int64
+	// CreatedAt is the time the provider created the notification at
+	CreatedAt time.Time
+}
+
+// Notifier verifies and parses the payment notifications posted by a payment provider
+type Notifier interface {
+	ParseNotification(r *http.Request) (*Notification, error)
+}
+
+func NewNotifier(paymentMethod string) (Notifier, error) {
+	switch paymentMethod {
+	case "stripe":
+		return NewStripeNotifierFromEnv()
+	case "wechat":
+		return NewWechatNotifierFromEnv(context.Background())
+	default:
+		return nil, fmt.Errorf("unsupported payment method: %s", paymentMethod)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stripe/stripe-go/v74/webhook"
+	"github.com/wechatpay-apiv3/wechatpay-go/core"
+)
+
+const testStripeSecret = "whsec_test"
+
+func stripeNotification(t *testing.T, payload []byte, secret string, ts time.Time) *http.Request {
+	signature := webhook.ComputeSignature(ts, payload, secret)
+	req := httptest.NewRequest(http.MethodPost, "/payment/stripe/notify", bytes.NewReader(payload))
+	req.Header.Set("Stripe-Signature", fmt.Sprintf("t=%d,v1=%s", ts.Unix(), hex.EncodeToString(signature)))
+	return req
+}
+
+func stripeEvent(id, typ, sessionID, paymentStatus string, amount int64) []byte {
+	return []byte(fmt.Sprintf(`{"id":%q,"object":"event","type":%q,"created":%d,"api_version":"2020-08-27",`+
+		`"data":{"object":{"id":%q,"object":"checkout.session","payment_status":%q,"amount_total":%d}}}`,
+		id, typ, time.Now().Unix(), sessionID, paymentStatus, amount))
+}
+
+func TestStripeNotifier(t *testing.T) {
+	n := &StripeNotifier{Secret: testStripeSecret}
+
+	got, err := n.ParseNotification(stripeNotification(t, stripeEvent("evt_1", "checkout.session.completed", "cs_1", "paid", 2000), testStripeSecret, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "evt_1" || got.TradeNO != "cs_1" || got.Status != PaymentSuccess || got.Amount != 2000 {
+		t.Errorf("unexpected notification %+v", got)
+	}
+
+	got, err = n.ParseNotification(stripeNotification(t, stripeEvent("evt_2", "checkout.session.expired", "cs_2", "unpaid", 2000), testStripeSecret, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != PaymentExpired || got.Amount != 0 {
+		t.Errorf("unexpected notification %+v", got)
+	}
+
+	for name, req := range map[string]*http.Request{
+		"wrong secret": stripeNotification(t, stripeEvent("evt_3", "checkout.session.completed", "cs_3", "paid", 2000), "whsec_other", time.Now()),
+		"replayed":     stripeNotification(t, stripeEvent("evt_4", "checkout.session.completed", "cs_4", "paid", 2000), testStripeSecret, time.Now().Add(-time.Hour)),
+	} {
+		if _, err := n.ParseNotification(req); !errors.Is(err, ErrInvalidNotification) {
+			t.Errorf("%s: expected ErrInvalidNotification, got %v", name, err)
+		}
+	}
+
+	tampered := stripeNotification(t, stripeEvent("evt_5", "checkout.session.completed", "cs_5", "paid", 2000), testStripeSecret, time.Now())
+	tampered.Body = http.NoBody
+	if _, err := n.ParseNotification(tampered); !errors.Is(err, ErrInvalidNotification) {
+		t.Errorf("tampered: expected ErrInvalidNotification, got %v", err)
+	}
+
+	for _, payload := range [][]byte{
+		stripeEvent("evt_6", "checkout.session.completed", "cs_6", "unpaid", 2000),
+		stripeEvent("evt_7", "customer.created", "cs_7", "", 0),
+	} {
+		if _, err := n.ParseNotification(stripeNotification(t, payload, testStripeSecret, time.Now())); !errors.Is(err, ErrIgnoredNotification) {
+			t.Errorf("expected ErrIgnoredNotification, got %v", err)
+		}
+	}
+}
+
+const testAPIv3Key = "0123456789abcdef0123456789abcdef"
+
+type wechatPlatform struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newWechatPlatform(t *testing.T) *wechatPlatform {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1234567890),
+		Subject:      pkix.Name{CommonName: "wechatpay platform"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wechatPlatform{key: key, cert: cert}
+}
+
+// notification encrypts the transaction with the APIv3 key and signs the body like wechat pay does
+func (p *wechatPlatform) notification(t *testing.T, id string, transaction map[string]any, ts time.Time) *http.Request {
+	plaintext, err := json.Marshal(transaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher([]byte(testAPIv3Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, associatedData := "abcdef123456", "transaction"
+	body, err := json.Marshal(map[string]any{
+		"id":            id,
+		"create_time":   ts.Format(time.RFC3339),
+		"event_type":    "TRANSACTION.SUCCESS",
+		"resource_type": "encrypt-resource",
+		"resource": map[string]any{
+			"algorithm":       "AEAD_AES_256_GCM",
+			"ciphertext":      base64.StdEncoding.EncodeToString(gcm.Seal(nil, []byte(nonce), plaintext, []byte(associatedData))),
+			"associated_data": associatedData,
+			"nonce":           nonce,
+			"original_type":   "transaction",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp, signNonce := fmt.Sprint(ts.Unix()), "5K8264ILTKCH16CQ2502SI8ZNMTM67VS"
+	digest := sha256.Sum256([]byte(timestamp + "\n" + signNonce + "\n" + string(body) + "\n"))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/payment/wechat/notify", bytes.NewReader(body))
+	req.Header.Set("Wechatpay-Serial", p.serial())
+	req.Header.Set("Wechatpay-Timestamp", timestamp)
+	req.Header.Set("Wechatpay-Nonce", signNonce)
+	req.Header.Set("Wechatpay-Signature", base64.StdEncoding.EncodeToString(signature))
+	return req
+}
+
+func (p *wechatPlatform) serial() string {
+	return fmt.Sprintf("%X", p.cert.SerialNumber.Bytes())
+}
+
+func TestWechatNotifier(t *testing.T) {
+	platform := newWechatPlatform(t)
+	n, err := NewWechatNotifier(testAPIv3Key, core.NewCertificateMapWithList([]*x509.Certificate{platform.cert}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paid := map[string]any{
+		"out_trade_no": "trade-1",
+		"trade_state":  "SUCCESS",
+		"amount":       map[string]any{"total": 100, "currency": "CNY"},
+	}
+
+	got, err := n.ParseNotification(platform.notification(t, "notify-1", paid, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "notify-1" || got.TradeNO != "trade-1" || got.Status != PaymentSuccess || got.Amount != 100 {
+		t.Errorf("unexpected notification %+v", got)
+	}
+
+	other := newWechatPlatform(t)
+	forged := other.notification(t, "notify-2", paid, time.Now())
+	forged.Header.Set("Wechatpay-Serial", platform.serial())
+	if _, err := n.ParseNotification(forged); !errors.Is(err, ErrInvalidNotification) {
+		t.Errorf("forged: expected ErrInvalidNotification, got %v", err)
+	}
+	if _, err := n.ParseNotification(platform.notification(t, "notify-3", paid, time.Now().Add(-10*time.Minute))); !errors.Is(err, ErrInvalidNotification) {
+		t.Errorf("replayed: expected ErrInvalidNotification, got %v", err)
+	}
+
+	notPaid := map[string]any{"out_trade_no": "trade-4", "trade_state": "NOTPAY"}
+	if _, err := n.ParseNotification(platform.notification(t, "notify-4", notPaid, time.Now())); !errors.Is(err, ErrIgnoredNotification) {
+		t.Errorf("expected ErrIgnoredNotification, got %v", err)
+	}
+}
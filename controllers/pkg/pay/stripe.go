@@ -15,6 +15,7 @@
 package pay
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -81,3 +82,52 @@ func (s StripePayment) ExpireSession(sessionID string) error {
 	}
 	return nil
 }
+
+func (s StripePayment) RefundPayment(sessionID, refundNO string, amount, _ int64, reason string) (string, error) {
+	ses, err := GetSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	if ses.PaymentIntent == nil {
+		return PaymentFailed, fmt.Errorf("checkout session %s is not paid", sessionID)
+	}
+	r, err := CreateRefund(ses.PaymentIntent.ID, refundNO, amount, reason)
+	if err != nil {
+		// stripe rejects the refund with a 4xx status, e.g. the amount exceeds the refundable amount
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.HTTPStatusCode >= 400 && stripeErr.HTTPStatusCode < 500 {
+			return PaymentFailed, err
+		}
+		return "", err
+	}
+	return stripeRefundStatus(r)
+}
+
+func (s StripePayment) GetRefundStatus(sessionID, refundNO string) (string, error) {
+	ses, err := GetSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	if ses.PaymentIntent == nil {
+		return PaymentFailed, fmt.Errorf("checkout session %s is not paid", sessionID)
+	}
+	r, err := GetRefund(ses.PaymentIntent.ID, refundNO)
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		return PaymentFailed, fmt.Errorf("refund %s is not made", refundNO)
+	}
+	return stripeRefundStatus(r)
+}
+
+func stripeRefundStatus(r *stripe.Refund) (string, error) {
+	switch r.Status {
+	case stripe.RefundStatusSucceeded:
+		return PaymentSuccess, nil
+	case stripe.RefundStatusFailed, stripe.RefundStatusCanceled:
+		return PaymentFailed, fmt.Errorf("refund %s is %s", r.ID, r.Status)
+	default:
+		return PaymentProcessing, nil
+	}
+}
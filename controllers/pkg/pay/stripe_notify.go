@@ -0,0 +1,90 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/stripe/stripe-go/v74"
+	"github.com/stripe/stripe-go/v74/webhook"
+)
+
+const StripeWebhookSecret = "STRIPE_WEBHOOK_SECRET"
+
+// StripeNotifier verifies the webhook events of stripe with the signing secret of the webhook endpoint
+type StripeNotifier struct {
+	Secret string
+	// Tolerance is how old an event can be, webhook.DefaultTolerance if zero
+	Tolerance time.Duration
+}
+
+func NewStripeNotifierFromEnv() (*StripeNotifier, error) {
+	secret := os.Getenv(StripeWebhookSecret)
+	if secret == "" {
+		return nil, fmt.Errorf("env %s is not set", StripeWebhookSecret)
+	}
+	return &StripeNotifier{Secret: secret}, nil
+}
+
+func (n *StripeNotifier) ParseNotification(r *http.Request) (*Notification, error) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, MaxNotificationSize))
+	if err != nil {
+		return nil, fmt.Errorf("read stripe event error: %w", err)
+	}
+	event, err := webhook.ConstructEventWithOptions(payload, r.Header.Get("Stripe-Signature"), n.Secret,
+		webhook.ConstructEventOptions{
+			Tolerance: n.Tolerance,
+			// only the id, status and amount of the checkout session are read, which are the same in all api versions
+			IgnoreAPIVersionMismatch: true,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNotification, err)
+	}
+
+	var status string
+	switch event.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+		status = PaymentSuccess
+	case "checkout.session.async_payment_failed":
+		status = PaymentFailed
+	case "checkout.session.expired":
+		status = PaymentExpired
+	default:
+		return nil, fmt.Errorf("%w: stripe event %s", ErrIgnoredNotification, event.Type)
+	}
+	ses := &stripe.CheckoutSession{}
+	if err := json.Unmarshal(event.Data.Raw, ses); err != nil {
+		return nil, fmt.Errorf("unmarshal checkout session of stripe event %s error: %w", event.ID, err)
+	}
+	// a completed session of a delayed payment method is not paid until async_payment_succeeded
+	if status == PaymentSuccess && ses.PaymentStatus != stripe.CheckoutSessionPaymentStatusPaid {
+		return nil, fmt.Errorf("%w: checkout session %s is %s", ErrIgnoredNotification, ses.ID, ses.PaymentStatus)
+	}
+	notification := &Notification{
+		ID:        event.ID,
+		TradeNO:   ses.ID,
+		Status:    status,
+		CreatedAt: time.Unix(event.Created, 0),
+	}
+	if status == PaymentSuccess {
+		notification.Amount = ses.AmountTotal
+	}
+	return notification, nil
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/stripe/stripe-go/v74"
 	"github.com/stripe/stripe-go/v74/checkout/session"
+	"github.com/stripe/stripe-go/v74/refund"
 )
 
 const StripeAPIKEY = "STRIPE_API_KEY"
@@ -79,3 +80,29 @@ func GetSession(sessionID string) (*stripe.CheckoutSession, error) {
 func ExpireSession(sessionID string) (*stripe.CheckoutSession, error) {
 	return session.Expire(sessionID, nil)
 }
+
+// CreateRefund refunds the amount of the payment intent, the refund number is used as the idempotency key so the
+// refund is made once if it is retried
+func CreateRefund(paymentIntentID, refundNO string, amount int64, reason string) (*stripe.Refund, error) {
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+		Amount:        stripe.Int64(amount),
+	}
+	params.SetIdempotencyKey(refundNO)
+	params.AddMetadata("refund_no", refundNO)
+	if reason != "" {
+		params.AddMetadata("reason", reason)
+	}
+	return refund.New(params)
+}
+
+// GetRefund returns the refund of the payment intent made with the refund number, nil if it is not made
+func GetRefund(paymentIntentID, refundNO string) (*stripe.Refund, error) {
+	iter := refund.List(&stripe.RefundListParams{PaymentIntent: stripe.String(paymentIntentID)})
+	for iter.Next() {
+		if r := iter.Refund(); r.Metadata["refund_no"] == refundNO {
+			return r, nil
+		}
+	}
+	return nil, iter.Err()
+}
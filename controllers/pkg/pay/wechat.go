@@ -14,11 +14,20 @@
 
 package pay
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/wechatpay-apiv3/wechatpay-go/core"
+	"github.com/wechatpay-apiv3/wechatpay-go/services/refunddomestic"
+)
 
 func (w WechatPayment) CreatePayment(amount int64, user, describe string) (string, string, error) {
 	tradeNO := GetRandomString(32)
-	codeURL, err := WechatPay(amount, user, tradeNO, describe, "")
+	// wechat pay posts the result of the payment to the callback, DefaultCallbackURL if not set
+	codeURL, err := WechatPay(amount, user, tradeNO, describe, os.Getenv(NotifyCallbackURL))
 	if err != nil {
 		return "", "", err
 	}
@@ -47,3 +56,43 @@ func (w WechatPayment) GetPaymentDetails(sessionID string) (string, int64, error
 func (w WechatPayment) ExpireSession(_ string) error {
 	return nil
 }
+
+func (w WechatPayment) RefundPayment(tradeNO, refundNO string, amount, total int64, reason string) (string, error) {
+	resp, err := WechatRefund(tradeNO, refundNO, amount, total, reason)
+	if err != nil {
+		// wechat pay rejects the refund with a 4xx status, e.g. the balance of the merchant is not enough
+		var apiErr *core.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 {
+			return PaymentFailed, err
+		}
+		return "", err
+	}
+	return wechatRefundStatus(refundNO, resp)
+}
+
+func (w WechatPayment) GetRefundStatus(_, refundNO string) (string, error) {
+	resp, err := WechatQueryRefund(refundNO)
+	if err != nil {
+		var apiErr *core.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return PaymentFailed, err
+		}
+		return "", err
+	}
+	return wechatRefundStatus(refundNO, resp)
+}
+
+func wechatRefundStatus(refundNO string, resp *refunddomestic.Refund) (string, error) {
+	if resp.Status == nil {
+		return PaymentProcessing, nil
+	}
+	switch *resp.Status {
+	case refunddomestic.STATUS_SUCCESS:
+		return PaymentSuccess, nil
+	case refunddomestic.STATUS_CLOSED:
+		return PaymentFailed, fmt.Errorf("refund %s is closed", refundNO)
+	default:
+		// an abnormal refund is not received by the user yet and is made again by the merchant
+		return PaymentProcessing, nil
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/wechatpay-apiv3/wechatpay-go/core"
+	"github.com/wechatpay-apiv3/wechatpay-go/core/auth/verifiers"
+	"github.com/wechatpay-apiv3/wechatpay-go/core/downloader"
+	"github.com/wechatpay-apiv3/wechatpay-go/core/notify"
+	"github.com/wechatpay-apiv3/wechatpay-go/services/payments"
+)
+
+// WechatNotifier verifies the payment notifications of wechat pay with the platform certificates and decrypts them
+// with the APIv3 key. The timestamp of a notification must be within 5 minutes.
+type WechatNotifier struct {
+	handler *notify.Handler
+}
+
+func NewWechatNotifier(apiV3Key string, certificates core.CertificateGetter) (*WechatNotifier, error) {
+	handler, err := notify.NewRSANotifyHandler(apiV3Key, verifiers.NewSHA256WithRSAVerifier(certificates))
+	if err != nil {
+		return nil, fmt.Errorf("new wechat notify handler error: %w", err)
+	}
+	return &WechatNotifier{handler: handler}, nil
+}
+
+// NewWechatNotifierFromEnv verifies the notifications with the platform certificates downloaded for the merchant
+func NewWechatNotifierFromEnv(ctx context.Context) (*WechatNotifier, error) {
+	// wechat pay posts the results to DefaultCallbackURL unless the callback is set to the notify endpoint
+	if os.Getenv(NotifyCallbackURL) == "" {
+		return nil, fmt.Errorf("%s is not set", NotifyCallbackURL)
+	}
+	// the client registers the certificate downloader of the merchant
+	if _, err := NewClient(ctx); err != nil {
+		return nil, fmt.Errorf("new wechat pay client err:%s", err)
+	}
+	return NewWechatNotifier(os.Getenv(MchAPIv3Key), downloader.MgrInstance().GetCertificateVisitor(os.Getenv(MchID)))
+}
+
+func (n *WechatNotifier) ParseNotification(r *http.Request) (*Notification, error) {
+	transaction := &payments.Transaction{}
+	req, err := n.handler.ParseNotifyRequest(r.Context(), r, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNotification, err)
+	}
+	if transaction.OutTradeNo == nil || transaction.TradeState == nil {
+		return nil, fmt.Errorf("%w: wechat notification %s has no trade", ErrIgnoredNotification, req.ID)
+	}
+
+	notification := &Notification{
+		ID:      req.ID,
+		TradeNO: *transaction.OutTradeNo,
+	}
+	if req.CreateTime != nil {
+		notification.CreatedAt = *req.CreateTime
+	} else {
+		notification.CreatedAt = time.Now()
+	}
+	switch *transaction.TradeState {
+	case StatusSuccess:
+		if transaction.Amount == nil || transaction.Amount.Total == nil {
+			return nil, fmt.Errorf("wechat notification %s has no amount", req.ID)
+		}
+		notification.Status, notification.Amount = PaymentSuccess, *transaction.Amount.Total
+	case StatusFail, "PAYERROR":
+		notification.Status = PaymentFailed
+	case "CLOSED", "REVOKED":
+		notification.Status = PaymentExpired
+	default:
+		return nil, fmt.Errorf("%w: wechat trade %s is %s", ErrIgnoredNotification, *transaction.OutTradeNo, *transaction.TradeState)
+	}
+	return notification, nil
+}
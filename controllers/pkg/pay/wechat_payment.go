@@ -26,6 +26,7 @@ import (
 	"github.com/wechatpay-apiv3/wechatpay-go/core/option"
 	"github.com/wechatpay-apiv3/wechatpay-go/services/payments"
 	"github.com/wechatpay-apiv3/wechatpay-go/services/payments/native"
+	"github.com/wechatpay-apiv3/wechatpay-go/services/refunddomestic"
 	"github.com/wechatpay-apiv3/wechatpay-go/utils"
 )
 
@@ -139,6 +140,49 @@ func WechatPay(amount int64, user, tradeNO, describe, callback string) (string,
 	return *resp.CodeUrl, nil
 }
 
+// 1 ¥ = amount 100, the refund is made once if it is retried with the same refundNO
+func WechatRefund(tradeNO, refundNO string, amount, total int64, reason string) (*refunddomestic.Refund, error) {
+	ctx := context.Background()
+	client, err := NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("new wechat pay client err:%s", err)
+	}
+	req := refunddomestic.CreateRequest{
+		OutTradeNo:  core.String(tradeNO),
+		OutRefundNo: core.String(refundNO),
+		Amount: &refunddomestic.AmountReq{
+			Refund:   core.Int64(amount),
+			Total:    core.Int64(total),
+			Currency: core.String("CNY"),
+		},
+	}
+	if reason != "" {
+		req.Reason = core.String(reason)
+	}
+	svc := refunddomestic.RefundsApiService{Client: client}
+	resp, _, err := svc.Create(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("call Refund err:%w", err)
+	}
+	return resp, nil
+}
+
+// WechatQueryRefund returns the refund of the refundNO, core.APIError with the status code 404 is returned if the
+// refund is not made
+func WechatQueryRefund(refundNO string) (*refunddomestic.Refund, error) {
+	ctx := context.Background()
+	client, err := NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("new wechat pay client err:%s", err)
+	}
+	svc := refunddomestic.RefundsApiService{Client: client}
+	resp, _, err := svc.QueryByOutRefundNo(ctx, refunddomestic.QueryByOutRefundNoRequest{OutRefundNo: core.String(refundNO)})
+	if err != nil {
+		return nil, fmt.Errorf("call QueryRefund err:%w", err)
+	}
+	return resp, nil
+}
+
 func GetRandomString(n int) string {
 	randBytes := make([]byte, n/2)
 	if _, err := rand.Read(randBytes); err != nil {
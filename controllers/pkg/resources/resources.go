@@ -254,6 +254,8 @@ type PropertyType struct {
 	UnitString string `json:"unit" bson:"unit"`
 	//charging cycle second
 	UnitPeriod string `json:"unit_period,omitempty" bson:"unit_period,omitempty"`
+	// LIMIT, USAGE. How the used amount of a pod is metered, the limit by default
+	Metering string `json:"metering,omitempty" bson:"metering,omitempty"`
 }
 
 type PropertyTypeLS struct {
@@ -271,6 +273,13 @@ const (
 	DIF = "DIF"
 )
 
+const (
+	// MeteringLimit meters a pod by the limits of its containers, falling back to the requests
+	MeteringLimit = "LIMIT"
+	// MeteringUsage meters a pod by the average of the usage sampled over the billing interval
+	MeteringUsage = "USAGE"
+)
+
 var DefaultPropertyTypeList = []PropertyType{
 	{
 		Name:      "cpu",
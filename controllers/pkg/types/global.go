@@ -219,6 +219,45 @@ func (Payment) TableName() string {
 	return "Payment"
 }
 
+const (
+	RefundStatusProcessing = "PROCESSING"
+	RefundStatusSuccess    = "SUCCESS"
+	RefundStatusFailed     = "FAILED"
+)
+
+// Refund is a refund of a payment, a payment may be refunded in several partial refunds
+type Refund struct {
+	ID        string    `gorm:"type:text;primary_key" json:"id" bson:"id"`
+	PaymentID string    `gorm:"type:text;not null;index" json:"paymentID" bson:"paymentID"`
+	UserUID   uuid.UUID `gorm:"column:userUid;type:uuid;not null" json:"userUID" bson:"userUID"`
+	Method    string    `gorm:"type:text;not null" json:"method" bson:"method"`
+	TradeNO   string    `gorm:"type:text;not null" json:"tradeNO" bson:"tradeNO"`
+	// Amount is the amount refunded to the user, Deduction is the amount debited from the balance, which includes the
+	// gift of the payment in proportion to the amount
+	Amount    int64     `gorm:"type:bigint;not null" json:"amount" bson:"amount"`
+	Deduction int64     `gorm:"type:bigint;not null" json:"deduction" bson:"deduction"`
+	Reason    string    `gorm:"type:text" json:"reason" bson:"reason"`
+	Status    string    `gorm:"type:text;not null" json:"status" bson:"status"`
+	Message   string    `gorm:"type:text" json:"message" bson:"message"`
+	CreatedAt time.Time `gorm:"type:timestamp(3) with time zone;default:current_timestamp()" json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time `gorm:"type:timestamp(3) with time zone;default:current_timestamp()" json:"updatedAt" bson:"updatedAt"`
+}
+
+func (Refund) TableName() string {
+	return "Refund"
+}
+
+// TaskLock is the lock of a periodic task run by the replicas of a service, it is held until it expires
+type TaskLock struct {
+	Name      string    `gorm:"type:text;primary_key" json:"name" bson:"name"`
+	Holder    string    `gorm:"type:text;not null" json:"holder" bson:"holder"`
+	ExpiresAt time.Time `gorm:"column:expiresAt;type:timestamp(3) with time zone;not null" json:"expiresAt" bson:"expiresAt"`
+}
+
+func (TaskLock) TableName() string {
+	return "TaskLock"
+}
+
 type InvoiceStatus string
 
 const (
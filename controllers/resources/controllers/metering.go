@@ -0,0 +1,219 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+const (
+	UsageSampleInterval = "USAGE_SAMPLE_INTERVAL"
+
+	// DefaultUsageSampleInterval is about the resolution of metrics-server, sampling faster reads the same values
+	DefaultUsageSampleInterval = 15 * time.Second
+)
+
+// meteredResources are the resources of a pod which can be metered by the usage
+var meteredResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+
+// podMetricsListGVK is the kind metrics-server serves the resource usage of pods as
+var podMetricsListGVK = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetricsList"}
+
+// MetricsSource samples the resource usage of the running pods
+type MetricsSource interface {
+	// PodUsage returns the usage of all the containers of the pods, init containers included
+	PodUsage(ctx context.Context) (map[types.NamespacedName]corev1.ResourceList, error)
+}
+
+// MetricsAPISource reads the usage of the pods from the metrics API, which metrics-server collects from the cgroup
+// stats of the containers on the kubelets
+type MetricsAPISource struct {
+	// Reader reads the pod metrics, it must not be a cached reader since pod metrics can not be watched
+	Reader client.Reader
+}
+
+func (s *MetricsAPISource) PodUsage(ctx context.Context) (map[types.NamespacedName]corev1.ResourceList, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(podMetricsListGVK)
+	if err := s.Reader.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("metrics API is not available, please check metrics-server is installed: %w", err)
+		}
+		return nil, fmt.Errorf("failed to list pod metrics: %w", err)
+	}
+	usage := make(map[types.NamespacedName]corev1.ResourceList, len(list.Items))
+	for i := range list.Items {
+		metrics := &list.Items[i]
+		containers, _, err := unstructured.NestedSlice(metrics.Object, "containers")
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics of pod %s/%s: %w", metrics.GetNamespace(), metrics.GetName(), err)
+		}
+		podUsage := corev1.ResourceList{}
+		for _, c := range containers {
+			container, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+			for _, name := range meteredResources {
+				value, _, err := unstructured.NestedString(container, "usage", name.String())
+				if err != nil || value == "" {
+					continue
+				}
+				q, err := resource.ParseQuantity(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s usage %s of pod %s/%s: %w", name, value, metrics.GetNamespace(), metrics.GetName(), err)
+				}
+				total := podUsage[name]
+				total.Add(q)
+				podUsage[name] = total
+			}
+		}
+		usage[types.NamespacedName{Namespace: metrics.GetNamespace(), Name: metrics.GetName()}] = podUsage
+	}
+	return usage, nil
+}
+
+// UsageMeter integrates the usage sampled from the source over the billing interval, a sample is held until the next
+// one, so the average of a pod is weighted by the time each sample is held for.
+type UsageMeter struct {
+	Source MetricsSource
+
+	lock sync.Mutex
+	pods map[types.NamespacedName]*usageIntegral
+}
+
+type usageIntegral struct {
+	// milliSeconds is the sum of the milli value of the usage multiplied by the seconds it is held for
+	milliSeconds map[corev1.ResourceName]float64
+	// seconds is the time the pod is sampled for in the interval
+	seconds float64
+	// last is the latest sample, nil if the pod is missing from the latest sample
+	last       corev1.ResourceList
+	lastSample time.Time
+}
+
+func NewUsageMeter(source MetricsSource) *UsageMeter {
+	return &UsageMeter{
+		Source: source,
+		pods:   make(map[types.NamespacedName]*usageIntegral),
+	}
+}
+
+// Sample samples the usage of the pods at now, a pod missing from the sample is held until now and then stops
+// being integrated.
+func (m *UsageMeter) Sample(ctx context.Context, now time.Time) error {
+	usage, err := m.Source.PodUsage(ctx)
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for pod, integral := range m.pods {
+		integral.hold(now)
+		if _, ok := usage[pod]; !ok {
+			integral.last = nil
+		}
+	}
+	for pod, podUsage := range usage {
+		integral, ok := m.pods[pod]
+		if !ok {
+			integral = &usageIntegral{milliSeconds: make(map[corev1.ResourceName]float64)}
+			m.pods[pod] = integral
+		}
+		integral.last = podUsage
+		integral.lastSample = now
+	}
+	return nil
+}
+
+// Drain returns the average usage of the pods sampled since the last drain and starts the next interval. A pod is
+// missing from the result if it is not sampled in the interval.
+func (m *UsageMeter) Drain(now time.Time) map[types.NamespacedName]corev1.ResourceList {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	avg := make(map[types.NamespacedName]corev1.ResourceList, len(m.pods))
+	for pod, integral := range m.pods {
+		integral.hold(now)
+		if usage := integral.average(); usage != nil {
+			avg[pod] = usage
+		}
+		if integral.last == nil {
+			delete(m.pods, pod)
+			continue
+		}
+		integral.milliSeconds = make(map[corev1.ResourceName]float64)
+		integral.seconds = 0
+	}
+	return avg
+}
+
+// hold integrates the latest sample until now
+func (i *usageIntegral) hold(now time.Time) {
+	if i.last == nil || !now.After(i.lastSample) {
+		return
+	}
+	seconds := now.Sub(i.lastSample).Seconds()
+	for _, name := range meteredResources {
+		q := i.last[name]
+		i.milliSeconds[name] += float64(q.MilliValue()) * seconds
+	}
+	i.seconds += seconds
+	i.lastSample = now
+}
+
+func (i *usageIntegral) average() corev1.ResourceList {
+	if i.seconds == 0 {
+		// sampled right at the end of the interval only
+		return i.last
+	}
+	usage := make(corev1.ResourceList, len(meteredResources))
+	for _, name := range meteredResources {
+		format := resource.BinarySI
+		if name == corev1.ResourceCPU {
+			format = resource.DecimalSI
+		}
+		usage[name] = *resource.NewMilliQuantity(int64(math.Ceil(i.milliSeconds[name]/i.seconds)), format)
+	}
+	return usage
+}
+
+// meteredByUsage returns whether the resource is metered by the usage instead of the limit
+func (r *MonitorReconciler) meteredByUsage(name corev1.ResourceName) bool {
+	return r.UsageMeter != nil && r.Properties.StringMap[name.String()].Metering == resources.MeteringUsage
+}
+
+// MeteringByUsage returns whether any of the properties is metered by the usage
+func MeteringByUsage(properties *resources.PropertyTypeLS) bool {
+	for _, name := range meteredResources {
+		if properties.StringMap[name.String()].Metering == resources.MeteringUsage {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+// Copyright © 2024 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/labring/sealos/controllers/pkg/resources"
+)
+
+// fakeMetricsSource returns the usage set before each sample
+type fakeMetricsSource struct {
+	usage map[types.NamespacedName]corev1.ResourceList
+}
+
+func (s *fakeMetricsSource) PodUsage(_ context.Context) (map[types.NamespacedName]corev1.ResourceList, error) {
+	return s.usage, nil
+}
+
+func usage(cpu, memory string) corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+func expectUsage(t *testing.T, got map[types.NamespacedName]corev1.ResourceList, pod types.NamespacedName, want corev1.ResourceList) {
+	t.Helper()
+	podUsage, ok := got[pod]
+	if !ok {
+		t.Fatalf("pod %s is not metered", pod)
+	}
+	for name, q := range want {
+		if value := podUsage[name]; value.Cmp(q) != 0 {
+			t.Errorf("pod %s %s usage = %s, want %s", pod, name, value.String(), q.String())
+		}
+	}
+}
+
+func TestUsageMeter(t *testing.T) {
+	a := types.NamespacedName{Namespace: "ns-test", Name: "a"}
+	b := types.NamespacedName{Namespace: "ns-test", Name: "b"}
+	c := types.NamespacedName{Namespace: "ns-test", Name: "c"}
+	source := &fakeMetricsSource{}
+	m := NewUsageMeter(source)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sample := func(at time.Duration, usage map[types.NamespacedName]corev1.ResourceList) {
+		source.usage = usage
+		if err := m.Sample(context.Background(), start.Add(at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sample(0, map[types.NamespacedName]corev1.ResourceList{
+		a: usage("100m", "100Mi"),
+		c: usage("1", "1Gi"),
+	})
+	// b is started and c is deleted
+	sample(30*time.Second, map[types.NamespacedName]corev1.ResourceList{
+		a: usage("300m", "100Mi"),
+		b: usage("50m", "10Mi"),
+	})
+	got := m.Drain(start.Add(time.Minute))
+	expectUsage(t, got, a, usage("200m", "100Mi"))
+	expectUsage(t, got, b, usage("50m", "10Mi"))
+	expectUsage(t, got, c, usage("1", "1Gi"))
+
+	// the latest sample is held over the interval
+	got = m.Drain(start.Add(2 * time.Minute))
+	expectUsage(t, got, a, usage("300m", "100Mi"))
+	if _, ok := got[c]; ok {
+		t.Errorf("deleted pod %s is metered", c)
+	}
+}
+
+func TestMonitorPodResourceUsageByUsage(t *testing.T) {
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns-test",
+				Labels:    map[string]string{resources.AppDeployLabelKey: name},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "node",
+				Containers: []corev1.Container{{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Limits: usage("1", "1Gi"),
+					},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, StartTime: &metav1.Time{Time: time.Now()}},
+		}
+	}
+	sampledPod, unsampledPod := newPod("sampled"), newPod("unsampled")
+	properties := make([]resources.PropertyType, len(resources.DefaultPropertyTypeList))
+	copy(properties, resources.DefaultPropertyTypeList)
+	for i := range properties {
+		if properties[i].Name == corev1.ResourceCPU.String() {
+			properties[i].Metering = resources.MeteringUsage
+		}
+	}
+	r := &MonitorReconciler{
+		Client:     fake.NewClientBuilder().WithObjects(sampledPod, unsampledPod).Build(),
+		Logger:     ctrl.Log.WithName("test"),
+		Properties: resources.NewPropertyTypeLS(properties),
+		UsageMeter: NewUsageMeter(&fakeMetricsSource{}),
+		podUsage: map[types.NamespacedName]corev1.ResourceList{
+			{Namespace: "ns-test", Name: "sampled"}: usage("250m", "300Mi"),
+		},
+	}
+
+	resUsed := map[string]map[corev1.ResourceName]*quantity{}
+	resNamed := make(map[string]*resources.ResourceNamed)
+	if err := r.monitorPodResourceUsage("ns-test", resUsed, resNamed, map[string]struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	for pod, want := range map[*corev1.Pod]corev1.ResourceList{
+		// cpu is metered by the usage and memory by the limit
+		sampledPod: usage("250m", "1Gi"),
+		// a pod not sampled yet is metered by the limit
+		unsampledPod: usage("1", "1Gi"),
+	} {
+		used := resUsed[resources.NewResourceNamed(pod).String()]
+		for name, q := range want {
+			if used[name].Cmp(q) != 0 {
+				t.Errorf("pod %s %s used = %s, want %s", pod.Name, name, used[name].String(), q.String())
+			}
+		}
+	}
+
+	_, monitored := r.getResourceUsed(resUsed[resources.NewResourceNamed(sampledPod).String()])
+	if cpu := monitored[r.Properties.StringMap[corev1.ResourceCPU.String()].Enum]; cpu != 250 {
+		t.Errorf("monitored cpu = %d, want 250", cpu)
+	}
+}
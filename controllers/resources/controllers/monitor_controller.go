@@ -88,6 +88,11 @@ type MonitorReconciler struct {
 	ObjStorageMetricsClient  *objstorage.MetricsClient
 	ObjStorageUserBackupSize map[string]int64
 	ObjectStorageInstance    string
+	// UsageMeter samples the usage of the pods, nil if no property is metered by the usage
+	UsageMeter          *UsageMeter
+	usageSampleInterval time.Duration
+	// podUsage is the average usage of the pods in the current billing interval
+	podUsage map[client.ObjectKey]corev1.ResourceList
 }
 
 type quantity struct {
@@ -119,6 +124,7 @@ const (
 //+kubebuilder:rbac:groups=app.sealos.io,resources=instances/status,verbs=get;list;watch
 //+kubebuilder:rbac:groups=dataprotection.apecloud.io,resources=backups,verbs=get;list;watch
 //+kubebuilder:rbac:groups=dataprotection.apecloud.io,resources=backups/status,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get;list
 
 func NewMonitorReconciler(mgr ctrl.Manager) (*MonitorReconciler, error) {
 	r := &MonitorReconciler{
@@ -129,6 +135,7 @@ func NewMonitorReconciler(mgr ctrl.Manager) (*MonitorReconciler, error) {
 		PromURL:               os.Getenv(PrometheusURL),
 		ObjectStorageInstance: os.Getenv(ObjectStorageInstance),
 		NvidiaGpu:             make(map[string]gpu.NvidiaGPU),
+		usageSampleInterval:   env.GetDurationEnvWithDefault(UsageSampleInterval, DefaultUsageSampleInterval),
 	}
 	concurrentLimit = env.GetInt64EnvWithDefault(ConcurrentLimit, DefaultConcurrencyLimit)
 	var err error
@@ -166,6 +173,9 @@ func InitIndexField(mgr ctrl.Manager) error {
 }
 
 func (r *MonitorReconciler) StartReconciler(ctx context.Context) error {
+	if r.UsageMeter != nil {
+		r.startSampleUsage()
+	}
 	r.startPeriodicReconcile()
 	if r.TrafficClient != nil || r.ObjStorageClient != nil {
 		r.startMonitorTraffic()
@@ -246,6 +256,25 @@ func (r *MonitorReconciler) startMonitorTraffic() {
 	}()
 }
 
+func (r *MonitorReconciler) startSampleUsage() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.usageSampleInterval)
+		for {
+			if err := r.UsageMeter.Sample(context.Background(), time.Now()); err != nil {
+				r.Logger.Error(err, "failed to sample pod usage")
+			}
+			select {
+			case <-ticker.C:
+			case <-r.stopCh:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
 func (r *MonitorReconciler) stopPeriodicReconcile() {
 	close(r.stopCh)
 	r.wg.Wait()
@@ -299,6 +328,9 @@ func (r *MonitorReconciler) processNamespaceList(namespaceList *corev1.Namespace
 }
 
 func (r *MonitorReconciler) preMonitorResourceUsage() error {
+	if r.UsageMeter != nil {
+		r.podUsage = r.UsageMeter.Drain(time.Now())
+	}
 	if r.ObjStorageMetricsClient != nil {
 		metrics, err := objstorage.QueryUserUsageAndTraffic(r.ObjStorageMetricsClient)
 		if err != nil {
@@ -427,6 +459,15 @@ func (r *MonitorReconciler) monitorPodResourceUsage(namespace string, resUsed ma
 		}
 		// skip pods that do not start for more than 1 minute
 		skip := pod.Status.Phase != corev1.PodRunning && (pod.Status.StartTime == nil || time.Since(pod.Status.StartTime.Time) > 1*time.Minute)
+		// the pods not sampled in the interval, e.g. just started, are metered by the limit
+		usage, sampled := r.podUsage[client.ObjectKeyFromObject(pod)]
+		if !skip && sampled {
+			for _, name := range meteredResources {
+				if r.meteredByUsage(name) {
+					resUsed[podResNamed.String()][name].Add(usage[name])
+				}
+			}
+		}
 		for _, container := range pod.Spec.Containers {
 			// gpu only use limit and not ignore pod pending status
 			if gpuRequest, ok := container.Resources.Limits[gpu.NvidiaGpuKey]; ok {
@@ -437,15 +478,15 @@ func (r *MonitorReconciler) monitorPodResourceUsage(namespace string, resUsed ma
 			if skip {
 				continue
 			}
-			if cpuRequest, ok := container.Resources.Limits[corev1.ResourceCPU]; ok {
-				resUsed[podResNamed.String()][corev1.ResourceCPU].Add(cpuRequest)
-			} else {
-				resUsed[podResNamed.String()][corev1.ResourceCPU].Add(container.Resources.Requests[corev1.ResourceCPU])
-			}
-			if memoryRequest, ok := container.Resources.Limits[corev1.ResourceMemory]; ok {
-				resUsed[podResNamed.String()][corev1.ResourceMemory].Add(memoryRequest)
-			} else {
-				resUsed[podResNamed.String()][corev1.ResourceMemory].Add(container.Resources.Requests[corev1.ResourceMemory])
+			for _, name := range meteredResources {
+				if sampled && r.meteredByUsage(name) {
+					continue
+				}
+				if limit, ok := container.Resources.Limits[name]; ok {
+					resUsed[podResNamed.String()][name].Add(limit)
+				} else {
+					resUsed[podResNamed.String()][name].Add(container.Resources.Requests[name])
+				}
 			}
 		}
 	}
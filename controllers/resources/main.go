@@ -153,6 +153,10 @@ func main() {
 		os.Exit(1)
 	}
 	reconciler.Properties = resources.DefaultPropertyTypeLS
+	if controllers.MeteringByUsage(reconciler.Properties) {
+		setupLog.Info("meter pods by the usage sampled from the metrics API")
+		reconciler.UsageMeter = controllers.NewUsageMeter(&controllers.MetricsAPISource{Reader: mgr.GetAPIReader()})
+	}
 	const (
 		MinioEndpoint          = "MINIO_ENDPOINT"
 		MinioAk                = "MINIO_AK"
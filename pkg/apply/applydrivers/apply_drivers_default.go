@@ -26,6 +26,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/labring/sealos/pkg/apply/processor"
+	"github.com/labring/sealos/pkg/audit"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/exec"
@@ -80,7 +81,28 @@ type Applier struct {
 	RunNewImages   []string
 }
 
-func (c *Applier) Apply() error {
+func (c *Applier) Apply() (err error) {
+	rec := audit.Begin(c.ClusterDesired, c.targetHosts())
+	defer func() { rec.End(err) }()
+	return c.apply()
+}
+
+// targetHosts returns the hosts changed by the apply, all hosts if the cluster is created
+func (c *Applier) targetHosts() []string {
+	if c.ClusterCurrent == nil || c.ClusterCurrent.CreationTimestamp.IsZero() {
+		return c.ClusterDesired.GetAllIPS()
+	}
+	mj, md := iputils.GetDiffHosts(c.ClusterCurrent.GetMasterIPAndPortList(), c.ClusterDesired.GetMasterIPAndPortList())
+	nj, nd := iputils.GetDiffHosts(c.ClusterCurrent.GetNodeIPAndPortList(), c.ClusterDesired.GetNodeIPAndPortList())
+	hosts := append(append(append(mj, md...), nj...), nd...)
+	if len(hosts) == 0 && len(c.RunNewImages) > 0 {
+		// images are run on master0
+		hosts = []string{c.ClusterDesired.GetMaster0IPAndPort()}
+	}
+	return hosts
+}
+
+func (c *Applier) apply() error {
 	// clusterErr and appErr should not appear in the same time
 	var clusterErr, appErr error
 	defer func() {
@@ -244,7 +266,9 @@ func (c *Applier) scaleCluster(mj, md, nj, nd []string) error {
 	return nil
 }
 
-func (c *Applier) Delete() error {
+func (c *Applier) Delete() (err error) {
+	rec := audit.Begin(c.ClusterDesired, c.ClusterDesired.GetAllIPS())
+	defer func() { rec.End(err) }()
 	t := metav1.Now()
 	c.ClusterDesired.DeletionTimestamp = &t
 	defer func() {
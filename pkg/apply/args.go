@@ -114,3 +114,17 @@ func (arg *ScaleArgs) RegisterFlags(fs *pflag.FlagSet, verb, action string) {
 		arg.SSH.RegisterFlags(fs)
 	}
 }
+
+type ReplaceArgs struct {
+	*ClusterName
+	*SSH
+	Old string
+	New string
+}
+
+func (arg *ReplaceArgs) RegisterFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&arg.ClusterName.ClusterName, "cluster", "c", "default", "name of cluster to applied replace action")
+	arg.SSH.RegisterFlags(fs)
+	fs.StringVar(&arg.Old, "old", "", "ip of the host to be replaced")
+	fs.StringVar(&arg.New, "new", "", "ip of the new host, it joins with the roles of the old one")
+}
@@ -240,3 +240,55 @@ func TestParseScaleArgsFlagsCorrect(t *testing.T) {
 		})
 	}
 }
+
+func TestParseReplaceArgsFlagsCorrect(t *testing.T) {
+	var tests = []struct {
+		args    []string
+		flags   *ReplaceArgs
+		desired *ReplaceArgs
+	}{
+		{
+			[]string{
+				"-c", "prod", "--old", "10.74.22.44", "--new", "10.74.22.45:2222",
+				"-u", "root", "-p", "s3cret",
+			},
+			&ReplaceArgs{
+				ClusterName: &ClusterName{},
+				SSH:         &SSH{},
+			},
+			&ReplaceArgs{
+				ClusterName: &ClusterName{ClusterName: "prod"},
+				SSH:         &SSH{User: "root", Password: "s3cret", Port: 22, Pk: path.Join(constants.GetHomeDir(), ".ssh", "id_rsa")},
+				Old:         "10.74.22.44",
+				New:         "10.74.22.45:2222",
+			},
+		},
+		{
+			[]string{"--cluster", "prod", "--old", "10.74.22.44", "--new", "10.74.22.45"},
+			&ReplaceArgs{
+				ClusterName: &ClusterName{},
+				SSH:         &SSH{},
+			},
+			&ReplaceArgs{
+				ClusterName: &ClusterName{ClusterName: "prod"},
+				SSH:         &SSH{Port: 22, Pk: path.Join(constants.GetHomeDir(), ".ssh", "id_rsa")},
+				Old:         "10.74.22.44",
+				New:         "10.74.22.45",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			fs := pflag.NewFlagSet("test", pflag.ExitOnError)
+			tt.flags.RegisterFlags(fs)
+			err := fs.Parse(tt.args)
+			if err != nil {
+				t.Errorf("parse flag error: %v", err)
+			}
+			if !equal(tt.flags, tt.desired) {
+				t.Errorf("cluster got %+v, want %+v", tt.flags, tt.desired)
+			}
+		})
+	}
+}
@@ -104,11 +104,14 @@ func (c *CreateProcessor) preProcess(cluster *v2.Cluster) error {
 		cluster.Status.Mounts[i].Env = maps.Merge(cluster.Status.Mounts[i].Env, env, c.ExtraEnvs)
 	}
 
-	rt, err := factory.New(cluster, c.ClusterFile.GetRuntimeConfig())
-	if err != nil {
-		return fmt.Errorf("failed to init runtime, %v", err)
+	// runtime may be set in advance, e.g. a fake one in tests
+	if c.Runtime == nil {
+		rt, err := factory.New(cluster, c.ClusterFile.GetRuntimeConfig())
+		if err != nil {
+			return fmt.Errorf("failed to init runtime, %v", err)
+		}
+		c.Runtime = rt
 	}
-	c.Runtime = rt
 	return nil
 }
 
@@ -117,10 +120,13 @@ func (c *CreateProcessor) RunConfig(cluster *v2.Cluster) error {
 	eg, _ := errgroup.WithContext(context.Background())
 	for _, cManifest := range cluster.Status.Mounts {
 		manifest := cManifest
-		eg.Go(func() error {
-			cfg := config.NewConfiguration(manifest.ImageName, manifest.MountPoint, c.ClusterFile.GetConfigs())
-			return cfg.Dump()
-		})
+		for _, mountPoint := range manifest.MountPoints() {
+			mountPoint := mountPoint
+			eg.Go(func() error {
+				cfg := config.NewConfiguration(manifest.ImageName, mountPoint, c.ClusterFile.GetConfigs())
+				return cfg.Dump()
+			})
+		}
 	}
 	return eg.Wait()
 }
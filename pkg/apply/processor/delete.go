@@ -117,7 +117,7 @@ func (d *DeleteProcessor) UnMountImage(cluster *v2.Cluster) error {
 	for _, mount := range cluster.Status.Mounts {
 		mount := mount
 		eg.Go(func() error {
-			return d.Buildah.Delete(mount.Name)
+			return deleteMount(d.Buildah, &mount)
 		})
 	}
 	return eg.Wait()
@@ -24,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/checker"
 	"github.com/labring/sealos/pkg/clusterfile"
 	"github.com/labring/sealos/pkg/config"
 	"github.com/labring/sealos/pkg/filesystem/rootfs"
@@ -40,6 +41,9 @@ import (
 
 var ForceOverride bool
 
+// ForceUpgrade bypasses the failed upgrade preflight checks
+var ForceUpgrade bool
+
 type InstallProcessor struct {
 	ClusterFile      clusterfile.Interface
 	Buildah          buildah.Interface
@@ -72,6 +76,7 @@ func (c *InstallProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, err
 		c.SyncStatusAndCheck,
 		c.ConfirmOverrideApps,
 		c.PreProcess,
+		c.UpgradePreflight,
 		c.RunConfig,
 		c.MountRootfs,
 		c.MirrorRegistry,
@@ -167,7 +172,7 @@ func (c *InstallProcessor) PreProcess(cluster *v2.Cluster) error {
 				continue
 			}
 			logger.Debug("trying to override app %s", img)
-			if err := c.Buildah.Delete(mount.Name); err != nil {
+			if err := deleteMount(c.Buildah, mount); err != nil {
 				return err
 			}
 		}
@@ -181,11 +186,15 @@ func (c *InstallProcessor) PreProcess(cluster *v2.Cluster) error {
 			Name:       bderInfo.Container,
 			MountPoint: bderInfo.MountPoint,
 			ImageName:  img,
+			Arch:       mountArch(bderInfo.OCIv1.Architecture),
 		}
 
 		if err = OCIToImageMount(c.Buildah, mount); err != nil {
 			return err
 		}
+		if err = mountVariants(c.Buildah, cluster, mount, bderInfo.FromImageID); err != nil {
+			return err
+		}
 		mount.Env = maps.Merge(mount.Env, c.ExtraEnvs)
 		// This code ensures that `cluster.Status.Mounts` always contains the latest `MountImage` instances
 		if index >= 0 {
@@ -203,6 +212,24 @@ func (c *InstallProcessor) PreProcess(cluster *v2.Cluster) error {
 	return nil
 }
 
+// UpgradePreflight validates the upgrade of the new rootfs mounts and prints the upgrade plan
+// before anything is mounted to the hosts
+func (c *InstallProcessor) UpgradePreflight(cluster *v2.Cluster) error {
+	var list []checker.Interface
+	for _, img := range c.NewMounts {
+		version := img.KubeVersion()
+		if version == "" {
+			continue
+		}
+		list = append(list, checker.NewUpgradeChecker(version, ForceUpgrade))
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	logger.Info("Executing UpgradePreflight Pipeline in InstallProcessor")
+	return checker.RunCheckList(list, cluster, checker.PhasePre)
+}
+
 func (c *InstallProcessor) UpgradeIfNeed(cluster *v2.Cluster) error {
 	logger.Info("Executing UpgradeIfNeed Pipeline in InstallProcessor")
 	for _, img := range c.NewMounts {
@@ -237,10 +264,13 @@ func (c *InstallProcessor) RunConfig(_ *v2.Cluster) error {
 	eg, _ := errgroup.WithContext(context.Background())
 	for _, cManifest := range c.NewMounts {
 		manifest := cManifest
-		eg.Go(func() error {
-			cfg := config.NewConfiguration(manifest.ImageName, manifest.MountPoint, c.ClusterFile.GetConfigs())
-			return cfg.Dump()
-		})
+		for _, mountPoint := range manifest.MountPoints() {
+			mountPoint := mountPoint
+			eg.Go(func() error {
+				cfg := config.NewConfiguration(manifest.ImageName, mountPoint, c.ClusterFile.GetConfigs())
+				return cfg.Dump()
+			})
+		}
 	}
 	return eg.Wait()
 }
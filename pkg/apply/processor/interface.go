@@ -224,11 +224,19 @@ func MountClusterImages(bdah buildah.Interface, cluster *v2.Cluster, skipApp boo
 			Name:       bderInfo.Container,
 			ImageName:  img,
 			MountPoint: bderInfo.MountPoint,
+			Arch:       mountArch(bderInfo.OCIv1.Architecture),
 		}
 		if err = OCIToImageMount(bdah, mount); err != nil {
 			return err
 		}
+		// hosts of other architectures get the contents of their own platform
+		if err = mountVariants(bdah, cluster, mount, bderInfo.FromImageID); err != nil {
+			return err
+		}
 		if idx >= 0 {
+			if err = deleteStaleVariants(bdah, cluster.Status.Mounts[idx].Variants, mount.Variants); err != nil {
+				return err
+			}
 			mount.Env = maps.Merge(mount.Env, cluster.Status.Mounts[idx].Env)
 			cluster.Status.Mounts[idx] = *mount
 		} else {
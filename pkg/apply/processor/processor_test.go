@@ -0,0 +1,184 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	buildahcli "github.com/containers/buildah"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/guest"
+	"github.com/labring/sealos/pkg/runtime"
+	"github.com/labring/sealos/pkg/ssh/sshtest"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+const testRootfsImage = "labring/kubernetes:v1.25.0"
+
+// fakeBuildah mounts images from local dirs, only images of the local architecture are supported
+type fakeBuildah struct {
+	buildah.Interface
+	mountPoints map[string]string
+}
+
+func (b *fakeBuildah) Pull([]string, ...buildah.FlagSetter) error { return nil }
+
+func (b *fakeBuildah) InspectImage(string, ...string) (*buildah.InspectOutput, error) {
+	return &buildah.InspectOutput{OCIv1: &ociv1.Image{Config: ociv1.ImageConfig{
+		Labels: map[string]string{
+			"sealos.io.type":       string(v2.RootfsImage),
+			"sealos.io.version":    v2.ImageTypeVersionKeyV1Beta1,
+			v2.ImageKubeVersionKey: "v1.25.0",
+		},
+		Cmd: []string{"bash guest.sh"},
+	}}}, nil
+}
+
+func (b *fakeBuildah) Create(name string, image string, _ ...buildah.FlagSetter) (buildahcli.BuilderInfo, error) {
+	return buildahcli.BuilderInfo{
+		Container:   name,
+		MountPoint:  b.mountPoints[image],
+		FromImageID: "sha256:" + name,
+		OCIv1:       ociv1.Image{Platform: ociv1.Platform{Architecture: string(v2.AMD64)}},
+	}, nil
+}
+
+func (b *fakeBuildah) Delete(string) error { return nil }
+
+// fakeRuntime records the hosts joined to the cluster
+type fakeRuntime struct {
+	runtime.Interface
+	mu          sync.Mutex
+	initialized bool
+	masters     []string
+	nodes       []string
+}
+
+func (r *fakeRuntime) Init() error {
+	r.initialized = true
+	return nil
+}
+
+func (r *fakeRuntime) ScaleUp(masters []string, nodes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.masters = append(r.masters, masters...)
+	r.nodes = append(r.nodes, nodes...)
+	return nil
+}
+
+func (r *fakeRuntime) SyncNodeIPVS(_, _ []string) error { return nil }
+
+type fakeClusterFile struct {
+	cluster *v2.Cluster
+}
+
+func (f *fakeClusterFile) Process() error                   { return nil }
+func (f *fakeClusterFile) GetCluster() *v2.Cluster          { return f.cluster }
+func (f *fakeClusterFile) GetConfigs() []v2.Config          { return nil }
+func (f *fakeClusterFile) GetRuntimeConfig() runtime.Config { return nil }
+
+func setupTest(t *testing.T) *fakeBuildah {
+	defaultRuntimeRootDir := constants.DefaultRuntimeRootDir
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	t.Cleanup(func() { constants.DefaultRuntimeRootDir = defaultRuntimeRootDir })
+
+	mountPoint := t.TempDir()
+	for _, name := range []string{"init.sh", "guest.sh"} {
+		p := filepath.Join(mountPoint, constants.ScriptsDirName, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("#!/bin/bash"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &fakeBuildah{mountPoints: map[string]string{testRootfsImage: mountPoint}}
+}
+
+func assertProvisioned(t *testing.T, cluster *v2.Cluster, hosts ...*sshtest.Host) {
+	t.Helper()
+	initScript := path.Join(constants.NewPathResolver(cluster.Name).RootFSScriptsPath(), "init.sh")
+	for _, h := range hosts {
+		if _, err := h.ReadFile(initScript); err != nil {
+			t.Errorf("rootfs is not copied to %s: %v", h.Addr, err)
+		}
+		for _, cmd := range []string{`^bash check\.sh$`, `^bash init\.sh$`, `^bash guest\.sh$`} {
+			if !h.Ran(cmd) {
+				t.Errorf("%s is not run on %s: %v", cmd, h.Addr, h.Commands())
+			}
+		}
+	}
+}
+
+func TestCreateProcessor(t *testing.T) {
+	bdah := setupTest(t)
+	master0, node := sshtest.NewHost(t), sshtest.NewHost(t)
+	cluster := sshtest.NewCluster("default", []*sshtest.Host{master0}, []*sshtest.Host{node})
+	cluster.Spec.Image = []string{testRootfsImage}
+
+	rt := &fakeRuntime{}
+	c := &CreateProcessor{
+		ClusterFile: &fakeClusterFile{cluster: cluster},
+		Buildah:     bdah,
+		Runtime:     rt,
+		Guest:       &guest.Default{},
+	}
+	if err := c.Execute(cluster); err != nil {
+		t.Fatal(err)
+	}
+	if !rt.initialized || len(rt.masters) != 0 || !reflect.DeepEqual(rt.nodes, []string{node.Addr}) {
+		t.Errorf("runtime is not initialized with the cluster hosts: %+v", rt)
+	}
+	assertProvisioned(t, cluster, master0, node)
+	if _, err := os.Stat(constants.Clusterfile(cluster.Name)); err != nil {
+		t.Errorf("Clusterfile is not saved: %v", err)
+	}
+}
+
+func TestScaleProcessor(t *testing.T) {
+	bdah := setupTest(t)
+	master0, node, newNode := sshtest.NewHost(t), sshtest.NewHost(t), sshtest.NewHost(t)
+	cluster := sshtest.NewCluster("default", []*sshtest.Host{master0}, []*sshtest.Host{node, newNode})
+	cluster.Spec.Image = []string{testRootfsImage}
+
+	rt := &fakeRuntime{}
+	c := &ScaleProcessor{
+		ClusterFile: &fakeClusterFile{cluster: cluster},
+		Buildah:     bdah,
+		Runtime:     rt,
+		Guest:       &guest.Default{},
+		NodesToJoin: []string{newNode.Addr},
+		IsScaleUp:   true,
+	}
+	if err := c.Execute(cluster); err != nil {
+		t.Fatal(err)
+	}
+	if rt.initialized || !reflect.DeepEqual(rt.nodes, []string{newNode.Addr}) {
+		t.Errorf("only the new node should be joined: %+v", rt)
+	}
+	assertProvisioned(t, cluster, newNode)
+	if node.Ran(`init\.sh`) {
+		t.Error("existing node should not be initialized again")
+	}
+}
@@ -203,6 +203,10 @@ func (c *ScaleProcessor) preProcess(cluster *v2.Cluster) error {
 		return err
 	}
 
+	// runtime may be set in advance, e.g. a fake one in tests
+	if c.Runtime != nil {
+		return nil
+	}
 	var rt runtime.Interface
 	if c.IsScaleUp {
 		rt, err = factory.New(cluster, c.ClusterFile.GetRuntimeConfig())
@@ -232,6 +236,9 @@ func (c *ScaleProcessor) PreProcessImage(cluster *v2.Cluster) error {
 			}
 			mount.Name = clusterManifest.Container
 			mount.MountPoint = clusterManifest.MountPoint
+			if err = remountVariants(c.Buildah, &mount); err != nil {
+				return err
+			}
 			cluster.Status.Mounts[i] = mount
 		}
 	}
@@ -244,10 +251,13 @@ func (c *ScaleProcessor) RunConfig(cluster *v2.Cluster) error {
 	eg, _ := errgroup.WithContext(context.Background())
 	for _, cManifest := range cluster.Status.Mounts {
 		manifest := cManifest
-		eg.Go(func() error {
-			cfg := config.NewConfiguration(manifest.ImageName, manifest.MountPoint, c.ClusterFile.GetConfigs())
-			return cfg.Dump()
-		})
+		for _, mountPoint := range manifest.MountPoints() {
+			mountPoint := mountPoint
+			eg.Go(func() error {
+				cfg := config.NewConfiguration(manifest.ImageName, mountPoint, c.ClusterFile.GetConfigs())
+				return cfg.Dump()
+			})
+		}
 	}
 	return eg.Wait()
 }
@@ -0,0 +1,163 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"context"
+	"fmt"
+	goruntime "runtime"
+
+	"github.com/containers/common/libimage"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/buildah"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const platformOS = "linux"
+
+// mountArch returns the architecture of a mounted image, arch is the architecture in its config
+func mountArch(arch string) v2.Arch {
+	if arch != "" {
+		return v2.Arch(arch)
+	}
+	return v2.Arch(goruntime.GOARCH)
+}
+
+func variantName(name string, arch v2.Arch) string {
+	return fmt.Sprintf("%s-%s", name, arch)
+}
+
+// mountVariants mounts the image of every other architecture of the cluster hosts besides
+// the one mounted at mount.MountPoint, the image must be a multi-arch image.
+func mountVariants(bdah buildah.Interface, cluster *v2.Cluster, mount *v2.MountImage, imageID string) error {
+	mount.Variants = nil
+	var arches []v2.Arch
+	for _, arch := range cluster.GetArches() {
+		if arch != mount.Arch {
+			arches = append(arches, arch)
+		}
+	}
+	if len(arches) == 0 {
+		return nil
+	}
+	rt := bdah.Runtime()
+	for _, arch := range arches {
+		id, err := lookupVariant(rt, mount.ImageName, imageID, arch)
+		if err != nil {
+			return err
+		}
+		if id == "" {
+			logger.Info("pulling image %s for %s hosts", mount.ImageName, arch)
+			err = bdah.Pull([]string{mount.ImageName},
+				buildah.WithPlatformOption(v1.Platform{OS: platformOS, Architecture: string(arch)}),
+				buildah.WithPullPolicyOption(buildah.PullIfMissing.String()))
+			if err != nil {
+				return fmt.Errorf("failed to pull image %s for %s: %w", mount.ImageName, arch, err)
+			}
+			img, _, err := rt.LookupImage(mount.ImageName, &libimage.LookupImageOptions{OS: platformOS, Architecture: string(arch)})
+			if err != nil {
+				return fmt.Errorf("image %s has no variant for %s: %w", mount.ImageName, arch, err)
+			}
+			id = img.ID()
+		}
+		info, err := bdah.Create(variantName(mount.Name, arch), id)
+		if err != nil {
+			return err
+		}
+		mount.Variants = append(mount.Variants, v2.MountVariant{
+			Arch:       arch,
+			Name:       info.Container,
+			ImageID:    id,
+			MountPoint: info.MountPoint,
+		})
+	}
+	// pulling the other platforms moves the image name to them, so tag the mounted image again
+	img, _, err := rt.LookupImage(imageID, nil)
+	if err != nil {
+		return err
+	}
+	return img.Tag(mount.ImageName)
+}
+
+// lookupVariant returns the id of a local image of arch that has ever been named as name,
+// an empty id is returned if there is no such image.
+func lookupVariant(rt *buildah.Runtime, name, imageID string, arch v2.Arch) (string, error) {
+	img, _, err := rt.LookupImage(imageID, nil)
+	if err != nil {
+		return "", err
+	}
+	names := img.Names()
+	if len(names) == 0 {
+		return "", nil
+	}
+	images, err := rt.ListImages(context.Background(), nil, nil)
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range images {
+		if candidate.ID() == imageID || !slices.Contains(candidate.NamesHistory(), names[0]) {
+			continue
+		}
+		data, err := candidate.Inspect(context.Background(), nil)
+		if err != nil {
+			return "", err
+		}
+		if data.Os == platformOS && data.Architecture == string(arch) {
+			logger.Debug("found local image %s of %s for %s", candidate.ID(), name, arch)
+			return candidate.ID(), nil
+		}
+	}
+	return "", nil
+}
+
+// remountVariants recreates the containers of variants whose mount points are lost
+func remountVariants(bdah buildah.Interface, mount *v2.MountImage) error {
+	for i, v := range mount.Variants {
+		info, err := bdah.Create(v.Name, v.ImageID)
+		if err != nil {
+			return err
+		}
+		mount.Variants[i].Name = info.Container
+		mount.Variants[i].MountPoint = info.MountPoint
+	}
+	return nil
+}
+
+// deleteMount deletes the containers of the mount and all its variants
+func deleteMount(bdah buildah.Interface, mount *v2.MountImage) error {
+	for _, v := range mount.Variants {
+		if err := bdah.Delete(v.Name); err != nil {
+			return err
+		}
+	}
+	return bdah.Delete(mount.Name)
+}
+
+// deleteStaleVariants deletes the containers of the variants that are not mounted anymore,
+// e.g. the last host of an architecture has been removed
+func deleteStaleVariants(bdah buildah.Interface, old, current []v2.MountVariant) error {
+	for _, v := range old {
+		if slices.ContainsFunc(current, func(c v2.MountVariant) bool { return c.Name == v.Name }) {
+			continue
+		}
+		if err := bdah.Delete(v.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/apply/applydrivers"
+	"github.com/labring/sealos/pkg/clusterfile"
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+func loadCluster(clusterName string) (*v2.Cluster, error) {
+	clusterFile := clusterfile.NewClusterFile(constants.Clusterfile(clusterName))
+	if err := clusterFile.Process(); err != nil {
+		return nil, err
+	}
+	return clusterFile.GetCluster(), nil
+}
+
+// replacePlan returns the old host to delete and the new host to add with the role of the old one
+func replacePlan(cluster *v2.Cluster, args *ReplaceArgs) (toDelete, toAdd *Cluster, err error) {
+	clusterName := args.ClusterName.ClusterName
+	defaultPort := defaultSSHPort(cluster.Spec.SSH.Port)
+	old := net.JoinHostPort(iputils.GetHostIPAndPortOrDefault(args.Old, defaultPort))
+
+	toDelete, toAdd = &Cluster{ClusterName: clusterName}, &Cluster{ClusterName: clusterName}
+	switch {
+	case slices.Contains(cluster.GetMasterIPAndPortList(), old), slices.Contains(cluster.GetMasterIPList(), args.Old):
+		toDelete.Masters, toAdd.Masters = args.Old, args.New
+	case slices.Contains(cluster.GetNodeIPAndPortList(), old), slices.Contains(cluster.GetNodeIPList(), args.Old):
+		toDelete.Nodes, toAdd.Nodes = args.Old, args.New
+	default:
+		return nil, nil, fmt.Errorf("host %s is not in cluster %s", args.Old, clusterName)
+	}
+	return toDelete, toAdd, nil
+}
+
+// ReplaceNode swaps the old host of the cluster for the new one with the same role.
+// The old host is deleted first, it does not have to be reachable, then the new host joins.
+func ReplaceNode(cmd *cobra.Command, args *ReplaceArgs) error {
+	if args.Old == "" || args.New == "" {
+		return errors.New("both --old and --new must be specified")
+	}
+	clusterName := args.ClusterName.ClusterName
+	cluster, err := loadCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	toDelete, toAdd, err := replacePlan(cluster, args)
+	if err != nil {
+		return err
+	}
+
+	// make sure the new host is able to join before the old one is removed
+	deleted := cluster.DeepCopy()
+	if err = Delete(deleted, &ScaleArgs{Cluster: toDelete}); err != nil {
+		return err
+	}
+	if err = verifyAndSetNodes(cmd, deleted.DeepCopy(), &ScaleArgs{Cluster: toAdd, SSH: args.SSH}); err != nil {
+		return err
+	}
+
+	logger.Info("start to delete host %s", args.Old)
+	applier, err := applydrivers.NewDefaultScaleApplier(cmd.Context(), cluster, deleted)
+	if err != nil {
+		return err
+	}
+	if err = applier.Apply(); err != nil {
+		return fmt.Errorf("delete host %s: %w", args.Old, err)
+	}
+
+	logger.Info("start to join host %s", args.New)
+	cluster, err = loadCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	joined := cluster.DeepCopy()
+	if err = verifyAndSetNodes(cmd, joined, &ScaleArgs{Cluster: toAdd, SSH: args.SSH}); err != nil {
+		return err
+	}
+	applier, err = applydrivers.NewDefaultScaleApplier(cmd.Context(), cluster, joined)
+	if err != nil {
+		return err
+	}
+	if err = applier.Apply(); err != nil {
+		return fmt.Errorf("host %s is deleted but %s failed to join, retry with `sealos add`: %w", args.Old, args.New, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apply
+
+import (
+	"testing"
+
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestReplacePlan(t *testing.T) {
+	cluster := &v2.Cluster{
+		Spec: v2.ClusterSpec{
+			SSH: v2.SSH{Port: 22},
+			Hosts: []v2.Host{
+				{IPS: []string{"192.168.16.99:22", "192.168.16.98:2222"}, Roles: []string{v2.MASTER}},
+				{IPS: []string{"192.168.16.1:22", "192.168.16.2:2222"}, Roles: []string{v2.NODE}},
+			},
+		},
+	}
+	cluster.Name = "default"
+	tests := []struct {
+		name       string
+		old        string
+		new        string
+		wantDelete *Cluster
+		wantAdd    *Cluster
+		wantErr    bool
+	}{
+		{
+			name:       "replace master",
+			old:        "192.168.16.99",
+			new:        "192.168.16.100",
+			wantDelete: &Cluster{Masters: "192.168.16.99", ClusterName: "default"},
+			wantAdd:    &Cluster{Masters: "192.168.16.100", ClusterName: "default"},
+		},
+		{
+			name:       "replace node",
+			old:        "192.168.16.1:22",
+			new:        "192.168.16.10",
+			wantDelete: &Cluster{Nodes: "192.168.16.1:22", ClusterName: "default"},
+			wantAdd:    &Cluster{Nodes: "192.168.16.10", ClusterName: "default"},
+		},
+		{
+			name:       "replace node with a custom port by its ip",
+			old:        "192.168.16.2",
+			new:        "192.168.16.11:2222",
+			wantDelete: &Cluster{Nodes: "192.168.16.2", ClusterName: "default"},
+			wantAdd:    &Cluster{Nodes: "192.168.16.11:2222", ClusterName: "default"},
+		},
+		{
+			name:    "host not in cluster",
+			old:     "192.168.16.3",
+			new:     "192.168.16.12",
+			wantErr: true,
+		},
+		{
+			name:    "host with another port",
+			old:     "192.168.16.1:2222",
+			new:     "192.168.16.12",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toDelete, toAdd, err := replacePlan(cluster, &ReplaceArgs{
+				ClusterName: &ClusterName{ClusterName: "default"},
+				Old:         tt.old,
+				New:         tt.new,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("replacePlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !equal(toDelete, tt.wantDelete) {
+				t.Errorf("replacePlan() toDelete = %+v, want %+v", toDelete, tt.wantDelete)
+			}
+			if !equal(toAdd, tt.wantAdd) {
+				t.Errorf("replacePlan() toAdd = %+v, want %+v", toAdd, tt.wantAdd)
+			}
+		})
+	}
+}
@@ -130,14 +130,17 @@ func (r *ClusterArgs) runArgs(cmd *cobra.Command, args *RunArgs, imageList []str
 		return err
 	}
 	if len(masters) > 0 {
-		host, port := iputils.GetHostIPAndPortOrDefault(masters[0], defaultPort)
-		master0addr := net.JoinHostPort(host, port)
-		r.setHostWithIpsPort(masters, []string{v2.MASTER, GetHostArch(execer, master0addr)})
+		arches, groups := groupHostsByArch(execer, joinHostsPort(masters, defaultPort))
+		// the group of master0 is set last since setHostWithIpsPort puts the host of its first ip in front
+		for i := len(arches) - 1; i >= 0; i-- {
+			r.setHostWithIpsPort(groups[arches[i]], []string{v2.MASTER, string(arches[i])})
+		}
 	}
 	if len(nodes) > 0 {
-		host, port := iputils.GetHostIPAndPortOrDefault(nodes[0], defaultPort)
-		node0addr := net.JoinHostPort(host, port)
-		r.setHostWithIpsPort(nodes, []string{v2.NODE, GetHostArch(execer, node0addr)})
+		arches, groups := groupHostsByArch(execer, joinHostsPort(nodes, defaultPort))
+		for _, arch := range arches {
+			r.setHostWithIpsPort(groups[arch], []string{v2.NODE, string(arch)})
+		}
 	}
 	r.cluster.Spec.Hosts = append(r.cluster.Spec.Hosts, r.hosts...)
 
@@ -171,6 +174,15 @@ func (r *ClusterArgs) setHostWithIpsPort(ips []string, roles []string) {
 	}
 }
 
+func joinHostsPort(hosts []string, defaultPort string) []string {
+	ret := make([]string, 0, len(hosts))
+	for i := range hosts {
+		host, port := iputils.GetHostIPAndPortOrDefault(hosts[i], defaultPort)
+		ret = append(ret, net.JoinHostPort(host, port))
+	}
+	return ret
+}
+
 func defaultSSHPort(port uint16) string {
 	if port == 0 {
 		port = v2.DefaultSSHPort
@@ -152,7 +152,7 @@ func verifyAndSetNodes(cmd *cobra.Command, cluster *v2.Cluster, scaleArgs *Scale
 	}
 	override := getSSHFromCommand(cmd)
 
-	getHostFunc := func(sliceStr string, role string, exclude []string) (*v2.Host, error) {
+	getHostFunc := func(sliceStr string, role string, exclude []string) ([]v2.Host, error) {
 		ss := strings.Split(sliceStr, ",")
 		addrs := make([]string, 0)
 		for _, s := range ss {
@@ -177,14 +177,20 @@ func verifyAndSetNodes(cmd *cobra.Command, cluster *v2.Cluster, scaleArgs *Scale
 			if err != nil {
 				return nil, err
 			}
-			host := &v2.Host{
-				IPS:   addrs,
-				Roles: []string{role, GetHostArch(execer, addrs[0])},
+			// hosts of different architectures are added as different host groups
+			arches, groups := groupHostsByArch(execer, addrs)
+			var added []v2.Host
+			for _, arch := range arches {
+				host := v2.Host{
+					IPS:   groups[arch],
+					Roles: []string{role, string(arch)},
+				}
+				if override != nil {
+					host.SSH = override
+				}
+				added = append(added, host)
 			}
-			if override != nil {
-				host.SSH = override
-			}
-			return host, nil
+			return added, nil
 		}
 		return nil, nil
 	}
@@ -192,12 +198,12 @@ func verifyAndSetNodes(cmd *cobra.Command, cluster *v2.Cluster, scaleArgs *Scale
 	if mastersToAdded, err := getHostFunc(masters, v2.MASTER, cluster.GetMasterIPAndPortList()); err != nil {
 		return err
 	} else if mastersToAdded != nil {
-		hosts = append(hosts, *mastersToAdded)
+		hosts = append(hosts, mastersToAdded...)
 	}
 	if nodesToAdded, err := getHostFunc(nodes, v2.NODE, cluster.GetNodeIPAndPortList()); err != nil {
 		return err
 	} else if nodesToAdded != nil {
-		hosts = append(hosts, *nodesToAdded)
+		hosts = append(hosts, nodesToAdded...)
 	}
 	cluster.Spec.Hosts = hosts
 	return nil
@@ -107,12 +107,25 @@ func getHostArch(execer exec.Interface) func(string) v2.Arch {
 }
 
 // GetHostArch returns the host architecture of the given ip using SSH.
-// Note that hosts of the same type(master/node) must have the same architecture,
-// so we only need to check the first host of the given type.
 func GetHostArch(execer exec.Interface, ip string) string {
 	return string(getHostArch(execer)(ip))
 }
 
+// groupHostsByArch detects the architecture of every host using SSH and groups the hosts by it,
+// arches are in the order of their first hosts, so the group of hosts[0] comes first.
+func groupHostsByArch(execer exec.Interface, hosts []string) (arches []v2.Arch, groups map[v2.Arch][]string) {
+	groups = make(map[v2.Arch][]string)
+	archOf := getHostArch(execer)
+	for _, host := range hosts {
+		arch := archOf(host)
+		if _, ok := groups[arch]; !ok {
+			arches = append(arches, arch)
+		}
+		groups[arch] = append(groups[arch], host)
+	}
+	return arches, groups
+}
+
 func GetImagesDiff(current, desired []string) []string {
 	return stringsutil.RemoveDuplicate(stringsutil.RemoveSubSlice(desired, current))
 }
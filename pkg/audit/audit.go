@@ -0,0 +1,308 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit keeps an append-only journal of the operations run against each cluster.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/system"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+type Result string
+
+const (
+	Succeeded Result = "Succeeded"
+	Failed    Result = "Failed"
+)
+
+// Entry is an operation run against a cluster
+type Entry struct {
+	ID      string `json:"id"`
+	Cluster string `json:"cluster"`
+	// the user running sealos, the user who ran sudo comes first if any
+	Operator string   `json:"operator"`
+	Command  string   `json:"command"`
+	Hosts    []string `json:"hosts,omitempty"`
+	// unified diff of the Clusterfile made by the operation
+	Diff      string    `json:"diff,omitempty"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Result    Result    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func (e *Entry) Duration() time.Duration {
+	return e.EndTime.Sub(e.StartTime)
+}
+
+// JournalFile returns the path of the audit journal of a cluster
+func JournalFile(cluster string) string {
+	return filepath.Join(constants.AuditPath(), cluster+".jsonl")
+}
+
+// Recorder records an operation into the journal once it is finished
+type Recorder struct {
+	cluster *v2.Cluster
+	entry   Entry
+	before  string
+}
+
+// Begin starts recording an operation run by the current process on the hosts of a cluster
+func Begin(cluster *v2.Cluster, hosts []string) *Recorder {
+	now := time.Now()
+	return &Recorder{
+		cluster: cluster,
+		entry: Entry{
+			ID:        strconv.FormatInt(now.UnixNano(), 36),
+			Cluster:   cluster.Name,
+			Operator:  operator(),
+			Command:   redactArgs(os.Args),
+			Hosts:     hosts,
+			StartTime: now,
+		},
+		before: readClusterfile(cluster.Name),
+	}
+}
+
+// End appends the entry of the operation to the journal, failing to record it never fails the operation
+func (r *Recorder) End(err error) {
+	r.entry.EndTime = time.Now()
+	r.entry.Result = Succeeded
+	if err != nil {
+		r.entry.Result = Failed
+		r.entry.Error = err.Error()
+	}
+	r.entry.Diff = diff(r.before, readClusterfile(r.cluster.Name))
+	if err := appendEntry(&r.entry); err != nil {
+		logger.Warn("failed to write audit journal of cluster %s: %v", r.cluster.Name, err)
+		return
+	}
+	if v, _ := system.Get(system.AuditConfigMapConfigKey); v != "" {
+		if mirror, _ := strconv.ParseBool(v); mirror && shouldMirror(os.Args) {
+			if err := mirrorEntry(r.cluster, &r.entry); err != nil {
+				logger.Warn("failed to mirror audit entry %s into cluster %s: %v", r.entry.ID, r.cluster.Name, err)
+			}
+		}
+	}
+}
+
+func appendEntry(entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(constants.AuditPath(), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(JournalFile(entry.Cluster), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// List returns the entries of a cluster, the oldest first
+func List(cluster string) ([]Entry, error) {
+	f, err := os.Open(JournalFile(cluster))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	// the diff of an entry may exceed the default token size
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry Entry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// a line may be truncated if sealos is killed while writing it
+			logger.Warn("skip broken line %d of audit journal %s: %v", line, f.Name(), err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+var ErrNotFound = errors.New("audit entry not found")
+
+// Get returns the entry of a cluster by id, a unique prefix of the id is accepted
+func Get(cluster, id string) (*Entry, error) {
+	entries, err := List(cluster)
+	if err != nil {
+		return nil, err
+	}
+	var found *Entry
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+		if strings.HasPrefix(entries[i].ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("audit entry id %s is ambiguous", id)
+			}
+			found = &entries[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return found, nil
+}
+
+func operator() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" && sudoUser != name {
+		name = fmt.Sprintf("%s(%s)", sudoUser, name)
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		name += "@" + hostname
+	}
+	return name
+}
+
+// secrets of the ssh config in the Clusterfile are not written into the journal
+var secretLineRegex = regexp.MustCompile(`^(\s*)((?:passwd|pkPasswd|pkData|password):\s*)(\S.*)$`)
+
+// flags whose values are not written into the journal, in both the "--flag value" and the "--flag=value" forms
+var (
+	secretFlags      = []string{"passwd", "password", "pk-passwd", "creds"}
+	secretShorthands = []string{"p"}
+)
+
+func redactArgs(args []string) string {
+	out := make([]string, len(args))
+	// the next arg is the value of a secret flag
+	maskNext := false
+	for i, arg := range args {
+		out[i] = arg
+		if maskNext {
+			out[i] = "******"
+			maskNext = false
+			continue
+		}
+		if arg == "--" {
+			// the rest are positional args
+			copy(out[i:], args[i:])
+			break
+		}
+		var name, value string
+		var hasValue bool
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue = strings.Cut(arg[2:], "=")
+			if !slices.Contains(secretFlags, name) {
+				continue
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// the value of a shorthand may follow it directly, as in -psecret
+			name = arg[1:2]
+			value, hasValue = strings.TrimPrefix(arg[2:], "="), len(arg) > 2
+			if !slices.Contains(secretShorthands, name) {
+				continue
+			}
+		default:
+			continue
+		}
+		if !hasValue {
+			maskNext = true
+			continue
+		}
+		out[i] = strings.TrimSuffix(arg, value) + "******"
+	}
+	return strings.Join(out, " ")
+}
+
+func readClusterfile(cluster string) string {
+	data, err := os.ReadFile(constants.Clusterfile(cluster))
+	if err != nil {
+		return ""
+	}
+	return redact(string(data))
+}
+
+func redact(data string) string {
+	var (
+		lines = strings.Split(data, "\n")
+		out   = make([]string, 0, len(lines))
+		// indent of the secret whose block scalar is being skipped, -1 if none
+		blockIndent = -1
+	)
+	for _, line := range lines {
+		if blockIndent >= 0 {
+			if strings.TrimSpace(line) == "" || len(line)-len(strings.TrimLeft(line, " ")) > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		if m := secretLineRegex.FindStringSubmatch(line); m != nil {
+			if strings.HasPrefix(m[3], "|") || strings.HasPrefix(m[3], ">") {
+				blockIndent = len(m[1])
+			}
+			line = m[1] + m[2] + "******"
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+func diff(before, after string) string {
+	if before == after {
+		return ""
+	}
+	out, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: "Clusterfile",
+		ToFile:   "Clusterfile",
+		Context:  3,
+	})
+	if err != nil {
+		logger.Debug("failed to diff Clusterfile: %v", err)
+		return ""
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func writeClusterfile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(constants.Clusterfile(name)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(constants.Clusterfile(name), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	defaultRuntimeRootDir := constants.DefaultRuntimeRootDir
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	defer func() { constants.DefaultRuntimeRootDir = defaultRuntimeRootDir }()
+
+	cluster := &v2.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	writeClusterfile(t, "default", "spec:\n  ssh:\n    passwd: secret1\n  hosts:\n  - ips:\n    - 192.168.0.2:22\n")
+	rec := Begin(cluster, []string{"192.168.0.3:22"})
+	writeClusterfile(t, "default", "spec:\n  ssh:\n    passwd: secret2\n  hosts:\n  - ips:\n    - 192.168.0.2:22\n    - 192.168.0.3:22\n")
+	rec.End(nil)
+
+	Begin(cluster, []string{"192.168.0.2:22"}).End(errors.New("exit status 1"))
+
+	entries, err := List("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	first, second := entries[0], entries[1]
+	if first.Result != Succeeded || second.Result != Failed || second.Error != "exit status 1" {
+		t.Errorf("unexpected results: %s, %s(%s)", first.Result, second.Result, second.Error)
+	}
+	if !strings.Contains(first.Diff, "+    - 192.168.0.3:22") {
+		t.Errorf("diff does not contain the added host:\n%s", first.Diff)
+	}
+	if strings.Contains(first.Diff, "secret") {
+		t.Errorf("diff leaks the ssh password:\n%s", first.Diff)
+	}
+	if second.Diff != "" {
+		t.Errorf("unchanged Clusterfile should have no diff:\n%s", second.Diff)
+	}
+
+	got, err := Get("default", first.ID)
+	if err != nil || got.ID != first.ID {
+		t.Errorf("Get(%s) = %v, %v", first.ID, got, err)
+	}
+	if _, err = Get("default", "not-exist"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of unknown id returns %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	in := "ssh:\n  pkData: |\n    -----BEGIN KEY-----\n    abc\n  passwd: '123'\n  user: root\n"
+	want := "ssh:\n  pkData: ******\n  passwd: ******\n  user: root\n"
+	if got := redact(in); got != want {
+		t.Errorf("redact() = %q, want %q", got, want)
+	}
+}
+
+func TestMirrorEntry(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx := context.Background()
+	for i := 0; i < maxMirroredEntries+2; i++ {
+		if err := mirrorEntryWithClient(ctx, client, &Entry{ID: fmt.Sprintf("%04d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cm, err := client.CoreV1().ConfigMaps(ConfigMapNamespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cm.Data) != maxMirroredEntries {
+		t.Errorf("got %d mirrored entries, want %d", len(cm.Data), maxMirroredEntries)
+	}
+	if _, ok := cm.Data["0000"]; ok {
+		t.Error("oldest entry should be trimmed")
+	}
+	if _, ok := cm.Data[fmt.Sprintf("%04d", maxMirroredEntries+1)]; !ok {
+		t.Error("latest entry is not mirrored")
+	}
+}
+
+func TestShouldMirror(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{args: []string{"sealos", "apply", "-f", "Clusterfile"}, want: true},
+		{args: []string{"sealos", "--debug", "exec", "--", "reset"}, want: true},
+		{args: []string{"sealos", "--debug", "reset", "--force"}, want: false},
+		{args: []string{"sealos", "delete", "--nodes", "192.168.0.3"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := shouldMirror(tt.args); got != tt.want {
+			t.Errorf("shouldMirror(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestAPIServerPort(t *testing.T) {
+	adminFile := filepath.Join(t.TempDir(), "admin.conf")
+	if got := apiServerPort(adminFile); got != constants.DefaultAPIServerPort {
+		t.Errorf("got port %d without kubeconfig, want %d", got, constants.DefaultAPIServerPort)
+	}
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: kubernetes
+  cluster:
+    server: https://apiserver.cluster.local:8443
+contexts:
+- name: kubernetes-admin@kubernetes
+  context:
+    cluster: kubernetes
+    user: kubernetes-admin
+current-context: kubernetes-admin@kubernetes
+`
+	if err := os.WriteFile(adminFile, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := apiServerPort(adminFile); got != 8443 {
+		t.Errorf("got port %d, want 8443", got)
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			args: []string{"sealos", "add", "--nodes", "192.168.0.3", "-p", "s3cret", "--pk-passwd", "phrase"},
+			want: "sealos add --nodes 192.168.0.3 -p ****** --pk-passwd ******",
+		},
+		{
+			args: []string{"sealos", "run", "--passwd=s3cret", "--pk-passwd=phrase", "-p=s3cret", "-ps3cret", "labring/kubernetes:v1.25.0"},
+			want: "sealos run --passwd=****** --pk-passwd=****** -p=****** -p****** labring/kubernetes:v1.25.0",
+		},
+		{
+			args: []string{"sealos", "reset", "--passwd", "--force"},
+			want: "sealos reset --passwd ******",
+		},
+		{
+			args: []string{"sealos", "exec", "--", "echo", "-p", "kept"},
+			want: "sealos exec -- echo -p kept",
+		},
+	}
+	for _, tt := range tests {
+		if got := redactArgs(tt.args); got != tt.want {
+			t.Errorf("redactArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
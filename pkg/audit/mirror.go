@@ -0,0 +1,130 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	kubeclient "github.com/labring/sealos/pkg/client-go/kubernetes"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/runtime/utils"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/iputils"
+)
+
+const (
+	ConfigMapName      = "sealos-audit"
+	ConfigMapNamespace = metav1.NamespaceSystem
+	// the size of a ConfigMap is limited, only the latest entries are mirrored
+	maxMirroredEntries = 100
+)
+
+// the cluster is torn down by these commands, there is nothing left to mirror the entry into
+var teardownCommands = map[string]bool{
+	"reset":  true,
+	"delete": true,
+}
+
+// shouldMirror reports whether the entry of a command line can be mirrored into the cluster
+func shouldMirror(args []string) bool {
+	for _, arg := range args[1:] {
+		// the root command only has bool flags, the first non-flag argument is the subcommand
+		if !strings.HasPrefix(arg, "-") {
+			return !teardownCommands[arg]
+		}
+	}
+	return true
+}
+
+func mirrorEntry(cluster *v2.Cluster, entry *Entry) error {
+	pathResolver := constants.NewPathResolver(cluster.Name)
+	adminFile := pathResolver.AdminFile()
+	apiServer := fmt.Sprintf("https://%s:%d", iputils.GetHostIP(cluster.GetMaster0IP()), apiServerPort(adminFile))
+	cli, err := kubeclient.NewKubernetesClient(adminFile, apiServer)
+	if err != nil {
+		return err
+	}
+	return mirrorEntryWithClient(context.Background(), cli.Kubernetes(), entry)
+}
+
+// apiServerPort returns the apiserver port configured in the admin kubeconfig, the default port if it is unknown
+func apiServerPort(adminFile string) int {
+	config, err := clientcmd.LoadFromFile(adminFile)
+	if err != nil {
+		return constants.DefaultAPIServerPort
+	}
+	cluster := utils.GetClusterFromKubeConfig(config)
+	if cluster == nil {
+		return constants.DefaultAPIServerPort
+	}
+	u, err := url.Parse(cluster.Server)
+	if err != nil {
+		return constants.DefaultAPIServerPort
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return constants.DefaultAPIServerPort
+	}
+	return port
+}
+
+func mirrorEntryWithClient(ctx context.Context, client kubernetes.Interface, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	cms := client.CoreV1().ConfigMaps(ConfigMapNamespace)
+	cm, err := cms.Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: ConfigMapNamespace},
+			Data:       map[string]string{entry.ID: string(data)},
+		}
+		_, err = cms.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[entry.ID] = string(data)
+	if len(cm.Data) > maxMirroredEntries {
+		ids := make([]string, 0, len(cm.Data))
+		for id := range cm.Data {
+			ids = append(ids, id)
+		}
+		// ids are ordered by time
+		sort.Strings(ids)
+		for _, id := range ids[:len(ids)-maxMirroredEntries] {
+			delete(cm.Data, id)
+		}
+	}
+	_, err = cms.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,84 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/ssh/sshtest"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestApply(t *testing.T) {
+	defaultRuntimeRootDir := constants.DefaultRuntimeRootDir
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	defer func() { constants.DefaultRuntimeRootDir = defaultRuntimeRootDir }()
+
+	master0, node := sshtest.NewHost(t), sshtest.NewHost(t)
+	cluster := sshtest.NewCluster("default", []*sshtest.Host{master0}, []*sshtest.Host{node})
+	if err := New(cluster).Apply(master0.Addr, node.Addr); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, h := range []*sshtest.Host{master0, node} {
+		for _, cmd := range []string{"bash check.sh", "bash init.sh"} {
+			if !h.Ran("^" + regexp.QuoteMeta(cmd) + "$") {
+				t.Errorf("%s is not run on %s: %v", cmd, h.Addr, h.Commands())
+			}
+		}
+		if !h.Ran(regexp.QuoteMeta("--domain " + constants.DefaultRegistryDomain)) {
+			t.Errorf("registry domain is not added to hosts of %s", h.Addr)
+		}
+	}
+	if !master0.Ran(`^bash init-registry\.sh$`) || node.Ran(`init-registry`) {
+		t.Error("registry should be initialized on master0 only")
+	}
+	htpasswd := path.Join(constants.GetRootWorkDir("default"), constants.EtcDirName, "registry_htpasswd")
+	if _, err := master0.ReadFile(htpasswd); err != nil {
+		t.Errorf("registry htpasswd is not copied to master0: %v", err)
+	}
+	lvscare := regexp.QuoteMeta("--domain " + constants.DefaultLvscareDomain)
+	if master0.Ran(lvscare) || !node.Ran(lvscare) {
+		t.Error("lvscare domain should be added to hosts of nodes only")
+	}
+}
+
+func TestApplyExternalLoadBalancer(t *testing.T) {
+	defaultRuntimeRootDir := constants.DefaultRuntimeRootDir
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	defer func() { constants.DefaultRuntimeRootDir = defaultRuntimeRootDir }()
+
+	master0, node := sshtest.NewHost(t), sshtest.NewHost(t)
+	cluster := sshtest.NewCluster("default", []*sshtest.Host{master0}, []*sshtest.Host{node})
+	cluster.Status.Mounts = []v2.MountImage{{
+		Name: "default-rootfs",
+		Type: v2.RootfsImage,
+		Env:  map[string]string{v2.ControlPlaneEndpointEnvKey: "lb.example.com:6443"},
+	}}
+	if err := New(cluster).Apply(master0.Addr, node.Addr); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, h := range []*sshtest.Host{master0, node} {
+		for _, domain := range []string{constants.DefaultAPIServerDomain, constants.DefaultLvscareDomain} {
+			if h.Ran(regexp.QuoteMeta("--domain " + domain)) {
+				t.Errorf("%s should not be added to hosts of %s behind an external load balancer", domain, h.Addr)
+			}
+		}
+	}
+}
@@ -29,18 +29,24 @@ type apiServerHostApplier struct{ common }
 
 func (*apiServerHostApplier) String() string { return "apiserver_host_applier" }
 
+// Filter skips all hosts if the API servers are behind an external load balancer,
+// the control plane endpoint is resolved by the DNS of the site
+func (*apiServerHostApplier) Filter(ctx Context, _ string) bool {
+	return ctx.GetCluster().GetControlPlaneEndpoint() == ""
+}
+
 func (*apiServerHostApplier) Undo(ctx Context, host string) error {
-	return ctx.GetRemoter().HostsDelete(host, constants.DefaultAPIServerDomain)
+	return ctx.GetRemoter().HostsDelete(host, ctx.GetCluster().GetAPIServerDomain())
 }
 
 func (a *apiServerHostApplier) Apply(ctx Context, host string) error {
 	if slices.Contains(ctx.GetCluster().GetMasterIPAndPortList(), host) {
-		if err := ctx.GetRemoter().HostsAdd(host, ctx.GetCluster().GetMaster0IP(), constants.DefaultAPIServerDomain); err != nil {
+		if err := ctx.GetRemoter().HostsAdd(host, ctx.GetCluster().GetMaster0IP(), ctx.GetCluster().GetAPIServerDomain()); err != nil {
 			return fmt.Errorf("failed to add hosts: %v", err)
 		}
 		return nil
 	}
-	if err := ctx.GetRemoter().HostsAdd(host, ctx.GetCluster().GetVIP(), constants.DefaultAPIServerDomain); err != nil {
+	if err := ctx.GetRemoter().HostsAdd(host, ctx.GetCluster().GetVIP(), ctx.GetCluster().GetAPIServerDomain()); err != nil {
 		return fmt.Errorf("failed to add hosts: %v", err)
 	}
 
@@ -52,7 +58,8 @@ type lvscareHostApplier struct{}
 func (*lvscareHostApplier) String() string { return "lvscare_host_applier" }
 
 func (*lvscareHostApplier) Filter(ctx Context, host string) bool {
-	return slices.Contains(ctx.GetCluster().GetNodeIPAndPortList(), host)
+	return ctx.GetCluster().GetControlPlaneEndpoint() == "" &&
+		slices.Contains(ctx.GetCluster().GetNodeIPAndPortList(), host)
 }
 
 func (*lvscareHostApplier) Undo(ctx Context, host string) error {
@@ -0,0 +1,177 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bundle packs everything needed to install a cluster without network access into one archive:
+// the images of the Clusterfile for every required architecture, the sealos binaries and the Clusterfile itself.
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/labring/sealos/pkg/version"
+)
+
+const (
+	ManifestFileName   = "manifest.json"
+	ClusterfileName    = "Clusterfile"
+	ImagesDirName      = "images"
+	BinDirName         = "bin"
+	ManifestAPIVersion = "bundle.sealos.io/v1"
+	imageArchiveSuffix = ".tar"
+	binaryFileMode     = 0755
+	platformOS         = "linux"
+)
+
+// Manifest describes the content of a bundle, every file is recorded with its checksum
+type Manifest struct {
+	APIVersion    string    `json:"apiVersion"`
+	SealosVersion string    `json:"sealosVersion"`
+	Created       time.Time `json:"created"`
+	Cluster       string    `json:"cluster"`
+	Arches        []string  `json:"arches"`
+	Images        []Image   `json:"images"`
+	Files         []File    `json:"files"`
+}
+
+// Image is an image archive of a platform in the bundle
+type Image struct {
+	Name string `json:"name"`
+	Arch string `json:"arch"`
+	Path string `json:"path"`
+}
+
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+func newManifest(cluster string, arches []string) *Manifest {
+	return &Manifest{
+		APIVersion:    ManifestAPIVersion,
+		SealosVersion: version.Get().GitVersion,
+		Created:       time.Now().UTC(),
+		Cluster:       cluster,
+		Arches:        arches,
+	}
+}
+
+// ImagesOf returns the image archives of the arch in the order of the Clusterfile
+func (m *Manifest) ImagesOf(arch string) []Image {
+	var images []Image
+	for _, img := range m.Images {
+		if img.Arch == arch {
+			images = append(images, img)
+		}
+	}
+	return images
+}
+
+func imageArchivePath(arch string, index int) string {
+	return filepath.Join(ImagesDirName, arch, fmt.Sprintf("%d%s", index, imageArchiveSuffix))
+}
+
+func binaryPath(arch, name string) string {
+	return filepath.Join(BinDirName, platformOS+"-"+arch, name)
+}
+
+func fileChecksum(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// checksum records the checksums of all files under dir except the manifest
+func (m *Manifest) checksum(dir string) error {
+	m.Files = nil
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == ManifestFileName {
+			return nil
+		}
+		sum, size, err := fileChecksum(path)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, File{Path: filepath.ToSlash(rel), Size: size, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	return nil
+}
+
+// Verify checks every file of the manifest against its checksum
+func (m *Manifest) Verify(dir string) error {
+	for _, f := range m.Files {
+		sum, size, err := fileChecksum(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return fmt.Errorf("bundle file %s: %v", f.Path, err)
+		}
+		if size != f.Size || sum != f.SHA256 {
+			return fmt.Errorf("bundle file %s is corrupted, checksum mismatch", f.Path)
+		}
+	}
+	return nil
+}
+
+func (m *Manifest) write(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFileName), data, 0644)
+}
+
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("not a sealos bundle: %v", err)
+	}
+	m := &Manifest{}
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %v", err)
+	}
+	if m.APIVersion != ManifestAPIVersion {
+		return nil, fmt.Errorf("unsupported bundle version %s", m.APIVersion)
+	}
+	return m, nil
+}
@@ -0,0 +1,92 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"golang.org/x/exp/slices"
+
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func TestManifestVerify(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		ClusterfileName:                      "kind: Cluster",
+		imageArchivePath("amd64", 0):         "image",
+		binaryPath(runtime.GOARCH, "sealos"): "binary",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := newManifest("default", []string{"amd64"})
+	m.Images = []Image{{Name: "labring/kubernetes:v1.25.0", Arch: "amd64", Path: imageArchivePath("amd64", 0)}}
+	if err := m.checksum(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.write(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != len(files) {
+		t.Errorf("manifest has %d files, want %d", len(m.Files), len(files))
+	}
+
+	read, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = read.Verify(dir); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+	if got := read.ImagesOf("amd64"); len(got) != 1 {
+		t.Errorf("ImagesOf(amd64) = %v", got)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, ClusterfileName), []byte("kind: Tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = read.Verify(dir); err == nil {
+		t.Error("Verify() should fail on a modified file")
+	}
+}
+
+func TestArches(t *testing.T) {
+	cluster := &v2.Cluster{Spec: v2.ClusterSpec{Hosts: []v2.Host{
+		{IPS: []string{"192.168.0.2:22"}, Roles: []string{v2.MASTER, string(v2.ARM64)}},
+		{IPS: []string{"192.168.0.3:22"}, Roles: []string{v2.NODE, string(v2.AMD64)}},
+		{IPS: []string{"192.168.0.4:22"}, Roles: []string{v2.NODE, string(v2.ARM64)}},
+	}}}
+	arches := clusterArches(cluster)
+	if !reflect.DeepEqual(arches, []string{"arm64", "amd64"}) {
+		t.Errorf("clusterArches() = %v", arches)
+	}
+	sorted := sortArches(arches)
+	if len(sorted) != len(arches) {
+		t.Errorf("sortArches() = %v", sorted)
+	}
+	if slices.Contains(arches, runtime.GOARCH) && sorted[len(sorted)-1] != runtime.GOARCH {
+		t.Errorf("sortArches() = %v, the local arch should be the last", sorted)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/containers/common/libimage"
+	"github.com/containers/storage/pkg/archive"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/clusterfile"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+type CreateOptions struct {
+	Clusterfile string
+	Output      string
+	// architectures of the images, defaults to the architectures of the hosts in the Clusterfile
+	Arches []string
+	TmpDir string
+}
+
+// clusterArches returns the architectures recorded in the roles of the hosts
+func clusterArches(cluster *v2.Cluster) []string {
+	var arches []string
+	for _, arch := range cluster.GetArches() {
+		arches = append(arches, string(arch))
+	}
+	return arches
+}
+
+// sortArches puts the local architecture last, so the local storage still holds the local
+// variant of the images after the bundle is created
+func sortArches(arches []string) []string {
+	var ret []string
+	for _, arch := range arches {
+		if arch != runtime.GOARCH {
+			ret = append(ret, arch)
+		}
+	}
+	if slices.Contains(arches, runtime.GOARCH) {
+		ret = append(ret, runtime.GOARCH)
+	}
+	return ret
+}
+
+// Create writes a gzipped bundle of the Clusterfile, its images and the sealos binaries to opts.Output
+func Create(opts *CreateOptions) error {
+	data, err := os.ReadFile(opts.Clusterfile)
+	if err != nil {
+		return err
+	}
+	cluster, err := clusterfile.GetClusterFromDataCompatV1(data)
+	if err != nil {
+		return err
+	}
+	if len(cluster.Spec.Image) == 0 {
+		return fmt.Errorf("no image is specified in %s", opts.Clusterfile)
+	}
+	arches := opts.Arches
+	if len(arches) == 0 {
+		arches = clusterArches(cluster)
+	}
+	if len(arches) == 0 {
+		arches = []string{runtime.GOARCH}
+	}
+
+	stage, err := os.MkdirTemp(opts.TmpDir, "sealos-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stage)
+
+	m := newManifest(cluster.Name, arches)
+	if err = os.WriteFile(filepath.Join(stage, ClusterfileName), data, 0644); err != nil {
+		return err
+	}
+	if err = saveImages(stage, cluster.Spec.Image, sortArches(arches), m); err != nil {
+		return err
+	}
+	if err = copyBinaries(stage); err != nil {
+		return err
+	}
+	if err = m.checksum(stage); err != nil {
+		return err
+	}
+	if err = m.write(stage); err != nil {
+		return err
+	}
+
+	logger.Info("writing bundle to %s", opts.Output)
+	if err = file.MkDirs(filepath.Dir(opts.Output)); err != nil {
+		return err
+	}
+	rc, err := archive.TarWithOptions(stage, &archive.TarOptions{Compression: archive.Gzip})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.Create(opts.Output)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, rc); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func saveImages(stage string, images []string, arches []string, m *Manifest) error {
+	bdah, err := buildah.New("")
+	if err != nil {
+		return err
+	}
+	for _, arch := range arches {
+		// the local variant may be built locally and missing in the registry, only pull it if missing
+		opts := []buildah.FlagSetter{buildah.WithPullPolicyOption(buildah.PullIfMissing.String())}
+		if arch != runtime.GOARCH {
+			opts = append(opts, buildah.WithPlatformOption(v1.Platform{OS: platformOS, Architecture: arch}))
+		}
+		for i, img := range images {
+			logger.Info("saving image %s for %s", img, arch)
+			if err = bdah.Pull([]string{img}, opts...); err != nil {
+				return fmt.Errorf("pull image %s for %s: %w", img, arch, err)
+			}
+			path := imageArchivePath(arch, i)
+			if err = file.MkDirs(filepath.Dir(filepath.Join(stage, path))); err != nil {
+				return err
+			}
+			err = bdah.Runtime().Save(context.Background(), []string{img}, buildah.OCIArchive, filepath.Join(stage, path), &libimage.SaveOptions{})
+			if err != nil {
+				return fmt.Errorf("save image %s for %s: %w", img, arch, err)
+			}
+			m.Images = append(m.Images, Image{Name: img, Arch: arch, Path: filepath.ToSlash(path)})
+		}
+	}
+	return nil
+}
+
+// copyBinaries copies the running sealos and the sealctl in PATH, they are only available for the local architecture
+func copyBinaries(stage string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	binaries := map[string]string{"sealos": self}
+	if sealctl, err := osexec.LookPath("sealctl"); err == nil {
+		binaries["sealctl"] = sealctl
+	} else {
+		logger.Warn("sealctl is not found in PATH, it is not included in the bundle")
+	}
+	for name, src := range binaries {
+		dst := filepath.Join(stage, binaryPath(runtime.GOARCH, name))
+		if err = file.MkDirs(filepath.Dir(dst)); err != nil {
+			return err
+		}
+		if err = copyFile(src, dst, binaryFileMode); err != nil {
+			return fmt.Errorf("copy %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
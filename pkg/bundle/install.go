@@ -0,0 +1,101 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/containers/storage/pkg/archive"
+	"golang.org/x/exp/slices"
+
+	"github.com/labring/sealos/pkg/buildah"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// Extract unpacks the bundle into dir and verifies the checksums of its files
+func Extract(bundle, dir string) (*Manifest, error) {
+	f, err := os.Open(bundle)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err = file.MkDirs(dir); err != nil {
+		return nil, err
+	}
+	logger.Info("extracting bundle %s", bundle)
+	if err = archive.Untar(f, dir, &archive.TarOptions{NoLchown: true}); err != nil {
+		return nil, fmt.Errorf("failed to extract bundle: %v", err)
+	}
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.Verify(dir); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadImages loads the images of all architectures of an extracted bundle into the local storage,
+// the images of the local architecture are loaded last so that the image names refer to them.
+// The other platforms are picked up by name history when mounting images for mixed-arch clusters.
+func LoadImages(dir string, m *Manifest) error {
+	if !slices.Contains(m.Arches, runtime.GOARCH) {
+		return fmt.Errorf("bundle has no images for %s, available: %v", runtime.GOARCH, m.Arches)
+	}
+	bdah, err := buildah.New("")
+	if err != nil {
+		return err
+	}
+	for _, arch := range sortArches(m.Arches) {
+		for _, img := range m.ImagesOf(arch) {
+			name, err := bdah.Load(filepath.Join(dir, filepath.FromSlash(img.Path)), buildah.OCIArchive)
+			if err != nil {
+				return fmt.Errorf("load image %s for %s: %w", img.Name, arch, err)
+			}
+			logger.Info("loaded image %s for %s", name, arch)
+		}
+	}
+	return nil
+}
+
+// InstallBinaries copies the binaries of the local architecture of an extracted bundle into binDir
+func InstallBinaries(dir, binDir string) error {
+	src := filepath.Join(dir, binaryPath(runtime.GOARCH, ""))
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("bundle has no binaries for %s: %v", runtime.GOARCH, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		dst := filepath.Join(binDir, entry.Name())
+		// write aside and rename, the running sealos may be the one being replaced
+		tmp := dst + ".tmp"
+		if err = copyFile(filepath.Join(src, entry.Name()), tmp, binaryFileMode); err != nil {
+			return err
+		}
+		if err = os.Rename(tmp, dst); err != nil {
+			return err
+		}
+		logger.Info("installed %s", dst)
+	}
+	return nil
+}
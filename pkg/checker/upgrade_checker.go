@@ -0,0 +1,304 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+// nosemgrep: go.lang.security.audit.xss.import-text-template.import-text-template
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/labring/sealos/pkg/client-go/kubernetes"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/template"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const (
+	UpgradeCheckPass = "PASS"
+	UpgradeCheckWarn = "WARN"
+	UpgradeCheckFail = "FAIL"
+)
+
+var ErrUpgradePreflightFailed = errors.New("upgrade preflight failed, use --force-upgrade to bypass it")
+
+// UpgradeChecker validates a kubernetes upgrade against the live cluster before it is attempted
+type UpgradeChecker struct {
+	version string
+	force   bool
+}
+
+type UpgradeCheckResult struct {
+	Name    string
+	Status  string
+	Message string
+}
+
+type UpgradePlan struct {
+	CurrentVersion string
+	TargetVersion  string
+	ControlPlanes  []string
+	Workers        []string
+	Checks         []UpgradeCheckResult
+}
+
+func (p *UpgradePlan) add(name string, err error, warnings ...string) {
+	switch {
+	case err != nil:
+		p.Checks = append(p.Checks, UpgradeCheckResult{Name: name, Status: UpgradeCheckFail, Message: err.Error()})
+	case len(warnings) > 0:
+		p.Checks = append(p.Checks, UpgradeCheckResult{Name: name, Status: UpgradeCheckWarn, Message: strings.Join(warnings, "; ")})
+	default:
+		p.Checks = append(p.Checks, UpgradeCheckResult{Name: name, Status: UpgradeCheckPass})
+	}
+}
+
+func (p *UpgradePlan) Failed() bool {
+	for _, check := range p.Checks {
+		if check.Status == UpgradeCheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *UpgradeChecker) Check(cluster *v2.Cluster, phase string) error {
+	if phase != PhasePre {
+		return nil
+	}
+	target, err := semver.NewVersion(n.version)
+	if err != nil {
+		return fmt.Errorf("invalid upgrade version %s: %v", n.version, err)
+	}
+	plan := &UpgradePlan{
+		TargetVersion: target.Original(),
+		ControlPlanes: cluster.GetMasterIPList(),
+		Workers:       cluster.GetNodeIPList(),
+	}
+	defer func() {
+		if err := n.Output(plan); err != nil {
+			logger.Error("failed to output upgrade plan: %v", err)
+		}
+	}()
+
+	data := constants.NewPathResolver(cluster.Name)
+	c, err := kubernetes.NewKubernetesClient(data.AdminFile(), "")
+	if err != nil {
+		plan.add("cluster connection", err)
+		return n.result(plan)
+	}
+	ctx := context.Background()
+	serverVersion, err := c.Discovery().ServerVersion()
+	if err != nil {
+		plan.add("cluster connection", err)
+		return n.result(plan)
+	}
+	current, err := semver.NewVersion(serverVersion.GitVersion)
+	if err != nil {
+		plan.add("cluster connection", fmt.Errorf("invalid server version %s: %v", serverVersion.GitVersion, err))
+		return n.result(plan)
+	}
+	plan.CurrentVersion = current.Original()
+
+	plan.add("version jump", CheckUpgradeVersion(current, target))
+
+	nodes, err := c.Kubernetes().CoreV1().Nodes().List(ctx, v1.ListOptions{})
+	if err == nil {
+		err = CheckKubeletSkew(nodes.Items, current, target)
+	}
+	plan.add("kubelet skew", err)
+
+	apiServers, err := c.Kubernetes().CoreV1().Pods(v1.NamespaceSystem).List(ctx, v1.ListOptions{
+		LabelSelector: "component=" + kubernetes.KubeAPIServer,
+	})
+	if err == nil {
+		err = CheckAPIServerSkew(apiServers.Items, current)
+	}
+	plan.add("apiserver skew", err)
+
+	metrics, err := c.Kubernetes().Discovery().RESTClient().Get().AbsPath("/metrics").DoRaw(ctx)
+	if err != nil {
+		plan.add("deprecated apis", nil, fmt.Sprintf("failed to fetch apiserver metrics: %v", err))
+	} else {
+		plan.add("deprecated apis", CheckDeprecatedAPIs(string(metrics), target))
+	}
+
+	health, err := c.Kubernetes().Discovery().RESTClient().Get().AbsPath("/healthz/etcd").DoRaw(ctx)
+	if err == nil && strings.TrimSpace(string(health)) != "ok" {
+		err = fmt.Errorf("etcd is not healthy: %s", health)
+	}
+	plan.add("etcd health", err)
+
+	return n.result(plan)
+}
+
+func (n *UpgradeChecker) result(plan *UpgradePlan) error {
+	if !plan.Failed() {
+		return nil
+	}
+	if n.force {
+		logger.Warn("upgrade preflight failed, continue because of --force")
+		return nil
+	}
+	return ErrUpgradePreflightFailed
+}
+
+// CheckUpgradeVersion only allows upgrading to the same or the next minor version
+func CheckUpgradeVersion(current, target *semver.Version) error {
+	if target.LessThan(current) {
+		return fmt.Errorf("cannot downgrade from %s to %s", current.Original(), target.Original())
+	}
+	if target.Major() != current.Major() {
+		return fmt.Errorf("cannot upgrade across major versions, %s -> %s", current.Original(), target.Original())
+	}
+	if target.Minor() > current.Minor()+1 {
+		return fmt.Errorf("can only upgrade to the next minor version, %s -> %s, upgrade to v%d.%d first",
+			current.Original(), target.Original(), current.Major(), current.Minor()+1)
+	}
+	return nil
+}
+
+// maxKubeletSkew is the number of minor versions kubelet may be older than kube-apiserver
+func maxKubeletSkew(apiServer *semver.Version) uint64 {
+	if apiServer.Minor() >= 28 {
+		return 3
+	}
+	return 2
+}
+
+// CheckKubeletSkew checks that no kubelet is newer than the current kube-apiserver
+// and no kubelet falls out of the supported skew once the control planes run the target version
+func CheckKubeletSkew(nodes []corev1.Node, current, target *semver.Version) error {
+	var errs []string
+	for _, node := range nodes {
+		kubelet, err := semver.NewVersion(node.Status.NodeInfo.KubeletVersion)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("node %s has invalid kubelet version %s", node.Name, node.Status.NodeInfo.KubeletVersion))
+			continue
+		}
+		if kubelet.Minor() > current.Minor() {
+			errs = append(errs, fmt.Sprintf("kubelet %s on node %s is newer than kube-apiserver %s", kubelet.Original(), node.Name, current.Original()))
+			continue
+		}
+		if kubelet.Minor()+maxKubeletSkew(target) < target.Minor() {
+			errs = append(errs, fmt.Sprintf("kubelet %s on node %s is more than %d minor versions older than %s",
+				kubelet.Original(), node.Name, maxKubeletSkew(target), target.Original()))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// CheckAPIServerSkew checks that every kube-apiserver runs the current minor version,
+// a previous upgrade interrupted halfway must be finished first
+func CheckAPIServerSkew(pods []corev1.Pod, current *semver.Version) error {
+	var errs []string
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if container.Name != kubernetes.KubeAPIServer {
+				continue
+			}
+			idx := strings.LastIndex(container.Image, ":")
+			if idx < 0 {
+				continue
+			}
+			version, err := semver.NewVersion(container.Image[idx+1:])
+			if err != nil {
+				continue
+			}
+			if version.Minor() != current.Minor() {
+				errs = append(errs, fmt.Sprintf("kube-apiserver on node %s runs %s", pod.Spec.NodeName, version.Original()))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("kube-apiservers are not all on %s: %s", current.Original(), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+var metricLabelRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// CheckDeprecatedAPIs finds the requested apis that are removed in the target version
+// from the apiserver_requested_deprecated_apis metric of kube-apiserver
+func CheckDeprecatedAPIs(metrics string, target *semver.Version) error {
+	var removed []string
+	scanner := bufio.NewScanner(strings.NewReader(metrics))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "apiserver_requested_deprecated_apis{") {
+			continue
+		}
+		labels := make(map[string]string)
+		for _, match := range metricLabelRegexp.FindAllStringSubmatch(line, -1) {
+			labels[match[1]] = match[2]
+		}
+		if labels["removed_release"] == "" {
+			continue
+		}
+		removedRelease, err := semver.NewVersion(labels["removed_release"])
+		if err != nil {
+			continue
+		}
+		if removedRelease.Major() == target.Major() && removedRelease.Minor() <= target.Minor() {
+			api := strings.Trim(labels["group"]+"/"+labels["version"], "/")
+			removed = append(removed, fmt.Sprintf("%s %s (removed in %s)", api, labels["resource"], labels["removed_release"]))
+		}
+	}
+	if len(removed) > 0 {
+		return fmt.Errorf("requested apis are removed in %s: %s", target.Original(), strings.Join(removed, ", "))
+	}
+	return nil
+}
+
+func (n *UpgradeChecker) Output(plan *UpgradePlan) error {
+	tpl, isOk, err := template.TryParse(`
+Upgrade Plan
+  Version: {{ .CurrentVersion }} -> {{ .TargetVersion }}
+  Control Planes (one by one):
+    {{- range .ControlPlanes }}
+	{{ . }}
+    {{- end }}
+  Workers: {{ len .Workers }}
+  Preflight Checks:
+    {{- range .Checks }}
+	[{{ .Status }}] {{ .Name }}{{ if .Message }}: {{ .Message }}{{ end }}
+    {{- end }}
+`)
+	if err != nil || !isOk {
+		if err != nil {
+			logger.Error("failed to render upgrade plan template. error: %s", err.Error())
+			return err
+		}
+		return errors.New("convert upgrade plan template failed")
+	}
+	return tpl.Execute(os.Stdout, plan)
+}
+
+func NewUpgradeChecker(version string, force bool) Interface {
+	return &UpgradeChecker{version: version, force: force}
+}
@@ -0,0 +1,75 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckUpgradeVersion(t *testing.T) {
+	tests := []struct {
+		current string
+		target  string
+		wantErr bool
+	}{
+		{"v1.25.3", "v1.25.16", false},
+		{"v1.25.3", "v1.26.0", false},
+		{"v1.25.3", "v1.28.0", true},
+		{"v1.26.1", "v1.25.3", true},
+	}
+	for _, tt := range tests {
+		err := CheckUpgradeVersion(semver.MustParse(tt.current), semver.MustParse(tt.target))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckUpgradeVersion(%s, %s) error = %v, wantErr %v", tt.current, tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckKubeletSkew(t *testing.T) {
+	node := func(name, version string) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: v1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: version}},
+		}
+	}
+	current := semver.MustParse("v1.26.3")
+	target := semver.MustParse("v1.27.4")
+	if err := CheckKubeletSkew([]corev1.Node{node("a", "v1.26.3"), node("b", "v1.25.1")}, current, target); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckKubeletSkew([]corev1.Node{node("a", "v1.24.3")}, current, target); err == nil {
+		t.Error("expected an error for a kubelet out of the skew")
+	}
+	if err := CheckKubeletSkew([]corev1.Node{node("a", "v1.27.0")}, current, target); err == nil {
+		t.Error("expected an error for a kubelet newer than kube-apiserver")
+	}
+}
+
+func TestCheckDeprecatedAPIs(t *testing.T) {
+	metrics := `# HELP apiserver_requested_deprecated_apis [STABLE] Gauge of deprecated APIs that have been requested
+apiserver_requested_deprecated_apis{group="policy",removed_release="1.25",resource="podsecuritypolicies",subresource="",version="v1beta1"} 1
+apiserver_requested_deprecated_apis{group="flowcontrol.apiserver.k8s.io",removed_release="1.29",resource="flowschemas",subresource="",version="v1beta2"} 1
+`
+	if err := CheckDeprecatedAPIs(metrics, semver.MustParse("v1.24.0")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckDeprecatedAPIs(metrics, semver.MustParse("v1.25.0")); err == nil {
+		t.Error("expected an error for an api removed in the target version")
+	}
+}
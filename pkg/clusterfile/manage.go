@@ -0,0 +1,137 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/containers/storage/pkg/archive"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/utils/file"
+)
+
+// the files of a cluster that are needed to manage it from another host
+var clusterStateFiles = []string{constants.DefaultClusterFileName, constants.PkiDirName, constants.EtcDirName}
+
+// ListClusters returns the names of the clusters that have a Clusterfile in the work dir
+func ListClusters() ([]string, error) {
+	entries, err := os.ReadDir(constants.WorkDir())
+	if err != nil {
+		return nil, err
+	}
+	var clusters []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if file.IsFile(constants.Clusterfile(entry.Name())) {
+			clusters = append(clusters, entry.Name())
+		}
+	}
+	sort.Strings(clusters)
+	return clusters, nil
+}
+
+// GetCurrentClusterName returns the cluster selected by SetCurrentClusterName,
+// empty if none is selected or the selected cluster is gone
+func GetCurrentClusterName() string {
+	data, err := os.ReadFile(constants.CurrentClusterFile())
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimSpace(string(data))
+	if name == "" || !file.IsFile(constants.Clusterfile(name)) {
+		return ""
+	}
+	return name
+}
+
+func SetCurrentClusterName(name string) error {
+	if !file.IsFile(constants.Clusterfile(name)) {
+		return fmt.Errorf("cluster %s not found", name)
+	}
+	return os.WriteFile(constants.CurrentClusterFile(), []byte(name+"\n"), 0644)
+}
+
+// ExportCluster writes a gzipped tarball of the Clusterfile, pki and etc dirs of the cluster
+func ExportCluster(name string, w io.Writer) error {
+	if !file.IsFile(constants.Clusterfile(name)) {
+		return fmt.Errorf("cluster %s not found", name)
+	}
+	var includes []string
+	for _, f := range clusterStateFiles {
+		if file.IsExist(filepath.Join(constants.ClusterDir(name), f)) {
+			includes = append(includes, f)
+		}
+	}
+	rc, err := archive.TarWithOptions(constants.ClusterDir(name), &archive.TarOptions{
+		Compression:  archive.Gzip,
+		IncludeFiles: includes,
+	})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+// ImportCluster restores a tarball written by ExportCluster into the work dir and returns the name of the cluster,
+// the name is read from the Clusterfile. An existing cluster is only overwritten if force is set.
+func ImportCluster(r io.Reader, force bool) (string, error) {
+	// extract next to the cluster dirs so the files can be renamed into place
+	tmpDir, err := os.MkdirTemp(constants.WorkDir(), ".import-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err = archive.Untar(r, tmpDir, &archive.TarOptions{NoLchown: true}); err != nil {
+		return "", fmt.Errorf("failed to extract cluster archive: %v", err)
+	}
+	cluster, err := GetClusterFromFile(filepath.Join(tmpDir, constants.DefaultClusterFileName))
+	if err != nil {
+		return "", err
+	}
+	name := cluster.Name
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid cluster name %q in Clusterfile", name)
+	}
+	if file.IsExist(constants.Clusterfile(name)) && !force {
+		return "", fmt.Errorf("cluster %s already exists, use --force to overwrite it", name)
+	}
+	if err = file.MkDirs(constants.ClusterDir(name)); err != nil {
+		return "", err
+	}
+	for _, f := range clusterStateFiles {
+		src := filepath.Join(tmpDir, f)
+		if !file.IsExist(src) {
+			continue
+		}
+		dst := filepath.Join(constants.ClusterDir(name), f)
+		if err = os.RemoveAll(dst); err != nil {
+			return "", err
+		}
+		if err = os.Rename(src, dst); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,106 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/labring/sealos/pkg/constants"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+	"github.com/labring/sealos/pkg/utils/yaml"
+)
+
+func saveTestCluster(t *testing.T, name string) {
+	t.Helper()
+	cluster := &v2.Cluster{
+		TypeMeta:   metav1.TypeMeta{Kind: "Cluster", APIVersion: v2.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v2.ClusterStatus{Phase: v2.ClusterSuccess},
+	}
+	if err := os.MkdirAll(constants.NewPathResolver(name).PkiPath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(constants.NewPathResolver(name).PkiPath(), "ca.crt"), []byte("ca"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.MarshalFile(constants.Clusterfile(name), cluster); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManageClusters(t *testing.T) {
+	defer func(dir string) { constants.DefaultRuntimeRootDir = dir }(constants.DefaultRuntimeRootDir)
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(constants.WorkDir(), "logs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	saveTestCluster(t, "default")
+	saveTestCluster(t, "prod")
+
+	clusters, err := ListClusters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(clusters, []string{"default", "prod"}) {
+		t.Errorf("ListClusters() = %v", clusters)
+	}
+
+	if got := GetCurrentClusterName(); got != "" {
+		t.Errorf("GetCurrentClusterName() = %q, want empty", got)
+	}
+	if err = SetCurrentClusterName("missing"); err == nil {
+		t.Error("SetCurrentClusterName() of a missing cluster should fail")
+	}
+	if err = SetCurrentClusterName("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetDefaultClusterName(); got != "prod" {
+		t.Errorf("GetDefaultClusterName() = %q, want prod", got)
+	}
+
+	var buf bytes.Buffer
+	if err = ExportCluster("prod", &buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if _, err = ImportCluster(bytes.NewReader(data), false); err == nil {
+		t.Error("ImportCluster() should not overwrite an existing cluster without force")
+	}
+
+	constants.DefaultRuntimeRootDir = t.TempDir()
+	name, err := ImportCluster(bytes.NewReader(data), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "prod" {
+		t.Errorf("ImportCluster() = %q, want prod", name)
+	}
+	cluster, err := GetClusterFromName("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cluster.Status.Phase != v2.ClusterSuccess {
+		t.Errorf("imported cluster phase = %q", cluster.Status.Phase)
+	}
+	if _, err = os.Stat(filepath.Join(constants.NewPathResolver("prod").PkiPath(), "ca.crt")); err != nil {
+		t.Errorf("pki is not imported: %v", err)
+	}
+}
@@ -31,6 +31,9 @@ import (
 var ErrClusterNotExist = fmt.Errorf("no cluster exist")
 
 func GetDefaultClusterName() (string, error) {
+	if current := GetCurrentClusterName(); current != "" {
+		return current, nil
+	}
 	files, err := os.ReadDir(constants.WorkDir())
 	if err != nil {
 		return "", err
@@ -46,6 +46,7 @@ const (
 	PkiEtcdDirName              = "etcd"
 	ScriptsDirName              = "scripts"
 	StaticsDirName              = "statics"
+	currentClusterFileName      = "current-cluster"
 )
 
 func GetHomeDir() string {
@@ -64,6 +65,11 @@ func Clusterfile(clusterName string) string {
 	return filepath.Join(DefaultRuntimeRootDir, clusterName, DefaultClusterFileName)
 }
 
+// CurrentClusterFile records the cluster selected by `sealos cluster use`
+func CurrentClusterFile() string {
+	return filepath.Join(DefaultRuntimeRootDir, currentClusterFileName)
+}
+
 func GetRuntimeRootDir(name string) string {
 	if v, ok := os.LookupEnv(strings.ToUpper(name) + "_RUNTIME_ROOT"); ok {
 		return v
@@ -75,6 +81,11 @@ func LogPath() string {
 	return filepath.Join(DefaultRuntimeRootDir, "logs")
 }
 
+// AuditPath is kept out of the cluster dirs so that the journal of a cluster survives its reset
+func AuditPath() string {
+	return filepath.Join(DefaultRuntimeRootDir, "audit")
+}
+
 func DataPath() string {
 	return filepath.Join(DefaultClusterRootFsDir, "data")
 }
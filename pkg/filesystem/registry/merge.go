@@ -0,0 +1,246 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/containers/image/v5/manifest"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/exp/slices"
+)
+
+// layout of the filesystem storage driver of the distribution registry
+const (
+	storageRoot     = "docker/registry/v2"
+	repositoriesDir = storageRoot + "/repositories/"
+	tagsDir         = "/_manifests/tags/"
+)
+
+type repoTag struct {
+	repo string
+	tag  string
+}
+
+// parseTagLink returns the repository and tag of a tag link file path relative to the registry dir
+func parseTagLink(rel string) (repoTag, bool) {
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, repositoriesDir) {
+		return repoTag{}, false
+	}
+	repo, tagPath, ok := strings.Cut(strings.TrimPrefix(rel, repositoriesDir), tagsDir)
+	if !ok {
+		return repoTag{}, false
+	}
+	tag, rest, ok := strings.Cut(tagPath, "/")
+	if !ok || rest != "current/link" {
+		return repoTag{}, false
+	}
+	return repoTag{repo: repo, tag: tag}, true
+}
+
+func isTagFile(rel string) bool {
+	return strings.Contains(filepath.ToSlash(rel), tagsDir)
+}
+
+func blobPath(dir string, d digest.Digest) string {
+	return filepath.Join(dir, storageRoot, "blobs", d.Algorithm().String(), d.Encoded()[:2], d.Encoded(), "data")
+}
+
+func writeLink(dir string, rel string, d digest.Digest) error {
+	p := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, []byte(d.String()), 0644)
+}
+
+// mergeRegistries merges the registry dirs of the platform variants of an image into dst.
+// Tags that point to different manifests in the sources are replaced by an image index
+// of all of them, so the registry serves every platform under the same name.
+func mergeRegistries(dst string, srcs []string) error {
+	tags := make(map[repoTag][]digest.Digest)
+	for _, src := range srcs {
+		err := filepath.Walk(filepath.Join(src, storageRoot), func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(src, p)
+			if err != nil {
+				return err
+			}
+			if rt, ok := parseTagLink(rel); ok {
+				data, err := os.ReadFile(p)
+				if err != nil {
+					return err
+				}
+				d, err := digest.Parse(strings.TrimSpace(string(data)))
+				if err != nil {
+					return fmt.Errorf("invalid tag link %s: %v", p, err)
+				}
+				if !slices.Contains(tags[rt], d) {
+					tags[rt] = append(tags[rt], d)
+				}
+				return nil
+			}
+			// the tag indexes are written again with the merged tags
+			if isTagFile(rel) {
+				return nil
+			}
+			return linkOrCopyFile(p, filepath.Join(dst, rel))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for rt, digests := range tags {
+		d := digests[0]
+		if len(digests) > 1 {
+			var err error
+			if d, err = writeIndex(dst, rt.repo, digests); err != nil {
+				return fmt.Errorf("failed to merge %s:%s: %v", rt.repo, rt.tag, err)
+			}
+		}
+		tagDir := path.Join(repositoriesDir+rt.repo+tagsDir, rt.tag)
+		if err := writeLink(dst, path.Join(tagDir, "current", "link"), d); err != nil {
+			return err
+		}
+		if err := writeLink(dst, path.Join(tagDir, "index", d.Algorithm().String(), d.Encoded(), "link"), d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeIndex writes an index of the manifests to the blobs of dst and links it to repo
+func writeIndex(dst string, repo string, digests []digest.Digest) (digest.Digest, error) {
+	index := v1.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: v1.MediaTypeImageIndex,
+	}
+	for _, d := range digests {
+		data, err := os.ReadFile(blobPath(dst, d))
+		if err != nil {
+			return "", err
+		}
+		mediaType := manifest.GuessMIMEType(data)
+		if strings.HasPrefix(mediaType, "application/vnd.docker.") {
+			index.MediaType = manifest.DockerV2ListMediaType
+		}
+		if manifest.MIMETypeIsMultiImage(mediaType) {
+			var list v1.Index
+			if err = json.Unmarshal(data, &list); err != nil {
+				return "", err
+			}
+			for _, desc := range list.Manifests {
+				if !slices.ContainsFunc(index.Manifests, func(m v1.Descriptor) bool { return m.Digest == desc.Digest }) {
+					index.Manifests = append(index.Manifests, desc)
+				}
+			}
+			continue
+		}
+		platform, err := manifestPlatform(dst, data)
+		if err != nil {
+			return "", err
+		}
+		index.Manifests = append(index.Manifests, v1.Descriptor{
+			MediaType: mediaType,
+			Digest:    d,
+			Size:      int64(len(data)),
+			Platform:  platform,
+		})
+	}
+	sort.SliceStable(index.Manifests, func(i, j int) bool {
+		return platformString(index.Manifests[i].Platform) < platformString(index.Manifests[j].Platform)
+	})
+	data, err := json.Marshal(index)
+	if err != nil {
+		return "", err
+	}
+	d := digest.FromBytes(data)
+	p := blobPath(dst, d)
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(p, data, 0644); err != nil {
+		return "", err
+	}
+	revision := path.Join(repositoriesDir+repo, "_manifests", "revisions", d.Algorithm().String(), d.Encoded(), "link")
+	return d, writeLink(dst, revision, d)
+}
+
+// manifestPlatform reads the platform of an image manifest from its config blob
+func manifestPlatform(dir string, data []byte) (*v1.Platform, error) {
+	var m v1.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	config, err := os.ReadFile(blobPath(dir, m.Config.Digest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config of manifest: %v", err)
+	}
+	var img v1.Image
+	if err = json.Unmarshal(config, &img); err != nil {
+		return nil, err
+	}
+	return &v1.Platform{OS: img.OS, Architecture: img.Architecture, Variant: img.Variant}, nil
+}
+
+func platformString(p *v1.Platform) string {
+	if p == nil {
+		return ""
+	}
+	return path.Join(p.OS, p.Architecture, p.Variant)
+}
+
+// linkOrCopyFile hard links src to dst, it falls back to copying across filesystems.
+// Existing files are kept since files of the same path have the same content in registries.
+func linkOrCopyFile(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,130 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func writeBlob(t *testing.T, dir string, data []byte) digest.Digest {
+	d := digest.FromBytes(data)
+	p := blobPath(dir, d)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// newRegistry writes a registry dir holding repo:tag of a single platform
+func newRegistry(t *testing.T, repo, tag, arch string) (string, digest.Digest) {
+	dir := t.TempDir()
+	config, _ := json.Marshal(v1.Image{Platform: v1.Platform{OS: "linux", Architecture: arch}})
+	configDigest := writeBlob(t, dir, config)
+	m, _ := json.Marshal(v1.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: v1.MediaTypeImageManifest,
+		Config:    v1.Descriptor{MediaType: v1.MediaTypeImageConfig, Digest: configDigest, Size: int64(len(config))},
+	})
+	d := writeBlob(t, dir, m)
+	repoDir := repositoriesDir + repo
+	for _, rel := range []string{
+		path.Join(repoDir, "_manifests/revisions/sha256", d.Encoded(), "link"),
+		path.Join(repoDir+tagsDir, tag, "current/link"),
+		path.Join(repoDir+tagsDir, tag, "index/sha256", d.Encoded(), "link"),
+	} {
+		if err := writeLink(dir, rel, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, d
+}
+
+func readTag(t *testing.T, dir, repo, tag string) digest.Digest {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path.Join(repositoriesDir+repo+tagsDir, tag, "current/link"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return digest.Digest(strings.TrimSpace(string(data)))
+}
+
+func TestMergeRegistries(t *testing.T) {
+	amd64, amd64Digest := newRegistry(t, "labring/kube-apiserver", "v1.25.0", "amd64")
+	arm64, arm64Digest := newRegistry(t, "labring/kube-apiserver", "v1.25.0", "arm64")
+
+	dst := t.TempDir()
+	if err := mergeRegistries(dst, []string{amd64, arm64}); err != nil {
+		t.Fatal(err)
+	}
+	d := readTag(t, dst, "labring/kube-apiserver", "v1.25.0")
+	data, err := os.ReadFile(blobPath(dst, d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index v1.Index
+	if err = json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	if index.MediaType != v1.MediaTypeImageIndex || len(index.Manifests) != 2 {
+		t.Fatalf("merged tag is not an index of both platforms: %s", data)
+	}
+	for i, want := range []struct {
+		arch   string
+		digest digest.Digest
+	}{{"amd64", amd64Digest}, {"arm64", arm64Digest}} {
+		got := index.Manifests[i]
+		if got.Digest != want.digest || got.Platform == nil || got.Platform.Architecture != want.arch {
+			t.Errorf("manifest %d = %+v, want %s of %s", i, got, want.digest, want.arch)
+		}
+		if _, err = os.Stat(blobPath(dst, want.digest)); err != nil {
+			t.Errorf("manifest of %s is not merged: %v", want.arch, err)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(dst, filepath.FromSlash(path.Join(repositoriesDir, "labring/kube-apiserver/_manifests/revisions/sha256", d.Encoded(), "link")))); err != nil {
+		t.Errorf("index is not linked to the repository: %v", err)
+	}
+}
+
+func TestMergeRegistriesSameManifest(t *testing.T) {
+	src, d := newRegistry(t, "labring/pause", "3.9", "amd64")
+	dst := t.TempDir()
+	if err := mergeRegistries(dst, []string{src, src}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTag(t, dst, "labring/pause", "3.9"); got != d {
+		t.Errorf("tag of the same manifest = %s, want %s", got, d)
+	}
+}
+
+func TestParseTagLink(t *testing.T) {
+	rt, ok := parseTagLink("docker/registry/v2/repositories/library/nginx/_manifests/tags/latest/current/link")
+	if !ok || rt.repo != "library/nginx" || rt.tag != "latest" {
+		t.Errorf("parseTagLink() = %+v, %v", rt, ok)
+	}
+	if _, ok = parseTagLink("docker/registry/v2/repositories/library/nginx/_manifests/tags/latest/index/sha256/abc/link"); ok {
+		t.Error("tag index link should not be parsed as the current tag")
+	}
+}
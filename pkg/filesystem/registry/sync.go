@@ -19,6 +19,7 @@ package registry
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -56,19 +57,72 @@ type impl struct {
 	mounts       []v2.MountImage
 }
 
+func registryDirs(mount v2.MountImage) []string {
+	var dirs []string
+	for _, mountPoint := range mount.MountPoints() {
+		if dir := filepath.Join(mountPoint, constants.RegistryDirName); file.IsDir(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
 func shouldSkip(mounts []v2.MountImage) bool {
 	for i := range mounts {
-		if file.IsDir(filepath.Join(mounts[i].MountPoint, constants.RegistryDirName)) {
+		if len(registryDirs(mounts[i])) > 0 {
 			return false
 		}
 	}
 	return true
 }
 
+// prepareRegistryDirs returns the registry dir of every mount, the registries of the platform
+// variants of an image are merged into a temporary dir which is removed by the returned func.
+func (s *impl) prepareRegistryDirs() ([]string, func(), error) {
+	var (
+		ret  []string
+		tmps []string
+	)
+	cleanup := func() {
+		for _, tmp := range tmps {
+			_ = os.RemoveAll(tmp)
+		}
+	}
+	for i := range s.mounts {
+		dirs := registryDirs(s.mounts[i])
+		if len(dirs) <= 1 {
+			ret = append(ret, dirs...)
+			continue
+		}
+		if err := file.MkDirs(s.pathResolver.TmpPath()); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		tmp, err := os.MkdirTemp(s.pathResolver.TmpPath(), "registry-")
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		tmps = append(tmps, tmp)
+		logger.Debug("merging registries of all platforms of image %s into %s", s.mounts[i].ImageName, tmp)
+		if err = mergeRegistries(tmp, dirs); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to merge registries of image %s: %w", s.mounts[i].ImageName, err)
+		}
+		ret = append(ret, tmp)
+	}
+	return ret, cleanup, nil
+}
+
 func (s *impl) Sync(ctx context.Context, hosts ...string) error {
 	if shouldSkip(s.mounts) {
 		return nil
 	}
+	dirs, cleanup, err := s.prepareRegistryDirs()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	logger.Info("trying default http mode to sync images to hosts %v", hosts)
 	// run `sealctl registry serve` to start a temporary registry
 	for i := range hosts {
@@ -114,11 +168,8 @@ func (s *impl) Sync(ctx context.Context, hosts ...string) error {
 		if !ok {
 			break
 		}
-		for j := range s.mounts {
-			registryDir := filepath.Join(s.mounts[j].MountPoint, constants.RegistryDirName)
-			if !file.IsDir(registryDir) {
-				continue
-			}
+		for j := range dirs {
+			registryDir := dirs[j]
 			eg.Go(func() (err error) {
 				switch opt.typ {
 				case httpMode:
@@ -60,30 +60,34 @@ func (f *defaultRootfs) mountRootfs(cluster *v2.Cluster, ipList []string) error
 	// TODO: remove this when rendering on client side is GA
 	for _, mount := range f.mounts {
 		src := mount
-		eg.Go(func() error {
-			if !file.IsExist(src.MountPoint) {
-				logger.Debug("Image %s not exist, render env continue", src.ImageName)
-				return nil
-			}
-			// TODO: if we are planing to support rendering templates for each host,
-			// then move this rendering process before ssh.CopyDir and do it one by one.
-			envs := v2.MergeEnvWithBuiltinKeys(src.Env, src)
-			err := renderTemplatesWithEnv(src.MountPoint, ipList, envProcessor, envs)
-			if err != nil {
-				return fmt.Errorf("failed to render env: %w", err)
-			}
-			dirs, err := file.StatDir(src.MountPoint, true)
-			if err != nil {
-				return fmt.Errorf("failed to stat files: %w", err)
-			}
-			if len(dirs) != 0 {
-				_, err = executils.RunBashCmd(fmt.Sprintf(constants.DefaultChmodBash, src.MountPoint))
+		// every platform variant is rendered, hosts get the one of their architecture
+		for _, mountPoint := range src.MountPoints() {
+			mountPoint := mountPoint
+			eg.Go(func() error {
+				if !file.IsExist(mountPoint) {
+					logger.Debug("Image %s not exist, render env continue", src.ImageName)
+					return nil
+				}
+				// TODO: if we are planing to support rendering templates for each host,
+				// then move this rendering process before ssh.CopyDir and do it one by one.
+				envs := v2.MergeEnvWithBuiltinKeys(src.Env, src)
+				err := renderTemplatesWithEnv(mountPoint, ipList, envProcessor, envs)
 				if err != nil {
-					return fmt.Errorf("run chmod to rootfs failed: %w", err)
+					return fmt.Errorf("failed to render env: %w", err)
 				}
-			}
-			return nil
-		})
+				dirs, err := file.StatDir(mountPoint, true)
+				if err != nil {
+					return fmt.Errorf("failed to stat files: %w", err)
+				}
+				if len(dirs) != 0 {
+					_, err = executils.RunBashCmd(fmt.Sprintf(constants.DefaultChmodBash, mountPoint))
+					if err != nil {
+						return fmt.Errorf("run chmod to rootfs failed: %w", err)
+					}
+				}
+				return nil
+			})
+		}
 	}
 	if err := eg.Wait(); err != nil {
 		return err
@@ -118,11 +122,12 @@ func (f *defaultRootfs) mountRootfs(cluster *v2.Cluster, ipList []string) error
 		ip := ipList[idx]
 		eg.Go(func() error {
 			var renderingRequired bool
+			arch := cluster.GetArchByIP(ip)
 			for i := range f.mounts {
 				if f.mounts[i].IsRootFs() || f.mounts[i].IsPatch() {
 					renderingRequired = true
 					// contents in rootfs/patch type images cannot be replicated asynchronously
-					if err := copyFn(f.mounts[i], ip, target); err != nil {
+					if err := copyFn(f.mounts[i].ForArch(arch), ip, target); err != nil {
 						return err
 					}
 				}
@@ -144,8 +149,9 @@ func (f *defaultRootfs) mountRootfs(cluster *v2.Cluster, ipList []string) error
 
 	eg, _ = errgroup.WithContext(ctx)
 	master0 := cluster.GetMaster0IPAndPort()
+	master0Arch := cluster.GetArchByIP(master0)
 	for idx := range f.mounts {
-		mountInfo := f.mounts[idx]
+		mountInfo := f.mounts[idx].ForArch(master0Arch)
 		eg.Go(func() error {
 			if mountInfo.IsApplication() {
 				targetDir := constants.GetAppWorkDir(cluster.Name, mountInfo.Name)
@@ -0,0 +1,79 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rootfs
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/ssh/sshtest"
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+func newMountPoint(t *testing.T, content string) string {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, constants.ScriptsDirName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, constants.ScriptsDirName, "init.sh"), []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestMountRootfsVariants(t *testing.T) {
+	master0 := sshtest.NewHost(t, sshtest.WithArch("x86_64"))
+	node := sshtest.NewHost(t, sshtest.WithArch("aarch64"))
+	cluster := sshtest.NewCluster("default", []*sshtest.Host{master0}, []*sshtest.Host{node})
+	cluster.Status.Mounts = []v2.MountImage{{
+		Name:       "default-rootfs",
+		ImageName:  "labring/kubernetes:v1.25.0",
+		Type:       v2.RootfsImage,
+		Arch:       v2.AMD64,
+		MountPoint: newMountPoint(t, "amd64"),
+		Variants: []v2.MountVariant{{
+			Arch:       v2.ARM64,
+			Name:       "default-rootfs-arm64",
+			MountPoint: newMountPoint(t, "arm64"),
+		}},
+	}}
+
+	fs, err := NewRootfsMounter(cluster.Status.Mounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = fs.MountRootfs(cluster, []string{master0.Addr, node.Addr}); err != nil {
+		t.Fatal(err)
+	}
+	initScript := path.Join(constants.NewPathResolver(cluster.Name).RootFSScriptsPath(), "init.sh")
+	for _, tc := range []struct {
+		host *sshtest.Host
+		want string
+	}{{master0, "amd64"}, {node, "arm64"}} {
+		data, err := tc.host.ReadFile(initScript)
+		if err != nil {
+			t.Fatalf("rootfs is not copied to %s host: %v", tc.want, err)
+		}
+		if string(data) != tc.want {
+			t.Errorf("%s host got rootfs of %s", tc.want, data)
+		}
+		if !tc.host.Ran(`render --debug=\S+ --clear`) {
+			t.Errorf("rootfs is not rendered on %s host", tc.want)
+		}
+	}
+}
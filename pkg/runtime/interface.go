@@ -14,6 +14,8 @@
 
 package runtime
 
+import "time"
+
 type Interface interface {
 	Ruler
 	Init() error
@@ -33,6 +35,38 @@ type CertManager interface {
 	UpdateCertSANs(certSANs []string) error
 }
 
+// NodeMaintainer is implemented by the runtimes that can take nodes out of service for maintenance,
+// the nodes are given by ip.
+type NodeMaintainer interface {
+	Cordon(nodes []string) error
+	Uncordon(nodes []string) error
+	// Drain cordons the nodes and evicts their pods, the nodes stay cordoned
+	Drain(nodes []string, opts *MaintenanceOptions) error
+	// Reboot drains, reboots and uncordons the nodes once they are ready again,
+	// masters are rebooted one by one, workers in batches
+	Reboot(nodes []string, opts *MaintenanceOptions) error
+}
+
+// EndpointManager is implemented by the runtimes that can move the control plane endpoint of a running cluster
+type EndpointManager interface {
+	// SetEndpoint changes the VIP and the API server domain, an empty value keeps the current one
+	SetEndpoint(vip, domain string) error
+}
+
+type MaintenanceOptions struct {
+	// number of workers handled at the same time
+	BatchSize int
+	// overrides the termination grace period of the pods, negative uses the pod's own
+	GracePeriod  time.Duration
+	DrainTimeout time.Duration
+	// how long to wait for a rebooted node to be ready
+	ReadyTimeout time.Duration
+	// evict the pods not managed by a controller, a node with such pods is not drained otherwise
+	Force bool
+	// evict the pods with emptyDir volumes, a node with such pods is not drained otherwise
+	DeleteEmptyDirData bool
+}
+
 type Config interface {
 	GetComponents() []any
 }
@@ -42,7 +42,7 @@ func (k *K3s) initMaster0() error {
 		func() error { return k.enableK3sService(master0) },
 		k.pullKubeConfigFromMaster0,
 		func() error {
-			return k.remoteUtil.HostsAdd(master0, iputils.GetHostIP(master0), constants.DefaultAPIServerDomain)
+			return k.remoteUtil.HostsAdd(master0, iputils.GetHostIP(master0), k.cluster.GetAPIServerDomain())
 		},
 		func() error { return k.copyKubeConfigFileToNodes(k.cluster.GetMaster0IPAndPort()) },
 	)
@@ -72,7 +72,7 @@ func (k *K3s) writeJoinConfigWithCallbacks(runMode string, callbacks ...callback
 
 	defaultCallbacks = append(defaultCallbacks,
 		func(c *Config) *Config {
-			c.ServerURL = fmt.Sprintf("https://%s:%d", constants.DefaultAPIServerDomain, c.HTTPSPort)
+			c.ServerURL = fmt.Sprintf("https://%s:%d", k.cluster.GetAPIServerDomain(), c.HTTPSPort)
 			return c
 		},
 	)
@@ -104,7 +104,7 @@ func (k *K3s) joinMaster(master string) error {
 		},
 		func() error { return k.enableK3sService(master) },
 		func() error {
-			return k.remoteUtil.HostsAdd(master, iputils.GetHostIP(master), constants.DefaultAPIServerDomain)
+			return k.remoteUtil.HostsAdd(master, iputils.GetHostIP(master), k.cluster.GetAPIServerDomain())
 		},
 		func() error { return k.copyKubeConfigFileToNodes(master) },
 	)
@@ -235,7 +235,7 @@ func (k *K3s) copyKubeConfigFileToNodes(hosts ...string) error {
 	if err != nil {
 		return errors.WithMessage(err, "read admin.config file failed")
 	}
-	newData := strings.ReplaceAll(string(data), "https://0.0.0.0", fmt.Sprintf("https://%s", constants.DefaultAPIServerDomain))
+	newData := strings.ReplaceAll(string(data), "https://0.0.0.0", fmt.Sprintf("https://%s", k.cluster.GetAPIServerDomain()))
 	if err = file.WriteFile(src, []byte(newData)); err != nil {
 		return errors.WithMessage(err, "write admin.config file failed")
 	}
@@ -116,7 +116,7 @@ func (k *K3s) overrideCertSans(c *Config) *Config {
 	masterIPs := iputils.GetHostIPs(k.cluster.GetMasterIPList())
 	var certSans []string
 	certSans = append(certSans, "127.0.0.1")
-	certSans = append(certSans, constants.DefaultAPIServerDomain)
+	certSans = append(certSans, k.cluster.GetAPIServerDomain())
 	certSans = append(certSans, k.cluster.GetVIP())
 	certSans = append(certSans, masterIPs...)
 	certSans = append(certSans, c.TLSSan...)
@@ -166,38 +166,54 @@ func (k *KubeadmRuntime) showKubeadmCert() error {
 	return k.sshCmdAsync(k.getMaster0IPAndPort(), fmt.Sprintf("%s%s", certCheck, vlogToStr(k.klogLevel)))
 }
 
-func (k *KubeadmRuntime) deleteAPIServer() error {
-	podIDSh := fmt.Sprintf("crictl ps -a --name %s -o json", kubernetes.KubeAPIServer)
-	type crictlPS struct {
-		Containers []struct {
-			ID           string `json:"id"`
-			PodSandboxID string `json:"podSandboxId"`
-		} `json:"containers"`
+type crictlPS struct {
+	Containers []struct {
+		ID           string `json:"id"`
+		PodSandboxID string `json:"podSandboxId"`
+	} `json:"containers"`
+}
+
+// listContainers lists the containers of a static pod on the host, the latest first
+func (k *KubeadmRuntime) listContainers(host, name, flags string) (*crictlPS, error) {
+	out, err := k.sshCmdToString(host, fmt.Sprintf("crictl ps %s --name %s -o json", flags, name))
+	if err != nil {
+		return nil, err
+	}
+	ps := &crictlPS{}
+	if err = json.Unmarshal([]byte(out), ps); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// deleteStaticPod removes the pod of a static pod on the host, which is recreated by kubelet at once,
+// it returns the id of the latest container of the removed pod
+func (k *KubeadmRuntime) deleteStaticPod(host, name string) (string, error) {
+	ps, err := k.listContainers(host, name, "-a")
+	if err != nil {
+		return "", err
+	}
+	if len(ps.Containers) == 0 {
+		return "", fmt.Errorf("not found %s pod running on %s", name, host)
+	}
+	podID := ps.Containers[0].PodSandboxID[:13]
+	logger.Debug("found podID %s in %s", podID, host)
+	//crictl stopp
+	if err = k.sshCmdAsync(host, fmt.Sprintf("crictl --timeout=10s stopp %s", podID)); err != nil {
+		return "", err
 	}
+	//crictl rmp
+	return ps.Containers[0].ID, k.sshCmdAsync(host, fmt.Sprintf("crictl rmp %s", podID))
+}
+
+func (k *KubeadmRuntime) deleteAPIServer() error {
 	logger.Info("delete pod apiserver from crictl")
 	eg, _ := errgroup.WithContext(context.Background())
 	for _, master := range k.getMasterIPAndPortList() {
 		m := master
 		eg.Go(func() error {
-			podIDJson, err := k.sshCmdToString(m, podIDSh)
-			if err != nil {
-				return err
-			}
-			ps := &crictlPS{}
-			if err = json.Unmarshal([]byte(podIDJson), ps); err != nil {
-				return err
-			}
-			if len(ps.Containers) > 0 {
-				podID := ps.Containers[0].PodSandboxID[:13]
-				logger.Debug("found podID %s in %s", podID, m)
-				//crictl stopp
-				if err = k.sshCmdAsync(m, fmt.Sprintf("crictl --timeout=10s stopp %s", podID)); err != nil {
-					return err
-				}
-				//crictl rmp
-				return k.sshCmdAsync(m, fmt.Sprintf("crictl rmp %s", podID))
-			}
-			return errors.New("not found apiServer pod running")
+			_, err := k.deleteStaticPod(m, kubernetes.KubeAPIServer)
+			return err
 		})
 	}
 	return eg.Wait()
@@ -0,0 +1,198 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+var evictionRetryPeriod = 5 * time.Second
+
+// drainer evicts the pods of a node through the eviction API,
+// so that PodDisruptionBudgets are respected. Evictions refused by a PDB are retried until the timeout.
+// Like kubectl drain, it refuses to drain a node with pods not managed by a controller or with emptyDir volumes
+// unless it is forced to.
+type drainer struct {
+	client clientset.Interface
+	// gracePeriod overrides the termination grace period of the pods, negative uses the pod's own
+	gracePeriod time.Duration
+	timeout     time.Duration
+	// force evicts the pods not managed by a controller, they are not recreated on another node
+	force bool
+	// deleteEmptyDirData evicts the pods with emptyDir volumes, the data of the volumes is lost
+	deleteEmptyDirData bool
+}
+
+// drainPlan is the pods of a node to be evicted, the pods left on it and the pods refusing the drain
+type drainPlan struct {
+	node    string
+	evict   []v1.Pod
+	skipped []string
+	refused []string
+}
+
+func (p *drainPlan) print() {
+	var b strings.Builder
+	fmt.Fprintf(&b, "drain plan of node %s: %d pods to evict", p.node, len(p.evict))
+	for _, pod := range p.evict {
+		fmt.Fprintf(&b, "\n\tevict %s/%s", pod.Namespace, pod.Name)
+	}
+	for _, pod := range p.skipped {
+		fmt.Fprintf(&b, "\n\tskip %s", pod)
+	}
+	for _, pod := range p.refused {
+		fmt.Fprintf(&b, "\n\trefuse %s", pod)
+	}
+	logger.Info(b.String())
+}
+
+// planDrain evicts the pods on the node except the ones of DaemonSets, mirror pods of static pods
+// and the finished pods. The pods not managed by a controller and the pods with emptyDir volumes
+// are refused unless the drainer is forced to evict them.
+func (d *drainer) planDrain(ctx context.Context, nodeName string) (*drainPlan, error) {
+	podList, err := d.client.CoreV1().Pods(metaV1.NamespaceAll).List(ctx, metaV1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	plan := &drainPlan{node: nodeName}
+	for _, pod := range podList.Items {
+		name := pod.Namespace + "/" + pod.Name
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			plan.skipped = append(plan.skipped, name+" (static pod)")
+			continue
+		}
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			plan.skipped = append(plan.skipped, name+" (finished)")
+			continue
+		}
+		controllerRef := metaV1.GetControllerOf(&pod)
+		if controllerRef != nil && controllerRef.Kind == "DaemonSet" {
+			plan.skipped = append(plan.skipped, name+" (DaemonSet)")
+			continue
+		}
+		if controllerRef == nil && !d.force {
+			plan.refused = append(plan.refused, name+" (not managed by a controller, force to evict it)")
+			continue
+		}
+		if hasEmptyDir(&pod) && !d.deleteEmptyDirData {
+			plan.refused = append(plan.refused, name+" (uses emptyDir volumes, delete the emptyDir data to evict it)")
+			continue
+		}
+		plan.evict = append(plan.evict, pod)
+	}
+	return plan, nil
+}
+
+func hasEmptyDir(pod *v1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *drainer) evict(ctx context.Context, pod v1.Pod) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+	if d.gracePeriod >= 0 {
+		gracePeriodSeconds := int64(d.gracePeriod.Seconds())
+		eviction.DeleteOptions = &metaV1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds}
+	}
+	return wait.PollUntilContextCancel(ctx, evictionRetryPeriod, true, func(ctx context.Context) (bool, error) {
+		err := d.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			return true, nil
+		case apierrors.IsTooManyRequests(err):
+			// refused by a PodDisruptionBudget, retry later
+			logger.Debug("evict pod %s/%s is refused, retrying: %v", pod.Namespace, pod.Name, err)
+			return false, nil
+		default:
+			return false, fmt.Errorf("evict pod %s/%s failed: %w", pod.Namespace, pod.Name, err)
+		}
+	})
+}
+
+func (d *drainer) waitForDeletion(ctx context.Context, pod v1.Pod) error {
+	return wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		p, err := d.client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metaV1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && p.UID != pod.UID) {
+			return true, nil
+		}
+		return false, nil
+	})
+}
+
+// Drain evicts the pods of the node and waits for them to be deleted, it returns the number of evicted pods
+func (d *drainer) Drain(nodeName string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	plan, err := d.planDrain(ctx, nodeName)
+	if err != nil {
+		return 0, err
+	}
+	plan.print()
+	if len(plan.refused) > 0 {
+		return 0, fmt.Errorf("drain node %s is refused by the pods: %s", nodeName, strings.Join(plan.refused, ", "))
+	}
+	pods := plan.evict
+	errCh := make(chan error, len(pods))
+	for _, pod := range pods {
+		go func(pod v1.Pod) {
+			if err := d.evict(ctx, pod); err != nil {
+				errCh <- err
+				return
+			}
+			if err := d.waitForDeletion(ctx, pod); err != nil {
+				errCh <- fmt.Errorf("wait for pod %s/%s to be deleted: %w", pod.Namespace, pod.Name, err)
+				return
+			}
+			errCh <- nil
+		}(pod)
+	}
+	var firstErr error
+	for range pods {
+		if err := <-errCh; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return len(pods), fmt.Errorf("drain node %s within %s: %w", nodeName, d.timeout, firstErr)
+	}
+	return len(pods), nil
+}
@@ -0,0 +1,294 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newDrainTestPod(name string, mutate func(pod *v1.Pod)) *v1.Pod {
+	isController := true
+	pod := &v1.Pod{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       k8stypes.UID("uid-" + name),
+			OwnerReferences: []metaV1.OwnerReference{{
+				APIVersion: "apps/v1",
+				Kind:       "ReplicaSet",
+				Name:       name + "-rs",
+				Controller: &isController,
+			}},
+		},
+		Spec:   v1.PodSpec{NodeName: "node1"},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+	if mutate != nil {
+		mutate(pod)
+	}
+	return pod
+}
+
+func drainTestPods() []runtime.Object {
+	return []runtime.Object{
+		newDrainTestPod("managed", nil),
+		newDrainTestPod("mirror", func(pod *v1.Pod) {
+			pod.OwnerReferences = nil
+			pod.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+		}),
+		newDrainTestPod("daemonset", func(pod *v1.Pod) {
+			pod.OwnerReferences[0].Kind = "DaemonSet"
+		}),
+		newDrainTestPod("finished", func(pod *v1.Pod) {
+			pod.OwnerReferences = nil
+			pod.Status.Phase = v1.PodSucceeded
+		}),
+		newDrainTestPod("bare", func(pod *v1.Pod) {
+			pod.OwnerReferences = nil
+		}),
+		newDrainTestPod("emptydir", func(pod *v1.Pod) {
+			pod.Spec.Volumes = []v1.Volume{{
+				Name:         "cache",
+				VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+			}}
+		}),
+	}
+}
+
+func podNames(pods []v1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPlanDrain(t *testing.T) {
+	tests := []struct {
+		name               string
+		force              bool
+		deleteEmptyDirData bool
+		wantEvict          []string
+		wantRefused        int
+	}{
+		{
+			name:        "refuse bare and emptyDir pods",
+			wantEvict:   []string{"managed"},
+			wantRefused: 2,
+		},
+		{
+			name:        "force evicts bare pods",
+			force:       true,
+			wantEvict:   []string{"bare", "managed"},
+			wantRefused: 1,
+		},
+		{
+			name:               "delete emptyDir data evicts emptyDir pods",
+			deleteEmptyDirData: true,
+			wantEvict:          []string{"emptydir", "managed"},
+			wantRefused:        1,
+		},
+		{
+			name:               "force and delete emptyDir data",
+			force:              true,
+			deleteEmptyDirData: true,
+			wantEvict:          []string{"bare", "emptydir", "managed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &drainer{
+				client:             fake.NewSimpleClientset(drainTestPods()...),
+				force:              tt.force,
+				deleteEmptyDirData: tt.deleteEmptyDirData,
+			}
+			plan, err := d.planDrain(context.Background(), "node1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := podNames(plan.evict); !reflect.DeepEqual(got, tt.wantEvict) {
+				t.Errorf("evict = %v, want %v", got, tt.wantEvict)
+			}
+			if len(plan.refused) != tt.wantRefused {
+				t.Errorf("refused = %v, want %d pods", plan.refused, tt.wantRefused)
+			}
+			// mirror, DaemonSet and finished pods are always left on the node
+			if len(plan.skipped) != 3 {
+				t.Errorf("skipped = %v, want 3 pods", plan.skipped)
+			}
+		})
+	}
+}
+
+// evictionReactor deletes the evicted pods, the first evictions of each pod, up to refusals, are refused by a PDB
+func evictionReactor(client *fake.Clientset, refusals int) (*sync.Map, k8stesting.ReactionFunc) {
+	attempts := &sync.Map{}
+	return attempts, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		n, _ := attempts.LoadOrStore(eviction.Name, new(int))
+		count := n.(*int)
+		*count++
+		if *count <= refusals {
+			return true, nil, apierrors.NewTooManyRequests("disruption budget", 0)
+		}
+		err := client.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+		return true, nil, err
+	}
+}
+
+func TestDrain(t *testing.T) {
+	period := evictionRetryPeriod
+	evictionRetryPeriod = 10 * time.Millisecond
+	defer func() { evictionRetryPeriod = period }()
+
+	client := fake.NewSimpleClientset(drainTestPods()...)
+	attempts, reactor := evictionReactor(client, 2)
+	client.PrependReactor("create", "pods", reactor)
+	d := &drainer{client: client, gracePeriod: -1, timeout: 10 * time.Second, force: true, deleteEmptyDirData: true}
+
+	evicted, err := d.Drain("node1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evicted != 3 {
+		t.Errorf("evicted = %d, want 3", evicted)
+	}
+	for _, name := range []string{"managed", "bare", "emptydir"} {
+		n, ok := attempts.Load(name)
+		if !ok || *n.(*int) != 3 {
+			t.Errorf("pod %s is not retried after the PDB refusals", name)
+		}
+		if _, err := client.CoreV1().Pods("default").Get(context.Background(), name, metaV1.GetOptions{}); !apierrors.IsNotFound(err) {
+			t.Errorf("pod %s is not deleted: %v", name, err)
+		}
+	}
+	for _, name := range []string{"mirror", "daemonset", "finished"} {
+		if _, ok := attempts.Load(name); ok {
+			t.Errorf("pod %s is evicted", name)
+		}
+	}
+}
+
+func TestDrainRefused(t *testing.T) {
+	client := fake.NewSimpleClientset(drainTestPods()...)
+	attempts, reactor := evictionReactor(client, 0)
+	client.PrependReactor("create", "pods", reactor)
+	d := &drainer{client: client, gracePeriod: -1, timeout: 10 * time.Second}
+
+	evicted, err := d.Drain("node1")
+	if err == nil {
+		t.Fatal("drain with bare and emptyDir pods is not refused")
+	}
+	if evicted != 0 {
+		t.Errorf("evicted = %d, want 0", evicted)
+	}
+	attempts.Range(func(key, _ any) bool {
+		t.Errorf("pod %s is evicted by a refused drain", key)
+		return true
+	})
+}
+
+func TestDrainTimeout(t *testing.T) {
+	period := evictionRetryPeriod
+	evictionRetryPeriod = 10 * time.Millisecond
+	defer func() { evictionRetryPeriod = period }()
+
+	client := fake.NewSimpleClientset(newDrainTestPod("managed", nil))
+	_, reactor := evictionReactor(client, 1<<30)
+	client.PrependReactor("create", "pods", reactor)
+	d := &drainer{client: client, gracePeriod: -1, timeout: 100 * time.Millisecond}
+
+	if _, err := d.Drain("node1"); err == nil {
+		t.Fatal("drain blocked by a PDB does not time out")
+	}
+}
+
+func TestRunInBatches(t *testing.T) {
+	ips := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}
+
+	var mu sync.Mutex
+	var running, maxRunning int
+	report := &nodeReport{operation: "test"}
+	err := runInBatches(ips, "node", 2, report, func(ip string) *nodeResult {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return &nodeResult{IP: ip, Role: "node"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxRunning != 2 {
+		t.Errorf("max running nodes = %d, want 2", maxRunning)
+	}
+	if len(report.results) != len(ips) {
+		t.Errorf("report has %d nodes, want %d", len(report.results), len(ips))
+	}
+
+	report = &nodeReport{operation: "test"}
+	var ran []string
+	err = runInBatches(ips, "node", 2, report, func(ip string) *nodeResult {
+		mu.Lock()
+		ran = append(ran, ip)
+		mu.Unlock()
+		result := &nodeResult{IP: ip, Role: "node"}
+		if ip == "10.0.0.3" {
+			result.Err = errors.New("drain failed")
+		}
+		return result
+	})
+	if err == nil {
+		t.Fatal("failed batch is not reported")
+	}
+	sort.Strings(ran)
+	if want := ips[:4]; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran on %v, want %v", ran, want)
+	}
+	var skipped []string
+	for _, result := range report.results {
+		if result.Skipped {
+			skipped = append(skipped, result.IP)
+		}
+	}
+	if want := ips[4:]; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+}
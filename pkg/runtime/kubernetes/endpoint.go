@@ -0,0 +1,354 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"time"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/labring/sealos/pkg/client-go/kubernetes"
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/runtime"
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+	"github.com/labring/sealos/pkg/utils/yaml"
+)
+
+const (
+	kubeProxyConfigMap       = "kube-proxy"
+	kubeProxyLabelSelector   = "k8s-app=kube-proxy"
+	clusterInfoConfigMap     = "cluster-info"
+	restartKubeletCmd        = "systemctl restart kubelet"
+	setKubeconfigServerFmt   = "sed -i 's#server: .*#server: %s#' %s"
+	staticPodRestartTimeout  = 5 * time.Minute
+	staticPodRestartInterval = 3 * time.Second
+)
+
+var _ runtime.EndpointManager = &KubeadmRuntime{}
+
+// SetEndpoint moves the control plane endpoint of the cluster to a new VIP and/or API server domain.
+// The old endpoint is kept in the cert SANs and served until every component has switched over,
+// so the cluster stays reachable during the whole process.
+func (k *KubeadmRuntime) SetEndpoint(vip, domain string) error {
+	if k.isExternalLoadBalancer() {
+		return fmt.Errorf("the apiservers are behind the external load balancer %s, change it on the load balancer instead", k.getControlPlaneEndpoint())
+	}
+	oldVIP, oldDomain := k.getVip(), k.getAPIServerDomain()
+	if vip == "" {
+		vip = oldVIP
+	}
+	if domain == "" {
+		domain = oldDomain
+	}
+	if vip == oldVIP && domain == oldDomain {
+		logger.Info("endpoint of cluster is already %s(%s), skip", domain, vip)
+		return nil
+	}
+	if net.ParseIP(vip) == nil {
+		return fmt.Errorf("invalid vip %s", vip)
+	}
+	if errs := validation.IsDNS1123Subdomain(domain); len(errs) > 0 {
+		return fmt.Errorf("invalid domain %s: %s", domain, strings.Join(errs, ", "))
+	}
+	if err := k.validateVIP(vip); err != nil {
+		return err
+	}
+	logger.Info("start to change endpoint of cluster from %s(%s) to %s(%s)", oldDomain, oldVIP, domain, vip)
+
+	// the old endpoint is served until the last step, so on failure the change can be retried. rollback only
+	// restores the cluster in memory, the steps already run on the hosts are left as they are and run again
+	// by the retry
+	origin := k.cluster.DeepCopy()
+	rollback := func() {
+		*k.cluster = *origin
+		k.config.APIServerDomain = oldDomain
+	}
+	if err := k.cluster.SetVIP(vip); err != nil {
+		rollback()
+		return err
+	}
+	k.cluster.SetAPIServerDomain(domain)
+	k.config.APIServerDomain = domain
+
+	oldServer := fmt.Sprintf("https://%s:%d", oldDomain, k.getAPIServerPort())
+	err := k.runPipelines("change cluster endpoint",
+		// the new names are added to the certs first, the apiservers serve both endpoints from now on
+		func() error { return k.updateEndpointCerts(vip, domain) },
+		k.restartAPIServers,
+		// lvscare proxies the new VIP before the nodes resolve the domain to it
+		func() error { return k.syncEndpointIPVS(oldVIP) },
+		k.updateEndpointHosts,
+		k.updateKubeConfigs,
+		k.restartControlPlanes,
+		k.restartKubelets,
+		func() error { return k.updateEndpointConfigMaps(oldVIP, oldServer) },
+		// nothing uses the old endpoint anymore
+		func() error { return k.cleanOldEndpoint(oldVIP, oldDomain) },
+	)
+	if err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) updateEndpointCerts(vip, domain string) error {
+	if err := k.CompleteKubeadmConfig(setCGroupDriverAndSocket, setCertificateKey); err != nil {
+		return err
+	}
+	if err := k.mergeWithBuiltinKubeadmConfig(); err != nil {
+		return err
+	}
+	k.setCertSANs(append(k.getCertSANs(), vip, domain))
+	k.setControlPlaneEndpoint(fmt.Sprintf("%s:%d", domain, k.getAPIServerPort()))
+	return k.runPipelines("update certs",
+		k.initCert,
+		k.saveNewKubeadmConfig,
+		k.setSavedControlPlaneEndpoint,
+		k.uploadConfigFromKubeadm,
+		k.syncCert,
+	)
+}
+
+// setSavedControlPlaneEndpoint updates the controlPlaneEndpoint of the kubeadm config saved by saveNewKubeadmConfig
+func (k *KubeadmRuntime) setSavedControlPlaneEndpoint() error {
+	configPath := path.Join(k.pathResolver.EtcPath(), defaultUpdateKubeadmFileName)
+	data, err := file.ReadAll(configPath)
+	if err != nil {
+		return err
+	}
+	obj, err := yaml.UnmarshalToMap(data)
+	if err != nil {
+		return err
+	}
+	endpoint := k.kubeadmConfig.ClusterConfiguration.ControlPlaneEndpoint
+	if err = unstructured.SetNestedField(obj, endpoint, "controlPlaneEndpoint"); err != nil {
+		return err
+	}
+	return yaml.MarshalFile(configPath, obj)
+}
+
+// restartAPIServers restarts the apiservers one by one to load the new certs
+func (k *KubeadmRuntime) restartAPIServers() error {
+	for _, master := range k.getMasterIPAndPortList() {
+		if err := k.restartStaticPod(master, kubernetes.KubeAPIServer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) restartControlPlanes() error {
+	for _, master := range k.getMasterIPAndPortList() {
+		for _, name := range []string{kubernetes.KubeControllerManager, kubernetes.KubeScheduler} {
+			if err := k.restartStaticPod(master, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restartStaticPod removes the pod of a static pod, which is recreated by kubelet at once,
+// and waits for a new container to be running
+func (k *KubeadmRuntime) restartStaticPod(host, name string) error {
+	logger.Info("restart %s on %s", name, host)
+	oldID, err := k.deleteStaticPod(host, name)
+	if err != nil {
+		return err
+	}
+	return k.waitStaticPodRestarted(host, name, oldID)
+}
+
+// waitStaticPodRestarted waits for a container of the static pod other than oldID to be running
+func (k *KubeadmRuntime) waitStaticPodRestarted(host, name, oldID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), staticPodRestartTimeout)
+	defer cancel()
+	err := wait.PollUntilContextCancel(ctx, staticPodRestartInterval, false, func(ctx context.Context) (bool, error) {
+		ps, err := k.listContainers(host, name, "--state running")
+		if err != nil {
+			logger.Debug("list %s containers on %s: %v", name, host, err)
+			return false, nil
+		}
+		return len(ps.Containers) > 0 && ps.Containers[0].ID != oldID, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s on %s is not running after restart: %w", name, host, err)
+	}
+	return nil
+}
+
+// syncEndpointIPVS points lvscare of the nodes to the new VIP and waits for kubelet to restart it,
+// lvscare already synced by a failed change only has to be running
+func (k *KubeadmRuntime) syncEndpointIPVS(oldVIP string) error {
+	if oldVIP == k.getVip() {
+		return nil
+	}
+	manifest := path.Join(kubernetesEtcStaticPod, fmt.Sprintf("%s.%s", constants.LvsCareStaticPodName, constants.YamlFileSuffix))
+	nodes := k.getNodeIPAndPortList()
+	oldIDs := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		if out, err := k.sshCmdToString(node, "cat "+manifest); err == nil && strings.Contains(out, k.getVipAndPort()) {
+			continue
+		}
+		ps, err := k.listContainers(node, constants.LvsCareStaticPodName, "--state running")
+		if err != nil {
+			return fmt.Errorf("failed to list lvscare on %s: %w", node, err)
+		}
+		if len(ps.Containers) > 0 {
+			oldIDs[node] = ps.Containers[0].ID
+		}
+	}
+	if err := k.syncNodeIPVSYaml(k.getMasterIPList(), k.getNodeIPList()); err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := k.waitStaticPodRestarted(node, constants.LvsCareStaticPodName, oldIDs[node]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateEndpointHosts resolves the domain to master0 on the masters and to the VIP on the nodes
+func (k *KubeadmRuntime) updateEndpointHosts() error {
+	for _, master := range k.getMasterIPAndPortList() {
+		if err := k.execHostsAppend(master, k.getMaster0IP(), k.getAPIServerDomain()); err != nil {
+			return fmt.Errorf("failed to add hosts on %s: %v", master, err)
+		}
+	}
+	for _, node := range k.getNodeIPAndPortList() {
+		if err := k.execHostsAppend(node, k.getVip(), k.getAPIServerDomain()); err != nil {
+			return fmt.Errorf("failed to add hosts on %s: %v", node, err)
+		}
+	}
+	return nil
+}
+
+// updateKubeConfigs regenerates the kubeconfigs of the control planes and points those of kubelet to the new endpoint
+func (k *KubeadmRuntime) updateKubeConfigs() error {
+	// the kubeconfigs are not regenerated if they exist with another server
+	for _, name := range []string{AdminConf, ControllerConf, SchedulerConf, KubeletConf} {
+		if err := file.CleanFiles(path.Join(k.pathResolver.EtcPath(), name)); err != nil {
+			return err
+		}
+	}
+	if err := k.CreateKubeConfigFiles(); err != nil {
+		return fmt.Errorf("failed to generate kubernetes conf: %w", err)
+	}
+	masters := k.getMasterIPAndPortList()
+	if err := k.SendJoinMasterKubeConfigs(masters, AdminConf, ControllerConf, SchedulerConf); err != nil {
+		return err
+	}
+	if err := k.SendJoinMasterKubeConfigs(masters[:1], KubeletConf); err != nil {
+		return err
+	}
+	// kubelet of the other hosts uses its own client cert, only the server is changed
+	setServer := fmt.Sprintf(setKubeconfigServerFmt, k.getClusterAPIServer(), path.Join(kubernetesEtc, KubeletConf))
+	for _, host := range append(masters[1:], k.getNodeIPAndPortList()...) {
+		if err := k.sshCmdAsync(host, setServer); err != nil {
+			return err
+		}
+	}
+	for _, master := range masters {
+		if err := k.copyMasterKubeConfig(master); err != nil {
+			return err
+		}
+	}
+	return k.copyKubeConfigFileToNodes(k.getNodeIPAndPortList()...)
+}
+
+func (k *KubeadmRuntime) restartKubelets() error {
+	for _, host := range append(k.getMasterIPAndPortList(), k.getNodeIPAndPortList()...) {
+		logger.Info("restart kubelet on %s", host)
+		if err := k.sshCmdAsync(host, restartKubeletCmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateEndpointConfigMaps points kube-proxy and the cluster-info used by kubeadm join to the new endpoint
+func (k *KubeadmRuntime) updateEndpointConfigMaps(oldVIP, oldServer string) error {
+	cli, err := k.getKubeInterface()
+	if err != nil {
+		return err
+	}
+	client := cli.Kubernetes()
+	ctx := context.Background()
+	newServer := k.getClusterAPIServer()
+
+	err = updateConfigMap(ctx, client, metaV1.NamespaceSystem, kubeProxyConfigMap, func(data map[string]string) {
+		data["config.conf"] = strings.ReplaceAll(data["config.conf"], oldVIP+"/32", k.getVip()+"/32")
+		data["kubeconfig.conf"] = strings.ReplaceAll(data["kubeconfig.conf"], oldServer, newServer)
+	})
+	if err != nil {
+		return err
+	}
+	err = updateConfigMap(ctx, client, metaV1.NamespacePublic, clusterInfoConfigMap, func(data map[string]string) {
+		data["kubeconfig"] = strings.ReplaceAll(data["kubeconfig"], oldServer, newServer)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("restart kube-proxy pods")
+	return client.CoreV1().Pods(metaV1.NamespaceSystem).DeleteCollection(ctx, metaV1.DeleteOptions{},
+		metaV1.ListOptions{LabelSelector: kubeProxyLabelSelector})
+}
+
+func updateConfigMap(ctx context.Context, client clientset.Interface, namespace, name string, fn func(map[string]string)) error {
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metaV1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get configmap %s/%s: %w", namespace, name, err)
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	fn(cm.Data)
+	if _, err = client.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metaV1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update configmap %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) cleanOldEndpoint(oldVIP, oldDomain string) error {
+	oldVIPAndPort := fmt.Sprintf("%s:%d", oldVIP, k.getAPIServerPort())
+	for _, node := range k.getNodeIPAndPortList() {
+		if oldVIP != k.getVip() {
+			if err := k.remoteUtil.IPVSClean(node, oldVIPAndPort); err != nil {
+				logger.Warn("failed to clean ipvs rule %s on %s: %v", oldVIPAndPort, node, err)
+			}
+		}
+	}
+	if oldDomain == k.getAPIServerDomain() {
+		return nil
+	}
+	for _, host := range append(k.getMasterIPAndPortList(), k.getNodeIPAndPortList()...) {
+		if err := k.remoteUtil.HostsDelete(host, oldDomain); err != nil {
+			logger.Warn("failed to delete hosts %s on %s: %v", oldDomain, host, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"fmt"
+	"net"
+	"path"
+
+	"k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm"
+
+	"github.com/labring/sealos/pkg/utils/file"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// the client certs of an external etcd are kept in this dir of the pki, so that they are sent to the masters with the other certs
+const externalEtcdPKIDir = "etcd-external"
+
+// getControlPlaneEndpoint returns the host:port of the external load balancer, empty if lvscare is used
+func (k *KubeadmRuntime) getControlPlaneEndpoint() string {
+	return k.cluster.GetControlPlaneEndpoint()
+}
+
+func (k *KubeadmRuntime) isExternalLoadBalancer() bool {
+	return k.getControlPlaneEndpoint() != ""
+}
+
+// getExternalEtcd returns the external etcd declared in the ClusterConfiguration of the Clusterfile,
+// nil if etcd is stacked on the masters
+func (k *KubeadmRuntime) getExternalEtcd() *kubeadm.ExternalEtcd {
+	if k.config.KubeadmConfig == nil {
+		return nil
+	}
+	return k.config.KubeadmConfig.Etcd.External
+}
+
+func (k *KubeadmRuntime) validateControlPlaneEndpoint() error {
+	if !k.isExternalLoadBalancer() {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(k.getControlPlaneEndpoint()); err != nil {
+		return fmt.Errorf("invalid control plane endpoint %s, it must be host:port: %v", k.getControlPlaneEndpoint(), err)
+	}
+	return nil
+}
+
+// setExternalEtcd copies the client certs of the external etcd, given as local files in the Clusterfile,
+// into the pki dir and points the ClusterConfiguration to where they are on the masters
+func (k *KubeadmRuntime) setExternalEtcd() error {
+	external := k.getExternalEtcd()
+	if external == nil {
+		return nil
+	}
+	if len(external.Endpoints) == 0 {
+		return fmt.Errorf("endpoints of external etcd are required")
+	}
+	// the config of the Clusterfile is shared, leave its local paths untouched
+	etcd := *external
+	for _, f := range []struct {
+		file *string
+		name string
+	}{
+		{&etcd.CAFile, "ca.crt"},
+		{&etcd.CertFile, "client.crt"},
+		{&etcd.KeyFile, "client.key"},
+	} {
+		if *f.file == "" {
+			continue
+		}
+		dst := path.Join(k.pathResolver.PkiPath(), externalEtcdPKIDir, f.name)
+		if file.IsExist(*f.file) {
+			if err := file.RecursionCopy(*f.file, dst); err != nil {
+				return fmt.Errorf("failed to copy %s of external etcd: %v", *f.file, err)
+			}
+		} else if !file.IsExist(dst) {
+			return fmt.Errorf("%s of external etcd not found", *f.file)
+		}
+		*f.file = path.Join(kubernetesEtcPKI, externalEtcdPKIDir, f.name)
+	}
+	logger.Info("using external etcd %v", etcd.Endpoints)
+	k.kubeadmConfig.Etcd.External = &etcd
+	k.kubeadmConfig.Etcd.Local = nil
+	return nil
+}
@@ -17,6 +17,7 @@ package kubernetes
 import (
 	"context"
 	"fmt"
+	"net"
 	"path"
 	"path/filepath"
 	"sync"
@@ -120,8 +121,14 @@ func (k *KubeadmRuntime) MergeKubeadmConfig() error {
 					return fmt.Errorf("failed to load kubeadm config from clusterfile: %v", err)
 				}
 			}
+			if err := k.setExternalEtcd(); err != nil {
+				return err
+			}
 			k.setKubeadmAPIVersion()
 			k.setFeatureGatesConfiguration()
+			if k.isExternalLoadBalancer() {
+				return nil
+			}
 			return k.validateVIP(k.getVip())
 		}()
 	})
@@ -166,6 +173,9 @@ func (k *KubeadmRuntime) getAPIServerDomain() string {
 }
 
 func (k *KubeadmRuntime) getClusterAPIServer() string {
+	if k.isExternalLoadBalancer() {
+		return "https://" + k.getControlPlaneEndpoint()
+	}
 	return fmt.Sprintf("https://%s:%d", k.getAPIServerDomain(), k.getAPIServerPort())
 }
 
@@ -177,7 +187,12 @@ func (k *KubeadmRuntime) initCertSANS() {
 	var certSans []string
 	certSans = append(certSans, "127.0.0.1")
 	certSans = append(certSans, k.getAPIServerDomain())
-	certSans = append(certSans, k.getVip())
+	if k.isExternalLoadBalancer() {
+		host, _, _ := net.SplitHostPort(k.getControlPlaneEndpoint())
+		certSans = append(certSans, host)
+	} else {
+		certSans = append(certSans, k.getVip())
+	}
 	certSans = append(certSans, k.getMasterIPList()...)
 	certSans = append(certSans, k.getCertSANs()...)
 	k.setCertSANs(certSans)
@@ -471,11 +486,15 @@ func (k *KubeadmRuntime) CompleteKubeadmConfig(fns ...func(*KubeadmRuntime) erro
 	}
 	k.setInitAdvertiseAddress(k.getMaster0IP())
 	k.setInitInternalIP(k.getMaster0IP())
-	k.setControlPlaneEndpoint(fmt.Sprintf("%s:%d", k.getAPIServerDomain(), k.getAPIServerPort()))
+	if k.isExternalLoadBalancer() {
+		k.setControlPlaneEndpoint(k.getControlPlaneEndpoint())
+	} else {
+		k.setControlPlaneEndpoint(fmt.Sprintf("%s:%d", k.getAPIServerDomain(), k.getAPIServerPort()))
+		k.setExcludeCIDRs()
+	}
 	if k.kubeadmConfig.ClusterConfiguration.APIServer.ExtraArgs == nil {
 		k.kubeadmConfig.ClusterConfiguration.APIServer.ExtraArgs = make(map[string]string)
 	}
-	k.setExcludeCIDRs()
 	k.initCertSANS()
 	k.setInitTaints()
 	// after all merging done, set default fields
@@ -492,7 +511,11 @@ func (k *KubeadmRuntime) generateJoinNodeConfigs(node string) ([]byte, error) {
 		return nil, err
 	}
 	k.cleanJoinLocalAPIEndPoint()
-	k.setAPIServerEndpoint(k.getVipAndPort())
+	if k.isExternalLoadBalancer() {
+		k.setAPIServerEndpoint(k.getControlPlaneEndpoint())
+	} else {
+		k.setAPIServerEndpoint(k.getVipAndPort())
+	}
 	k.setJoinInternalIP(iputils.GetHostIP(node))
 
 	conversion, err := k.kubeadmConfig.ToConvertedKubeadmConfig()
@@ -0,0 +1,310 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/labring/sealos/pkg/constants"
+	"github.com/labring/sealos/pkg/runtime"
+	"github.com/labring/sealos/pkg/utils/iputils"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+const (
+	// reboot in the background so the ssh session returns before the connection is dropped
+	rebootNodeCmd       = "nohup sh -c 'sleep 3 && systemctl reboot' >/dev/null 2>&1 &"
+	checkCRIShimCmd     = "systemctl is-active image-cri-shim"
+	defaultReadyTimeout = 10 * time.Minute
+	rebootPollPeriod    = 5 * time.Second
+)
+
+var _ runtime.NodeMaintainer = &KubeadmRuntime{}
+
+func (k *KubeadmRuntime) Cordon(nodes []string) error {
+	return k.setUnschedulable(nodes, true)
+}
+
+func (k *KubeadmRuntime) Uncordon(nodes []string) error {
+	return k.setUnschedulable(nodes, false)
+}
+
+func (k *KubeadmRuntime) setUnschedulable(nodes []string, unschedulable bool) error {
+	client, err := k.getKubeInterface()
+	if err != nil {
+		return err
+	}
+	for _, ip := range nodes {
+		name, err := k.getNodeNameByIP(client.Kubernetes(), ip)
+		if err != nil {
+			return err
+		}
+		if err = setNodeUnschedulable(client.Kubernetes(), name, unschedulable); err != nil {
+			return err
+		}
+		logger.Info("node %s(%s) unschedulable: %t", name, ip, unschedulable)
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) Drain(nodes []string, opts *runtime.MaintenanceOptions) error {
+	client, err := k.getKubeInterface()
+	if err != nil {
+		return err
+	}
+	d := newMaintenanceDrainer(client.Kubernetes(), opts)
+	report := &nodeReport{operation: "drain"}
+	defer report.print()
+	masters, workers := k.splitByRole(nodes)
+	// drain the masters one by one, losing several control planes at once may break the quorum of etcd
+	if err = runInBatches(masters, "master", 1, report, func(ip string) *nodeResult {
+		return k.drainNode(ip, "master", d)
+	}); err != nil {
+		report.skip("worker", workers...)
+		return err
+	}
+	return runInBatches(workers, "worker", opts.BatchSize, report, func(ip string) *nodeResult {
+		return k.drainNode(ip, "worker", d)
+	})
+}
+
+func (k *KubeadmRuntime) drainNode(ip, role string, d *drainer) *nodeResult {
+	start := time.Now()
+	result := &nodeResult{IP: ip, Role: role}
+	defer func() {
+		result.Duration = time.Since(start)
+		if result.Err != nil {
+			logger.Error("failed to drain %s %s: %v", role, ip, result.Err)
+		}
+	}()
+	result.Name, result.Err = k.getNodeNameByIP(d.client, ip)
+	if result.Err != nil {
+		return result
+	}
+	if result.Err = setNodeUnschedulable(d.client, result.Name, true); result.Err != nil {
+		return result
+	}
+	result.EvictedPods, result.Err = d.Drain(result.Name)
+	return result
+}
+
+func (k *KubeadmRuntime) Reboot(nodes []string, opts *runtime.MaintenanceOptions) error {
+	client, err := k.getKubeInterface()
+	if err != nil {
+		return err
+	}
+	d := newMaintenanceDrainer(client.Kubernetes(), opts)
+	report := &nodeReport{operation: "reboot"}
+	defer report.print()
+	masters, workers := k.splitByRole(nodes)
+	if err = runInBatches(masters, "master", 1, report, func(ip string) *nodeResult {
+		return k.rebootNode(ip, "master", d, opts.ReadyTimeout)
+	}); err != nil {
+		report.skip("worker", workers...)
+		return err
+	}
+	logger.Info("start to reboot %d worker nodes in batches of %d", len(workers), opts.BatchSize)
+	return runInBatches(workers, "worker", opts.BatchSize, report, func(ip string) *nodeResult {
+		return k.rebootNode(ip, "worker", d, opts.ReadyTimeout)
+	})
+}
+
+// rebootNode drains the node, reboots it over ssh, waits for kubelet to be ready after the reboot
+// and for image-cri-shim and lvscare to be healthy, then uncordons it.
+// The node is left cordoned if it does not come back.
+func (k *KubeadmRuntime) rebootNode(ip, role string, d *drainer, readyTimeout time.Duration) *nodeResult {
+	start := time.Now()
+	result := &nodeResult{IP: ip, Role: role}
+	defer func() {
+		result.Duration = time.Since(start)
+		if result.Err != nil {
+			logger.Error("failed to reboot %s %s: %v", role, ip, result.Err)
+		}
+	}()
+	if readyTimeout <= 0 {
+		readyTimeout = defaultReadyTimeout
+	}
+	client := d.client
+	result.Name, result.Err = k.getNodeNameByIP(client, ip)
+	if result.Err != nil {
+		return result
+	}
+	node, err := client.CoreV1().Nodes().Get(context.Background(), result.Name, metaV1.GetOptions{})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	bootID := node.Status.NodeInfo.BootID
+
+	if result.Err = setNodeUnschedulable(client, result.Name, true); result.Err != nil {
+		return result
+	}
+	result.EvictedPods, result.Err = d.Drain(result.Name)
+	if result.Err != nil {
+		if err := setNodeUnschedulable(client, result.Name, false); err != nil {
+			logger.Error("failed to uncordon node %s: %v", result.Name, err)
+		}
+		return result
+	}
+
+	logger.Info("rebooting node %s", result.Name)
+	if result.Err = k.sshCmdAsync(ip, rebootNodeCmd); result.Err != nil {
+		return result
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+	if result.Err = waitForNodeRebooted(ctx, client, result.Name, bootID); result.Err != nil {
+		return result
+	}
+	if result.Err = k.waitForCRIShim(ctx, ip); result.Err != nil {
+		return result
+	}
+	if slices.Contains(k.getNodeIPAndPortList(), ip) {
+		if result.Err = waitForLvscare(ctx, client, result.Name); result.Err != nil {
+			return result
+		}
+	}
+	result.Err = setNodeUnschedulable(client, result.Name, false)
+	return result
+}
+
+func newMaintenanceDrainer(client clientset.Interface, opts *runtime.MaintenanceOptions) *drainer {
+	d := &drainer{
+		client:             client,
+		gracePeriod:        opts.GracePeriod,
+		timeout:            opts.DrainTimeout,
+		force:              opts.Force,
+		deleteEmptyDirData: opts.DeleteEmptyDirData,
+	}
+	if d.timeout <= 0 {
+		d.timeout = defaultUpgradeDrainTimeout
+	}
+	return d
+}
+
+// splitByRole returns the given nodes that are masters and workers of the cluster, in the order of the cluster
+func (k *KubeadmRuntime) splitByRole(nodes []string) (masters, workers []string) {
+	for _, ip := range k.getMasterIPAndPortList() {
+		if slices.Contains(nodes, ip) {
+			masters = append(masters, ip)
+		}
+	}
+	for _, ip := range k.getNodeIPAndPortList() {
+		if slices.Contains(nodes, ip) {
+			workers = append(workers, ip)
+		}
+	}
+	return
+}
+
+// getNodeNameByIP finds the node by its InternalIP, falls back to the lower case hostname of the host
+func (k *KubeadmRuntime) getNodeNameByIP(client clientset.Interface, ip string) (string, error) {
+	nodes, err := client.CoreV1().Nodes().List(context.Background(), metaV1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	hostIP := iputils.GetHostIP(ip)
+	for _, node := range nodes.Items {
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == v1.NodeInternalIP && addr.Address == hostIP {
+				return node.Name, nil
+			}
+		}
+	}
+	hostname, err := k.remoteUtil.Hostname(ip)
+	if err != nil {
+		return "", fmt.Errorf("node of %s not found: %v", ip, err)
+	}
+	return strings.ToLower(hostname), nil
+}
+
+func setNodeUnschedulable(client clientset.Interface, name string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	_, err := client.CoreV1().Nodes().Patch(context.Background(), name, k8stypes.StrategicMergePatchType, []byte(patch), metaV1.PatchOptions{})
+	return err
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == v1.NodeReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// waitForNodeRebooted waits until kubelet reports a new boot id and the node is ready
+func waitForNodeRebooted(ctx context.Context, client clientset.Interface, name, bootID string) error {
+	err := wait.PollUntilContextCancel(ctx, rebootPollPeriod, false, func(ctx context.Context) (bool, error) {
+		node, err := client.CoreV1().Nodes().Get(ctx, name, metaV1.GetOptions{})
+		if err != nil {
+			// the apiserver is unavailable while a master reboots
+			logger.Debug("get node %s: %v", name, err)
+			return false, nil
+		}
+		return node.Status.NodeInfo.BootID != bootID && isNodeReady(node), nil
+	})
+	if err != nil {
+		return fmt.Errorf("node %s is not ready after reboot: %w", name, err)
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) waitForCRIShim(ctx context.Context, ip string) error {
+	err := wait.PollUntilContextCancel(ctx, rebootPollPeriod, true, func(ctx context.Context) (bool, error) {
+		out, err := k.sshCmdToString(ip, checkCRIShimCmd)
+		if err != nil {
+			logger.Debug("check image-cri-shim on %s: %v", ip, err)
+			return false, nil
+		}
+		return strings.TrimSpace(out) == "active", nil
+	})
+	if err != nil {
+		return fmt.Errorf("image-cri-shim on %s is not active: %w", ip, err)
+	}
+	return nil
+}
+
+// waitForLvscare waits for the lvscare static pod of a worker, which proxies the apiservers, to be ready
+func waitForLvscare(ctx context.Context, client clientset.Interface, nodeName string) error {
+	podName := constants.LvsCareStaticPodName + "-" + nodeName
+	err := wait.PollUntilContextCancel(ctx, rebootPollPeriod, true, func(ctx context.Context) (bool, error) {
+		pod, err := client.CoreV1().Pods(metaV1.NamespaceSystem).Get(ctx, podName, metaV1.GetOptions{})
+		if err != nil {
+			logger.Debug("get pod %s: %v", podName, err)
+			return false, nil
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == v1.PodReady {
+				return cond.Status == v1.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("lvscare on node %s is not ready: %w", nodeName, err)
+	}
+	return nil
+}
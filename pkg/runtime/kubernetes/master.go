@@ -140,9 +140,11 @@ func (k *KubeadmRuntime) joinMasters(masters []string) error {
 			return fmt.Errorf("exec kubeadm join in %s failed %v", master, err)
 		}
 
-		err = k.execHostsAppend(master, master, k.getAPIServerDomain())
-		if err != nil {
-			return fmt.Errorf("add master0 apiserver domain hosts in %s failed %v", master, err)
+		if !k.isExternalLoadBalancer() {
+			err = k.execHostsAppend(master, master, k.getAPIServerDomain())
+			if err != nil {
+				return fmt.Errorf("add master0 apiserver domain hosts in %s failed %v", master, err)
+			}
 		}
 
 		err = k.copyMasterKubeConfig(master)
@@ -155,6 +157,10 @@ func (k *KubeadmRuntime) joinMasters(masters []string) error {
 }
 
 func (k *KubeadmRuntime) SyncNodeIPVS(mastersIPList, nodeIPList []string) error {
+	if k.isExternalLoadBalancer() {
+		logger.Debug("skip syncing lvscare, the apiservers are behind %s", k.getControlPlaneEndpoint())
+		return nil
+	}
 	return k.syncNodeIPVSYaml(strings.RemoveDuplicate(mastersIPList), nodeIPList)
 }
 
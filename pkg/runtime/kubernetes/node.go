@@ -50,10 +50,11 @@ func (k *KubeadmRuntime) joinNodes(newNodesIPList []string) error {
 				return fmt.Errorf("failed to copy join node kubeadm config %s %v", node, err)
 			}
 			k.mu.Unlock()
-			logger.Info("run ipvs once module: %s", node)
-			err = k.execIPVS(node, masters)
-			if err != nil {
-				return fmt.Errorf("run ipvs once failed %v", err)
+			if !k.isExternalLoadBalancer() {
+				logger.Info("run ipvs once module: %s", node)
+				if err = k.execIPVS(node, masters); err != nil {
+					return fmt.Errorf("run ipvs once failed %v", err)
+				}
 			}
 			logger.Info("start join node: %s", node)
 			joinCmd := k.Command(JoinNode)
@@ -0,0 +1,121 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+type nodeResult struct {
+	IP          string
+	Name        string
+	Role        string
+	EvictedPods int
+	Duration    time.Duration
+	Skipped     bool
+	Err         error
+}
+
+func (r *nodeResult) outcome() string {
+	switch {
+	case r.Skipped:
+		return "skipped"
+	case r.Err != nil:
+		return "failed: " + r.Err.Error()
+	default:
+		return "succeeded"
+	}
+}
+
+// nodeReport collects the outcome of every node of an upgrade or a maintenance
+type nodeReport struct {
+	operation string
+	mu        sync.Mutex
+	results   []*nodeResult
+}
+
+func (r *nodeReport) add(result *nodeResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+}
+
+func (r *nodeReport) skip(role string, ips ...string) {
+	for _, ip := range ips {
+		r.add(&nodeResult{IP: ip, Role: role, Skipped: true})
+	}
+}
+
+func (r *nodeReport) print() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.results) == 0 {
+		return
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tNAME\tROLE\tEVICTED PODS\tDURATION\tRESULT")
+	for _, result := range r.results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			result.IP, result.Name, result.Role, result.EvictedPods, result.Duration.Round(time.Second), result.outcome())
+	}
+	_ = w.Flush()
+	logger.Info("%s report:\n%s", r.operation, buf.String())
+}
+
+// runInBatches runs fn on batchSize nodes at the same time, the next batch starts once the previous one is done.
+// It stops at the first failed batch and marks the remaining nodes as skipped.
+func runInBatches(ips []string, role string, batchSize int, report *nodeReport, fn func(ip string) *nodeResult) error {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	for i := 0; i < len(ips); i += batchSize {
+		end := i + batchSize
+		if end > len(ips) {
+			end = len(ips)
+		}
+		batch := ips[i:end]
+		results := make([]*nodeResult, len(batch))
+		var wg sync.WaitGroup
+		for j, ip := range batch {
+			wg.Add(1)
+			go func(j int, ip string) {
+				defer wg.Done()
+				results[j] = fn(ip)
+			}(j, ip)
+		}
+		wg.Wait()
+
+		var errs []error
+		for _, result := range results {
+			report.add(result)
+			if result.Err != nil {
+				errs = append(errs, result.Err)
+			}
+		}
+		if len(errs) > 0 {
+			report.skip(role, ips[end:]...)
+			return errors.Join(errs...)
+		}
+	}
+	return nil
+}
@@ -36,6 +36,9 @@ rm -rf %s && (ip link delete kube-ipvs0 >/dev/null 2>&1 || true)
 func (k *KubeadmRuntime) reset() error {
 	k.resetNodes(k.getNodeIPAndPortList())
 	k.resetMasters(k.getMasterIPAndPortList())
+	if external := k.getExternalEtcd(); external != nil {
+		logger.Warn("data of the cluster is kept in external etcd %v, clean it before creating a new cluster on it", external.Endpoints)
+	}
 	return nil
 }
 
@@ -67,7 +70,12 @@ func (k *KubeadmRuntime) resetMasters(nodes []string) {
 
 func (k *KubeadmRuntime) resetNode(node string, cleanHook func()) error {
 	logger.Info("start to reset node: %s", node)
-	resetCmd := fmt.Sprintf(remoteCleanMasterOrNode, vlogToStr(k.klogLevel), k.getEtcdDataDir())
+	etcdDataDir := k.getEtcdDataDir()
+	if k.getExternalEtcd() != nil {
+		// nothing of etcd is on the hosts
+		etcdDataDir = ""
+	}
+	resetCmd := fmt.Sprintf(remoteCleanMasterOrNode, vlogToStr(k.klogLevel), etcdDataDir)
 
 	resetCmdErr := k.sshCmdAsync(node, resetCmd)
 	if cleanHook != nil {
@@ -81,7 +89,7 @@ func (k *KubeadmRuntime) resetNode(node string, cleanHook func()) error {
 	if removeKubeConfigErr != nil {
 		logger.Error("failed to clean node, exec command %s failed, %v", removeKubeConfig, removeKubeConfigErr)
 	}
-	if slices.Contains(k.cluster.GetNodeIPAndPortList(), node) {
+	if slices.Contains(k.cluster.GetNodeIPAndPortList(), node) && !k.isExternalLoadBalancer() {
 		ipvscleanErr := k.execIPVSClean(node)
 		if ipvscleanErr != nil {
 			logger.Error("failed to clean node route and ipvs failed, %v", ipvscleanErr)
@@ -132,7 +132,7 @@ func newKubeadmRuntime(cluster *v2.Cluster, kubeadm *types.KubeadmConfig) (*Kube
 		cluster: cluster,
 		config: &types.Config{
 			KubeadmConfig:   kubeadm,
-			APIServerDomain: constants.DefaultAPIServerDomain,
+			APIServerDomain: cluster.GetAPIServerDomain(),
 		},
 		kubeadmConfig: types.NewKubeadmConfig(),
 		execer:        execer,
@@ -166,7 +166,7 @@ func (k *KubeadmRuntime) Validate() error {
 	if k.getKubeVersionFromImage() == "" && k.cluster.DeletionTimestamp.IsZero() {
 		return fmt.Errorf("cluster image kubernetes version cannot be empty")
 	}
-	return nil
+	return k.validateControlPlaneEndpoint()
 }
 
 func (k *KubeadmRuntime) Upgrade(version string) error {
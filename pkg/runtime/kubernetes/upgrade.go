@@ -35,7 +35,6 @@ import (
 const (
 	upgradeApplyCmd = "kubeadm upgrade apply --certificate-renewal=false --config %s --yes"
 	upradeNodeCmd   = "kubeadm upgrade node --certificate-renewal=false --skip-phases preflight"
-	//drainNodeCmd    = "kubectl drain %s --ignore-daemonsets"
 	cordonNodeCmd   = "kubectl cordon %s"
 	uncordonNodeCmd = "kubectl uncordon %s"
 	daemonReload    = "systemctl daemon-reload"
@@ -51,6 +50,10 @@ EOF`
 )
 
 func (k *KubeadmRuntime) upgradeCluster(version string) error {
+	opts := k.getUpgradeOptions()
+	report := &nodeReport{operation: "upgrade"}
+	defer report.print()
+
 	logger.Info("Change ClusterConfiguration up to newVersion if need.")
 	conversion, err := k.autoUpdateConfig(version)
 	if err != nil {
@@ -58,37 +61,31 @@ func (k *KubeadmRuntime) upgradeCluster(version string) error {
 	}
 	//upgrade master0
 	logger.Info("start to upgrade master0")
+	start := time.Now()
 	err = k.upgradeMaster0(conversion, version)
+	report.add(&nodeResult{
+		IP:       k.getMaster0IPAndPort(),
+		Role:     "master0",
+		Duration: time.Since(start),
+		Err:      err,
+	})
 	if err != nil {
 		return err
 	}
 	//upgrade other control-planes and worker nodes
-	var upgradeNodes []string
-	for _, node := range append(k.getMasterIPAndPortList(), k.getNodeIPAndPortList()...) {
-		if node == k.getMaster0IPAndPort() {
+	var masters []string
+	for _, master := range k.getMasterIPAndPortList() {
+		if master == k.getMaster0IPAndPort() {
 			continue
 		}
-		upgradeNodes = append(upgradeNodes, node)
+		masters = append(masters, master)
 	}
 	logger.Info("start to upgrade other control-planes and worker nodes")
-	return k.upgradeOtherNodes(upgradeNodes, version)
+	return k.upgradeOtherNodes(masters, k.getNodeIPAndPortList(), version, opts, report)
 }
 
 func (k *KubeadmRuntime) upgradeMaster0(conversion *types.ConvertedKubeadmConfig, version string) error {
 	master0ip := k.getMaster0IP()
-	sver := semver.MustParse(version)
-	if gte(sver, V1260) {
-		if err := k.changeCRIVersion(master0ip); err != nil {
-			return err
-		}
-	}
-
-	if gte(sver, V1270) {
-		if err := k.changeKubeletExtraArgs(master0ip); err != nil {
-			return err
-		}
-	}
-
 	master0Name, err := k.remoteUtil.Hostname(master0ip)
 	if err != nil {
 		return err
@@ -125,6 +122,14 @@ func (k *KubeadmRuntime) upgradeMaster0(conversion *types.ConvertedKubeadmConfig
 		fmt.Sprintf(upgradeApplyCmd, upgradeConfigPath),
 		//kubectl cordon <node-to-cordon>
 		fmt.Sprintf(cordonNodeCmd, master0Name),
+	)
+	if err != nil {
+		return err
+	}
+	if err = k.prepareKubelet(master0ip, version); err != nil {
+		return err
+	}
+	err = k.sshCmdAsync(master0ip,
 		//install kubelet:{version},kubectl{version} at master0
 		fmt.Sprintf(installKubectlCmd, kubeBinaryPath),
 		fmt.Sprintf(installKubeletCmd, kubeBinaryPath),
@@ -138,62 +143,111 @@ func (k *KubeadmRuntime) upgradeMaster0(conversion *types.ConvertedKubeadmConfig
 	return k.tryUncordonNode(master0ip, master0Name)
 }
 
-func (k *KubeadmRuntime) upgradeOtherNodes(ips []string, version string) error {
-	sver := semver.MustParse(version)
-	for _, ip := range ips {
-		if gte(sver, V1260) {
-			if err := k.changeCRIVersion(ip); err != nil {
-				return err
-			}
-		}
-
-		if gte(sver, V1270) {
-			if err := k.changeKubeletExtraArgs(ip); err != nil {
-				return err
-			}
+// upgradeOtherNodes upgrades the control planes one by one, then the workers in batches,
+// the workers are drained before kubelet is restarted. It stops at the first failed batch.
+func (k *KubeadmRuntime) upgradeOtherNodes(masters, workers []string, version string, opts *upgradeOptions, report *nodeReport) error {
+	for i, ip := range masters {
+		result := k.upgradeNode(ip, "master", version, nil)
+		report.add(result)
+		if result.Err != nil {
+			report.skip("master", masters[i+1:]...)
+			report.skip("worker", workers...)
+			return result.Err
 		}
+	}
+	if len(workers) == 0 {
+		return nil
+	}
 
-		nodename, err := k.remoteUtil.Hostname(ip)
+	var d *drainer
+	if opts.drain {
+		client, err := k.getKubeInterface()
 		if err != nil {
 			return err
 		}
-		//default nodeName in k8s is the lower case of their hostname because of DNS protocol.
-		nodename = strings.ToLower(nodename)
-		kubeBinaryPath := k.pathResolver.RootFSBinPath()
-		//assure the connection to api-server succeed before executing upgrade cmds
-		if err = k.pingAPIServer(); err != nil {
-			return err
+		d = &drainer{
+			client:             client.Kubernetes(),
+			gracePeriod:        opts.gracePeriod,
+			timeout:            opts.drainTimeout,
+			force:              opts.drainForce,
+			deleteEmptyDirData: opts.drainDeleteEmptyDirData,
 		}
+	}
+	logger.Info("start to upgrade %d worker nodes in batches of %d", len(workers), opts.batchSize)
+	return runInBatches(workers, "worker", opts.batchSize, report, func(ip string) *nodeResult {
+		return k.upgradeNode(ip, "worker", version, d)
+	})
+}
 
-		// force cri to pull the image
-		err = k.imagePull(ip, version)
-		if err != nil {
-			logger.Error("image pull pre-upgrade failed: %s", err.Error())
+// upgradeNode upgrades a node other than master0, the node is drained before kubelet is restarted if d is not nil
+func (k *KubeadmRuntime) upgradeNode(ip, role, version string, d *drainer) *nodeResult {
+	start := time.Now()
+	result := &nodeResult{IP: ip, Role: role}
+	defer func() {
+		result.Duration = time.Since(start)
+		if result.Err != nil {
+			logger.Error("failed to upgrade %s %s: %v", role, ip, result.Err)
 		}
+	}()
 
-		logger.Info("upgrade node %s", nodename)
-		err = k.sshCmdAsync(ip,
-			//install kubeadm:{version} at the node
-			fmt.Sprintf(installKubeadmCmd, kubeBinaryPath),
-			//upgrade other control-plane and nodes
-			upradeNodeCmd,
-			//kubectl cordon <node-to-cordon>
-			fmt.Sprintf(cordonNodeCmd, nodename),
-			//install kubelet:{version},kubectl{version} at the node
-			fmt.Sprintf(installKubectlCmd, kubeBinaryPath),
-			fmt.Sprintf(installKubeletCmd, kubeBinaryPath),
-			//reload kubelet daemon
-			daemonReload,
-			restartKubelet,
-		)
-		if err != nil {
-			return err
-		}
-		if err = k.tryUncordonNode(ip, nodename); err != nil {
-			return err
+	nodename, err := k.remoteUtil.Hostname(ip)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	//default nodeName in k8s is the lower case of their hostname because of DNS protocol.
+	nodename = strings.ToLower(nodename)
+	result.Name = nodename
+	kubeBinaryPath := k.pathResolver.RootFSBinPath()
+	//assure the connection to api-server succeed before executing upgrade cmds
+	if result.Err = k.pingAPIServer(); result.Err != nil {
+		return result
+	}
+
+	// force cri to pull the image
+	err = k.imagePull(ip, version)
+	if err != nil {
+		logger.Error("image pull pre-upgrade failed: %s", err.Error())
+	}
+
+	logger.Info("upgrade node %s", nodename)
+	result.Err = k.sshCmdAsync(ip,
+		//install kubeadm:{version} at the node
+		fmt.Sprintf(installKubeadmCmd, kubeBinaryPath),
+		//upgrade other control-plane and nodes
+		upradeNodeCmd,
+		//kubectl cordon <node-to-cordon>
+		fmt.Sprintf(cordonNodeCmd, nodename),
+	)
+	if result.Err != nil {
+		return result
+	}
+	if d != nil {
+		result.EvictedPods, result.Err = d.Drain(nodename)
+		if result.Err != nil {
+			// kubelet is not restarted yet, let the node serve again
+			if err := k.tryUncordonNode(ip, nodename); err != nil {
+				logger.Error("failed to uncordon node %s: %v", nodename, err)
+			}
+			return result
 		}
 	}
-	return nil
+	if result.Err = k.prepareKubelet(ip, version); result.Err != nil {
+		return result
+	}
+	result.Err = k.sshCmdAsync(ip,
+		//install kubelet:{version},kubectl{version} at the node
+		fmt.Sprintf(installKubectlCmd, kubeBinaryPath),
+		fmt.Sprintf(installKubeletCmd, kubeBinaryPath),
+		//reload kubelet daemon
+		daemonReload,
+		restartKubelet,
+	)
+	if result.Err != nil {
+		return result
+	}
+	result.Err = k.tryUncordonNode(ip, nodename)
+	return result
 }
 
 func (k *KubeadmRuntime) autoUpdateConfig(version string) (*types.ConvertedKubeadmConfig, error) {
@@ -297,6 +351,21 @@ func (k *KubeadmRuntime) tryUncordonNode(ip, nodename string) error {
 	return nil
 }
 
+// prepareKubelet changes the cri and kubelet configs required by the version, it restarts kubelet so the node
+// must be cordoned and drained first
+func (k *KubeadmRuntime) prepareKubelet(ip, version string) error {
+	sver := semver.MustParse(version)
+	if gte(sver, V1260) {
+		if err := k.changeCRIVersion(ip); err != nil {
+			return err
+		}
+	}
+	if gte(sver, V1270) {
+		return k.changeKubeletExtraArgs(ip)
+	}
+	return nil
+}
+
 func (k *KubeadmRuntime) changeCRIVersion(ip string) error {
 	return k.sshCmdAsync(ip,
 		"sed -i \"s/v1alpha2/v1/\" /etc/image-cri-shim.yaml",
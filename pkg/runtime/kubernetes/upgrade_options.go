@@ -0,0 +1,100 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labring/sealos/pkg/env"
+	"github.com/labring/sealos/pkg/utils/logger"
+)
+
+// envs of the cluster to tune the upgrade of the worker nodes, e.g.
+// sealos run --env UPGRADE_BATCH_SIZE=10 --env UPGRADE_DRAIN_TIMEOUT=10m
+const (
+	upgradeBatchSizeEnv        = "UPGRADE_BATCH_SIZE"
+	upgradeDrainEnv            = "UPGRADE_DRAIN"
+	upgradeDrainGracePeriodEnv = "UPGRADE_DRAIN_GRACE_PERIOD"
+	upgradeDrainTimeoutEnv     = "UPGRADE_DRAIN_TIMEOUT"
+	// evict the pods not managed by a controller and the pods with emptyDir volumes, which refuse the drain by default
+	upgradeDrainForceEnv              = "UPGRADE_DRAIN_FORCE"
+	upgradeDrainDeleteEmptyDirDataEnv = "UPGRADE_DRAIN_DELETE_EMPTYDIR_DATA"
+
+	defaultUpgradeBatchSize    = 1
+	defaultUpgradeDrainTimeout = 5 * time.Minute
+)
+
+type upgradeOptions struct {
+	// number of workers upgraded at the same time, control planes are always upgraded one by one
+	batchSize int
+	drain     bool
+	// negative uses the termination grace period of the pods
+	gracePeriod             time.Duration
+	drainTimeout            time.Duration
+	drainForce              bool
+	drainDeleteEmptyDirData bool
+}
+
+func (k *KubeadmRuntime) getUpgradeOptions() *upgradeOptions {
+	opts := &upgradeOptions{
+		batchSize:    defaultUpgradeBatchSize,
+		drain:        true,
+		gracePeriod:  -1,
+		drainTimeout: defaultUpgradeDrainTimeout,
+	}
+	envs := env.NewEnvProcessor(k.cluster).Getenv(k.getMaster0IP())
+	if v, ok := envs[upgradeBatchSizeEnv]; ok {
+		if size, err := strconv.Atoi(v); err == nil && size > 0 {
+			opts.batchSize = size
+		} else {
+			logger.Warn("invalid %s %q, use default %d", upgradeBatchSizeEnv, v, opts.batchSize)
+		}
+	}
+	if v, ok := envs[upgradeDrainEnv]; ok {
+		if drain, err := strconv.ParseBool(v); err == nil {
+			opts.drain = drain
+		} else {
+			logger.Warn("invalid %s %q, use default %t", upgradeDrainEnv, v, opts.drain)
+		}
+	}
+	for key, value := range map[string]*bool{
+		upgradeDrainForceEnv:              &opts.drainForce,
+		upgradeDrainDeleteEmptyDirDataEnv: &opts.drainDeleteEmptyDirData,
+	} {
+		if v, ok := envs[key]; ok {
+			if b, err := strconv.ParseBool(v); err == nil {
+				*value = b
+			} else {
+				logger.Warn("invalid %s %q, use default %t", key, v, *value)
+			}
+		}
+	}
+	if v, ok := envs[upgradeDrainGracePeriodEnv]; ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			opts.gracePeriod = d
+		} else {
+			logger.Warn("invalid %s %q, use the grace period of the pods", upgradeDrainGracePeriodEnv, v)
+		}
+	}
+	if v, ok := envs[upgradeDrainTimeoutEnv]; ok {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			opts.drainTimeout = d
+		} else {
+			logger.Warn("invalid %s %q, use default %s", upgradeDrainTimeoutEnv, v, opts.drainTimeout)
+		}
+	}
+	return opts
+}
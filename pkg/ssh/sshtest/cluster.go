@@ -0,0 +1,49 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshtest
+
+import (
+	v2 "github.com/labring/sealos/pkg/types/v1beta1"
+)
+
+// ClusterArch returns the cluster arch of the host, the role set in the Clusterfile
+func (h *Host) ClusterArch() v2.Arch {
+	if h.Arch == "aarch64" || h.Arch == "arm64" {
+		return v2.ARM64
+	}
+	return v2.AMD64
+}
+
+// NewCluster returns a cluster of the fake hosts, the first master is master0
+func NewCluster(name string, masters, nodes []*Host) *v2.Cluster {
+	cluster := &v2.Cluster{}
+	cluster.Name = name
+	cluster.Kind = "Cluster"
+	cluster.APIVersion = v2.SchemeGroupVersion.String()
+	cluster.Spec.SSH = v2.SSH{User: User, Passwd: Password}
+	for _, m := range masters {
+		cluster.Spec.Hosts = append(cluster.Spec.Hosts, v2.Host{
+			IPS:   []string{m.Addr},
+			Roles: []string{v2.MASTER, string(m.ClusterArch())},
+		})
+	}
+	for _, n := range nodes {
+		cluster.Spec.Hosts = append(cluster.Spec.Hosts, v2.Host{
+			IPS:   []string{n.Addr},
+			Roles: []string{v2.NODE, string(n.ClusterArch())},
+		})
+	}
+	return cluster
+}
@@ -0,0 +1,262 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sshtest provides fake hosts for testing code that works on cluster hosts over ssh.
+//
+// Every fake host runs an embedded ssh server on its own loopback address, so the ssh and exec
+// clients of sealos connect to it like to any remote host. Files copied to a fake host are kept
+// in a temporary directory that serves as the root of the host, and commands are answered by a
+// scripted responder instead of being executed.
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// User and Password are the credentials accepted by fake hosts
+	User     = "root"
+	Password = "sealos-test"
+)
+
+// the loopback addresses of fake hosts start from 127.0.1.1, 127.0.0.1 is considered as
+// the local host by the exec package and its commands are never sent over ssh
+var lastAddr uint32
+
+func nextLoopbackAddr() string {
+	n := atomic.AddUint32(&lastAddr, 1)
+	return fmt.Sprintf("127.0.%d.%d", 1+n/254, 1+n%254)
+}
+
+// Host is a fake host serving ssh on a loopback address
+type Host struct {
+	// Addr is the ip:port of the ssh server
+	Addr string
+	// Root is the directory holding the files of the host
+	Root string
+	// Hostname is answered to the hostname command
+	Hostname string
+	// Arch is answered to the arch and uname -m commands, e.g. x86_64 or aarch64
+	Arch string
+
+	listener net.Listener
+	config   *ssh.ServerConfig
+
+	mu       sync.Mutex
+	rules    []rule
+	commands []string
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+type HostOption func(*Host)
+
+// WithArch sets the machine architecture of the host, it is the output of `arch`
+func WithArch(arch string) HostOption {
+	return func(h *Host) {
+		h.Arch = arch
+	}
+}
+
+// WithHostname sets the hostname of the host
+func WithHostname(hostname string) HostOption {
+	return func(h *Host) {
+		h.Hostname = hostname
+	}
+}
+
+// NewHost starts a fake host, it is stopped when the test finishes
+func NewHost(t testing.TB, opts ...HostOption) *Host {
+	t.Helper()
+	var (
+		listener net.Listener
+		err      error
+	)
+	// some addresses may be taken by other fake hosts of parallel test binaries
+	for i := 0; i < 10; i++ {
+		if listener, err = net.Listen("tcp", net.JoinHostPort(nextLoopbackAddr(), "0")); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("failed to listen on a loopback address: %v", err)
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == User && string(password) == Password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected for %s", c.User())
+		},
+	}
+	config.AddHostKey(signer)
+
+	h := &Host{
+		Addr:     listener.Addr().String(),
+		Root:     t.TempDir(),
+		Arch:     "x86_64",
+		listener: listener,
+		config:   config,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	h.Hostname = "fake-" + filepath.Base(h.Root)
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.handleBuiltins()
+
+	h.wg.Add(1)
+	go h.serve()
+	t.Cleanup(h.Close)
+	return h
+}
+
+// IP returns the ip of the host
+func (h *Host) IP() string {
+	ip, _, _ := net.SplitHostPort(h.Addr)
+	return ip
+}
+
+// Path returns the path in the root directory of an absolute path on the host
+func (h *Host) Path(path string) string {
+	return filepath.Join(h.Root, filepath.Clean("/"+path))
+}
+
+// ReadFile reads a file of the host
+func (h *Host) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(h.Path(path))
+}
+
+// WriteFile writes a file to the host, the parent directories are created if missing
+func (h *Host) WriteFile(path string, data []byte) error {
+	p := h.Path(path)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+// Close stops the ssh server of the host, connections kept by cached clients are closed too
+func (h *Host) Close() {
+	_ = h.listener.Close()
+	h.mu.Lock()
+	for conn := range h.conns {
+		_ = conn.Close()
+	}
+	h.mu.Unlock()
+	h.wg.Wait()
+}
+
+func (h *Host) serve() {
+	defer h.wg.Done()
+	for {
+		conn, err := h.listener.Accept()
+		if err != nil {
+			return
+		}
+		h.mu.Lock()
+		h.conns[conn] = struct{}{}
+		h.mu.Unlock()
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.handleConn(conn)
+			h.mu.Lock()
+			delete(h.conns, conn)
+			h.mu.Unlock()
+		}()
+	}
+}
+
+func (h *Host) handleConn(conn net.Conn) {
+	defer conn.Close()
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, h.config)
+	if err != nil {
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+	var wg sync.WaitGroup
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.handleSession(channel, requests)
+		}()
+	}
+	wg.Wait()
+}
+
+func (h *Host) handleSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		switch req.Type {
+		case "pty-req", "env":
+			_ = req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			code := h.run(payload.Command, channel, channel.Stderr())
+			_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(code)}))
+			return
+		case "subsystem":
+			var payload struct{ Name string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || payload.Name != "sftp" {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			server := sftp.NewRequestServer(channel, newRootHandlers(h.Root))
+			if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+				_ = server.Close()
+			}
+			return
+		default:
+			_ = req.Reply(false, nil)
+		}
+	}
+}
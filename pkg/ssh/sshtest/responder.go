@@ -0,0 +1,184 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshtest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Command is a command received by a fake host
+type Command struct {
+	Host *Host
+	// Line is the command, without the other commands of the same command line
+	Line string
+	// Args is Line split by spaces
+	Args   []string
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// HandlerFunc answers a command and returns its exit code
+type HandlerFunc func(*Command) int
+
+type rule struct {
+	re *regexp.Regexp
+	fn HandlerFunc
+}
+
+// Handle registers fn for the commands matching the regular expression pattern.
+// Rules registered later take precedence, commands matching no rule succeed without output.
+func (h *Host) Handle(pattern string, fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rules = append(h.rules, rule{re: regexp.MustCompile(pattern), fn: fn})
+}
+
+// Reply returns a handler that prints output and succeeds
+func Reply(output string) HandlerFunc {
+	return func(c *Command) int {
+		_, _ = io.WriteString(c.Stdout, output)
+		return 0
+	}
+}
+
+// Fail returns a handler that prints output to stderr and exits with code
+func Fail(code int, output string) HandlerFunc {
+	return func(c *Command) int {
+		_, _ = io.WriteString(c.Stderr, output)
+		return code
+	}
+}
+
+// Commands returns the commands received by the host in order
+func (h *Host) Commands() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.commands...)
+}
+
+// Ran reports whether the host has received a command matching the regular expression pattern
+func (h *Host) Ran(pattern string) bool {
+	re := regexp.MustCompile(pattern)
+	for _, cmd := range h.Commands() {
+		if re.MatchString(cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCommands splits a command line joined by `;` and `&&`, quotes are not taken into account
+func splitCommands(line string) []string {
+	var cmds []string
+	for _, part := range strings.Split(line, ";") {
+		for _, cmd := range strings.Split(part, "&&") {
+			if cmd = strings.TrimSpace(cmd); cmd != "" {
+				cmds = append(cmds, cmd)
+			}
+		}
+	}
+	return cmds
+}
+
+// run answers a command line, it stops at the first failed command like `set -e`
+func (h *Host) run(line string, stdout, stderr io.Writer) int {
+	for _, cmd := range splitCommands(line) {
+		h.mu.Lock()
+		h.commands = append(h.commands, cmd)
+		var fn HandlerFunc
+		for i := len(h.rules) - 1; i >= 0; i-- {
+			if h.rules[i].re.MatchString(cmd) {
+				fn = h.rules[i].fn
+				break
+			}
+		}
+		h.mu.Unlock()
+		if fn == nil {
+			continue
+		}
+		if code := fn(&Command{Host: h, Line: cmd, Args: strings.Fields(cmd), Stdout: stdout, Stderr: stderr}); code != 0 {
+			return code
+		}
+	}
+	return 0
+}
+
+// handleBuiltins registers the commands that are answered from the host itself
+func (h *Host) handleBuiltins() {
+	h.Handle(`^(arch|uname -m)$`, func(c *Command) int {
+		fmt.Fprintln(c.Stdout, c.Host.Arch)
+		return 0
+	})
+	h.Handle(`^hostname$`, func(c *Command) int {
+		fmt.Fprintln(c.Stdout, c.Host.Hostname)
+		return 0
+	})
+	h.Handle(`^date \+%s$`, func(c *Command) int {
+		fmt.Fprintln(c.Stdout, time.Now().Unix())
+		return 0
+	})
+	h.Handle(`^cat \S+$`, func(c *Command) int {
+		data, err := c.Host.ReadFile(c.Args[1])
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "cat: %s: No such file or directory\n", c.Args[1])
+			return 1
+		}
+		_, _ = c.Stdout.Write(data)
+		return 0
+	})
+	h.Handle(`^sha256sum \S+$`, func(c *Command) int {
+		data, err := c.Host.ReadFile(c.Args[1])
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "sha256sum: %s: No such file or directory\n", c.Args[1])
+			return 1
+		}
+		fmt.Fprintf(c.Stdout, "%x  %s\n", sha256.Sum256(data), c.Args[1])
+		return 0
+	})
+	h.Handle(`^mkdir -p( \S+)+$`, func(c *Command) int {
+		for _, dir := range c.Args[2:] {
+			if err := os.MkdirAll(c.Host.Path(dir), 0755); err != nil {
+				fmt.Fprintln(c.Stderr, err)
+				return 1
+			}
+		}
+		return 0
+	})
+	h.Handle(`^rm -(r|f|rf|fr)( \S+)+$`, func(c *Command) int {
+		for _, p := range c.Args[2:] {
+			if err := os.RemoveAll(c.Host.Path(p)); err != nil {
+				fmt.Fprintln(c.Stderr, err)
+				return 1
+			}
+		}
+		return 0
+	})
+	h.Handle(`^test -[efd] \S+$`, func(c *Command) int {
+		info, err := os.Stat(c.Host.Path(c.Args[2]))
+		switch {
+		case err != nil:
+			return 1
+		case c.Args[1] == "-f" && !info.Mode().IsRegular(), c.Args[1] == "-d" && !info.IsDir():
+			return 1
+		}
+		return 0
+	})
+}
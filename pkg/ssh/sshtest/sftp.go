@@ -0,0 +1,134 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshtest
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/sftp"
+)
+
+// rootFS serves sftp requests with the files under root, like a chroot
+type rootFS struct {
+	root string
+}
+
+func newRootHandlers(root string) sftp.Handlers {
+	fs := &rootFS{root: root}
+	return sftp.Handlers{FileGet: fs, FilePut: fs, FileCmd: fs, FileList: fs}
+}
+
+func (fs *rootFS) path(p string) string {
+	return filepath.Join(fs.root, filepath.Clean("/"+p))
+}
+
+func (fs *rootFS) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	return os.Open(fs.path(r.Filepath))
+}
+
+func (fs *rootFS) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	pflags := r.Pflags()
+	flags := os.O_WRONLY
+	if pflags.Read {
+		flags = os.O_RDWR
+	}
+	if pflags.Creat {
+		flags |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flags |= os.O_EXCL
+	}
+	if pflags.Append {
+		flags |= os.O_APPEND
+	}
+	return os.OpenFile(fs.path(r.Filepath), flags, 0644)
+}
+
+func (fs *rootFS) Filecmd(r *sftp.Request) error {
+	p := fs.path(r.Filepath)
+	switch r.Method {
+	case "Setstat":
+		if r.AttrFlags().Permissions {
+			return os.Chmod(p, r.Attributes().FileMode())
+		}
+		if r.AttrFlags().Size {
+			return os.Truncate(p, int64(r.Attributes().Size))
+		}
+		return nil
+	case "Rename", "PosixRename":
+		return os.Rename(p, fs.path(r.Target))
+	case "Rmdir", "Remove":
+		return os.Remove(p)
+	case "Mkdir":
+		return os.Mkdir(p, 0755)
+	case "Symlink":
+		// the target is kept as is, it is resolved in the root of the host
+		return os.Symlink(r.Filepath, fs.path(r.Target))
+	case "Link":
+		return os.Link(p, fs.path(r.Target))
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (fs *rootFS) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	p := fs.path(r.Filepath)
+	switch r.Method {
+	case "List":
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]os.FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return listerAt(infos), nil
+	case "Stat":
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	case "Lstat":
+		info, err := os.Lstat(p)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
@@ -0,0 +1,101 @@
+// Copyright © 2023 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labring/sealos/pkg/exec"
+	"github.com/labring/sealos/pkg/ssh"
+)
+
+func newExecer(t *testing.T, hosts ...*Host) exec.Interface {
+	execer, err := exec.New(ssh.NewCacheClientFromCluster(NewCluster("default", hosts, nil), false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return execer
+}
+
+func TestCmd(t *testing.T) {
+	h := NewHost(t, WithArch("aarch64"), WithHostname("master0"))
+	h.Handle(`^kubeadm version`, Reply("v1.25.0"))
+	h.Handle(`^containerd --version$`, Fail(127, "command not found"))
+	execer := newExecer(t, h)
+
+	for cmd, want := range map[string]string{
+		"arch":                     "aarch64",
+		"hostname":                 "master0",
+		"kubeadm version -o short": "v1.25.0",
+	} {
+		out, err := execer.CmdToString(h.Addr, cmd, "")
+		if err != nil {
+			t.Fatalf("%s: %v", cmd, err)
+		}
+		if out != want {
+			t.Errorf("%s = %q, want %q", cmd, out, want)
+		}
+	}
+	if _, err := execer.Cmd(h.Addr, "containerd --version"); err == nil {
+		t.Error("failed command should return an error")
+	}
+	if err := execer.CmdAsync(h.Addr, "mkdir -p /etc/sealos", "cd /etc/sealos && bash init.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(h.Path("/etc/sealos")); err != nil || !info.IsDir() {
+		t.Errorf("mkdir is not applied to the host root: %v", err)
+	}
+	if !h.Ran(`^bash init\.sh$`) {
+		t.Errorf("commands joined by && are not recorded: %v", h.Commands())
+	}
+}
+
+func TestCopyAndFetch(t *testing.T) {
+	h := NewHost(t)
+	execer := newExecer(t, h)
+
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "scripts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "scripts", "init.sh"), []byte("echo init"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := execer.Copy(h.Addr, src, "/var/lib/sealos/data/default/rootfs"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := h.ReadFile("/var/lib/sealos/data/default/rootfs/scripts/init.sh")
+	if err != nil || string(data) != "echo init" {
+		t.Fatalf("copied file = %q, %v", data, err)
+	}
+	out, err := execer.CmdToString(h.Addr, "cat /var/lib/sealos/data/default/rootfs/scripts/init.sh", "")
+	if err != nil || out != "echo init" {
+		t.Errorf("cat = %q, %v", out, err)
+	}
+
+	if err = h.WriteFile("/etc/kubernetes/admin.conf", []byte("kubeconfig")); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(t.TempDir(), "admin.conf")
+	if err = execer.Fetch(h.Addr, "/etc/kubernetes/admin.conf", dst); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = os.ReadFile(dst); err != nil || !strings.EqualFold(string(data), "kubeconfig") {
+		t.Errorf("fetched file = %q, %v", data, err)
+	}
+}
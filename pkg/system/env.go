@@ -92,6 +92,11 @@ var configOptions = []ConfigOption{
 		Description:  "whether to sync runtime root dir to all master nodes for backup purpose",
 		DefaultValue: "true",
 	},
+	{
+		Key:          AuditConfigMapConfigKey,
+		Description:  "whether to mirror the audit journal of a cluster into a ConfigMap inside the cluster",
+		DefaultValue: "false",
+	},
 }
 
 const (
@@ -102,6 +107,7 @@ const (
 	BuildahLogLevelConfigKey   = "BUILDAH_LOG_LEVEL"
 	ContainerStorageConfEnvKey = "CONTAINERS_STORAGE_CONF"
 	SyncWorkDirEnvKey          = "SYNC_WORKDIR"
+	AuditConfigMapConfigKey    = "AUDIT_CONFIGMAP"
 )
 
 func (*envSystemConfig) getValueOrDefault(key string) (*ConfigOption, error) {
@@ -94,6 +94,41 @@ type MountImage struct {
 	Labels     map[string]string `json:"labels,omitempty"`
 	Cmd        []string          `json:"cmd,omitempty"`
 	Entrypoint []string          `json:"entrypoint,omitempty"`
+	// Arch is the architecture of the image mounted at MountPoint
+	Arch Arch `json:"arch,omitempty"`
+	// Variants are the mounts of the image for the other architectures of a mixed-arch cluster
+	Variants []MountVariant `json:"variants,omitempty"`
+}
+
+// MountVariant is the mount of another platform of a multi-arch image
+type MountVariant struct {
+	Arch       Arch   `json:"arch"`
+	Name       string `json:"name"`
+	ImageID    string `json:"imageID"`
+	MountPoint string `json:"mountPoint"`
+}
+
+// ForArch returns the mount of the image for arch, the mount itself is returned
+// if there is no variant for arch
+func (m *MountImage) ForArch(arch Arch) MountImage {
+	ret := *m
+	for _, v := range m.Variants {
+		if v.Arch == arch {
+			ret.Name, ret.MountPoint, ret.Arch = v.Name, v.MountPoint, v.Arch
+			ret.Variants = nil
+			break
+		}
+	}
+	return ret
+}
+
+// MountPoints returns the mount points of all the platforms of the image
+func (m *MountImage) MountPoints() []string {
+	ret := []string{m.MountPoint}
+	for _, v := range m.Variants {
+		ret = append(ret, v.MountPoint)
+	}
+	return ret
 }
 
 func (m *MountImage) KubeVersion() string {
@@ -17,12 +17,17 @@ limitations under the License.
 package v1beta1
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	"github.com/Masterminds/semver/v3"
 	"golang.org/x/exp/slices"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	stringsutil "github.com/labring/sealos/pkg/utils/strings"
 
+	"github.com/labring/sealos/pkg/constants"
 	"github.com/labring/sealos/pkg/utils/iputils"
 	"github.com/labring/sealos/pkg/utils/maps"
 )
@@ -181,6 +186,36 @@ func (c *Cluster) GetRolesByIP(ip string) []string {
 	return nil
 }
 
+// GetArchByIP returns the architecture in the roles of the host, it is empty if not recorded.
+// ip matches the host with or without the ssh port.
+func (c *Cluster) GetArchByIP(ip string) Arch {
+	for _, host := range c.Spec.Hosts {
+		if !slices.Contains(host.IPS, ip) && !slices.Contains(iputils.GetHostIPs(host.IPS), iputils.GetHostIP(ip)) {
+			continue
+		}
+		for _, role := range host.Roles {
+			if role == string(AMD64) || role == string(ARM64) {
+				return Arch(role)
+			}
+		}
+	}
+	return ""
+}
+
+// GetArches returns the distinct architectures of all hosts
+func (c *Cluster) GetArches() []Arch {
+	var arches []Arch
+	for _, host := range c.Spec.Hosts {
+		for _, role := range host.Roles {
+			arch := Arch(role)
+			if (arch == AMD64 || arch == ARM64) && !slices.Contains(arches, arch) {
+				arches = append(arches, arch)
+			}
+		}
+	}
+	return arches
+}
+
 func (c *Cluster) GetDistribution() string {
 	root := c.GetRootfsImage()
 	if root != nil {
@@ -192,6 +227,11 @@ func (c *Cluster) GetDistribution() string {
 const (
 	defaultVIP          = "10.103.97.2"
 	DefaultLvsCareImage = "sealos.hub:5000/sealos/lvscare:latest"
+	// APIServerDomainEnvKey is the env overriding the API server domain of the cluster
+	APIServerDomainEnvKey = "apiServerDomain"
+	// ControlPlaneEndpointEnvKey is the env declaring the host:port of an external load balancer
+	// in front of the API servers, which replaces the VIP and lvscare
+	ControlPlaneEndpointEnvKey = "controlPlaneEndpoint"
 )
 
 func (c *Cluster) GetVIP() string {
@@ -203,6 +243,65 @@ func (c *Cluster) GetVIP() string {
 	return defaultVIP
 }
 
+var envReferenceRegex = regexp.MustCompile(`^\$(?:\((\w+)\)|\{(\w+)\}|(\w+))$`)
+
+// SetVIP changes the VIP through the env referenced by the vip label of the rootfs image,
+// so that the new VIP is kept when the image is mounted again.
+func (c *Cluster) SetVIP(vip string) error {
+	root := c.GetRootfsImage()
+	if root == nil {
+		return fmt.Errorf("rootfs image of cluster %s is not mounted", c.Name)
+	}
+	label := maps.GetFromKeys(root.Labels, ImageVIPKey)
+	match := envReferenceRegex.FindStringSubmatch(strings.TrimSpace(label))
+	if match == nil {
+		return fmt.Errorf("vip of image %s is fixed to %q by its label, it cannot be changed", root.ImageName, label)
+	}
+	c.setEnv(strings.Join(match[1:], ""), vip)
+	return nil
+}
+
+// GetAPIServerDomain returns the domain of the API server, which resolves to the masters or the VIP on every host
+func (c *Cluster) GetAPIServerDomain() string {
+	if root := c.GetRootfsImage(); root != nil && root.Env[APIServerDomainEnvKey] != "" {
+		return root.Env[APIServerDomainEnvKey]
+	}
+	return constants.DefaultAPIServerDomain
+}
+
+func (c *Cluster) SetAPIServerDomain(domain string) {
+	c.setEnv(APIServerDomainEnvKey, domain)
+}
+
+// GetControlPlaneEndpoint returns the host:port of the external load balancer of the API servers,
+// it is empty if the cluster uses the VIP served by lvscare
+func (c *Cluster) GetControlPlaneEndpoint() string {
+	if root := c.GetRootfsImage(); root != nil {
+		return root.Env[ControlPlaneEndpointEnvKey]
+	}
+	return ""
+}
+
+// setEnv sets an env in the spec, which is merged into the mounts when they are mounted again,
+// and in the rootfs mount so that it takes effect immediately
+func (c *Cluster) setEnv(key, value string) {
+	kv := fmt.Sprintf("%s=%s", key, value)
+	idx := slices.IndexFunc(c.Spec.Env, func(env string) bool { return strings.HasPrefix(env, key+"=") })
+	if idx >= 0 {
+		c.Spec.Env[idx] = kv
+	} else {
+		c.Spec.Env = append(c.Spec.Env, kv)
+	}
+	for i := range c.Status.Mounts {
+		if c.Status.Mounts[i].IsRootFs() {
+			if c.Status.Mounts[i].Env == nil {
+				c.Status.Mounts[i].Env = make(map[string]string)
+			}
+			c.Status.Mounts[i].Env[key] = value
+		}
+	}
+}
+
 func (c *Cluster) GetImageEndpoint() string {
 	root := c.GetRootfsImage()
 	if root != nil {
@@ -325,6 +325,11 @@ func (in *MountImage) DeepCopyInto(out *MountImage) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Variants != nil {
+		in, out := &in.Variants, &out.Variants
+		*out = make([]MountVariant, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -338,6 +343,22 @@ func (in *MountImage) DeepCopy() *MountImage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MountVariant) DeepCopyInto(out *MountVariant) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MountVariant.
+func (in *MountVariant) DeepCopy() *MountVariant {
+	if in == nil {
+		return nil
+	}
+	out := new(MountVariant)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RegistryConfig) DeepCopyInto(out *RegistryConfig) {
 	*out = *in
@@ -1,10 +1,12 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/labring/sealos/controllers/pkg/database/cockroach"
 	"github.com/labring/sealos/service/account/dao"
 	"github.com/labring/sealos/service/account/helper"
 )
@@ -115,3 +117,42 @@ func authenticateAdminRequest(c *gin.Context) error {
 	}
 	return nil
 }
+
+// RefundPayment
+// @Summary Refund payment
+// @Description Refund the whole or a part of a payment, the balance is debited with the refund amount and the gift of it
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param request body helper.AdminRefundPaymentReq true "Refund payment request"
+// @Success 200 {object} map[string]interface{} "successfully refund payment"
+// @Failure 400 {object} helper.ErrorMessage "failed to parse refund payment request or the payment can not be refunded"
+// @Failure 401 {object} helper.ErrorMessage "authenticate error"
+// @Failure 500 {object} helper.ErrorMessage "failed to refund payment"
+// @Router /admin/v1alpha1/refund-payment [post]
+func AdminRefundPayment(c *gin.Context) {
+	err := authenticateAdminRequest(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, helper.ErrorMessage{Error: fmt.Sprintf("authenticate error : %v", err)})
+		return
+	}
+	req, err := helper.ParseAdminRefundPaymentReq(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: fmt.Sprintf("failed to parse refund payment request: %v", err)})
+		return
+	}
+	refund, err := dao.DBClient.RefundPayment(req)
+	if err != nil {
+		if errors.Is(err, cockroach.ErrRefundExceedsPayment) || errors.Is(err, cockroach.ErrRefundExceedsBalance) ||
+			errors.Is(err, cockroach.ErrRefundInvoiced) {
+			c.JSON(http.StatusBadRequest, helper.ErrorMessage{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, helper.ErrorMessage{Error: fmt.Sprintf("failed to refund payment : %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":    refund,
+		"message": "successfully refund payment",
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/labring/sealos/controllers/pkg/database/cockroach"
+	"github.com/labring/sealos/controllers/pkg/pay"
 
 	"github.com/labring/sealos/controllers/pkg/types"
 
@@ -73,11 +74,16 @@ type Interface interface {
 	ReconcileActiveBilling(startTime, endTime time.Time) error
 	ArchiveHourlyBilling(hourStart, hourEnd time.Time) error
 	ActiveBilling(req resources.ActiveBilling) error
+	RefundPayment(req *helper.AdminRefundPaymentReq) (*types.Refund, error)
+	ReconcileRefunds(before time.Time) error
+	TryLockTask(name string, ttl time.Duration) (bool, error)
 }
 
 type Account struct {
 	*MongoDB
 	*Cockroach
+	// newPayHandler returns the payment provider of the payment method, pay.NewPayHandler if nil
+	newPayHandler func(paymentMethod string) (pay.Interface, error)
 }
 
 type MongoDB struct {
@@ -1555,6 +1561,102 @@ func (m *Account) UseGiftCode(req *helper.UseGiftCodeReq) (*types.GiftCode, erro
 	return giftCode, nil
 }
 
+// RefundPayment debits the balance of the user before the payment provider makes the refund, the deduction is returned
+// if the provider rejects the refund. The refund is kept in processing if it is unknown whether it is made.
+func (m *Account) RefundPayment(req *helper.AdminRefundPaymentReq) (*types.Refund, error) {
+	payment, err := m.ck.GetPaymentWithID(req.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	newPayHandler := m.newPayHandler
+	if newPayHandler == nil {
+		newPayHandler = pay.NewPayHandler
+	}
+	payHandler, err := newPayHandler(payment.Method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment handler: %v", err)
+	}
+
+	refund := &types.Refund{
+		PaymentID: payment.ID,
+		UserUID:   payment.UserUID,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+	}
+	if err = m.ck.CreateRefund(refund); err != nil {
+		return nil, fmt.Errorf("failed to create refund: %w", err)
+	}
+	// 1 cent of the payment providers = 10000 amount
+	status, err := payHandler.RefundPayment(refund.TradeNO, refund.ID, refund.Amount/10000, payment.Amount/10000, refund.Reason)
+	switch {
+	case status == pay.PaymentFailed:
+		message := "refund is rejected by the payment provider"
+		if err != nil {
+			message = err.Error()
+		}
+		if ferr := m.ck.FinishRefund(refund.ID, types.RefundStatusFailed, message); ferr != nil {
+			return nil, fmt.Errorf("failed to revert refund %s: %v, refund error: %s", refund.ID, ferr, message)
+		}
+		return nil, fmt.Errorf("failed to refund payment: %s", message)
+	case err != nil:
+		logrus.Errorf("failed to confirm refund %s of payment %s, keep it in processing: %v", refund.ID, payment.ID, err)
+		return refund, fmt.Errorf("refund %s is in processing: %v", refund.ID, err)
+	case status == pay.PaymentSuccess:
+		if err = m.ck.FinishRefund(refund.ID, types.RefundStatusSuccess, ""); err != nil {
+			return refund, fmt.Errorf("failed to finish refund %s: %v", refund.ID, err)
+		}
+		refund.Status = types.RefundStatusSuccess
+	}
+	return refund, nil
+}
+
+// ReconcileRefunds finishes the refunds which are in processing since before with the status of the payment provider,
+// a refund stays in processing until the provider finishes it
+func (m *Account) ReconcileRefunds(before time.Time) error {
+	refunds, err := m.ck.GetProcessingRefunds(before)
+	if err != nil {
+		return err
+	}
+	newPayHandler := m.newPayHandler
+	if newPayHandler == nil {
+		newPayHandler = pay.NewPayHandler
+	}
+	var errs []error
+	for i := range refunds {
+		refund := &refunds[i]
+		payHandler, err := newPayHandler(refund.Method)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get payment handler of refund %s: %v", refund.ID, err))
+			continue
+		}
+		status, err := payHandler.GetRefundStatus(refund.TradeNO, refund.ID)
+		switch {
+		case status == pay.PaymentFailed:
+			message := "refund is not made by the payment provider"
+			if err != nil {
+				message = err.Error()
+			}
+			err = m.ck.FinishRefund(refund.ID, types.RefundStatusFailed, message)
+		case err != nil:
+			err = fmt.Errorf("failed to get status: %v", err)
+		case status == pay.PaymentSuccess:
+			err = m.ck.FinishRefund(refund.ID, types.RefundStatusSuccess, "")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to reconcile refund %s: %w", refund.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// taskLockHolder identifies the replica holding the locks of the periodic tasks
+var taskLockHolder = uuid.NewString()
+
+// TryLockTask takes the lock of a periodic task for ttl, the task is skipped by the replicas which do not hold the lock
+func (m *Account) TryLockTask(name string, ttl time.Duration) (bool, error) {
+	return m.ck.TryLockTask(name, taskLockHolder, ttl)
+}
+
 func (m *Account) GetRechargeDiscount(req helper.AuthReq) (helper.RechargeDiscountResp, error) {
 	userQuery := &types.UserQueryOpts{UID: req.GetAuth().UserUID}
 	userDiscount, err := m.ck.GetUserRechargeDiscount(userQuery)
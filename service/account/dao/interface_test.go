@@ -3,6 +3,7 @@ package dao
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -10,6 +11,8 @@ import (
 
 	"github.com/labring/sealos/service/account/helper"
 
+	"github.com/labring/sealos/controllers/pkg/database/cockroach"
+	"github.com/labring/sealos/controllers/pkg/pay"
 	"github.com/labring/sealos/controllers/pkg/types"
 )
 
@@ -623,6 +626,143 @@ func TestAccount_UseGiftCode(t *testing.T) {
 	t.Logf("giftcode = %+v", giftcode)
 }
 
+func TestAccount_RefundPayment(t *testing.T) {
+	db, err := newAccountForTest("", os.Getenv("GLOBAL_COCKROACH_URI"), os.Getenv("LOCAL_COCKROACH_URI"))
+	if err != nil {
+		t.Fatalf("NewAccountInterface() error = %v", err)
+		return
+	}
+	account := db.(*Account)
+	provider := &pay.MockPayment{}
+	account.newPayHandler = func(string) (pay.Interface, error) {
+		return provider, nil
+	}
+	newPayment := func(amount, gift int64, invoiced bool) *types.Payment {
+		payment := &types.Payment{
+			PaymentRaw: types.PaymentRaw{
+				RegionUserOwner: "1fgtm0mn",
+				Method:          "mock",
+				Amount:          amount,
+				Gift:            gift,
+				TradeNO:         pay.GetRandomString(32),
+				InvoicedAt:      invoiced,
+			},
+		}
+		if err := account.ck.Payment(payment); err != nil {
+			t.Fatalf("Payment() error = %v", err)
+		}
+		return payment
+	}
+	payment := newPayment(9_000_000, 1_000_000, false)
+	balance := func() int64 {
+		a, err := account.ck.GetAccount(&types.UserQueryOpts{UID: payment.UserUID})
+		if err != nil {
+			t.Fatalf("GetAccount() error = %v", err)
+		}
+		return a.Balance
+	}
+	before := balance()
+
+	// the gift of a partial refund is rounded down, 1_000_000 * 4/9
+	refund, err := db.RefundPayment(&helper.AdminRefundPaymentReq{PaymentID: payment.ID, Amount: 4_000_000})
+	if err != nil {
+		t.Fatalf("RefundPayment() error = %v", err)
+	}
+	if refund.Status != types.RefundStatusSuccess || refund.Deduction != 4_444_444 || provider.Refunded(payment.TradeNO) != 400 {
+		t.Errorf("unexpected refund %+v, refunded %d", refund, provider.Refunded(payment.TradeNO))
+	}
+	if got := balance(); got != before-4_444_444 {
+		t.Errorf("balance = %d, want %d", got, before-4_444_444)
+	}
+
+	if _, err = db.RefundPayment(&helper.AdminRefundPaymentReq{PaymentID: payment.ID, Amount: 7_000_000}); !errors.Is(err, cockroach.ErrRefundExceedsPayment) {
+		t.Errorf("RefundPayment() over the payment error = %v, want ErrRefundExceedsPayment", err)
+	}
+
+	// the deduction of a rejected refund is returned
+	provider.RefundErr = errors.New("rejected")
+	if _, err = db.RefundPayment(&helper.AdminRefundPaymentReq{PaymentID: payment.ID}); err == nil {
+		t.Errorf("RefundPayment() rejected by the provider should fail")
+	}
+	if got := balance(); got != before-4_444_444 {
+		t.Errorf("balance after the rejected refund = %d, want %d", got, before-4_444_444)
+	}
+
+	// the refund of the rest deducts the rest of the gift, it is debited while the provider is processing it
+	provider.RefundErr = nil
+	provider.RefundStatus = pay.PaymentProcessing
+	refund, err = db.RefundPayment(&helper.AdminRefundPaymentReq{PaymentID: payment.ID})
+	if err != nil {
+		t.Fatalf("RefundPayment() error = %v", err)
+	}
+	if refund.Status != types.RefundStatusProcessing || refund.Amount != 5_000_000 || refund.Deduction != 5_555_556 {
+		t.Errorf("unexpected refund of the rest %+v", refund)
+	}
+	if got := balance(); got != before-10_000_000 {
+		t.Errorf("balance with the refund in processing = %d, want %d", got, before-10_000_000)
+	}
+	if err = db.ReconcileRefunds(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ReconcileRefunds() error = %v", err)
+	}
+	provider.RefundStatus = pay.PaymentSuccess
+	if err = db.ReconcileRefunds(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("ReconcileRefunds() error = %v", err)
+	}
+
+	refunds, err := account.ck.GetRefunds(payment.ID)
+	if err != nil {
+		t.Fatalf("GetRefunds() error = %v", err)
+	}
+	want := []struct {
+		status            string
+		amount, deduction int64
+	}{
+		{types.RefundStatusSuccess, 4_000_000, 4_444_444},
+		{types.RefundStatusFailed, 5_000_000, 5_555_556},
+		{types.RefundStatusSuccess, 5_000_000, 5_555_556},
+	}
+	if len(refunds) != len(want) {
+		t.Fatalf("refunds = %+v, want %d refunds", refunds, len(want))
+	}
+	for i, w := range want {
+		if refunds[i].Status != w.status || refunds[i].Amount != w.amount || refunds[i].Deduction != w.deduction {
+			t.Errorf("refund %d = %+v, want %+v", i, refunds[i], w)
+		}
+	}
+	if got := balance(); got != before-10_000_000 {
+		t.Errorf("balance after the refunds = %d, want %d", got, before-10_000_000)
+	}
+	if provider.Refunded(payment.TradeNO) != 900 {
+		t.Errorf("refunded %d, want 900", provider.Refunded(payment.TradeNO))
+	}
+
+	invoiced := newPayment(1_000_000, 0, true)
+	if _, err = db.RefundPayment(&helper.AdminRefundPaymentReq{PaymentID: invoiced.ID}); !errors.Is(err, cockroach.ErrRefundInvoiced) {
+		t.Errorf("RefundPayment() of the invoiced payment error = %v, want ErrRefundInvoiced", err)
+	}
+
+	// the balance which is spent can not be refunded
+	spent := newPayment(1_000_000, 0, false)
+	used := balance()
+	if err = account.ck.AddDeductionBalance(&types.UserQueryOpts{UID: payment.UserUID}, used); err != nil {
+		t.Fatalf("AddDeductionBalance() error = %v", err)
+	}
+	defer func() {
+		if err := account.ck.AddDeductionBalance(&types.UserQueryOpts{UID: payment.UserUID}, -used); err != nil {
+			t.Errorf("AddDeductionBalance() error = %v", err)
+		}
+	}()
+	if _, err = db.RefundPayment(&helper.AdminRefundPaymentReq{PaymentID: spent.ID}); !errors.Is(err, cockroach.ErrRefundExceedsBalance) {
+		t.Errorf("RefundPayment() of the spent balance error = %v, want ErrRefundExceedsBalance", err)
+	}
+	if refunds, err = account.ck.GetRefunds(spent.ID); err != nil || len(refunds) != 0 {
+		t.Errorf("refunds of the spent payment = %+v, %v, want none", refunds, err)
+	}
+	if got := balance(); got != used {
+		t.Errorf("balance after the refused refund = %d, want %d", got, used)
+	}
+}
+
 func TestAccount_GetUserRealNameInfo(t *testing.T) {
 	db, err := newAccountForTest("", os.Getenv("GLOBAL_COCKROACH_URI"), os.Getenv("LOCAL_COCKROACH_URI"))
 	if err != nil {
@@ -611,3 +611,31 @@ func ParseAdminChargeBillingReq(c *gin.Context) (*AdminChargeBillingReq, error)
 	}
 	return rechargeBilling, nil
 }
+
+// AdminRefundPaymentReq refunds a payment of any user, the refund is made to the user who paid
+type AdminRefundPaymentReq struct {
+	// @Summary Payment ID
+	// @Description ID of the payment to be refunded
+	// @JSONSchema required
+	PaymentID string `json:"paymentID" bson:"paymentID" binding:"required" example:"payment-id-1"`
+
+	// @Summary Refund amount
+	// @Description Refund amount, the whole refundable amount of the payment is refunded if it is zero
+	Amount int64 `json:"amount" bson:"amount" example:"10000000"`
+
+	// @Summary Refund reason
+	// @Description Refund reason
+	Reason string `json:"reason,omitempty" bson:"reason" example:"duplicate payment"`
+}
+
+func ParseAdminRefundPaymentReq(c *gin.Context) (*AdminRefundPaymentReq, error) {
+	refundPayment := &AdminRefundPaymentReq{}
+	if err := c.ShouldBindJSON(refundPayment); err != nil {
+		return nil, fmt.Errorf("bind json error: %v", err)
+	}
+	// the payment providers refund in cents, 1 cent = 10000 amount
+	if refundPayment.Amount < 0 || refundPayment.Amount%10000 != 0 {
+		return nil, fmt.Errorf("invalid refund amount: %d", refundPayment.Amount)
+	}
+	return refundPayment, nil
+}
@@ -75,7 +75,8 @@ func RegisterPayRouter() {
 		POST(helper.GetUserRealNameInfo, api.GetUserRealNameInfo)
 	router.Group(helper.AdminGroup).
 		GET(helper.AdminGetAccountWithWorkspace, api.AdminGetAccountWithWorkspaceID).
-		POST(helper.AdminChargeBilling, api.AdminChargeBilling)
+		POST(helper.AdminChargeBilling, api.AdminChargeBilling).
+		POST(helper.AdminRefundPayment, api.AdminRefundPayment)
 	//POST(helper.AdminActiveBilling, api.AdminActiveBilling)
 	docs.SwaggerInfo.Host = env.GetEnvWithDefault("SWAGGER_HOST", "localhost:2333")
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
@@ -107,6 +108,9 @@ func RegisterPayRouter() {
 	// process hourly archive
 	go startHourlyBillingActiveArchive(ctx)
 
+	// finish the refunds left in processing by the payment providers
+	go startRefundReconcile(ctx)
+
 	// Wait for interrupt signal.
 	<-rootCtx.Done()
 
@@ -139,6 +143,37 @@ func startRewardProcessingTimer(ctx context.Context) {
 	}
 }
 
+func startRefundReconcile(ctx context.Context) {
+	tickerTime, err := time.ParseDuration(env.GetEnvWithDefault("REFUND_RECONCILE_INTERVAL", "1m"))
+	if err != nil {
+		logrus.Errorf("Failed to parse REFUND_RECONCILE_INTERVAL: %v", err)
+		tickerTime = time.Minute
+	}
+	ticker := time.NewTicker(tickerTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case t := <-ticker.C:
+			// the lock outlives an interval, so it is renewed by its holder before it expires
+			locked, err := dao.DBClient.TryLockTask("refund-reconcile", 2*tickerTime)
+			if err != nil {
+				logrus.Errorf("Error locking refund reconcile: %v", err)
+				continue
+			}
+			if !locked {
+				continue
+			}
+			// a refund in processing for less than an interval may be still being made
+			if err := dao.DBClient.ReconcileRefunds(t.Add(-tickerTime)); err != nil {
+				logrus.Errorf("Error reconciling refunds: %v", err)
+			}
+		case <-ctx.Done():
+			logrus.Info("Refund reconcile timer stopped")
+			return
+		}
+	}
+}
+
 var lastReconcileTime atomic.Value
 
 func startReconcileBilling(ctx context.Context) {
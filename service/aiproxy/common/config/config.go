@@ -189,6 +189,47 @@ func SetGeminiVersion(version string) {
 	geminiVersion.Store(version)
 }
 
+var (
+	// 响应缓存时间(秒)
+	responseCacheTTL atomic.Int64
+	// 单个响应缓存的最大大小(字节)
+	responseCacheMaxSize atomic.Int64
+	// 命中响应缓存时按原价的比例计费，0 表示免费
+	responseCachePriceRatio atomic.Value
+	// 未启用 redis 时内存响应缓存的总大小(字节)
+	ResponseCacheMemoryMaxSize = int64(env.Int("RESPONSE_CACHE_MEMORY_MAX_SIZE_MB", 256)) << 20
+)
+
+func init() {
+	responseCacheTTL.Store(3600)
+	responseCacheMaxSize.Store(1 << 20)
+	responseCachePriceRatio.Store(float64(0))
+}
+
+func GetResponseCacheTTL() int64 {
+	return responseCacheTTL.Load()
+}
+
+func SetResponseCacheTTL(ttl int64) {
+	responseCacheTTL.Store(ttl)
+}
+
+func GetResponseCacheMaxSize() int64 {
+	return responseCacheMaxSize.Load()
+}
+
+func SetResponseCacheMaxSize(size int64) {
+	responseCacheMaxSize.Store(size)
+}
+
+func GetResponseCachePriceRatio() float64 {
+	return responseCachePriceRatio.Load().(float64)
+}
+
+func SetResponseCachePriceRatio(ratio float64) {
+	responseCachePriceRatio.Store(ratio)
+}
+
 var billingEnabled atomic.Bool
 
 func init() {
@@ -0,0 +1,35 @@
+package filestore_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/labring/sealos/service/aiproxy/common/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := filestore.NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+
+	err = store.Put(ctx, "files/group/file-1", strings.NewReader("hello"), -1)
+	assert.NoError(t, err)
+
+	r, err := store.Get(ctx, "files/group/file-1")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(r)
+	r.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	assert.NoError(t, store.Delete(ctx, "files/group/file-1"))
+	_, err = store.Get(ctx, "files/group/file-1")
+	assert.ErrorIs(t, err, filestore.ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, "files/group/file-1"))
+
+	err = store.Put(ctx, "../escape", strings.NewReader("hello"), -1)
+	assert.Error(t, err)
+}
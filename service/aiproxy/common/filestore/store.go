@@ -0,0 +1,53 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/labring/sealos/service/aiproxy/common/env"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+// Store keeps the files uploaded by the files api
+type Store interface {
+	// Put writes the object, size is -1 if it is unknown
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+const (
+	TypeLocal = "local"
+	TypeS3    = "s3"
+)
+
+var Default Store
+
+func Init() error {
+	switch storeType := env.String("FILE_STORE", TypeLocal); storeType {
+	case TypeLocal:
+		store, err := NewLocalStore(env.String("FILE_STORE_LOCAL_DIR", "./data/files"))
+		if err != nil {
+			return err
+		}
+		Default = store
+	case TypeS3:
+		store, err := NewS3Store(&S3Config{
+			Endpoint:        env.String("FILE_STORE_S3_ENDPOINT", ""),
+			Bucket:          env.String("FILE_STORE_S3_BUCKET", ""),
+			Region:          env.String("FILE_STORE_S3_REGION", "us-east-1"),
+			AccessKeyID:     env.String("FILE_STORE_S3_ACCESS_KEY", ""),
+			SecretAccessKey: env.String("FILE_STORE_S3_SECRET_KEY", ""),
+		})
+		if err != nil {
+			return err
+		}
+		Default = store
+	default:
+		return fmt.Errorf("unknown file store: %s", storeType)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// thrift compact protocol, only the parts needed to write the parquet metadata
+// https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+type compactWriter struct {
+	buf bytes.Buffer
+	// last field id of each nested struct
+	lastFields []int16
+	lastField  int16
+}
+
+func (w *compactWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf.Write(b[:n])
+}
+
+func zigzag32(v int32) uint64 {
+	return uint64(uint32((v << 1) ^ (v >> 31)))
+}
+
+func zigzag64(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (w *compactWriter) fieldHeader(id int16, typ byte) {
+	delta := id - w.lastField
+	if delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(zigzag32(int32(id)))
+	}
+	w.lastField = id
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.fieldHeader(id, compactI32)
+	w.varint(zigzag32(v))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.fieldHeader(id, compactI64)
+	w.varint(zigzag64(v))
+}
+
+func (w *compactWriter) rawString(s string) {
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *compactWriter) string(id int16, s string) {
+	w.fieldHeader(id, compactBinary)
+	w.rawString(s)
+}
+
+func (w *compactWriter) listHeader(id int16, elemType byte, size int) {
+	w.fieldHeader(id, compactList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		w.buf.WriteByte(0xf0 | elemType)
+		w.varint(uint64(size))
+	}
+}
+
+func (w *compactWriter) i32List(id int16, values []int32) {
+	w.listHeader(id, compactI32, len(values))
+	for _, v := range values {
+		w.varint(zigzag32(v))
+	}
+}
+
+func (w *compactWriter) stringList(id int16, values []string) {
+	w.listHeader(id, compactBinary, len(values))
+	for _, v := range values {
+		w.rawString(v)
+	}
+}
+
+// structBegin starts a nested struct, a field header is written if id is not 0,
+// list elements are written with id 0
+func (w *compactWriter) structBegin(id int16) {
+	if id != 0 {
+		w.fieldHeader(id, compactStruct)
+	}
+	w.lastFields = append(w.lastFields, w.lastField)
+	w.lastField = 0
+}
+
+func (w *compactWriter) structEnd() {
+	w.buf.WriteByte(0)
+	w.lastField = w.lastFields[len(w.lastFields)-1]
+	w.lastFields = w.lastFields[:len(w.lastFields)-1]
+}
+
+// end writes the stop field of the top level struct
+func (w *compactWriter) end() {
+	w.buf.WriteByte(0)
+}
@@ -0,0 +1,347 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// a minimal parquet writer for flat tables of required and optional columns,
+// every row group holds one uncompressed plain encoded data page per column
+// https://github.com/apache/parquet-format
+
+const magic = "PAR1"
+
+// DefaultRowGroupSize is the number of rows buffered in memory before a row group is written
+const DefaultRowGroupSize = 10000
+
+type ColumnType int
+
+const (
+	String ColumnType = iota
+	Int64
+	Double
+	Bool
+	// Timestamp is stored as milliseconds since the unix epoch
+	Timestamp
+)
+
+type Column struct {
+	Name string
+	Type ColumnType
+	// Optional columns accept nil values, which are written as nulls
+	Optional bool
+}
+
+// parquet physical types
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+// parquet converted types
+const (
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+)
+
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+	pageTypeData       = 0
+	codecUncompressed  = 0
+)
+
+func (t ColumnType) physicalType() int32 {
+	switch t {
+	case Int64, Timestamp:
+		return typeInt64
+	case Double:
+		return typeDouble
+	case Bool:
+		return typeBoolean
+	default:
+		return typeByteArray
+	}
+}
+
+type columnBuffer struct {
+	data  bytes.Buffer
+	bools []bool
+	// defined holds whether each value of an optional column is not null
+	defined []bool
+}
+
+type columnChunk struct {
+	offset int64
+	size   int64
+}
+
+type rowGroup struct {
+	columns []columnChunk
+	rows    int64
+}
+
+type Writer struct {
+	w            io.Writer
+	columns      []Column
+	buffers      []columnBuffer
+	rowGroups    []rowGroup
+	offset       int64
+	rows         int64
+	totalRows    int64
+	RowGroupSize int
+	closed       bool
+}
+
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("parquet: no columns")
+	}
+	writer := &Writer{
+		w:            w,
+		columns:      columns,
+		buffers:      make([]columnBuffer, len(columns)),
+		RowGroupSize: DefaultRowGroupSize,
+	}
+	if err := writer.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *Writer) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+// Write appends a row, the values must match the column types:
+// string, int64, float64, bool and time.Time, or be nil for optional columns
+func (w *Writer) Write(row []any) error {
+	if w.closed {
+		return errors.New("parquet: writer is closed")
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: expected %d values, got %d", len(w.columns), len(row))
+	}
+	for i, column := range w.columns {
+		if err := w.buffers[i].append(column, row[i]); err != nil {
+			return err
+		}
+	}
+	w.rows++
+	if w.rows >= int64(w.RowGroupSize) {
+		return w.flush()
+	}
+	return nil
+}
+
+func (b *columnBuffer) append(column Column, value any) error {
+	if column.Optional {
+		b.defined = append(b.defined, value != nil)
+		if value == nil {
+			return nil
+		}
+	}
+	var ok bool
+	switch column.Type {
+	case String:
+		var v string
+		if v, ok = value.(string); ok {
+			_ = binary.Write(&b.data, binary.LittleEndian, uint32(len(v)))
+			b.data.WriteString(v)
+		}
+	case Int64:
+		var v int64
+		if v, ok = value.(int64); ok {
+			_ = binary.Write(&b.data, binary.LittleEndian, v)
+		}
+	case Double:
+		var v float64
+		if v, ok = value.(float64); ok {
+			_ = binary.Write(&b.data, binary.LittleEndian, math.Float64bits(v))
+		}
+	case Bool:
+		var v bool
+		if v, ok = value.(bool); ok {
+			b.bools = append(b.bools, v)
+		}
+	case Timestamp:
+		var v time.Time
+		if v, ok = value.(time.Time); ok {
+			_ = binary.Write(&b.data, binary.LittleEndian, v.UnixMilli())
+		}
+	}
+	if !ok {
+		return fmt.Errorf("parquet: invalid value type %T for column %s", value, column.Name)
+	}
+	return nil
+}
+
+// pageData returns the definition levels of an optional column followed by the plain encoded values,
+// booleans are bit packed
+func (b *columnBuffer) pageData(column Column) []byte {
+	values := b.data.Bytes()
+	if column.Type == Bool {
+		values = bitPack(b.bools)
+	}
+	if !column.Optional {
+		return values
+	}
+	// the definition levels of a flat optional column are 0 for null and 1 for a value,
+	// written as one bit packed run of the rle/bit-packing hybrid encoding prefixed by its length
+	levels := bitPack(b.defined)
+	data := make([]byte, 0, 4+binary.MaxVarintLen64+len(levels)+len(values))
+	data = binary.LittleEndian.AppendUint32(data, 0)
+	data = binary.AppendUvarint(data, uint64(len(levels))<<1|1)
+	data = append(data, levels...)
+	binary.LittleEndian.PutUint32(data, uint32(len(data)-4))
+	return append(data, values...)
+}
+
+// bitPack packs the values into bits from the least significant bit, padded to whole bytes
+func bitPack(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+func (b *columnBuffer) reset() {
+	b.data.Reset()
+	b.bools = b.bools[:0]
+	b.defined = b.defined[:0]
+}
+
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	group := rowGroup{
+		columns: make([]columnChunk, len(w.columns)),
+		rows:    w.rows,
+	}
+	for i := range w.columns {
+		data := w.buffers[i].pageData(w.columns[i])
+
+		var header compactWriter
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.structBegin(5)
+		header.i32(1, int32(w.rows))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.structEnd()
+		header.end()
+
+		offset := w.offset
+		if err := w.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := w.write(data); err != nil {
+			return err
+		}
+		group.columns[i] = columnChunk{
+			offset: offset,
+			size:   w.offset - offset,
+		}
+		w.buffers[i].reset()
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	w.totalRows += w.rows
+	w.rows = 0
+	return nil
+}
+
+// Close writes the buffered rows and the file footer, it does not close the underlying writer
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	var meta compactWriter
+	meta.i32(1, 1)
+
+	meta.listHeader(2, compactStruct, len(w.columns)+1)
+	meta.structBegin(0)
+	meta.string(4, "schema")
+	meta.i32(5, int32(len(w.columns)))
+	meta.structEnd()
+	for _, column := range w.columns {
+		meta.structBegin(0)
+		meta.i32(1, column.Type.physicalType())
+		if column.Optional {
+			meta.i32(3, repetitionOptional)
+		} else {
+			meta.i32(3, repetitionRequired)
+		}
+		meta.string(4, column.Name)
+		switch column.Type {
+		case String:
+			meta.i32(6, convertedUTF8)
+		case Timestamp:
+			meta.i32(6, convertedTimestampMillis)
+		}
+		meta.structEnd()
+	}
+
+	meta.i64(3, w.totalRows)
+
+	meta.listHeader(4, compactStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		meta.structBegin(0)
+		meta.listHeader(1, compactStruct, len(group.columns))
+		var totalSize int64
+		for i, chunk := range group.columns {
+			totalSize += chunk.size
+			meta.structBegin(0)
+			meta.i64(2, chunk.offset)
+			meta.structBegin(3)
+			meta.i32(1, w.columns[i].Type.physicalType())
+			meta.i32List(2, []int32{encodingPlain, encodingRLE})
+			meta.stringList(3, []string{w.columns[i].Name})
+			meta.i32(4, codecUncompressed)
+			meta.i64(5, group.rows)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.structEnd()
+			meta.structEnd()
+		}
+		meta.i64(2, totalSize)
+		meta.i64(3, group.rows)
+		meta.structEnd()
+	}
+
+	meta.string(6, "aiproxy")
+	meta.end()
+
+	if err := w.write(meta.buf.Bytes()); err != nil {
+		return err
+	}
+	var footer [8]byte
+	binary.LittleEndian.PutUint32(footer[:4], uint32(meta.buf.Len()))
+	copy(footer[4:], magic)
+	return w.write(footer[:])
+}
@@ -0,0 +1,295 @@
+package parquet_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labring/sealos/service/aiproxy/common/parquet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, []parquet.Column{
+		{Name: "name", Type: parquet.String},
+		{Name: "count", Type: parquet.Int64},
+		{Name: "amount", Type: parquet.Double},
+		{Name: "ok", Type: parquet.Bool},
+		{Name: "at", Type: parquet.Timestamp},
+	})
+	assert.NoError(t, err)
+	w.RowGroupSize = 2
+
+	for i := range 3 {
+		err := w.Write([]any{"row", int64(i), float64(i) / 2, i%2 == 0, time.UnixMilli(int64(i))})
+		assert.NoError(t, err)
+	}
+	assert.Error(t, w.Write([]any{"row", 1, 0.5, true, time.Now()}))
+	assert.NoError(t, w.Close())
+
+	data := buf.Bytes()
+	assert.Equal(t, "PAR1", string(data[:4]))
+	assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8 : len(data)-4]))
+	assert.Less(t, footerSize, len(data)-12)
+	footer := data[len(data)-8-footerSize : len(data)-8]
+	// field 1 i32 version 1
+	assert.Equal(t, []byte{0x15, 0x02}, footer[:2])
+	// the last field is the created_by string followed by the stop field
+	assert.True(t, bytes.HasSuffix(footer, []byte("aiproxy\x00")))
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	columns := []parquet.Column{
+		{Name: "name", Type: parquet.String},
+		{Name: "count", Type: parquet.Int64},
+		{Name: "amount", Type: parquet.Double},
+		{Name: "ok", Type: parquet.Bool},
+		{Name: "at", Type: parquet.Timestamp},
+		{Name: "opt_name", Type: parquet.String, Optional: true},
+		{Name: "opt_count", Type: parquet.Int64, Optional: true},
+		{Name: "opt_amount", Type: parquet.Double, Optional: true},
+		{Name: "opt_ok", Type: parquet.Bool, Optional: true},
+		{Name: "opt_at", Type: parquet.Timestamp, Optional: true},
+	}
+	var rows [][]any
+	for i := range 11 {
+		row := []any{
+			"row-" + strings.Repeat("x", i),
+			int64(i) - 5,
+			float64(i) / 3,
+			i%3 == 0,
+			time.UnixMilli(1700000000000 + int64(i)),
+		}
+		// every column is null in a different pattern, spanning the bit packed bytes of the levels
+		for j := range 5 {
+			if (i+j)%(j+2) == 0 {
+				row = append(row, nil)
+			} else {
+				row = append(row, row[j])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, columns)
+	require.NoError(t, err)
+	w.RowGroupSize = 4
+	for _, row := range rows {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+
+	got := readParquet(t, buf.Bytes())
+	require.Equal(t, len(columns), len(got.columns))
+	for i, column := range columns {
+		assert.Equal(t, column.Name, got.columns[i].name)
+		assert.Equal(t, column.Optional, got.columns[i].optional, column.Name)
+	}
+	require.Len(t, got.rows, len(rows))
+	for i, row := range rows {
+		for j, value := range row {
+			if at, ok := value.(time.Time); ok {
+				value = at.UnixMilli()
+			}
+			assert.Equal(t, value, got.rows[i][j], "column %s row %d", columns[j].Name, i)
+		}
+	}
+}
+
+// the test reads the files back without the writer, the thrift structs are decoded into maps of the field ids
+
+type parquetColumn struct {
+	name     string
+	typ      int64
+	optional bool
+}
+
+type parquetFile struct {
+	columns []parquetColumn
+	rows    [][]any
+}
+
+func readParquet(t *testing.T, data []byte) *parquetFile {
+	t.Helper()
+	require.Equal(t, "PAR1", string(data[:4]))
+	require.Equal(t, "PAR1", string(data[len(data)-4:]))
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8 : len(data)-4]))
+	meta, _ := readStruct(t, data[len(data)-8-footerSize:len(data)-8])
+
+	file := &parquetFile{}
+	// the first schema element is the root
+	for _, elem := range meta[2].([]any)[1:] {
+		schema := elem.(map[int16]any)
+		file.columns = append(file.columns, parquetColumn{
+			name:     schema[4].(string),
+			typ:      schema[1].(int64),
+			optional: schema[3].(int64) == 1,
+		})
+	}
+	for _, group := range meta[4].([]any) {
+		group := group.(map[int16]any)
+		numRows := int(group[3].(int64))
+		groupRows := make([][]any, numRows)
+		for i := range groupRows {
+			groupRows[i] = make([]any, len(file.columns))
+		}
+		for i, chunk := range group[1].([]any) {
+			chunkMeta := chunk.(map[int16]any)[3].(map[int16]any)
+			require.Equal(t, file.columns[i].typ, chunkMeta[1].(int64))
+			require.Equal(t, int64(numRows), chunkMeta[5].(int64))
+			values := readPage(t, data[chunkMeta[9].(int64):], file.columns[i], numRows)
+			for j, value := range values {
+				groupRows[j][i] = value
+			}
+		}
+		file.rows = append(file.rows, groupRows...)
+	}
+	require.Equal(t, int64(len(file.rows)), meta[3].(int64))
+	return file
+}
+
+// readPage decodes a plain encoded data page, the values of an optional column are nil if they are null
+func readPage(t *testing.T, data []byte, column parquetColumn, numValues int) []any {
+	t.Helper()
+	header, n := readStruct(t, data)
+	require.Equal(t, int64(0), header[1].(int64), "page type")
+	require.Equal(t, int64(numValues), header[5].(map[int16]any)[1].(int64))
+	page := data[n : n+int(header[3].(int64))]
+
+	defined := make([]bool, numValues)
+	for i := range defined {
+		defined[i] = true
+	}
+	if column.optional {
+		size := int(binary.LittleEndian.Uint32(page))
+		defined = readLevels(t, page[4:4+size], numValues)
+		page = page[4+size:]
+	}
+	values := make([]any, numValues)
+	var bit int
+	for i := range values {
+		if !defined[i] {
+			continue
+		}
+		switch column.typ {
+		case 0:
+			values[i] = page[bit/8]&(1<<(bit%8)) != 0
+			bit++
+		case 2:
+			values[i] = int64(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case 5:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case 6:
+			size := int(binary.LittleEndian.Uint32(page))
+			values[i] = string(page[4 : 4+size])
+			page = page[4+size:]
+		default:
+			t.Fatalf("unexpected type %d of column %s", column.typ, column.name)
+		}
+	}
+	return values
+}
+
+// readLevels decodes the definition levels of bit width 1 in the rle/bit-packing hybrid encoding
+func readLevels(t *testing.T, data []byte, numValues int) []bool {
+	t.Helper()
+	r := bytes.NewReader(data)
+	var levels []bool
+	for len(levels) < numValues {
+		header, err := binary.ReadUvarint(r)
+		require.NoError(t, err)
+		if header&1 == 0 {
+			value, err := r.ReadByte()
+			require.NoError(t, err)
+			for range header >> 1 {
+				levels = append(levels, value == 1)
+			}
+			continue
+		}
+		// groups of 8 values in one byte each
+		for range header >> 1 {
+			b, err := r.ReadByte()
+			require.NoError(t, err)
+			for i := range 8 {
+				levels = append(levels, b&(1<<i) != 0)
+			}
+		}
+	}
+	return levels[:numValues]
+}
+
+// readStruct decodes a struct of the thrift compact protocol, it returns the fields and the decoded size
+func readStruct(t *testing.T, data []byte) (map[int16]any, int) {
+	t.Helper()
+	r := bytes.NewReader(data)
+	fields := readCompactStruct(t, r)
+	return fields, len(data) - r.Len()
+}
+
+func readCompactStruct(t *testing.T, r *bytes.Reader) map[int16]any {
+	fields := make(map[int16]any)
+	var id int16
+	for {
+		b, err := r.ReadByte()
+		require.NoError(t, err)
+		if b == 0 {
+			return fields
+		}
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(readZigzag(t, r))
+		}
+		fields[id] = readCompactValue(t, r, b&0x0f)
+	}
+}
+
+func readCompactValue(t *testing.T, r *bytes.Reader, typ byte) any {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 5, 6:
+		return readZigzag(t, r)
+	case 8:
+		size, err := binary.ReadUvarint(r)
+		require.NoError(t, err)
+		b := make([]byte, size)
+		_, err = io.ReadFull(r, b)
+		require.NoError(t, err)
+		return string(b)
+	case 9:
+		b, err := r.ReadByte()
+		require.NoError(t, err)
+		size := uint64(b >> 4)
+		if size == 15 {
+			size, err = binary.ReadUvarint(r)
+			require.NoError(t, err)
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = readCompactValue(t, r, b&0x0f)
+		}
+		return list
+	case 12:
+		return readCompactStruct(t, r)
+	default:
+		t.Fatalf("unexpected thrift compact type %d", typ)
+		return nil
+	}
+}
+
+func readZigzag(t *testing.T, r *bytes.Reader) int64 {
+	v, err := binary.ReadUvarint(r)
+	require.NoError(t, err)
+	return int64(v>>1) ^ -int64(v&1)
+}
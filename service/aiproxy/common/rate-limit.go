@@ -91,3 +91,151 @@ func (l *InMemoryRateLimiter) Request(key string, maxRequestNum int, duration ti
 
 	return false
 }
+
+// InMemoryTokenRateLimiter limits the number of tokens per window,
+// the window is approximated by the weighted sum of the current and previous fixed buckets
+type InMemoryTokenRateLimiter struct {
+	store              map[string]*TokenRateLimitWindow
+	mutex              sync.Mutex
+	expirationDuration time.Duration
+}
+
+type TokenRateLimitWindow struct {
+	bucket     int64
+	current    int64
+	previous   int64
+	lastAccess int64
+}
+
+func (l *InMemoryTokenRateLimiter) Init(expirationDuration time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.store == nil {
+		l.store = make(map[string]*TokenRateLimitWindow)
+		l.expirationDuration = expirationDuration
+		if expirationDuration > 0 {
+			go l.clearExpiredItems()
+		}
+	}
+}
+
+func (l *InMemoryTokenRateLimiter) clearExpiredItems() {
+	ticker := time.NewTicker(l.expirationDuration)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mutex.Lock()
+		now := time.Now().Unix()
+		for key, window := range l.store {
+			if now-window.lastAccess > int64(l.expirationDuration.Seconds()) {
+				delete(l.store, key)
+			}
+		}
+		l.mutex.Unlock()
+	}
+}
+
+// roll moves the window forward to the bucket, caller must hold the lock
+func (w *TokenRateLimitWindow) roll(bucket int64) {
+	switch {
+	case bucket == w.bucket:
+	case bucket == w.bucket+1:
+		w.previous = w.current
+		w.current = 0
+		w.bucket = bucket
+	case bucket > w.bucket:
+		w.previous = 0
+		w.current = 0
+		w.bucket = bucket
+	}
+}
+
+// Request reserves tokens in the window, it returns the bucket the tokens were
+// recorded in, which must be passed to Adjust when the actual usage is known.
+// A request is always admitted when the window is empty, so that a single
+// request larger than the limit can not be blocked forever.
+func (l *InMemoryTokenRateLimiter) Request(key string, maxTokens int64, tokens int64, duration time.Duration) (bool, int64, time.Duration) {
+	now := time.Now()
+	bucket, weight, retryAfter := SlidingWindowBucket(now, duration)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	window, ok := l.store[key]
+	if !ok {
+		window = &TokenRateLimitWindow{bucket: bucket}
+		l.store[key] = window
+	}
+	window.lastAccess = now.Unix()
+	window.roll(bucket)
+
+	used := int64(float64(window.previous)*weight) + window.current
+	if used > 0 && used+tokens > maxTokens {
+		return false, bucket, retryAfter
+	}
+	window.current += tokens
+	return true, bucket, 0
+}
+
+// Adjust corrects the tokens recorded in the bucket, delta can be negative
+func (l *InMemoryTokenRateLimiter) Adjust(key string, bucket int64, delta int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	window, ok := l.store[key]
+	if !ok {
+		return
+	}
+	var count *int64
+	switch bucket {
+	case window.bucket:
+		count = &window.current
+	case window.bucket - 1:
+		count = &window.previous
+	default:
+		return
+	}
+	*count += delta
+	if *count < 0 {
+		*count = 0
+	}
+}
+
+// SlidingWindowBucket returns the fixed bucket index of now, the weight of the
+// previous bucket and the duration until the current bucket ends
+func SlidingWindowBucket(now time.Time, duration time.Duration) (int64, float64, time.Duration) {
+	nowMs := now.UnixMilli()
+	durationMs := duration.Milliseconds()
+	bucket := nowMs / durationMs
+	elapsed := nowMs - bucket*durationMs
+	weight := 1 - float64(elapsed)/float64(durationMs)
+	return bucket, weight, time.Duration(durationMs-elapsed) * time.Millisecond
+}
+
+type InMemoryConcurrencyLimiter struct {
+	store map[string]int64
+	mutex sync.Mutex
+}
+
+func (l *InMemoryConcurrencyLimiter) Acquire(key string, maxConcurrency int64) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.store == nil {
+		l.store = make(map[string]int64)
+	}
+	if l.store[key] >= maxConcurrency {
+		return false
+	}
+	l.store[key]++
+	return true
+}
+
+func (l *InMemoryConcurrencyLimiter) Release(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.store[key] <= 1 {
+		delete(l.store, key)
+		return
+	}
+	l.store[key]--
+}
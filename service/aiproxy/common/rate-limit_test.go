@@ -0,0 +1,42 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestInMemoryTokenRateLimiter(t *testing.T) {
+	convey.Convey("TestInMemoryTokenRateLimiter", t, func() {
+		var limiter InMemoryTokenRateLimiter
+		limiter.Init(0)
+
+		ok, bucket, _ := limiter.Request("group", 100, 80, time.Hour)
+		convey.So(ok, convey.ShouldBeTrue)
+
+		ok, _, retryAfter := limiter.Request("group", 100, 30, time.Hour)
+		convey.So(ok, convey.ShouldBeFalse)
+		convey.So(retryAfter, convey.ShouldBeGreaterThan, 0)
+
+		// the actual usage is less than the estimate
+		limiter.Adjust("group", bucket, -60)
+		ok, _, _ = limiter.Request("group", 100, 30, time.Hour)
+		convey.So(ok, convey.ShouldBeTrue)
+
+		// a single request larger than the limit is admitted on an empty window
+		ok, _, _ = limiter.Request("other", 100, 1000, time.Hour)
+		convey.So(ok, convey.ShouldBeTrue)
+	})
+}
+
+func TestInMemoryConcurrencyLimiter(t *testing.T) {
+	convey.Convey("TestInMemoryConcurrencyLimiter", t, func() {
+		var limiter InMemoryConcurrencyLimiter
+		convey.So(limiter.Acquire("token", 2), convey.ShouldBeTrue)
+		convey.So(limiter.Acquire("token", 2), convey.ShouldBeTrue)
+		convey.So(limiter.Acquire("token", 2), convey.ShouldBeFalse)
+		limiter.Release("token")
+		convey.So(limiter.Acquire("token", 2), convey.ShouldBeTrue)
+	})
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labring/sealos/service/aiproxy/common/ctxkey"
+	"github.com/labring/sealos/service/aiproxy/middleware"
+	dbmodel "github.com/labring/sealos/service/aiproxy/model"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/openai"
+	"github.com/labring/sealos/service/aiproxy/relay/controller"
+	"github.com/labring/sealos/service/aiproxy/relay/model"
+)
+
+// https://platform.openai.com/docs/api-reference/batch
+
+func batchErrorResponse(c *gin.Context, err error) {
+	var notFound dbmodel.NotFoundError
+	if errors.As(err, &notFound) {
+		relayErrorResponse(c, openai.ErrorWrapper(err, "batch_not_found", http.StatusNotFound))
+		return
+	}
+	relayErrorResponse(c, openai.ErrorWrapper(err, "batch_failed", http.StatusInternalServerError))
+}
+
+func CreateBatch(c *gin.Context) {
+	group := c.MustGet(ctxkey.Group).(*dbmodel.GroupCache)
+	token := c.MustGet(ctxkey.Token).(*dbmodel.TokenCache)
+
+	var req model.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		relayErrorResponse(c, openai.ErrorWrapper(err, "invalid_batch_request", http.StatusBadRequest))
+		return
+	}
+	batch, bizErr := controller.CreateBatch(c.Request.Context(), group, token, &req)
+	if bizErr != nil {
+		middleware.GetLogger(c).Errorf("create batch failed: %s", bizErr)
+		relayErrorResponse(c, bizErr)
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+func GetBatch(c *gin.Context) {
+	group := c.MustGet(ctxkey.Group).(*dbmodel.GroupCache)
+	batch, err := dbmodel.GetGroupBatchByID(group.ID, c.Param("id"))
+	if err != nil {
+		batchErrorResponse(c, err)
+		return
+	}
+	// the stored status is returned if the upstream is unavailable, it is synced in the background anyway
+	if err := controller.SyncBatch(c.Request.Context(), batch); err != nil {
+		middleware.GetLogger(c).Errorf("sync batch %s failed: %v", batch.ID, err)
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+func ListBatches(c *gin.Context) {
+	group := c.MustGet(ctxkey.Group).(*dbmodel.GroupCache)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, 100)
+	batches, err := dbmodel.GetGroupBatches(group.ID, c.Query("after"), limit+1)
+	if err != nil {
+		batchErrorResponse(c, err)
+		return
+	}
+	hasMore := len(batches) > limit
+	if hasMore {
+		batches = batches[:limit]
+	}
+	resp := gin.H{
+		"object":   "list",
+		"data":     batches,
+		"has_more": hasMore,
+	}
+	if len(batches) > 0 {
+		resp["first_id"] = batches[0].ID
+		resp["last_id"] = batches[len(batches)-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func CancelBatch(c *gin.Context) {
+	group := c.MustGet(ctxkey.Group).(*dbmodel.GroupCache)
+	batch, err := dbmodel.GetGroupBatchByID(group.ID, c.Param("id"))
+	if err != nil {
+		batchErrorResponse(c, err)
+		return
+	}
+	if err := controller.CancelBatch(c.Request.Context(), batch); err != nil {
+		relayErrorResponse(c, openai.ErrorWrapper(err, "cancel_batch_failed", http.StatusBadRequest))
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
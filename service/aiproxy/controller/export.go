@@ -0,0 +1,354 @@
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labring/sealos/service/aiproxy/common"
+	"github.com/labring/sealos/service/aiproxy/common/env"
+	"github.com/labring/sealos/service/aiproxy/common/filestore"
+	"github.com/labring/sealos/service/aiproxy/common/parquet"
+	"github.com/labring/sealos/service/aiproxy/middleware"
+	"github.com/labring/sealos/service/aiproxy/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatParquet = "parquet"
+
+	ExportTypeLogs  = "logs"
+	ExportTypeDaily = "daily"
+)
+
+var (
+	// write the daily usage of all groups of the previous day to the file store
+	usageExportEnabled = env.Bool("USAGE_EXPORT_ENABLED", false)
+	usageExportFormat  = env.String("USAGE_EXPORT_FORMAT", ExportFormatCSV)
+	usageExportPrefix  = env.String("USAGE_EXPORT_PREFIX", "usage/daily")
+)
+
+var logExportColumns = []parquet.Column{
+	{Name: "id", Type: parquet.Int64},
+	{Name: "request_id", Type: parquet.String},
+	{Name: "request_at", Type: parquet.Timestamp},
+	{Name: "group", Type: parquet.String},
+	{Name: "token_id", Type: parquet.Int64},
+	{Name: "token_name", Type: parquet.String},
+	{Name: "model", Type: parquet.String},
+	{Name: "mode", Type: parquet.Int64},
+	{Name: "channel", Type: parquet.Int64},
+	{Name: "endpoint", Type: parquet.String},
+	{Name: "code", Type: parquet.Int64},
+	{Name: "prompt_tokens", Type: parquet.Int64},
+	{Name: "completion_tokens", Type: parquet.Int64},
+	{Name: "price", Type: parquet.Double},
+	{Name: "completion_price", Type: parquet.Double},
+	{Name: "used_amount", Type: parquet.Double},
+	{Name: "cache_hit", Type: parquet.Bool},
+}
+
+func logExportRow(l *model.Log) []any {
+	return []any{
+		int64(l.ID),
+		l.RequestID,
+		l.RequestAt,
+		l.GroupID,
+		int64(l.TokenID),
+		l.TokenName,
+		l.Model,
+		int64(l.Mode),
+		int64(l.ChannelID),
+		l.Endpoint,
+		int64(l.Code),
+		int64(l.PromptTokens),
+		int64(l.CompletionTokens),
+		l.Price,
+		l.CompletionPrice,
+		l.UsedAmount,
+		l.CacheHit,
+	}
+}
+
+var dailyUsageExportColumns = []parquet.Column{
+	{Name: "date", Type: parquet.String},
+	{Name: "group", Type: parquet.String},
+	{Name: "token_name", Type: parquet.String},
+	{Name: "model", Type: parquet.String},
+	{Name: "requests", Type: parquet.Int64},
+	{Name: "failed_requests", Type: parquet.Int64},
+	{Name: "cache_hit_requests", Type: parquet.Int64},
+	{Name: "prompt_tokens", Type: parquet.Int64},
+	{Name: "completion_tokens", Type: parquet.Int64},
+	{Name: "total_tokens", Type: parquet.Int64},
+	{Name: "used_amount", Type: parquet.Double},
+}
+
+func dailyUsageExportRow(u *model.DailyUsage) []any {
+	return []any{
+		u.Date,
+		u.GroupID,
+		u.TokenName,
+		u.Model,
+		u.Requests,
+		u.FailedRequests,
+		u.CacheHitRequests,
+		u.PromptTokens,
+		u.CompletionTokens,
+		u.TotalTokens,
+		u.UsedAmount,
+	}
+}
+
+type tableWriter interface {
+	Write(row []any) error
+	Close() error
+}
+
+type csvTableWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVTableWriter(w io.Writer, columns []parquet.Column) (*csvTableWriter, error) {
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return nil, err
+	}
+	return &csvTableWriter{
+		w:      cw,
+		record: make([]string, len(columns)),
+	}, nil
+}
+
+func (w *csvTableWriter) Write(row []any) error {
+	for i, value := range row {
+		switch v := value.(type) {
+		case nil:
+			w.record[i] = ""
+		case string:
+			w.record[i] = v
+		case int64:
+			w.record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			w.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			w.record[i] = strconv.FormatBool(v)
+		case time.Time:
+			w.record[i] = v.UTC().Format(time.RFC3339Nano)
+		default:
+			w.record[i] = fmt.Sprint(v)
+		}
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *csvTableWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func newTableWriter(w io.Writer, format string, columns []parquet.Column) (tableWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVTableWriter(w, columns)
+	case ExportFormatParquet:
+		return parquet.NewWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+func exportContentType(format string) string {
+	if format == ExportFormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// exportUsage writes the logs or the daily usages of a group, all groups if group is empty
+func exportUsage(w io.Writer, exportType string, format string, group string, tokenName string, startTimestamp time.Time, endTimestamp time.Time, loc *time.Location) error {
+	switch exportType {
+	case ExportTypeLogs:
+		tw, err := newTableWriter(w, format, logExportColumns)
+		if err != nil {
+			return err
+		}
+		err = model.IterateLogs(group, tokenName, startTimestamp, endTimestamp, func(logs []*model.Log) error {
+			for _, l := range logs {
+				if err := tw.Write(logExportRow(l)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return tw.Close()
+	case ExportTypeDaily:
+		tw, err := newTableWriter(w, format, dailyUsageExportColumns)
+		if err != nil {
+			return err
+		}
+		err = model.IterateDailyUsage(group, tokenName, startTimestamp, endTimestamp, loc, func(usage *model.DailyUsage) error {
+			return tw.Write(dailyUsageExportRow(usage))
+		})
+		if err != nil {
+			return err
+		}
+		return tw.Close()
+	default:
+		return fmt.Errorf("unknown export type: %s", exportType)
+	}
+}
+
+func exportUsageResponse(c *gin.Context, group string) {
+	exportType := c.DefaultQuery("type", ExportTypeDaily)
+	if exportType != ExportTypeLogs && exportType != ExportTypeDaily {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid type")
+		return
+	}
+	format := c.DefaultQuery("format", ExportFormatCSV)
+	if format != ExportFormatCSV && format != ExportFormatParquet {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid format")
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if startTimestamp <= 0 || endTimestamp <= startTimestamp {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid time range")
+		return
+	}
+	loc := time.UTC
+	if timezone := c.Query("timezone"); timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(timezone)
+		if err != nil {
+			middleware.ErrorResponse(c, http.StatusOK, "invalid timezone")
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("usage-%s-%d-%d.%s", exportType, startTimestamp, endTimestamp, format)
+	if group != "" {
+		filename = group + "-" + filename
+	}
+	c.Header("Content-Type", exportContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	err := exportUsage(
+		c.Writer,
+		exportType,
+		format,
+		group,
+		c.Query("token_name"),
+		time.UnixMilli(startTimestamp),
+		time.UnixMilli(endTimestamp),
+		loc,
+	)
+	if err != nil {
+		// the response has been started, the error can only be logged
+		middleware.GetLogger(c).Errorf("export usage failed: %s", err.Error())
+	}
+}
+
+// ExportLogs exports the logs or the daily usages of a group, all groups if the group query is empty
+func ExportLogs(c *gin.Context) {
+	exportUsageResponse(c, c.Query("group"))
+}
+
+func ExportGroupLogs(c *gin.Context) {
+	group := c.Param("group")
+	if group == "" {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid parameter")
+		return
+	}
+	exportUsageResponse(c, group)
+}
+
+func dailyUsageExportKey(date string) string {
+	return fmt.Sprintf("%s/%s.%s", usageExportPrefix, date, usageExportFormat)
+}
+
+// exportDailyUsage writes the daily usage of all groups of a day to the file store
+func exportDailyUsage(ctx context.Context, day time.Time) error {
+	key := dailyUsageExportKey(day.Format(time.DateOnly))
+	r, err := filestore.Default.Get(ctx, key)
+	if err == nil {
+		r.Close()
+		return nil
+	}
+	if !errors.Is(err, filestore.ErrNotFound) {
+		return err
+	}
+	if common.RedisEnabled {
+		// only one instance exports the day
+		ok, err := common.RDB.SetNX(ctx, "usage_export:"+key, 1, time.Hour).Result()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(exportUsage(pw, ExportTypeDaily, usageExportFormat, "", "", day, day.AddDate(0, 0, 1), time.UTC))
+	}()
+	err = filestore.Default.Put(ctx, key, pr, -1)
+	pr.CloseWithError(err)
+	return err
+}
+
+// ExportDailyUsage writes the daily usage file of the previous day once the day is over
+func ExportDailyUsage(ctx context.Context, wg *sync.WaitGroup, frequency time.Duration) {
+	defer wg.Done()
+
+	if !usageExportEnabled {
+		return
+	}
+	if usageExportFormat != ExportFormatCSV && usageExportFormat != ExportFormatParquet {
+		log.Errorf("invalid usage export format: %s", usageExportFormat)
+		return
+	}
+
+	var lastExported time.Time
+	export := func() {
+		yesterday := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+		if yesterday.Equal(lastExported) {
+			return
+		}
+		if err := exportDailyUsage(ctx, yesterday); err != nil {
+			log.Errorf("failed to export daily usage of %s: %v", yesterday.Format(time.DateOnly), err)
+			return
+		}
+		lastExported = yesterday
+	}
+
+	export()
+	ticker := time.NewTicker(frequency)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			export()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labring/sealos/service/aiproxy/common/ctxkey"
+	"github.com/labring/sealos/service/aiproxy/common/env"
+	"github.com/labring/sealos/service/aiproxy/common/filestore"
+	"github.com/labring/sealos/service/aiproxy/common/random"
+	"github.com/labring/sealos/service/aiproxy/middleware"
+	dbmodel "github.com/labring/sealos/service/aiproxy/model"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/openai"
+)
+
+// https://platform.openai.com/docs/api-reference/files
+
+var fileMaxBytes = int64(env.Int("FILE_MAX_SIZE_MB", 200)) << 20
+
+func fileErrorResponse(c *gin.Context, err error) {
+	var notFound dbmodel.NotFoundError
+	if errors.As(err, &notFound) {
+		relayErrorResponse(c, openai.ErrorWrapper(err, "file_not_found", http.StatusNotFound))
+		return
+	}
+	relayErrorResponse(c, openai.ErrorWrapper(err, "file_failed", http.StatusInternalServerError))
+}
+
+func UploadFile(c *gin.Context) {
+	group := c.MustGet(ctxkey.Group).(*dbmodel.GroupCache)
+	token := c.MustGet(ctxkey.Token).(*dbmodel.TokenCache)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, fileMaxBytes+1<<20)
+	purpose := c.PostForm("purpose")
+	if purpose != dbmodel.FilePurposeBatch {
+		relayErrorResponse(c, openai.ErrorWrapperWithMessage("only the batch purpose is supported", "invalid_file_request", http.StatusBadRequest))
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		relayErrorResponse(c, openai.ErrorWrapper(err, "invalid_file_request", http.StatusBadRequest))
+		return
+	}
+	if fileHeader.Size > fileMaxBytes {
+		relayErrorResponse(c, openai.ErrorWrapperWithMessage(
+			fmt.Sprintf("file is too large, max size is %d bytes", fileMaxBytes),
+			"file_too_large",
+			http.StatusRequestEntityTooLarge,
+		))
+		return
+	}
+	f, err := fileHeader.Open()
+	if err != nil {
+		relayErrorResponse(c, openai.ErrorWrapper(err, "invalid_file_request", http.StatusBadRequest))
+		return
+	}
+	defer f.Close()
+
+	id := "file-" + random.GetUUID()
+	file := &dbmodel.File{
+		ID:         id,
+		GroupID:    group.ID,
+		TokenID:    token.ID,
+		Filename:   fileHeader.Filename,
+		Purpose:    purpose,
+		Bytes:      fileHeader.Size,
+		StorageKey: dbmodel.FileStorageKey(group.ID, id),
+	}
+	ctx := c.Request.Context()
+	if err := filestore.Default.Put(ctx, file.StorageKey, f, fileHeader.Size); err != nil {
+		relayErrorResponse(c, openai.ErrorWrapper(err, "upload_file_failed", http.StatusInternalServerError))
+		return
+	}
+	if err := dbmodel.CreateFile(file); err != nil {
+		if err := filestore.Default.Delete(ctx, file.StorageKey); err != nil {
+			middleware.GetLogger(c).Errorf("failed to delete file %s: %v", file.ID, err)
+		}
+		relayErrorResponse(c, openai.ErrorWrapper(err, "upload_file_failed", http.StatusInternalServerError))
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+func ListFiles(c *gin.Context) {
+	group := c.MustGet(ctxkey.Group).(*dbmodel.GroupCache)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 10000 {
+		limit = 10000
+	}
+	files, err := dbmodel.GetGroupFiles(group.ID, c.Query("purpose"), limit)
+	if err != nil {
+		fileErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     files,
+		"has_more": false,
+	})
+}
+
+func GetFile(c *gin.Context) {
+	group := c.MustGet(ctxkey.Group).(*dbmodel.GroupCache)
+	file, err := dbmodel.GetGroupFileByID(group.ID, c.Param("id"))
+	if err != nil {
+		fileErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+func DeleteFile(c *gin.Context) {
+	group := c.MustGet(ctxkey.Group).(*dbmodel.GroupCache)
+	file, err := dbmodel.GetGroupFileByID(group.ID, c.Param("id"))
+	if err != nil {
+		fileErrorResponse(c, err)
+		return
+	}
+	if err := dbmodel.DeleteGroupFileByID(group.ID, file.ID); err != nil {
+		fileErrorResponse(c, err)
+		return
+	}
+	if err := filestore.Default.Delete(c.Request.Context(), file.StorageKey); err != nil {
+		middleware.GetLogger(c).Errorf("failed to delete file %s: %v", file.ID, err)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      file.ID,
+		"object":  "file",
+		"deleted": true,
+	})
+}
+
+func GetFileContent(c *gin.Context) {
+	group := c.MustGet(ctxkey.Group).(*dbmodel.GroupCache)
+	file, err := dbmodel.GetGroupFileByID(group.ID, c.Param("id"))
+	if err != nil {
+		fileErrorResponse(c, err)
+		return
+	}
+	content, err := filestore.Default.Get(c.Request.Context(), file.StorageKey)
+	if err != nil {
+		if errors.Is(err, filestore.ErrNotFound) {
+			relayErrorResponse(c, openai.ErrorWrapper(err, "file_not_found", http.StatusNotFound))
+			return
+		}
+		fileErrorResponse(c, err)
+		return
+	}
+	defer content.Close()
+	c.DataFromReader(http.StatusOK, file.Bytes, "application/octet-stream", content, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", file.Filename),
+	})
+}
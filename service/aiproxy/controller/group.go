@@ -116,6 +116,78 @@ func UpdateGroupQPM(c *gin.Context) {
 	middleware.SuccessResponse(c, nil)
 }
 
+type UpdateGroupTPMRequest struct {
+	TPM int64 `json:"tpm"`
+}
+
+func UpdateGroupTPM(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid parameter")
+		return
+	}
+	req := UpdateGroupTPMRequest{}
+	err := json.NewDecoder(c.Request.Body).Decode(&req)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid parameter")
+		return
+	}
+	err = model.UpdateGroupTPM(id, req.TPM)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusOK, err.Error())
+		return
+	}
+	middleware.SuccessResponse(c, nil)
+}
+
+type UpdateGroupConcurrencyRequest struct {
+	Concurrency int64 `json:"concurrency"`
+}
+
+func UpdateGroupConcurrency(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid parameter")
+		return
+	}
+	req := UpdateGroupConcurrencyRequest{}
+	err := json.NewDecoder(c.Request.Body).Decode(&req)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid parameter")
+		return
+	}
+	err = model.UpdateGroupConcurrency(id, req.Concurrency)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusOK, err.Error())
+		return
+	}
+	middleware.SuccessResponse(c, nil)
+}
+
+type UpdateGroupResponseCacheRequest struct {
+	ResponseCache bool `json:"response_cache"`
+}
+
+func UpdateGroupResponseCache(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid parameter")
+		return
+	}
+	req := UpdateGroupResponseCacheRequest{}
+	err := json.NewDecoder(c.Request.Body).Decode(&req)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusOK, "invalid parameter")
+		return
+	}
+	err = model.UpdateGroupResponseCache(id, req.ResponseCache)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusOK, err.Error())
+		return
+	}
+	middleware.SuccessResponse(c, nil)
+}
+
 type UpdateGroupStatusRequest struct {
 	Status int `json:"status"`
 }
@@ -170,8 +242,11 @@ func DeleteGroups(c *gin.Context) {
 }
 
 type CreateGroupRequest struct {
-	ID  string `json:"id"`
-	QPM int64  `json:"qpm"`
+	ID            string `json:"id"`
+	QPM           int64  `json:"qpm"`
+	TPM           int64  `json:"tpm"`
+	Concurrency   int64  `json:"concurrency"`
+	ResponseCache bool   `json:"response_cache"`
 }
 
 func CreateGroup(c *gin.Context) {
@@ -182,8 +257,11 @@ func CreateGroup(c *gin.Context) {
 		return
 	}
 	if err := model.CreateGroup(&model.Group{
-		ID:  group.ID,
-		QPM: group.QPM,
+		ID:            group.ID,
+		QPM:           group.QPM,
+		TPM:           group.TPM,
+		Concurrency:   group.Concurrency,
+		ResponseCache: group.ResponseCache,
 	}); err != nil {
 		middleware.ErrorResponse(c, http.StatusOK, err.Error())
 		return
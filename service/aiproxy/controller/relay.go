@@ -11,6 +11,7 @@ import (
 	"github.com/labring/sealos/service/aiproxy/common/helper"
 	"github.com/labring/sealos/service/aiproxy/middleware"
 	dbmodel "github.com/labring/sealos/service/aiproxy/model"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/anthropic"
 	"github.com/labring/sealos/service/aiproxy/relay/controller"
 	"github.com/labring/sealos/service/aiproxy/relay/meta"
 	"github.com/labring/sealos/service/aiproxy/relay/model"
@@ -33,6 +34,8 @@ func relayHelper(meta *meta.Meta, c *gin.Context) *model.ErrorWithStatusCode {
 		return controller.RelaySTTHelper(meta, c)
 	case relaymode.Rerank:
 		return controller.RerankHelper(meta, c)
+	case relaymode.Anthropic:
+		return controller.RelayAnthropicHelper(meta, c)
 	default:
 		return controller.RelayTextHelper(meta, c)
 	}
@@ -52,7 +55,7 @@ func Relay(c *gin.Context) {
 	lastFailedChannelID := meta.Channel.ID
 	requestID := c.GetString(string(helper.RequestIDKey))
 	retryTimes := config.GetRetryTimes()
-	if !shouldRetry(c, bizErr.StatusCode) {
+	if !shouldRetry(c, bizErr) {
 		retryTimes = 0
 	}
 	for i := retryTimes; i > 0; i-- {
@@ -80,9 +83,13 @@ func Relay(c *gin.Context) {
 	}
 	if bizErr != nil {
 		message := bizErr.Message
-		if bizErr.StatusCode == http.StatusTooManyRequests {
+		if bizErr.StatusCode == http.StatusTooManyRequests && bizErr.Code != middleware.ErrCodeRateLimitExceeded {
 			message = "The upstream load of the current group is saturated, please try again later"
 		}
+		if meta.Mode == relaymode.Anthropic {
+			c.JSON(bizErr.StatusCode, anthropic.NewErrorResponse(bizErr, helper.MessageWithRequestID(message, requestID)))
+			return
+		}
 		c.JSON(bizErr.StatusCode, gin.H{
 			"error": &model.Error{
 				Message: helper.MessageWithRequestID(message, requestID),
@@ -94,7 +101,12 @@ func Relay(c *gin.Context) {
 	}
 }
 
-func shouldRetry(_ *gin.Context, statusCode int) bool {
+func shouldRetry(_ *gin.Context, bizErr *model.ErrorWithStatusCode) bool {
+	statusCode := bizErr.StatusCode
+	// aiproxy's own rate limit is not related to the channel
+	if bizErr.Code == middleware.ErrCodeRateLimitExceeded {
+		return false
+	}
 	if statusCode == http.StatusTooManyRequests {
 		return true
 	}
@@ -120,3 +132,16 @@ func RelayNotImplemented(c *gin.Context) {
 		},
 	})
 }
+
+// relayErrorResponse renders an error in the openai format
+func relayErrorResponse(c *gin.Context, bizErr *model.ErrorWithStatusCode) {
+	requestID := c.GetString(string(helper.RequestIDKey))
+	c.JSON(bizErr.StatusCode, gin.H{
+		"error": &model.Error{
+			Message: helper.MessageWithRequestID(bizErr.Message, requestID),
+			Code:    bizErr.Code,
+			Param:   bizErr.Param,
+			Type:    bizErr.Type,
+		},
+	})
+}
@@ -170,11 +170,14 @@ func validateToken(token AddTokenRequest) error {
 }
 
 type AddTokenRequest struct {
-	Name      string   `json:"name"`
-	Subnet    string   `json:"subnet"`
-	Models    []string `json:"models"`
-	ExpiredAt int64    `json:"expiredAt"`
-	Quota     float64  `json:"quota"`
+	Name          string   `json:"name"`
+	Subnet        string   `json:"subnet"`
+	Models        []string `json:"models"`
+	ExpiredAt     int64    `json:"expiredAt"`
+	Quota         float64  `json:"quota"`
+	TPM           int64    `json:"tpm"`
+	Concurrency   int64    `json:"concurrency"`
+	ResponseCache bool     `json:"response_cache"`
 }
 
 func AddToken(c *gin.Context) {
@@ -199,13 +202,16 @@ func AddToken(c *gin.Context) {
 	}
 
 	cleanToken := &model.Token{
-		GroupID:   group,
-		Name:      model.EmptyNullString(token.Name),
-		Key:       random.GenerateKey(),
-		ExpiredAt: expiredAt,
-		Quota:     token.Quota,
-		Models:    token.Models,
-		Subnet:    token.Subnet,
+		GroupID:       group,
+		Name:          model.EmptyNullString(token.Name),
+		Key:           random.GenerateKey(),
+		ExpiredAt:     expiredAt,
+		Quota:         token.Quota,
+		Models:        token.Models,
+		Subnet:        token.Subnet,
+		TPM:           token.TPM,
+		Concurrency:   token.Concurrency,
+		ResponseCache: token.ResponseCache,
 	}
 	err = model.InsertToken(cleanToken, c.Query("auto_create_group") == "true")
 	if err != nil {
@@ -306,6 +312,9 @@ func UpdateToken(c *gin.Context) {
 	cleanToken.Quota = token.Quota
 	cleanToken.Models = token.Models
 	cleanToken.Subnet = token.Subnet
+	cleanToken.TPM = token.TPM
+	cleanToken.Concurrency = token.Concurrency
+	cleanToken.ResponseCache = token.ResponseCache
 	err = model.UpdateToken(cleanToken)
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusOK, err.Error())
@@ -346,6 +355,9 @@ func UpdateGroupToken(c *gin.Context) {
 	cleanToken.Quota = token.Quota
 	cleanToken.Models = token.Models
 	cleanToken.Subnet = token.Subnet
+	cleanToken.TPM = token.TPM
+	cleanToken.Concurrency = token.Concurrency
+	cleanToken.ResponseCache = token.ResponseCache
 	err = model.UpdateToken(cleanToken)
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusOK, err.Error())
@@ -19,6 +19,8 @@ import (
 	"github.com/labring/sealos/service/aiproxy/common/balance"
 	"github.com/labring/sealos/service/aiproxy/common/client"
 	"github.com/labring/sealos/service/aiproxy/common/config"
+	"github.com/labring/sealos/service/aiproxy/common/filestore"
+	"github.com/labring/sealos/service/aiproxy/controller"
 	"github.com/labring/sealos/service/aiproxy/middleware"
 	"github.com/labring/sealos/service/aiproxy/model"
 	relaycontroller "github.com/labring/sealos/service/aiproxy/relay/controller"
@@ -44,7 +46,7 @@ func initializeServices() error {
 	}
 
 	client.Init()
-	return nil
+	return filestore.Init()
 }
 
 func initializeBalance() error {
@@ -115,10 +117,12 @@ func initializeCaches() error {
 }
 
 func startSyncServices(ctx context.Context, wg *sync.WaitGroup) {
-	wg.Add(3)
+	wg.Add(5)
 	go model.SyncOptions(ctx, wg, time.Second*5)
 	go model.SyncChannelCache(ctx, wg, time.Second*5)
 	go model.SyncModelConfigCache(ctx, wg, time.Second*5)
+	go relaycontroller.SyncBatches(ctx, wg, time.Minute)
+	go controller.ExportDailyUsage(ctx, wg, time.Hour)
 }
 
 func setupHTTPServer() (*http.Server, *gin.Engine) {
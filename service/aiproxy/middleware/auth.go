@@ -49,6 +49,10 @@ func TokenAuth(c *gin.Context) {
 	log := GetLogger(c)
 	ctx := c.Request.Context()
 	key := c.Request.Header.Get("Authorization")
+	if key == "" {
+		// anthropic sdk
+		key = c.Request.Header.Get("X-Api-Key")
+	}
 	key = strings.TrimPrefix(
 		strings.TrimPrefix(key, "Bearer "),
 		"sk-",
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"slices"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/labring/sealos/service/aiproxy/common/config"
@@ -55,6 +57,13 @@ func Distribute(c *gin.Context) {
 		return
 	}
 
+	group := c.MustGet(ctxkey.Group).(*model.GroupCache)
+	release, ok := checkConcurrencyLimit(c, group, token, requestModel)
+	if !ok {
+		return
+	}
+	defer release()
+
 	c.Set(string(ctxkey.OriginalModel), requestModel)
 	ctx := context.WithValue(c.Request.Context(), ctxkey.OriginalModel, requestModel)
 	c.Request = c.Request.WithContext(ctx)
@@ -63,6 +72,51 @@ func Distribute(c *gin.Context) {
 	c.Next()
 }
 
+const concurrencyRetryAfter = time.Second
+
+func checkConcurrencyLimit(c *gin.Context, group *model.GroupCache, token *model.TokenCache, requestModel string) (func(), bool) {
+	ctx := c.Request.Context()
+	releases := make([]func(), 0, 3)
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+
+	r, ok := ForceConcurrencyLimit(ctx, "group_concurrency:"+group.ID, group.Concurrency)
+	if !ok {
+		abortWithRateLimit(c, concurrencyRetryAfter,
+			fmt.Sprintf("group (%s) has too many concurrent requests, limit: %d", group.ID, group.Concurrency),
+		)
+		return nil, false
+	}
+	releases = append(releases, r)
+
+	r, ok = ForceConcurrencyLimit(ctx, "token_concurrency:"+strconv.Itoa(token.ID), token.Concurrency)
+	if !ok {
+		release()
+		abortWithRateLimit(c, concurrencyRetryAfter,
+			fmt.Sprintf("token (%s[%d]) has too many concurrent requests, limit: %d", token.Name, token.ID, token.Concurrency),
+		)
+		return nil, false
+	}
+	releases = append(releases, r)
+
+	if modelConfig, exists := model.CacheGetModelConfig(requestModel); exists {
+		r, ok = ForceConcurrencyLimit(ctx, "model_concurrency:"+requestModel, modelConfig.Concurrency)
+		if !ok {
+			release()
+			abortWithRateLimit(c, concurrencyRetryAfter,
+				fmt.Sprintf("model %s has too many concurrent requests, limit: %d", requestModel, modelConfig.Concurrency),
+			)
+			return nil, false
+		}
+		releases = append(releases, r)
+	}
+
+	return release, true
+}
+
 func NewMetaByContext(c *gin.Context) *meta.Meta {
 	channel := c.MustGet(ctxkey.Channel).(*model.Channel)
 	originalModel := c.MustGet(string(ctxkey.OriginalModel)).(string)
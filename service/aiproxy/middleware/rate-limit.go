@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -99,3 +100,162 @@ func GlobalAPIRateLimit(c *gin.Context) {
 	}
 	c.Next()
 }
+
+var (
+	inMemoryTokenRateLimiter   common.InMemoryTokenRateLimiter
+	inMemoryConcurrencyLimiter common.InMemoryConcurrencyLimiter
+)
+
+const ErrCodeRateLimitExceeded = "rate_limit_exceeded"
+
+// 1. 使用两个固定窗口近似滑动窗口，上一个窗口的token数按剩余时间比例计入
+// 2. 如果窗口内没有token，直接放行，避免单个超大请求永远无法通过
+// 3. 放行时先按预估token数记入当前窗口，请求结束后再修正
+var tokenRateLimitScript = `
+local current_key = KEYS[1]
+local previous_key = KEYS[2]
+local max_tokens = tonumber(ARGV[1])
+local tokens = tonumber(ARGV[2])
+local weight = tonumber(ARGV[3])
+local window = tonumber(ARGV[4])
+
+local current = tonumber(redis.call('GET', current_key) or '0')
+local previous = tonumber(redis.call('GET', previous_key) or '0')
+local used = math.floor(previous * weight) + current
+
+if used > 0 and used + tokens > max_tokens then
+    return 0
+end
+redis.call('INCRBY', current_key, tokens)
+redis.call('PEXPIRE', current_key, window * 2)
+return 1
+`
+
+var adjustTokenRateLimitScript = `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return 0
+end
+local count = redis.call('INCRBY', KEYS[1], ARGV[1])
+if count < 0 then
+    redis.call('SET', KEYS[1], 0, 'KEEPTTL')
+end
+return 1
+`
+
+func tokenRateLimitBucketKey(key string, bucket int64) string {
+	return fmt.Sprintf("%s:%d", key, bucket)
+}
+
+func redisTokenRateLimitRequest(ctx context.Context, key string, maxTokens int64, tokens int64, duration time.Duration) (bool, int64, time.Duration, error) {
+	bucket, weight, retryAfter := common.SlidingWindowBucket(time.Now(), duration)
+	result, err := common.RDB.Eval(ctx, tokenRateLimitScript,
+		[]string{tokenRateLimitBucketKey(key, bucket), tokenRateLimitBucketKey(key, bucket-1)},
+		maxTokens, tokens, weight, duration.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return false, bucket, 0, err
+	}
+	if result != 1 {
+		return false, bucket, retryAfter, nil
+	}
+	return true, bucket, 0, nil
+}
+
+// TokenRateLimitReservation is the tokens reserved by ForceTokenRateLimit,
+// call Settle with the actual tokens once the response is done
+type TokenRateLimitReservation struct {
+	key    string
+	bucket int64
+	tokens int64
+	redis  bool
+}
+
+// ForceTokenRateLimit reserves tokens for key, ignore redis error
+func ForceTokenRateLimit(ctx context.Context, key string, maxTokens int64, tokens int64, duration time.Duration) (*TokenRateLimitReservation, bool, time.Duration) {
+	if maxTokens <= 0 {
+		return nil, true, 0
+	}
+	if common.RedisEnabled {
+		ok, bucket, retryAfter, err := redisTokenRateLimitRequest(ctx, key, maxTokens, tokens, duration)
+		if err == nil {
+			if !ok {
+				return nil, false, retryAfter
+			}
+			return &TokenRateLimitReservation{key: key, bucket: bucket, tokens: tokens, redis: true}, true, 0
+		}
+		log.Error("token rate limit error: " + err.Error())
+	}
+	inMemoryTokenRateLimiter.Init(config.RateLimitKeyExpirationDuration)
+	ok, bucket, retryAfter := inMemoryTokenRateLimiter.Request(key, maxTokens, tokens, duration)
+	if !ok {
+		return nil, false, retryAfter
+	}
+	return &TokenRateLimitReservation{key: key, bucket: bucket, tokens: tokens}, true, 0
+}
+
+// Settle corrects the reserved tokens to the actual tokens
+func (r *TokenRateLimitReservation) Settle(ctx context.Context, tokens int64) {
+	if r == nil || tokens == r.tokens {
+		return
+	}
+	delta := tokens - r.tokens
+	r.tokens = tokens
+	if r.redis {
+		err := common.RDB.Eval(ctx, adjustTokenRateLimitScript, []string{tokenRateLimitBucketKey(r.key, r.bucket)}, delta).Err()
+		if err == nil {
+			return
+		}
+		log.Error("adjust token rate limit error: " + err.Error())
+		return
+	}
+	inMemoryTokenRateLimiter.Adjust(r.key, r.bucket, delta)
+}
+
+var concurrencyLimitScript = `
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+if count >= tonumber(ARGV[1]) then
+    return 0
+end
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`
+
+var releaseConcurrencyScript = `
+local count = redis.call('DECR', KEYS[1])
+if count <= 0 then
+    redis.call('DEL', KEYS[1])
+end
+return 1
+`
+
+// the counter of a crashed instance will be released after this duration
+const concurrencyKeyExpiration = 10 * time.Minute
+
+// ForceConcurrencyLimit acquires a slot of key, ignore redis error.
+// The returned function must be called to release the slot once the request is done.
+func ForceConcurrencyLimit(ctx context.Context, key string, maxConcurrency int64) (func(), bool) {
+	if maxConcurrency <= 0 {
+		return func() {}, true
+	}
+	if common.RedisEnabled {
+		result, err := common.RDB.Eval(ctx, concurrencyLimitScript, []string{key}, maxConcurrency, concurrencyKeyExpiration.Milliseconds()).Int64()
+		if err == nil {
+			if result != 1 {
+				return nil, false
+			}
+			return func() {
+				if err := common.RDB.Eval(context.Background(), releaseConcurrencyScript, []string{key}).Err(); err != nil {
+					log.Error("release concurrency error: " + err.Error())
+				}
+			}, true
+		}
+		log.Error("concurrency limit error: " + err.Error())
+	}
+	if !inMemoryConcurrencyLimiter.Acquire(key, maxConcurrency) {
+		return nil, false
+	}
+	return func() {
+		inMemoryConcurrencyLimiter.Release(key)
+	}, true
+}
@@ -2,7 +2,11 @@ package middleware
 
 import (
 	"fmt"
+	"math"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/labring/sealos/service/aiproxy/common"
@@ -25,6 +29,28 @@ func abortWithMessage(c *gin.Context, statusCode int, message string) {
 	c.Abort()
 }
 
+func abortWithRateLimit(c *gin.Context, retryAfter time.Duration, message string) {
+	GetLogger(c).Error(message)
+	SetRetryAfter(c, retryAfter)
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": &model.Error{
+			Message: helper.MessageWithRequestID(message, c.GetString(string(helper.RequestIDKey))),
+			Type:    ErrorTypeAIPROXY,
+			Code:    ErrCodeRateLimitExceeded,
+		},
+	})
+	c.Abort()
+}
+
+// SetRetryAfter sets the Retry-After header in seconds, at least 1 second
+func SetRetryAfter(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
+
 func getRequestModel(c *gin.Context) (string, error) {
 	path := c.Request.URL.Path
 	switch {
@@ -0,0 +1,170 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+const (
+	ErrBatchNotFound = "batch"
+)
+
+// https://platform.openai.com/docs/api-reference/batch/object
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// Batch is a batch created on an upstream channel, the input and the collected results are aiproxy files
+type Batch struct {
+	CreatedAt            time.Time          `gorm:"index"                                    json:"created_at"`
+	Metadata             map[string]string  `gorm:"serializer:fastjson;type:text"            json:"metadata,omitempty"`
+	Errors               any                `gorm:"serializer:fastjson;type:text"            json:"errors,omitempty"`
+	ID                   string             `gorm:"primaryKey"                               json:"id"`
+	GroupID              string             `gorm:"index"                                    json:"-"`
+	TokenName            string             `json:"-"`
+	Model                string             `gorm:"index"                                    json:"model"`
+	Endpoint             string             `json:"endpoint"`
+	CompletionWindow     string             `json:"completion_window"`
+	Status               string             `gorm:"index"                                    json:"status"`
+	InputFileID          string             `json:"input_file_id"`
+	OutputFileID         string             `json:"output_file_id,omitempty"`
+	ErrorFileID          string             `json:"error_file_id,omitempty"`
+	UpstreamID           string             `json:"-"`
+	UpstreamOutputFileID string             `json:"-"`
+	UpstreamErrorFileID  string             `json:"-"`
+	RequestCounts        BatchRequestCounts `gorm:"embedded;embeddedPrefix:request_counts_" json:"request_counts"`
+	InProgressAt         int64              `json:"in_progress_at,omitempty"`
+	ExpiresAt            int64              `json:"expires_at,omitempty"`
+	FinalizingAt         int64              `json:"finalizing_at,omitempty"`
+	CompletedAt          int64              `json:"completed_at,omitempty"`
+	FailedAt             int64              `json:"failed_at,omitempty"`
+	ExpiredAt            int64              `json:"expired_at,omitempty"`
+	CancellingAt         int64              `json:"cancelling_at,omitempty"`
+	CancelledAt          int64              `json:"cancelled_at,omitempty"`
+	PromptTokens         int                `json:"-"`
+	CompletionTokens     int                `json:"-"`
+	ChannelID            int                `gorm:"index"                                    json:"-"`
+	TokenID              int                `gorm:"index"                                    json:"-"`
+	// the results are collected and billed once the batch is terminal
+	Collected bool `gorm:"index" json:"-"`
+}
+
+// MarshalJSON renders the openai batch object
+func (b *Batch) MarshalJSON() ([]byte, error) {
+	type Alias Batch
+	return json.Marshal(&struct {
+		*Alias
+		Object    string `json:"object"`
+		CreatedAt int64  `json:"created_at"`
+	}{
+		Alias:     (*Alias)(b),
+		Object:    "batch",
+		CreatedAt: b.CreatedAt.Unix(),
+	})
+}
+
+func (b *Batch) IsTerminal() bool {
+	switch b.Status {
+	case BatchStatusFailed, BatchStatusCompleted, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+func CreateBatch(batch *Batch) error {
+	return DB.Create(batch).Error
+}
+
+func GetGroupBatchByID(group string, id string) (*Batch, error) {
+	if id == "" || group == "" {
+		return nil, errors.New("id or group is empty")
+	}
+	batch := Batch{}
+	err := DB.
+		Where("id = ? and group_id = ?", id, group).
+		First(&batch).Error
+	return &batch, HandleNotFound(err, ErrBatchNotFound)
+}
+
+// GetGroupBatches lists the batches created before the batch `after`, newest first
+func GetGroupBatches(group string, after string, limit int) (batches []*Batch, err error) {
+	tx := DB.Where("group_id = ?", group)
+	if after != "" {
+		afterBatch, err := GetGroupBatchByID(group, after)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where("created_at < ?", afterBatch.CreatedAt)
+	}
+	err = tx.Order("created_at desc").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+func GetUncollectedBatches(limit int) (batches []*Batch, err error) {
+	err = DB.
+		Where("collected = ?", false).
+		Order("created_at").
+		Limit(limit).
+		Find(&batches).Error
+	return batches, err
+}
+
+// UpdateBatchUpstreamStatus saves the status synced from the upstream batch
+func UpdateBatchUpstreamStatus(batch *Batch) error {
+	result := DB.
+		Model(batch).
+		Select(
+			"status",
+			"errors",
+			"upstream_output_file_id",
+			"upstream_error_file_id",
+			"request_counts_total",
+			"request_counts_completed",
+			"request_counts_failed",
+			"in_progress_at",
+			"expires_at",
+			"finalizing_at",
+			"completed_at",
+			"failed_at",
+			"expired_at",
+			"cancelling_at",
+			"cancelled_at",
+		).
+		Updates(batch)
+	return HandleUpdateResult(result, ErrBatchNotFound)
+}
+
+// CollectBatch marks the results of the batch as collected,
+// it returns false if they have already been collected by someone else
+func CollectBatch(batch *Batch) (bool, error) {
+	result := DB.
+		Model(&Batch{}).
+		Where("id = ? and collected = ?", batch.ID, false).
+		Updates(map[string]any{
+			"collected":         true,
+			"output_file_id":    batch.OutputFileID,
+			"error_file_id":     batch.ErrorFileID,
+			"prompt_tokens":     batch.PromptTokens,
+			"completion_tokens": batch.CompletionTokens,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
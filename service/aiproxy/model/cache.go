@@ -53,30 +53,36 @@ func (t redisTime) MarshalBinary() ([]byte, error) {
 }
 
 type TokenCache struct {
-	ExpiredAt  redisTime        `json:"expired_at"  redis:"e"`
-	Group      string           `json:"group"       redis:"g"`
-	Key        string           `json:"-"           redis:"-"`
-	Name       string           `json:"name"        redis:"n"`
-	Subnet     string           `json:"subnet"      redis:"s"`
-	Models     redisStringSlice `json:"models"      redis:"m"`
-	ID         int              `json:"id"          redis:"i"`
-	Status     int              `json:"status"      redis:"st"`
-	Quota      float64          `json:"quota"       redis:"q"`
-	UsedAmount float64          `json:"used_amount" redis:"u"`
+	ExpiredAt     redisTime        `json:"expired_at"     redis:"e"`
+	Group         string           `json:"group"          redis:"g"`
+	Key           string           `json:"-"              redis:"-"`
+	Name          string           `json:"name"           redis:"n"`
+	Subnet        string           `json:"subnet"         redis:"s"`
+	Models        redisStringSlice `json:"models"         redis:"m"`
+	ID            int              `json:"id"             redis:"i"`
+	Status        int              `json:"status"         redis:"st"`
+	Quota         float64          `json:"quota"          redis:"q"`
+	UsedAmount    float64          `json:"used_amount"    redis:"u"`
+	TPM           int64            `json:"tpm"            redis:"tp"`
+	Concurrency   int64            `json:"concurrency"    redis:"c"`
+	ResponseCache bool             `json:"response_cache" redis:"rc"`
 }
 
 func (t *Token) ToTokenCache() *TokenCache {
 	return &TokenCache{
-		ID:         t.ID,
-		Group:      t.GroupID,
-		Key:        t.Key,
-		Name:       t.Name.String(),
-		Models:     t.Models,
-		Subnet:     t.Subnet,
-		Status:     t.Status,
-		ExpiredAt:  redisTime(t.ExpiredAt),
-		Quota:      t.Quota,
-		UsedAmount: t.UsedAmount,
+		ID:            t.ID,
+		Group:         t.GroupID,
+		Key:           t.Key,
+		Name:          t.Name.String(),
+		Models:        t.Models,
+		Subnet:        t.Subnet,
+		Status:        t.Status,
+		ExpiredAt:     redisTime(t.ExpiredAt),
+		Quota:         t.Quota,
+		UsedAmount:    t.UsedAmount,
+		TPM:           t.TPM,
+		Concurrency:   t.Concurrency,
+		ResponseCache: t.ResponseCache,
 	}
 }
 
@@ -182,16 +188,22 @@ func CacheIncreaseTokenUsedAmount(key string, amount float64) error {
 }
 
 type GroupCache struct {
-	ID     string `json:"-"      redis:"-"`
-	Status int    `json:"status" redis:"st"`
-	QPM    int64  `json:"qpm"    redis:"q"`
+	ID            string `json:"-"              redis:"-"`
+	Status        int    `json:"status"         redis:"st"`
+	QPM           int64  `json:"qpm"            redis:"q"`
+	TPM           int64  `json:"tpm"            redis:"tp"`
+	Concurrency   int64  `json:"concurrency"    redis:"c"`
+	ResponseCache bool   `json:"response_cache" redis:"rc"`
 }
 
 func (g *Group) ToGroupCache() *GroupCache {
 	return &GroupCache{
-		ID:     g.ID,
-		Status: g.Status,
-		QPM:    g.QPM,
+		ID:            g.ID,
+		Status:        g.Status,
+		QPM:           g.QPM,
+		TPM:           g.TPM,
+		Concurrency:   g.Concurrency,
+		ResponseCache: g.ResponseCache,
 	}
 }
 
@@ -216,6 +228,48 @@ func CacheUpdateGroupQPM(id string, qpm int64) error {
 	return updateGroupQPMScript.Run(context.Background(), common.RDB, []string{fmt.Sprintf(GroupCacheKey, id)}, qpm).Err()
 }
 
+var updateGroupTPMScript = redis.NewScript(`
+	if redis.call("HExists", KEYS[1], "tp") == 1 then
+		redis.call("HSet", KEYS[1], "tp", ARGV[1])
+	end
+	return redis.status_reply("ok")
+`)
+
+func CacheUpdateGroupTPM(id string, tpm int64) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return updateGroupTPMScript.Run(context.Background(), common.RDB, []string{fmt.Sprintf(GroupCacheKey, id)}, tpm).Err()
+}
+
+var updateGroupConcurrencyScript = redis.NewScript(`
+	if redis.call("HExists", KEYS[1], "c") == 1 then
+		redis.call("HSet", KEYS[1], "c", ARGV[1])
+	end
+	return redis.status_reply("ok")
+`)
+
+func CacheUpdateGroupConcurrency(id string, concurrency int64) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return updateGroupConcurrencyScript.Run(context.Background(), common.RDB, []string{fmt.Sprintf(GroupCacheKey, id)}, concurrency).Err()
+}
+
+var updateGroupResponseCacheScript = redis.NewScript(`
+	if redis.call("HExists", KEYS[1], "rc") == 1 then
+		redis.call("HSet", KEYS[1], "rc", ARGV[1])
+	end
+	return redis.status_reply("ok")
+`)
+
+func CacheUpdateGroupResponseCache(id string, enabled bool) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return updateGroupResponseCacheScript.Run(context.Background(), common.RDB, []string{fmt.Sprintf(GroupCacheKey, id)}, enabled).Err()
+}
+
 var updateGroupStatusScript = redis.NewScript(`
 	if redis.call("HExists", KEYS[1], "status") then
 		redis.call("HSet", KEYS[1], "status", ARGV[1])
@@ -593,9 +647,20 @@ func SyncChannelCache(ctx context.Context, wg *sync.WaitGroup, frequency time.Du
 	}
 }
 
-//nolint:gosec
 func CacheGetRandomSatisfiedChannel(model string) (*Channel, error) {
+	return CacheGetRandomSatisfiedChannelWithFilter(model, nil)
+}
+
+// CacheGetRandomSatisfiedChannelWithFilter only picks the channels accepted by the filter
+//
+//nolint:gosec
+func CacheGetRandomSatisfiedChannelWithFilter(model string, filter func(*Channel) bool) (*Channel, error) {
 	channels := GetEnabledModel2Channels()[model]
+	if filter != nil {
+		channels = slices.DeleteFunc(slices.Clone(channels), func(ch *Channel) bool {
+			return !filter(ch)
+		})
+	}
 	if len(channels) == 0 {
 		return nil, errors.New("model not found")
 	}
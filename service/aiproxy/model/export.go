@@ -0,0 +1,168 @@
+package model
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExportBatchSize is the number of logs loaded per page when exporting
+const ExportBatchSize = 1000
+
+// IterateLogs pages through the logs of a group in [startTimestamp, endTimestamp) ordered by request time,
+// an empty group iterates over all groups. Keyset pagination on (request_at, id) is used,
+// so a group export is served by the idx_group_reqat_token index and the memory use is bounded by one page
+func IterateLogs(group string, tokenName string, startTimestamp time.Time, endTimestamp time.Time, fn func(logs []*Log) error) error {
+	var (
+		lastRequestAt time.Time
+		lastID        int
+	)
+	for {
+		tx := LogDB.Model(&Log{})
+		if group != "" {
+			tx = tx.Where("group_id = ?", group)
+		}
+		tx = tx.Where("request_at >= ? AND request_at < ?", startTimestamp, endTimestamp)
+		if tokenName != "" {
+			tx = tx.Where("token_name = ?", tokenName)
+		}
+		if lastID != 0 {
+			tx = tx.Where("request_at > ? OR (request_at = ? AND id > ?)", lastRequestAt, lastRequestAt, lastID)
+		}
+		var logs []*Log
+		err := tx.
+			Order("request_at asc, id asc").
+			Limit(ExportBatchSize).
+			Find(&logs).Error
+		if err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+		if err := fn(logs); err != nil {
+			return err
+		}
+		if len(logs) < ExportBatchSize {
+			return nil
+		}
+		last := logs[len(logs)-1]
+		lastRequestAt = last.RequestAt
+		lastID = last.ID
+	}
+}
+
+// DailyUsage is the usage of a token of a group on a model in one day
+type DailyUsage struct {
+	Date             string  `json:"date"`
+	GroupID          string  `json:"group"`
+	TokenName        string  `json:"token_name"`
+	Model            string  `json:"model"`
+	Requests         int64   `json:"requests"`
+	FailedRequests   int64   `json:"failed_requests"`
+	CacheHitRequests int64   `json:"cache_hit_requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	UsedAmount       float64 `json:"used_amount"`
+}
+
+type dailyUsageKey struct {
+	group     string
+	tokenName string
+	model     string
+}
+
+type dailyUsageAggregator struct {
+	date    string
+	usages  map[dailyUsageKey]*DailyUsage
+	amounts map[dailyUsageKey]decimal.Decimal
+	fn      func(usage *DailyUsage) error
+}
+
+func (a *dailyUsageAggregator) add(date string, log *Log) error {
+	if date != a.date {
+		if err := a.flush(); err != nil {
+			return err
+		}
+		a.date = date
+	}
+	key := dailyUsageKey{
+		group:     log.GroupID,
+		tokenName: log.TokenName,
+		model:     log.Model,
+	}
+	usage, ok := a.usages[key]
+	if !ok {
+		usage = &DailyUsage{
+			Date:      date,
+			GroupID:   log.GroupID,
+			TokenName: log.TokenName,
+			Model:     log.Model,
+		}
+		a.usages[key] = usage
+	}
+	usage.Requests++
+	if log.Code != http.StatusOK {
+		usage.FailedRequests++
+	}
+	if log.CacheHit {
+		usage.CacheHitRequests++
+	}
+	usage.PromptTokens += int64(log.PromptTokens)
+	usage.CompletionTokens += int64(log.CompletionTokens)
+	usage.TotalTokens += int64(log.PromptTokens + log.CompletionTokens)
+	a.amounts[key] = a.amounts[key].Add(decimal.NewFromFloat(log.UsedAmount))
+	return nil
+}
+
+// flush emits the usages of the current day sorted by group, token and model
+func (a *dailyUsageAggregator) flush() error {
+	usages := make([]*DailyUsage, 0, len(a.usages))
+	for key, usage := range a.usages {
+		usage.UsedAmount = a.amounts[key].InexactFloat64()
+		usages = append(usages, usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].GroupID != usages[j].GroupID {
+			return usages[i].GroupID < usages[j].GroupID
+		}
+		if usages[i].TokenName != usages[j].TokenName {
+			return usages[i].TokenName < usages[j].TokenName
+		}
+		return usages[i].Model < usages[j].Model
+	})
+	for _, usage := range usages {
+		if err := a.fn(usage); err != nil {
+			return err
+		}
+	}
+	clear(a.usages)
+	clear(a.amounts)
+	return nil
+}
+
+// IterateDailyUsage aggregates the logs into group × token × model × day usages,
+// the days are split in the given location. The logs are read in request time order,
+// so only the usages of one day are held in memory
+func IterateDailyUsage(group string, tokenName string, startTimestamp time.Time, endTimestamp time.Time, loc *time.Location, fn func(usage *DailyUsage) error) error {
+	aggregator := &dailyUsageAggregator{
+		usages:  make(map[dailyUsageKey]*DailyUsage),
+		amounts: make(map[dailyUsageKey]decimal.Decimal),
+		fn:      fn,
+	}
+	err := IterateLogs(group, tokenName, startTimestamp, endTimestamp, func(logs []*Log) error {
+		for _, log := range logs {
+			if err := aggregator.add(log.RequestAt.In(loc).Format(time.DateOnly), log); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return aggregator.flush()
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	json "github.com/json-iterator/go"
+)
+
+const (
+	ErrFileNotFound = "file"
+)
+
+const (
+	FilePurposeBatch       = "batch"
+	FilePurposeBatchOutput = "batch_output"
+)
+
+// File is a file uploaded by the files api or collected from a batch, the content lives in the file store
+type File struct {
+	CreatedAt  time.Time `gorm:"index"                     json:"created_at"`
+	ID         string    `gorm:"primaryKey"                json:"id"`
+	GroupID    string    `gorm:"index"                     json:"-"`
+	Filename   string    `json:"filename"`
+	Purpose    string    `gorm:"index"                     json:"purpose"`
+	StorageKey string    `json:"-"`
+	Bytes      int64     `json:"bytes"`
+	TokenID    int       `gorm:"index"                     json:"-"`
+}
+
+// MarshalJSON renders the openai file object
+func (f *File) MarshalJSON() ([]byte, error) {
+	type Alias File
+	return json.Marshal(&struct {
+		*Alias
+		Object    string `json:"object"`
+		CreatedAt int64  `json:"created_at"`
+	}{
+		Alias:     (*Alias)(f),
+		Object:    "file",
+		CreatedAt: f.CreatedAt.Unix(),
+	})
+}
+
+func CreateFile(file *File) error {
+	return DB.Create(file).Error
+}
+
+func GetGroupFileByID(group string, id string) (*File, error) {
+	if id == "" || group == "" {
+		return nil, errors.New("id or group is empty")
+	}
+	file := File{}
+	err := DB.
+		Where("id = ? and group_id = ?", id, group).
+		First(&file).Error
+	return &file, HandleNotFound(err, ErrFileNotFound)
+}
+
+func GetGroupFiles(group string, purpose string, limit int) (files []*File, err error) {
+	tx := DB.Where("group_id = ?", group)
+	if purpose != "" {
+		tx = tx.Where("purpose = ?", purpose)
+	}
+	err = tx.Order("created_at desc").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+func DeleteGroupFileByID(group string, id string) error {
+	if id == "" || group == "" {
+		return errors.New("id or group is empty")
+	}
+	result := DB.
+		Where("id = ? and group_id = ?", id, group).
+		Delete(&File{})
+	return HandleUpdateResult(result, ErrFileNotFound)
+}
+
+func FileStorageKey(group string, id string) string {
+	return "files/" + group + "/" + id
+}
@@ -24,14 +24,17 @@ const (
 )
 
 type Group struct {
-	CreatedAt    time.Time `json:"created_at"`
-	AccessedAt   time.Time `json:"accessed_at"`
-	ID           string    `gorm:"primaryKey"         json:"id"`
-	Tokens       []*Token  `gorm:"foreignKey:GroupID" json:"-"`
-	Status       int       `gorm:"default:1;index"    json:"status"`
-	UsedAmount   float64   `gorm:"index"              json:"used_amount"`
-	QPM          int64     `gorm:"index"              json:"qpm"`
-	RequestCount int       `gorm:"index"              json:"request_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	AccessedAt    time.Time `json:"accessed_at"`
+	ID            string    `gorm:"primaryKey"         json:"id"`
+	Tokens        []*Token  `gorm:"foreignKey:GroupID" json:"-"`
+	Status        int       `gorm:"default:1;index"    json:"status"`
+	UsedAmount    float64   `gorm:"index"              json:"used_amount"`
+	QPM           int64     `gorm:"index"              json:"qpm"`
+	TPM           int64     `gorm:"index"              json:"tpm"`
+	Concurrency   int64     `json:"concurrency"`
+	ResponseCache bool      `json:"response_cache"`
+	RequestCount  int       `gorm:"index"              json:"request_count"`
 }
 
 func (g *Group) BeforeDelete(tx *gorm.DB) (err error) {
@@ -180,6 +183,42 @@ func UpdateGroupQPM(id string, qpm int64) (err error) {
 	return HandleUpdateResult(result, ErrGroupNotFound)
 }
 
+func UpdateGroupTPM(id string, tpm int64) (err error) {
+	defer func() {
+		if err == nil {
+			if err := CacheUpdateGroupTPM(id, tpm); err != nil {
+				log.Error("cache update group tpm failed: " + err.Error())
+			}
+		}
+	}()
+	result := DB.Model(&Group{}).Where("id = ?", id).Update("tpm", tpm)
+	return HandleUpdateResult(result, ErrGroupNotFound)
+}
+
+func UpdateGroupConcurrency(id string, concurrency int64) (err error) {
+	defer func() {
+		if err == nil {
+			if err := CacheUpdateGroupConcurrency(id, concurrency); err != nil {
+				log.Error("cache update group concurrency failed: " + err.Error())
+			}
+		}
+	}()
+	result := DB.Model(&Group{}).Where("id = ?", id).Update("concurrency", concurrency)
+	return HandleUpdateResult(result, ErrGroupNotFound)
+}
+
+func UpdateGroupResponseCache(id string, enabled bool) (err error) {
+	defer func() {
+		if err == nil {
+			if err := CacheUpdateGroupResponseCache(id, enabled); err != nil {
+				log.Error("cache update group response cache failed: " + err.Error())
+			}
+		}
+	}()
+	result := DB.Model(&Group{}).Where("id = ?", id).Update("response_cache", enabled)
+	return HandleUpdateResult(result, ErrGroupNotFound)
+}
+
 func UpdateGroupStatus(id string, status int) (err error) {
 	defer func() {
 		if err == nil {
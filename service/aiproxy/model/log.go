@@ -42,6 +42,7 @@ type Log struct {
 	ChannelID        int            `gorm:"index"                                                                                                                  json:"channel"`
 	Code             int            `gorm:"index"                                                                                                                  json:"code"`
 	Mode             int            `json:"mode"`
+	CacheHit         bool           `json:"cache_hit"`
 }
 
 func (l *Log) MarshalJSON() ([]byte, error) {
@@ -74,6 +75,7 @@ func RecordConsumeLog(
 	endpoint string,
 	content string,
 	mode int,
+	cacheHit bool,
 	requestDetail *RequestDetail,
 ) error {
 	defer func() {
@@ -106,6 +108,7 @@ func RecordConsumeLog(
 		ChannelID:        channelID,
 		Endpoint:         endpoint,
 		Content:          content,
+		CacheHit:         cacheHit,
 		RequestDetail:    requestDetail,
 	}
 	return LogDB.Create(log).Error
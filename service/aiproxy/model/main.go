@@ -130,6 +130,8 @@ func migrateDB() error {
 		&Group{},
 		&Option{},
 		&ModelConfig{},
+		&File{},
+		&Batch{},
 	)
 	if err != nil {
 		return err
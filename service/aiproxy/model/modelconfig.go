@@ -71,6 +71,12 @@ type ModelConfig struct {
 	Type        int     `json:"type"`
 	InputPrice  float64 `json:"input_price"`
 	OutputPrice float64 `json:"output_price"`
+	// prices of the batch api, 0 means the same as the normal prices
+	BatchInputPrice  float64 `json:"batch_input_price"`
+	BatchOutputPrice float64 `json:"batch_output_price"`
+	// limits shared by all groups, 0 means no limit
+	TPM         int64 `json:"tpm"`
+	Concurrency int64 `json:"concurrency"`
 }
 
 func (c *ModelConfig) MarshalJSON() ([]byte, error) {
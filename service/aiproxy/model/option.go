@@ -47,6 +47,9 @@ func InitOptionMap() error {
 	config.OptionMap["GeminiSafetySetting"] = config.GetGeminiSafetySetting()
 	config.OptionMap["GeminiVersion"] = config.GetGeminiVersion()
 	config.OptionMap["GroupMaxTokenNum"] = strconv.FormatInt(int64(config.GetGroupMaxTokenNum()), 10)
+	config.OptionMap["ResponseCacheTTL"] = strconv.FormatInt(config.GetResponseCacheTTL(), 10)
+	config.OptionMap["ResponseCacheMaxSize"] = strconv.FormatInt(config.GetResponseCacheMaxSize(), 10)
+	config.OptionMap["ResponseCachePriceRatio"] = strconv.FormatFloat(config.GetResponseCachePriceRatio(), 'f', -1, 64)
 	config.OptionMapRWMutex.Unlock()
 	err := loadOptionsFromDatabase(true)
 	if err != nil {
@@ -224,6 +227,27 @@ func updateOptionMap(key string, value string, isInit bool) (err error) {
 			return err
 		}
 		config.SetDefaultChannelModelMapping(newMapping)
+	case "ResponseCacheTTL":
+		ttl, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		config.SetResponseCacheTTL(ttl)
+	case "ResponseCacheMaxSize":
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		config.SetResponseCacheMaxSize(size)
+	case "ResponseCachePriceRatio":
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		if ratio < 0 || ratio > 1 {
+			return errors.New("response cache price ratio must be between 0 and 1")
+		}
+		config.SetResponseCachePriceRatio(ratio)
 	case "RetryTimes":
 		retryTimes, err := strconv.ParseInt(value, 10, 32)
 		if err != nil {
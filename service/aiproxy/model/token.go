@@ -41,6 +41,10 @@ type Token struct {
 	Quota        float64         `json:"quota"`
 	UsedAmount   float64         `gorm:"index"                                     json:"used_amount"`
 	RequestCount int             `gorm:"index"                                     json:"request_count"`
+	TPM          int64           `json:"tpm"`
+	Concurrency  int64           `json:"concurrency"`
+	// cache the responses of deterministic requests
+	ResponseCache bool `json:"response_cache"`
 }
 
 func (t *Token) MarshalJSON() ([]byte, error) {
@@ -58,6 +58,7 @@ func BatchRecordConsume(
 	endpoint string,
 	content string,
 	mode int,
+	cacheHit bool,
 	requestDetail *RequestDetail,
 ) error {
 	errs := []error{}
@@ -78,6 +79,7 @@ func BatchRecordConsume(
 		endpoint,
 		content,
 		mode,
+		cacheHit,
 		requestDetail,
 	)
 	if err != nil {
@@ -11,6 +11,7 @@ import (
 	"github.com/labring/sealos/service/aiproxy/model"
 	"github.com/labring/sealos/service/aiproxy/relay/meta"
 	relaymodel "github.com/labring/sealos/service/aiproxy/relay/model"
+	"github.com/labring/sealos/service/aiproxy/relay/relaymode"
 	"github.com/labring/sealos/service/aiproxy/relay/utils"
 )
 
@@ -33,6 +34,13 @@ func (a *Adaptor) SetupRequestHeader(meta *meta.Meta, c *gin.Context, req *http.
 		anthropicVersion = "2023-06-01"
 	}
 	req.Header.Set("Anthropic-Version", anthropicVersion)
+	if meta.Mode == relaymode.Anthropic {
+		// the native client knows which beta features it needs
+		if anthropicBeta := c.Request.Header.Get("Anthropic-Beta"); anthropicBeta != "" {
+			req.Header.Set("Anthropic-Beta", anthropicBeta)
+		}
+		return nil
+	}
 	req.Header.Set("Anthropic-Beta", "messages-2023-12-15")
 
 	// https://x.com/alexalbert__/status/1812921642143900036
@@ -45,6 +53,18 @@ func (a *Adaptor) SetupRequestHeader(meta *meta.Meta, c *gin.Context, req *http.
 }
 
 func (a *Adaptor) ConvertRequest(meta *meta.Meta, req *http.Request) (http.Header, io.Reader, error) {
+	if meta.Mode == relaymode.Anthropic {
+		data, err := ConvertNativeRequest(meta, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		data2, err := json.Marshal(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, bytes.NewReader(data2), nil
+	}
+
 	data, err := ConvertRequest(meta, req)
 	if err != nil {
 		return nil, nil, err
@@ -62,6 +82,14 @@ func (a *Adaptor) DoRequest(_ *meta.Meta, _ *gin.Context, req *http.Request) (*h
 }
 
 func (a *Adaptor) DoResponse(meta *meta.Meta, c *gin.Context, resp *http.Response) (usage *relaymodel.Usage, err *relaymodel.ErrorWithStatusCode) {
+	if meta.Mode == relaymode.Anthropic {
+		if utils.IsStreamResponse(resp) {
+			err, usage = NativeStreamHandler(meta, c, resp)
+		} else {
+			err, usage = NativeHandler(meta, c, resp)
+		}
+		return
+	}
 	if utils.IsStreamResponse(resp) {
 		err, usage = StreamHandler(meta, c, resp)
 	} else {
@@ -70,6 +98,10 @@ func (a *Adaptor) DoResponse(meta *meta.Meta, c *gin.Context, resp *http.Respons
 	return
 }
 
+func (a *Adaptor) SupportAnthropicNative(_ *meta.Meta) bool {
+	return true
+}
+
 func (a *Adaptor) GetModelList() []*model.ModelConfig {
 	return ModelList
 }
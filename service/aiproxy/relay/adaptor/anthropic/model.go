@@ -10,6 +10,7 @@ type ImageSource struct {
 	Type      string `json:"type"`
 	MediaType string `json:"media_type"`
 	Data      string `json:"data"`
+	URL       string `json:"url,omitempty"`
 }
 
 type Content struct {
@@ -56,8 +57,10 @@ type Request struct {
 }
 
 type Usage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
 type Error struct {
@@ -93,3 +96,60 @@ type StreamResponse struct {
 	Type         string    `json:"type"`
 	Index        int       `json:"index"`
 }
+
+// MessagesRequest is the request of the native messages api,
+// the system prompt and the message contents can be either a string or a list of blocks
+type MessagesRequest struct {
+	ToolChoice    *ToolChoice        `json:"tool_choice,omitempty"`
+	Metadata      *Metadata          `json:"metadata,omitempty"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	System        any                `json:"system,omitempty"`
+	Model         string             `json:"model"`
+	Messages      []*MessagesMessage `json:"messages"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Tools         []Tool             `json:"tools,omitempty"`
+	MaxTokens     int                `json:"max_tokens"`
+	TopK          int                `json:"top_k,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+}
+
+type MessagesMessage struct {
+	Content any    `json:"content"`
+	Role    string `json:"role"`
+}
+
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// ContentBlock is a block of the native messages api, the content of a tool_result can be a string or a list of blocks
+type ContentBlock struct {
+	Input     any          `json:"input,omitempty"`
+	Content   any          `json:"content,omitempty"`
+	Source    *ImageSource `json:"source,omitempty"`
+	Type      string       `json:"type"`
+	Text      string       `json:"text,omitempty"`
+	ID        string       `json:"id,omitempty"`
+	Name      string       `json:"name,omitempty"`
+	ToolUseID string       `json:"tool_use_id,omitempty"`
+	IsError   bool         `json:"is_error,omitempty"`
+}
+
+// MessagesResponse is the response of the native messages api
+type MessagesResponse struct {
+	StopReason   *string   `json:"stop_reason"`
+	StopSequence *string   `json:"stop_sequence"`
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	Role         string    `json:"role"`
+	Model        string    `json:"model"`
+	Content      []Content `json:"content"`
+	Usage        Usage     `json:"usage"`
+}
+
+type ErrorResponse struct {
+	Error Error  `json:"error"`
+	Type  string `json:"type"`
+}
@@ -0,0 +1,274 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	json "github.com/json-iterator/go"
+	"github.com/labring/sealos/service/aiproxy/common"
+	"github.com/labring/sealos/service/aiproxy/middleware"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/openai"
+	"github.com/labring/sealos/service/aiproxy/relay/meta"
+	"github.com/labring/sealos/service/aiproxy/relay/model"
+	"github.com/labring/sealos/service/aiproxy/relay/utils"
+)
+
+// native messages api
+// https://docs.anthropic.com/en/api/messages
+
+func UnmarshalMessagesRequest(req *http.Request) (*MessagesRequest, error) {
+	var request MessagesRequest
+	err := common.UnmarshalBodyReusable(req, &request)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ConvertNativeRequest keeps the native request as is, only the model is replaced with the actual model
+func ConvertNativeRequest(meta *meta.Meta, req *http.Request) (map[string]any, error) {
+	reqMap, err := utils.UnmarshalMap(req)
+	if err != nil {
+		return nil, err
+	}
+	reqMap["model"] = meta.ActualModelName
+	stream, _ := reqMap["stream"].(bool)
+	meta.Set("stream", stream)
+	return reqMap, nil
+}
+
+// ParseContentBlocks parses a string or a list of blocks into blocks
+func ParseContentBlocks(content any) []*ContentBlock {
+	switch v := content.(type) {
+	case string:
+		return []*ContentBlock{{Type: "text", Text: v}}
+	case []any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var blocks []*ContentBlock
+		if err := json.Unmarshal(data, &blocks); err != nil {
+			return nil
+		}
+		return blocks
+	default:
+		return nil
+	}
+}
+
+// ContentBlocksText joins the text of the text blocks
+func ContentBlocksText(content any) string {
+	var builder strings.Builder
+	for _, block := range ParseContentBlocks(content) {
+		if block.Type == "text" {
+			builder.WriteString(block.Text)
+		}
+	}
+	return builder.String()
+}
+
+// GetPromptTokens approximates the prompt tokens of a native request
+func GetPromptTokens(request *MessagesRequest, model string) int {
+	var builder strings.Builder
+	builder.WriteString(ContentBlocksText(request.System))
+	for _, message := range request.Messages {
+		for _, block := range ParseContentBlocks(message.Content) {
+			switch block.Type {
+			case "text":
+				builder.WriteString(block.Text)
+			case toolUseType:
+				builder.WriteString(block.Name)
+				args, _ := json.Marshal(block.Input)
+				builder.Write(args)
+			case "tool_result":
+				builder.WriteString(ContentBlocksText(block.Content))
+			}
+		}
+	}
+	for _, tool := range request.Tools {
+		builder.WriteString(tool.Name)
+		builder.WriteString(tool.Description)
+		schema, _ := json.Marshal(tool.InputSchema)
+		builder.Write(schema)
+	}
+	return openai.CountTokenText(builder.String(), model)
+}
+
+func (u *Usage) ToUsage() *model.Usage {
+	promptTokens := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return &model.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      promptTokens + u.OutputTokens,
+	}
+}
+
+// NativeUsage accumulates the usage of native stream events,
+// message_start carries the input tokens and message_delta carries the output tokens
+type NativeUsage struct {
+	usage Usage
+}
+
+func (n *NativeUsage) Add(event *StreamResponse) {
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			n.usage.InputTokens = event.Message.Usage.InputTokens
+			n.usage.CacheCreationInputTokens = event.Message.Usage.CacheCreationInputTokens
+			n.usage.CacheReadInputTokens = event.Message.Usage.CacheReadInputTokens
+			n.usage.OutputTokens = event.Message.Usage.OutputTokens
+		}
+	case "message_delta":
+		if event.Usage != nil && event.Usage.OutputTokens > 0 {
+			n.usage.OutputTokens = event.Usage.OutputTokens
+		}
+	}
+}
+
+func (n *NativeUsage) Usage() *model.Usage {
+	return n.usage.ToUsage()
+}
+
+func NativeStreamHandler(_ *meta.Meta, c *gin.Context, resp *http.Response) (*model.ErrorWithStatusCode, *model.Usage) {
+	defer resp.Body.Close()
+
+	log := middleware.GetLogger(c)
+
+	common.SetEventStreamHeaders(c)
+	c.Writer.WriteHeader(resp.StatusCode)
+
+	var usage NativeUsage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if _, err := c.Writer.Write(line); err != nil {
+			log.Error("error writing stream response: " + err.Error())
+			break
+		}
+		_, _ = c.Writer.Write([]byte{'\n'})
+		if len(line) == 0 {
+			c.Writer.Flush()
+			continue
+		}
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		var event StreamResponse
+		if err := json.Unmarshal(bytes.TrimSpace(line[5:]), &event); err != nil {
+			log.Error("error unmarshalling stream response: " + err.Error())
+			continue
+		}
+		usage.Add(&event)
+	}
+	c.Writer.Flush()
+
+	if err := scanner.Err(); err != nil {
+		log.Error("error reading stream: " + err.Error())
+	}
+
+	return nil, usage.Usage()
+}
+
+func NativeHandler(_ *meta.Meta, c *gin.Context, resp *http.Response) (*model.ErrorWithStatusCode, *model.Usage) {
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return openai.ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	return NativeResponse(c, resp.StatusCode, respBody)
+}
+
+// NativeResponse writes the native response body as is and returns its usage
+func NativeResponse(c *gin.Context, statusCode int, respBody []byte) (*model.ErrorWithStatusCode, *model.Usage) {
+	var claudeResponse Response
+	err := json.Unmarshal(respBody, &claudeResponse)
+	if err != nil {
+		return openai.ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if claudeResponse.Error.Type != "" {
+		return &model.ErrorWithStatusCode{
+			Error: model.Error{
+				Message: claudeResponse.Error.Message,
+				Type:    claudeResponse.Error.Type,
+				Param:   "",
+				Code:    claudeResponse.Error.Type,
+			},
+			StatusCode: statusCode,
+		}, nil
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(statusCode)
+	_, _ = c.Writer.Write(respBody)
+	return nil, claudeResponse.Usage.ToUsage()
+}
+
+// ErrorType returns the native error type of a relay error
+func ErrorType(err *model.ErrorWithStatusCode) string {
+	switch err.Type {
+	case "invalid_request_error", "authentication_error", "permission_error",
+		"not_found_error", "request_too_large", "rate_limit_error", "api_error", "overloaded_error":
+		return err.Type
+	}
+	switch err.StatusCode {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+func NewErrorResponse(err *model.ErrorWithStatusCode, message string) *ErrorResponse {
+	return &ErrorResponse{
+		Type: "error",
+		Error: Error{
+			Type:    ErrorType(err),
+			Message: message,
+		},
+	}
+}
+
+func nativeEventType(data []byte) string {
+	var event struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(data, &event)
+	return event.Type
+}
+
+// WriteNativeEvent writes a raw native stream event, as returned by the aws bedrock event stream
+func WriteNativeEvent(c *gin.Context, data []byte) error {
+	return writeEvent(c.Writer, nativeEventType(data), data)
+}
+
+func writeEvent(w gin.ResponseWriter, event string, data []byte) error {
+	if _, err := w.WriteString("event: " + event + "\ndata: "); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if _, err := w.WriteString("\n\n"); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,487 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	json "github.com/json-iterator/go"
+	"github.com/labring/sealos/service/aiproxy/common"
+	"github.com/labring/sealos/service/aiproxy/common/conv"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/openai"
+	"github.com/labring/sealos/service/aiproxy/relay/meta"
+	relaymodel "github.com/labring/sealos/service/aiproxy/relay/model"
+	"github.com/labring/sealos/service/aiproxy/relay/relaymode"
+)
+
+// OpenAICompatibleAdaptor relays the native messages api to channels that only support the chat completions api,
+// the request is converted before the wrapped adaptor sees it and the response is converted while it is written
+type OpenAICompatibleAdaptor struct {
+	adaptor.Adaptor
+}
+
+func NewOpenAICompatibleAdaptor(a adaptor.Adaptor) *OpenAICompatibleAdaptor {
+	return &OpenAICompatibleAdaptor{Adaptor: a}
+}
+
+const metaNativeStream = "anthropic_native_stream"
+
+// chatCompletionsMode switches the mode for the wrapped adaptor and returns a func to switch it back
+func chatCompletionsMode(meta *meta.Meta) func() {
+	mode := meta.Mode
+	meta.Mode = relaymode.ChatCompletions
+	return func() { meta.Mode = mode }
+}
+
+func (a *OpenAICompatibleAdaptor) GetRequestURL(meta *meta.Meta) (string, error) {
+	defer chatCompletionsMode(meta)()
+	return a.Adaptor.GetRequestURL(meta)
+}
+
+func (a *OpenAICompatibleAdaptor) SetupRequestHeader(meta *meta.Meta, c *gin.Context, req *http.Request) error {
+	defer chatCompletionsMode(meta)()
+	return a.Adaptor.SetupRequestHeader(meta, c, req)
+}
+
+func (a *OpenAICompatibleAdaptor) ConvertRequest(meta *meta.Meta, req *http.Request) (http.Header, io.Reader, error) {
+	request, err := UnmarshalMessagesRequest(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := json.Marshal(ConvertMessagesRequestToOpenAI(meta, request))
+	if err != nil {
+		return nil, nil, err
+	}
+	meta.Set(metaNativeStream, request.Stream)
+
+	// the original request is kept as is, so it can be retried on another channel
+	chatRequest := req.WithContext(context.WithValue(req.Context(), common.RequestBodyKey{}, data))
+	chatRequest.Body = io.NopCloser(bytes.NewReader(data))
+	chatRequest.ContentLength = int64(len(data))
+
+	defer chatCompletionsMode(meta)()
+	return a.Adaptor.ConvertRequest(meta, chatRequest)
+}
+
+func (a *OpenAICompatibleAdaptor) DoRequest(meta *meta.Meta, c *gin.Context, req *http.Request) (*http.Response, error) {
+	defer chatCompletionsMode(meta)()
+	return a.Adaptor.DoRequest(meta, c, req)
+}
+
+func (a *OpenAICompatibleAdaptor) DoResponse(meta *meta.Meta, c *gin.Context, resp *http.Response) (*relaymodel.Usage, *relaymodel.ErrorWithStatusCode) {
+	rawWriter := c.Writer
+	w := newOpenAIResponseWriter(rawWriter, meta, meta.GetBool(metaNativeStream))
+	c.Writer = w
+	restore := chatCompletionsMode(meta)
+	usage, relayErr := a.Adaptor.DoResponse(meta, c, resp)
+	restore()
+	c.Writer = rawWriter
+	if relayErr != nil {
+		return usage, relayErr
+	}
+	if err := w.finish(usage); err != nil {
+		return usage, openai.ErrorWrapper(err, "convert_response_failed", http.StatusInternalServerError)
+	}
+	return usage, nil
+}
+
+func ConvertMessagesRequestToOpenAI(meta *meta.Meta, request *MessagesRequest) *relaymodel.GeneralOpenAIRequest {
+	openAIRequest := &relaymodel.GeneralOpenAIRequest{
+		Model:       meta.ActualModelName,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		Stream:      request.Stream,
+	}
+	if len(request.StopSequences) > 0 {
+		openAIRequest.Stop = request.StopSequences
+	}
+	if request.Metadata != nil {
+		openAIRequest.User = request.Metadata.UserID
+	}
+	if system := ContentBlocksText(request.System); system != "" {
+		openAIRequest.Messages = append(openAIRequest.Messages, &relaymodel.Message{
+			Role:    "system",
+			Content: system,
+		})
+	}
+	for _, message := range request.Messages {
+		openAIRequest.Messages = append(openAIRequest.Messages, convertMessageToOpenAI(message)...)
+	}
+	for _, tool := range request.Tools {
+		openAIRequest.Tools = append(openAIRequest.Tools, &relaymodel.Tool{
+			Type: "function",
+			Function: relaymodel.Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+	if request.ToolChoice != nil {
+		switch request.ToolChoice.Type {
+		case "auto":
+			openAIRequest.ToolChoice = "auto"
+		case "any":
+			openAIRequest.ToolChoice = "required"
+		case "none":
+			openAIRequest.ToolChoice = "none"
+		case "tool":
+			openAIRequest.ToolChoice = map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name": request.ToolChoice.Name,
+				},
+			}
+		}
+	}
+	return openAIRequest
+}
+
+// convertMessageToOpenAI converts a native message, the tool results become tool messages placed before the rest of the content
+func convertMessageToOpenAI(message *MessagesMessage) []*relaymodel.Message {
+	var messages []*relaymodel.Message
+	var contents []relaymodel.MessageContent
+	var toolCalls []*relaymodel.Tool
+	for _, block := range ParseContentBlocks(message.Content) {
+		switch block.Type {
+		case "text":
+			contents = append(contents, relaymodel.MessageContent{
+				Type: relaymodel.ContentTypeText,
+				Text: block.Text,
+			})
+		case "image":
+			if block.Source == nil {
+				continue
+			}
+			url := block.Source.URL
+			if block.Source.Type == "base64" {
+				url = "data:" + block.Source.MediaType + ";base64," + block.Source.Data
+			}
+			contents = append(contents, relaymodel.MessageContent{
+				Type:     relaymodel.ContentTypeImageURL,
+				ImageURL: &relaymodel.ImageURL{URL: url},
+			})
+		case toolUseType:
+			args, _ := json.Marshal(block.Input)
+			toolCalls = append(toolCalls, &relaymodel.Tool{
+				ID:   block.ID,
+				Type: "function",
+				Function: relaymodel.Function{
+					Name:      block.Name,
+					Arguments: conv.BytesToString(args),
+				},
+			})
+		case "tool_result":
+			messages = append(messages, &relaymodel.Message{
+				Role:       "tool",
+				ToolCallID: block.ToolUseID,
+				Content:    ContentBlocksText(block.Content),
+			})
+		}
+	}
+	if len(contents) == 0 && len(toolCalls) == 0 {
+		return messages
+	}
+	openAIMessage := &relaymodel.Message{
+		Role:      message.Role,
+		ToolCalls: toolCalls,
+	}
+	switch {
+	case len(contents) == 1 && contents[0].Type == relaymodel.ContentTypeText:
+		openAIMessage.Content = contents[0].Text
+	case len(contents) > 0:
+		openAIMessage.Content = contents
+	}
+	return append(messages, openAIMessage)
+}
+
+func stopReasonOpenAI2Claude(reason string) string {
+	switch reason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return toolUseType
+	default:
+		return "end_turn"
+	}
+}
+
+func ResponseOpenAI2Claude(meta *meta.Meta, response *openai.TextResponse, usage *relaymodel.Usage) *MessagesResponse {
+	stopReason := "end_turn"
+	claudeResponse := &MessagesResponse{
+		ID:         "msg_" + response.ID,
+		Type:       "message",
+		Role:       "assistant",
+		Model:      meta.OriginModelName,
+		Content:    make([]Content, 0),
+		StopReason: &stopReason,
+	}
+	if usage != nil {
+		claudeResponse.Usage = Usage{
+			InputTokens:  usage.PromptTokens,
+			OutputTokens: usage.CompletionTokens,
+		}
+	}
+	if len(response.Choices) == 0 {
+		return claudeResponse
+	}
+	choice := response.Choices[0]
+	stopReason = stopReasonOpenAI2Claude(choice.FinishReason)
+	if text := choice.Message.StringContent(); text != "" {
+		claudeResponse.Content = append(claudeResponse.Content, Content{
+			Type: "text",
+			Text: text,
+		})
+	}
+	for _, toolCall := range choice.Message.ToolCalls {
+		var input any
+		if err := json.Unmarshal(conv.StringToBytes(toolCall.Function.Arguments), &input); err != nil || input == nil {
+			input = map[string]any{}
+		}
+		claudeResponse.Content = append(claudeResponse.Content, Content{
+			Type:  toolUseType,
+			ID:    toolCall.ID,
+			Name:  toolCall.Function.Name,
+			Input: input,
+		})
+	}
+	return claudeResponse
+}
+
+type openAIStreamToolCall struct {
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+	Index int `json:"index"`
+}
+
+type openAIStreamChunk struct {
+	Usage   *relaymodel.Usage `json:"usage"`
+	ID      string            `json:"id"`
+	Choices []struct {
+		FinishReason *string `json:"finish_reason"`
+		Delta        struct {
+			Content   string                  `json:"content"`
+			ToolCalls []*openAIStreamToolCall `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// openAIResponseWriter converts the chat completions response written by the wrapped adaptor into the native format,
+// the stream is converted line by line and the non stream response is converted when it is finished
+type openAIResponseWriter struct {
+	gin.ResponseWriter
+	meta          *meta.Meta
+	err           error
+	stopReason    string
+	blockType     string
+	buf           bytes.Buffer
+	blockIndex    int
+	toolCallIndex int
+	stream        bool
+	started       bool
+}
+
+func newOpenAIResponseWriter(w gin.ResponseWriter, meta *meta.Meta, stream bool) *openAIResponseWriter {
+	rw := &openAIResponseWriter{
+		ResponseWriter: w,
+		meta:           meta,
+		stream:         stream,
+		blockIndex:     -1,
+	}
+	rw.setContentType()
+	return rw
+}
+
+func (w *openAIResponseWriter) setContentType() {
+	w.Header().Del("Content-Length")
+	if w.stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+}
+
+func (w *openAIResponseWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	if !w.stream {
+		return len(b), nil
+	}
+	for w.err == nil {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		w.handleLine(bytes.TrimSpace(w.buf.Next(i + 1)))
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(b), nil
+}
+
+func (w *openAIResponseWriter) WriteString(s string) (int, error) {
+	return w.Write(conv.StringToBytes(s))
+}
+
+func (w *openAIResponseWriter) handleLine(line []byte) {
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return
+	}
+	data := bytes.TrimSpace(line[5:])
+	if bytes.Equal(data, []byte(openai.Done)) {
+		return
+	}
+	var chunk openAIStreamChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return
+	}
+	if !w.started {
+		w.start(chunk.ID)
+	}
+	if len(chunk.Choices) == 0 {
+		return
+	}
+	choice := chunk.Choices[0]
+	if choice.Delta.Content != "" {
+		if w.blockType != "text" {
+			w.startBlock("text", map[string]any{
+				"type": "text",
+				"text": "",
+			})
+		}
+		w.event("content_block_delta", map[string]any{
+			"type":  "content_block_delta",
+			"index": w.blockIndex,
+			"delta": map[string]any{
+				"type": "text_delta",
+				"text": choice.Delta.Content,
+			},
+		})
+	}
+	for _, toolCall := range choice.Delta.ToolCalls {
+		if w.blockType != toolUseType || toolCall.Index != w.toolCallIndex {
+			w.toolCallIndex = toolCall.Index
+			w.startBlock(toolUseType, map[string]any{
+				"type":  toolUseType,
+				"id":    toolCall.ID,
+				"name":  toolCall.Function.Name,
+				"input": map[string]any{},
+			})
+		}
+		if toolCall.Function.Arguments != "" {
+			w.event("content_block_delta", map[string]any{
+				"type":  "content_block_delta",
+				"index": w.blockIndex,
+				"delta": map[string]any{
+					"type":         "input_json_delta",
+					"partial_json": toolCall.Function.Arguments,
+				},
+			})
+		}
+	}
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		w.stopBlock()
+		w.stopReason = stopReasonOpenAI2Claude(*choice.FinishReason)
+	}
+}
+
+func (w *openAIResponseWriter) start(id string) {
+	w.started = true
+	w.event("message_start", map[string]any{
+		"type": "message_start",
+		"message": &MessagesResponse{
+			ID:      "msg_" + id,
+			Type:    "message",
+			Role:    "assistant",
+			Model:   w.meta.OriginModelName,
+			Content: make([]Content, 0),
+			Usage: Usage{
+				InputTokens: w.meta.PromptTokens,
+			},
+		},
+	})
+}
+
+func (w *openAIResponseWriter) startBlock(blockType string, block map[string]any) {
+	w.stopBlock()
+	w.blockIndex++
+	w.blockType = blockType
+	w.event("content_block_start", map[string]any{
+		"type":          "content_block_start",
+		"index":         w.blockIndex,
+		"content_block": block,
+	})
+}
+
+func (w *openAIResponseWriter) stopBlock() {
+	if w.blockType == "" {
+		return
+	}
+	w.blockType = ""
+	w.event("content_block_stop", map[string]any{
+		"type":  "content_block_stop",
+		"index": w.blockIndex,
+	})
+}
+
+func (w *openAIResponseWriter) event(event string, object any) {
+	if w.err != nil {
+		return
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		w.err = err
+		return
+	}
+	w.setContentType()
+	w.err = writeEvent(w.ResponseWriter, event, data)
+}
+
+func (w *openAIResponseWriter) finish(usage *relaymodel.Usage) error {
+	if !w.stream {
+		var response openai.TextResponse
+		if err := json.Unmarshal(w.buf.Bytes(), &response); err != nil {
+			return err
+		}
+		data, err := json.Marshal(ResponseOpenAI2Claude(w.meta, &response, usage))
+		if err != nil {
+			return err
+		}
+		w.setContentType()
+		_, err = w.ResponseWriter.Write(data)
+		return err
+	}
+
+	if !w.started {
+		w.start("")
+	}
+	w.stopBlock()
+	if w.stopReason == "" {
+		w.stopReason = "end_turn"
+	}
+	outputTokens := 0
+	if usage != nil {
+		outputTokens = usage.CompletionTokens
+	}
+	w.event("message_delta", map[string]any{
+		"type": "message_delta",
+		"delta": map[string]any{
+			"stop_reason":   w.stopReason,
+			"stop_sequence": nil,
+		},
+		"usage": map[string]any{
+			"output_tokens": outputTokens,
+		},
+	})
+	w.event("message_stop", map[string]any{
+		"type": "message_stop",
+	})
+	return w.err
+}
@@ -0,0 +1,80 @@
+package anthropic_test
+
+import (
+	"testing"
+
+	json "github.com/json-iterator/go"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/anthropic"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/openai"
+	"github.com/labring/sealos/service/aiproxy/relay/meta"
+	relaymodel "github.com/labring/sealos/service/aiproxy/relay/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertMessagesRequestToOpenAI(t *testing.T) {
+	var request anthropic.MessagesRequest
+	err := json.Unmarshal([]byte(`{
+		"model": "claude-3-5-sonnet",
+		"max_tokens": 1024,
+		"system": [{"type": "text", "text": "You are a weather bot."}],
+		"tool_choice": {"type": "any"},
+		"tools": [{"name": "get_weather", "input_schema": {"type": "object"}}],
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_1", "content": "sunny"}, {"type": "text", "text": "Thanks"}]}
+		]
+	}`), &request)
+	assert.NoError(t, err)
+
+	m := &meta.Meta{ActualModelName: "gpt-4o"}
+	openAIRequest := anthropic.ConvertMessagesRequestToOpenAI(m, &request)
+	assert.Equal(t, "gpt-4o", openAIRequest.Model)
+	assert.Equal(t, 1024, openAIRequest.MaxTokens)
+	assert.Equal(t, "required", openAIRequest.ToolChoice)
+	assert.Len(t, openAIRequest.Tools, 1)
+	assert.Equal(t, "get_weather", openAIRequest.Tools[0].Function.Name)
+
+	roles := make([]string, 0, len(openAIRequest.Messages))
+	for _, message := range openAIRequest.Messages {
+		roles = append(roles, message.Role)
+	}
+	assert.Equal(t, []string{"system", "user", "assistant", "tool", "user"}, roles)
+	assert.Equal(t, "You are a weather bot.", openAIRequest.Messages[0].Content)
+	assert.Equal(t, "toolu_1", openAIRequest.Messages[2].ToolCalls[0].ID)
+	assert.JSONEq(t, `{"city": "Paris"}`, openAIRequest.Messages[2].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "toolu_1", openAIRequest.Messages[3].ToolCallID)
+	assert.Equal(t, "sunny", openAIRequest.Messages[3].Content)
+	assert.Equal(t, "Thanks", openAIRequest.Messages[4].Content)
+}
+
+func TestResponseOpenAI2Claude(t *testing.T) {
+	response := &openai.TextResponse{
+		ID: "chatcmpl-1",
+		Choices: []*openai.TextResponseChoice{
+			{
+				FinishReason: "tool_calls",
+				Message: relaymodel.Message{
+					Role: "assistant",
+					ToolCalls: []*relaymodel.Tool{
+						{
+							ID:       "call_1",
+							Type:     "function",
+							Function: relaymodel.Function{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+						},
+					},
+				},
+			},
+		},
+	}
+	m := &meta.Meta{OriginModelName: "claude-3-5-sonnet"}
+	claudeResponse := anthropic.ResponseOpenAI2Claude(m, response, &relaymodel.Usage{PromptTokens: 10, CompletionTokens: 5})
+	assert.Equal(t, "msg_chatcmpl-1", claudeResponse.ID)
+	assert.Equal(t, "claude-3-5-sonnet", claudeResponse.Model)
+	assert.Equal(t, "tool_use", *claudeResponse.StopReason)
+	assert.Len(t, claudeResponse.Content, 1)
+	assert.Equal(t, "get_weather", claudeResponse.Content[0].Name)
+	assert.Equal(t, map[string]any{"city": "Paris"}, claudeResponse.Content[0].Input)
+	assert.Equal(t, 10, claudeResponse.Usage.InputTokens)
+	assert.Equal(t, 5, claudeResponse.Usage.OutputTokens)
+}
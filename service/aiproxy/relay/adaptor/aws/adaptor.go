@@ -37,6 +37,10 @@ func (a *Adaptor) DoResponse(meta *meta.Meta, c *gin.Context, _ *http.Response)
 	return adaptor.(utils.AwsAdapter).DoResponse(meta, c)
 }
 
+func (a *Adaptor) SupportAnthropicNative(meta *meta.Meta) bool {
+	return adaptors[meta.ActualModelName]._type == AwsClaude
+}
+
 func (a *Adaptor) GetModelList() (models []*model.ModelConfig) {
 	models = make([]*model.ModelConfig, 0, len(adaptors))
 	for _, model := range adaptors {
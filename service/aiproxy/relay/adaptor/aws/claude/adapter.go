@@ -5,14 +5,17 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	json "github.com/json-iterator/go"
 	"github.com/labring/sealos/service/aiproxy/relay/adaptor/anthropic"
 	"github.com/labring/sealos/service/aiproxy/relay/adaptor/aws/utils"
 	"github.com/labring/sealos/service/aiproxy/relay/meta"
 	"github.com/labring/sealos/service/aiproxy/relay/model"
+	"github.com/labring/sealos/service/aiproxy/relay/relaymode"
 )
 
 const (
-	ConvertedRequest = "convertedRequest"
+	ConvertedRequest       = "convertedRequest"
+	ConvertedNativeRequest = "convertedNativeRequest"
 )
 
 var _ utils.AwsAdapter = new(Adaptor)
@@ -20,6 +23,23 @@ var _ utils.AwsAdapter = new(Adaptor)
 type Adaptor struct{}
 
 func (a *Adaptor) ConvertRequest(meta *meta.Meta, req *http.Request) (http.Header, io.Reader, error) {
+	if meta.Mode == relaymode.Anthropic {
+		reqMap, err := anthropic.ConvertNativeRequest(meta, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		// the model is the model id of the invoke input and bedrock streams by api
+		delete(reqMap, "model")
+		delete(reqMap, "stream")
+		reqMap["anthropic_version"] = anthropicVersion
+		body, err := json.Marshal(reqMap)
+		if err != nil {
+			return nil, nil, err
+		}
+		meta.Set(ConvertedNativeRequest, body)
+		return nil, nil, nil
+	}
+
 	r, err := anthropic.ConvertRequest(meta, req)
 	if err != nil {
 		return nil, nil, err
@@ -29,6 +49,14 @@ func (a *Adaptor) ConvertRequest(meta *meta.Meta, req *http.Request) (http.Heade
 }
 
 func (a *Adaptor) DoResponse(meta *meta.Meta, c *gin.Context) (usage *model.Usage, err *model.ErrorWithStatusCode) {
+	if meta.Mode == relaymode.Anthropic {
+		if meta.GetBool("stream") {
+			err, usage = NativeStreamHandler(meta, c)
+		} else {
+			err, usage = NativeHandler(meta, c)
+		}
+		return
+	}
 	if meta.GetBool("stream") {
 		err, usage = StreamHandler(meta, c)
 	} else {
@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
+	"github.com/labring/sealos/service/aiproxy/common"
 	"github.com/labring/sealos/service/aiproxy/common/helper"
 	"github.com/labring/sealos/service/aiproxy/common/render"
 	"github.com/labring/sealos/service/aiproxy/middleware"
@@ -84,6 +85,8 @@ var AwsModelIDMap = map[string]awsModelItem{
 	},
 }
 
+const anthropicVersion = "bedrock-2023-05-31"
+
 func awsModelID(requestModel string) (string, error) {
 	if awsModelID, ok := AwsModelIDMap[requestModel]; ok {
 		return awsModelID.ID, nil
@@ -110,7 +113,7 @@ func Handler(meta *meta.Meta, c *gin.Context) (*relaymodel.ErrorWithStatusCode,
 	}
 	claudeReq := convReq.(*anthropic.Request)
 	awsClaudeReq := &Request{
-		AnthropicVersion: "bedrock-2023-05-31",
+		AnthropicVersion: anthropicVersion,
 	}
 	if err = copier.Copy(awsClaudeReq, claudeReq); err != nil {
 		return utils.WrapErr(errors.Wrap(err, "copy request")), nil
@@ -167,7 +170,7 @@ func StreamHandler(meta *meta.Meta, c *gin.Context) (*relaymodel.ErrorWithStatus
 	claudeReq := convReq.(*anthropic.Request)
 
 	awsClaudeReq := &Request{
-		AnthropicVersion: "bedrock-2023-05-31",
+		AnthropicVersion: anthropicVersion,
 	}
 	if err = copier.Copy(awsClaudeReq, claudeReq); err != nil {
 		return utils.WrapErr(errors.Wrap(err, "copy request")), nil
@@ -251,3 +254,92 @@ func StreamHandler(meta *meta.Meta, c *gin.Context) (*relaymodel.ErrorWithStatus
 
 	return nil, &usage
 }
+
+func nativeRequestBody(meta *meta.Meta) ([]byte, error) {
+	body, ok := meta.Get(ConvertedNativeRequest)
+	if !ok {
+		return nil, errors.New("request not found")
+	}
+	return body.([]byte), nil
+}
+
+func NativeHandler(meta *meta.Meta, c *gin.Context) (*relaymodel.ErrorWithStatusCode, *relaymodel.Usage) {
+	awsModelID, err := awsModelID(meta.ActualModelName)
+	if err != nil {
+		return utils.WrapErr(errors.Wrap(err, "awsModelID")), nil
+	}
+	body, err := nativeRequestBody(meta)
+	if err != nil {
+		return utils.WrapErr(err), nil
+	}
+
+	awsResp, err := meta.AwsClient().InvokeModel(c.Request.Context(), &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(awsModelID),
+		Accept:      aws.String("application/json"),
+		ContentType: aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return utils.WrapErr(errors.Wrap(err, "InvokeModel")), nil
+	}
+
+	return anthropic.NativeResponse(c, http.StatusOK, awsResp.Body)
+}
+
+func NativeStreamHandler(meta *meta.Meta, c *gin.Context) (*relaymodel.ErrorWithStatusCode, *relaymodel.Usage) {
+	log := middleware.GetLogger(c)
+	awsModelID, err := awsModelID(meta.ActualModelName)
+	if err != nil {
+		return utils.WrapErr(errors.Wrap(err, "awsModelID")), nil
+	}
+	body, err := nativeRequestBody(meta)
+	if err != nil {
+		return utils.WrapErr(err), nil
+	}
+
+	awsResp, err := meta.AwsClient().InvokeModelWithResponseStream(c.Request.Context(), &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(awsModelID),
+		Accept:      aws.String("application/json"),
+		ContentType: aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return utils.WrapErr(errors.Wrap(err, "InvokeModelWithResponseStream")), nil
+	}
+	stream := awsResp.GetStream()
+	defer stream.Close()
+
+	common.SetEventStreamHeaders(c)
+	var usage anthropic.NativeUsage
+
+	c.Stream(func(_ io.Writer) bool {
+		event, ok := <-stream.Events()
+		if !ok {
+			return false
+		}
+
+		switch v := event.(type) {
+		case *types.ResponseStreamMemberChunk:
+			claudeResp := anthropic.StreamResponse{}
+			err := json.Unmarshal(v.Value.Bytes, &claudeResp)
+			if err != nil {
+				log.Error("error unmarshalling stream response: " + err.Error())
+				return false
+			}
+			usage.Add(&claudeResp)
+			if err := anthropic.WriteNativeEvent(c, v.Value.Bytes); err != nil {
+				log.Error("error stream response: " + err.Error())
+				return false
+			}
+			return true
+		case *types.UnknownUnionMember:
+			log.Error("unknown tag: " + v.Tag)
+			return false
+		default:
+			log.Errorf("union is nil or unknown type: %v", v)
+			return false
+		}
+	})
+
+	return nil, usage.Usage()
+}
@@ -1,6 +1,7 @@
 package adaptor
 
 import (
+	"context"
 	"io"
 	"net/http"
 
@@ -23,3 +24,17 @@ type Adaptor interface {
 type GetBalance interface {
 	GetBalance(channel *model.Channel) (float64, error)
 }
+
+// AnthropicNative is implemented by adaptors that can relay the anthropic messages api without conversion
+type AnthropicNative interface {
+	SupportAnthropicNative(meta *meta.Meta) bool
+}
+
+// Batch is implemented by the batch adaptors of channels that support the openai files and batches apis
+type Batch interface {
+	UploadFile(ctx context.Context, meta *meta.Meta, filename string, content io.Reader) (string, error)
+	GetFileContent(ctx context.Context, meta *meta.Meta, id string) (io.ReadCloser, error)
+	CreateBatch(ctx context.Context, meta *meta.Meta, req *relaymodel.BatchRequest) (*relaymodel.BatchResponse, error)
+	RetrieveBatch(ctx context.Context, meta *meta.Meta, id string) (*relaymodel.BatchResponse, error)
+	CancelBatch(ctx context.Context, meta *meta.Meta, id string) (*relaymodel.BatchResponse, error)
+}
@@ -0,0 +1,132 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	json "github.com/json-iterator/go"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor"
+	"github.com/labring/sealos/service/aiproxy/relay/meta"
+	relaymodel "github.com/labring/sealos/service/aiproxy/relay/model"
+	"github.com/labring/sealos/service/aiproxy/relay/utils"
+)
+
+var _ adaptor.Batch = (*BatchAdaptor)(nil)
+
+// BatchAdaptor calls the files and batches apis of the channel,
+// it is not a part of Adaptor so that the adaptors embedding Adaptor do not claim batch support
+type BatchAdaptor struct{}
+
+func batchURL(meta *meta.Meta, path string) string {
+	u := meta.Channel.BaseURL
+	if u == "" {
+		u = baseURL
+	}
+	if meta.GetBool(MetaBaseURLNoV1) {
+		return u + path
+	}
+	return u + "/v1" + path
+}
+
+func (a *BatchAdaptor) do(ctx context.Context, meta *meta.Meta, method string, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, batchURL(meta, path), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Authorization", "Bearer "+meta.Channel.Key)
+	resp, err := utils.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(utils.RelayDefaultErrorHanlder(resp).Message)
+	}
+	return resp, nil
+}
+
+func (a *BatchAdaptor) doJSON(ctx context.Context, meta *meta.Meta, method string, path string, body any, v any) error {
+	var reader io.Reader
+	var contentType string
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	resp, err := a.do(ctx, meta, method, path, reader, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (a *BatchAdaptor) UploadFile(ctx context.Context, meta *meta.Meta, filename string, content io.Reader) (string, error) {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		err := func() error {
+			if err := mw.WriteField("purpose", "batch"); err != nil {
+				return err
+			}
+			part, err := mw.CreateFormFile("file", filename)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(part, content); err != nil {
+				return err
+			}
+			return mw.Close()
+		}()
+		pw.CloseWithError(err)
+	}()
+
+	resp, err := a.do(ctx, meta, http.MethodPost, "/files", pr, mw.FormDataContentType())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return "", err
+	}
+	return file.ID, nil
+}
+
+func (a *BatchAdaptor) GetFileContent(ctx context.Context, meta *meta.Meta, id string) (io.ReadCloser, error) {
+	resp, err := a.do(ctx, meta, http.MethodGet, "/files/"+id+"/content", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (a *BatchAdaptor) CreateBatch(ctx context.Context, meta *meta.Meta, req *relaymodel.BatchRequest) (*relaymodel.BatchResponse, error) {
+	var batch relaymodel.BatchResponse
+	err := a.doJSON(ctx, meta, http.MethodPost, "/batches", req, &batch)
+	return &batch, err
+}
+
+func (a *BatchAdaptor) RetrieveBatch(ctx context.Context, meta *meta.Meta, id string) (*relaymodel.BatchResponse, error) {
+	var batch relaymodel.BatchResponse
+	err := a.doJSON(ctx, meta, http.MethodGet, "/batches/"+id, nil, &batch)
+	return &batch, err
+}
+
+func (a *BatchAdaptor) CancelBatch(ctx context.Context, meta *meta.Meta, id string) (*relaymodel.BatchResponse, error) {
+	var batch relaymodel.BatchResponse
+	err := a.doJSON(ctx, meta, http.MethodPost, "/batches/"+id+"/cancel", nil, &batch)
+	return &batch, err
+}
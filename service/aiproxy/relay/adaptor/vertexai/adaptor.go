@@ -40,6 +40,10 @@ func (a *Adaptor) DoResponse(meta *meta.Meta, c *gin.Context, resp *http.Respons
 	return adaptor.DoResponse(meta, c, resp)
 }
 
+func (a *Adaptor) SupportAnthropicNative(meta *meta.Meta) bool {
+	return modelMapping[meta.ActualModelName] == VerterAIClaude
+}
+
 func (a *Adaptor) GetModelList() []*model.ModelConfig {
 	return modelList
 }
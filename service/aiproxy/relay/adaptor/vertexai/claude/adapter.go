@@ -60,6 +60,21 @@ func (a *Adaptor) ConvertRequest(meta *meta.Meta, request *http.Request) (http.H
 		return nil, nil, errors.New("request is nil")
 	}
 
+	if meta.Mode == relaymode.Anthropic {
+		reqMap, err := anthropic.ConvertNativeRequest(meta, request)
+		if err != nil {
+			return nil, nil, err
+		}
+		// the model is in the url
+		delete(reqMap, "model")
+		reqMap["anthropic_version"] = anthropicVersion
+		data, err := json.Marshal(reqMap)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, bytes.NewReader(data), nil
+	}
+
 	claudeReq, err := anthropic.ConvertRequest(meta, request)
 	if err != nil {
 		return nil, nil, err
@@ -85,6 +100,14 @@ func (a *Adaptor) ConvertRequest(meta *meta.Meta, request *http.Request) (http.H
 }
 
 func (a *Adaptor) DoResponse(meta *meta.Meta, c *gin.Context, resp *http.Response) (usage *relaymodel.Usage, err *relaymodel.ErrorWithStatusCode) {
+	if meta.Mode == relaymode.Anthropic {
+		if utils.IsStreamResponse(resp) {
+			err, usage = anthropic.NativeStreamHandler(meta, c, resp)
+		} else {
+			err, usage = anthropic.NativeHandler(meta, c, resp)
+		}
+		return
+	}
 	if utils.IsStreamResponse(resp) {
 		err, usage = anthropic.StreamHandler(meta, c, resp)
 	} else {
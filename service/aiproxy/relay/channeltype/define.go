@@ -69,6 +69,16 @@ func GetAdaptor(channel int) (adaptor.Adaptor, bool) {
 	return a, ok
 }
 
+// ChannelBatchAdaptor are the channels that support the files and batches apis
+var ChannelBatchAdaptor = map[int]adaptor.Batch{
+	1: &openai.BatchAdaptor{},
+}
+
+func GetBatchAdaptor(channel int) (adaptor.Batch, bool) {
+	a, ok := ChannelBatchAdaptor[channel]
+	return a, ok
+}
+
 var ChannelNames = map[int]string{}
 
 func init() {
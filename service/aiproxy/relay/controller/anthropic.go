@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labring/sealos/service/aiproxy/middleware"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/anthropic"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/openai"
+	"github.com/labring/sealos/service/aiproxy/relay/channeltype"
+	"github.com/labring/sealos/service/aiproxy/relay/meta"
+	"github.com/labring/sealos/service/aiproxy/relay/model"
+	billingprice "github.com/labring/sealos/service/aiproxy/relay/price"
+)
+
+func RelayAnthropicHelper(meta *meta.Meta, c *gin.Context) *model.ErrorWithStatusCode {
+	log := middleware.GetLogger(c)
+	ctx := c.Request.Context()
+
+	messagesRequest, err := anthropic.UnmarshalMessagesRequest(c.Request)
+	if err != nil {
+		log.Errorf("get and validate messages request failed: %s", err.Error())
+		return openai.ErrorWrapper(err, "invalid_messages_request", http.StatusBadRequest)
+	}
+
+	// get model price
+	price, completionPrice, ok := billingprice.GetModelPrice(meta.OriginModelName, meta.ActualModelName)
+	if !ok {
+		return openai.ErrorWrapper(fmt.Errorf("model price not found: %s", meta.OriginModelName), "model_price_not_found", http.StatusInternalServerError)
+	}
+	// pre-consume balance
+	promptTokens := anthropic.GetPromptTokens(messagesRequest, meta.ActualModelName)
+	meta.PromptTokens = promptTokens
+	preCheckReq := &PreCheckGroupBalanceReq{
+		PromptTokens: promptTokens,
+		MaxTokens:    messagesRequest.MaxTokens,
+		Price:        price,
+	}
+	ok, postGroupConsumer, err := preCheckGroupBalance(ctx, preCheckReq, meta)
+	if err != nil {
+		log.Errorf("get group (%s) balance failed: %v", meta.Group.ID, err)
+		return openai.ErrorWrapper(
+			fmt.Errorf("get group (%s) balance failed", meta.Group.ID),
+			"get_group_quota_failed",
+			http.StatusInternalServerError,
+		)
+	}
+	if !ok {
+		return openai.ErrorWrapper(errors.New("group balance is not enough"), "insufficient_group_balance", http.StatusForbidden)
+	}
+
+	channelAdaptor, ok := channeltype.GetAdaptor(meta.Channel.Type)
+	if !ok {
+		return openai.ErrorWrapper(fmt.Errorf("invalid channel type: %d", meta.Channel.Type), "invalid_channel_type", http.StatusBadRequest)
+	}
+	// channels without the native api are relayed through the chat completions api
+	if native, ok := channelAdaptor.(adaptor.AnthropicNative); !ok || !native.SupportAnthropicNative(meta) {
+		channelAdaptor = anthropic.NewOpenAICompatibleAdaptor(channelAdaptor)
+	}
+
+	tokenRateLimitReservations, rateLimitErr := preCheckTokenRateLimit(c, preCheckReq, meta)
+	if rateLimitErr != nil {
+		return rateLimitErr
+	}
+
+	// do response
+	usage, detail, respErr := DoHelper(channelAdaptor, c, meta)
+	tokenRateLimitReservations.Settle(context.Background(), usage)
+	if respErr != nil {
+		if detail != nil {
+			log.Errorf("do messages failed: %s\nrequest detail:\n%s\nresponse detail:\n%s", respErr, detail.RequestBody, detail.ResponseBody)
+		} else {
+			log.Errorf("do messages failed: %s", respErr)
+		}
+		ConsumeWaitGroup.Add(1)
+		go postConsumeAmount(context.Background(),
+			&ConsumeWaitGroup,
+			postGroupConsumer,
+			respErr.StatusCode,
+			c.Request.URL.Path,
+			usage,
+			meta,
+			price,
+			completionPrice,
+			respErr.String(),
+			detail,
+		)
+		return respErr
+	}
+	// post-consume amount
+	ConsumeWaitGroup.Add(1)
+	go postConsumeAmount(context.Background(),
+		&ConsumeWaitGroup,
+		postGroupConsumer,
+		http.StatusOK,
+		c.Request.URL.Path,
+		usage,
+		meta,
+		price,
+		completionPrice,
+		"",
+		nil,
+	)
+	return nil
+}
@@ -0,0 +1,548 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/labring/sealos/service/aiproxy/common/balance"
+	"github.com/labring/sealos/service/aiproxy/common/filestore"
+	"github.com/labring/sealos/service/aiproxy/common/random"
+	"github.com/labring/sealos/service/aiproxy/middleware"
+	"github.com/labring/sealos/service/aiproxy/model"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor"
+	"github.com/labring/sealos/service/aiproxy/relay/adaptor/openai"
+	"github.com/labring/sealos/service/aiproxy/relay/channeltype"
+	"github.com/labring/sealos/service/aiproxy/relay/meta"
+	relaymodel "github.com/labring/sealos/service/aiproxy/relay/model"
+	billingprice "github.com/labring/sealos/service/aiproxy/relay/price"
+	"github.com/labring/sealos/service/aiproxy/relay/relaymode"
+)
+
+// https://platform.openai.com/docs/api-reference/batch/create
+
+var batchEndpoints = map[string]int{
+	"/v1/chat/completions": relaymode.ChatCompletions,
+	"/v1/completions":      relaymode.Completions,
+	"/v1/embeddings":       relaymode.Embeddings,
+}
+
+const (
+	batchCompletionWindow = "24h"
+	batchMaxRequests      = 50000
+	batchMaxLineSize      = 16 * 1024 * 1024
+	batchEndpoint         = "/v1/batches"
+)
+
+type batchInputLine struct {
+	Body     map[string]any `json:"body"`
+	CustomID string         `json:"custom_id"`
+	Method   string         `json:"method"`
+	URL      string         `json:"url"`
+}
+
+func scanBatchInput(r io.Reader, fn func(line *batchInputLine) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), batchMaxLineSize)
+	n := 0
+	for scanner.Scan() {
+		n++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var line batchInputLine
+		if err := json.Unmarshal(data, &line); err != nil {
+			return fmt.Errorf("line %d: invalid json: %w", n, err)
+		}
+		if err := fn(&line); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+type batchInput struct {
+	model        string
+	promptTokens int
+	maxTokens    int
+	requests     int
+}
+
+// checkBatchInput validates the input file, all the requests must use the same endpoint and model
+func checkBatchInput(r io.Reader, endpoint string, mode int) (*batchInput, error) {
+	input := &batchInput{}
+	customIDs := make(map[string]struct{})
+	err := scanBatchInput(r, func(line *batchInputLine) error {
+		if line.Method != http.MethodPost {
+			return fmt.Errorf("unsupported method: %s", line.Method)
+		}
+		if line.URL != endpoint {
+			return fmt.Errorf("url %s does not match the batch endpoint %s", line.URL, endpoint)
+		}
+		if line.CustomID == "" {
+			return errors.New("custom_id is required")
+		}
+		if _, ok := customIDs[line.CustomID]; ok {
+			return fmt.Errorf("duplicate custom_id: %s", line.CustomID)
+		}
+		customIDs[line.CustomID] = struct{}{}
+		modelName, _ := line.Body["model"].(string)
+		if modelName == "" {
+			return errors.New("no model provided")
+		}
+		if input.model == "" {
+			input.model = modelName
+		} else if input.model != modelName {
+			return fmt.Errorf("all requests must use the same model, got %s and %s", input.model, modelName)
+		}
+
+		data, err := json.Marshal(line.Body)
+		if err != nil {
+			return err
+		}
+		var request relaymodel.GeneralOpenAIRequest
+		if err := json.Unmarshal(data, &request); err != nil {
+			return fmt.Errorf("invalid body: %w", err)
+		}
+		input.promptTokens += openai.GetPromptTokens(&meta.Meta{Mode: mode}, &request)
+		input.maxTokens += request.MaxTokens
+
+		input.requests++
+		if input.requests > batchMaxRequests {
+			return fmt.Errorf("a batch can contain at most %d requests", batchMaxRequests)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if input.requests == 0 {
+		return nil, errors.New("input file is empty")
+	}
+	return input, nil
+}
+
+// convertBatchInput replaces the model of the requests with the actual model of the channel
+func convertBatchInput(r io.Reader, w io.Writer, actualModel string) error {
+	return scanBatchInput(r, func(line *batchInputLine) error {
+		line.Body["model"] = actualModel
+		data, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+func getBatchAdaptor(channel *model.Channel) (adaptor.Batch, bool) {
+	return channeltype.GetBatchAdaptor(channel.Type)
+}
+
+func newBatchMeta(channel *model.Channel, batch *model.Batch) *meta.Meta {
+	return meta.NewMeta(
+		channel,
+		relaymode.Batches,
+		batch.Model,
+		meta.WithRequestID(batch.ID),
+		meta.WithGroup(&model.GroupCache{ID: batch.GroupID}),
+		meta.WithToken(&model.TokenCache{ID: batch.TokenID, Name: batch.TokenName}),
+	)
+}
+
+func applyUpstreamBatch(batch *model.Batch, upstream *relaymodel.BatchResponse) {
+	batch.Status = upstream.Status
+	batch.Errors = upstream.Errors
+	batch.UpstreamOutputFileID = upstream.OutputFileID
+	batch.UpstreamErrorFileID = upstream.ErrorFileID
+	batch.RequestCounts = model.BatchRequestCounts{
+		Total:     upstream.RequestCounts.Total,
+		Completed: upstream.RequestCounts.Completed,
+		Failed:    upstream.RequestCounts.Failed,
+	}
+	batch.InProgressAt = upstream.InProgressAt
+	batch.ExpiresAt = upstream.ExpiresAt
+	batch.FinalizingAt = upstream.FinalizingAt
+	batch.CompletedAt = upstream.CompletedAt
+	batch.FailedAt = upstream.FailedAt
+	batch.ExpiredAt = upstream.ExpiredAt
+	batch.CancellingAt = upstream.CancellingAt
+	batch.CancelledAt = upstream.CancelledAt
+}
+
+func CreateBatch(ctx context.Context, group *model.GroupCache, token *model.TokenCache, req *relaymodel.BatchRequest) (*model.Batch, *relaymodel.ErrorWithStatusCode) {
+	mode, ok := batchEndpoints[req.Endpoint]
+	if !ok {
+		return nil, openai.ErrorWrapperWithMessage("unsupported endpoint: "+req.Endpoint, "invalid_batch_request", http.StatusBadRequest)
+	}
+	if req.CompletionWindow == "" {
+		req.CompletionWindow = batchCompletionWindow
+	}
+	if req.CompletionWindow != batchCompletionWindow {
+		return nil, openai.ErrorWrapperWithMessage("unsupported completion window: "+req.CompletionWindow, "invalid_batch_request", http.StatusBadRequest)
+	}
+
+	inputFile, err := model.GetGroupFileByID(group.ID, req.InputFileID)
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "input_file_not_found", http.StatusNotFound)
+	}
+	if inputFile.Purpose != model.FilePurposeBatch {
+		return nil, openai.ErrorWrapperWithMessage("input file must be uploaded with the batch purpose", "invalid_batch_request", http.StatusBadRequest)
+	}
+
+	content, err := filestore.Default.Get(ctx, inputFile.StorageKey)
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "get_file_failed", http.StatusInternalServerError)
+	}
+	input, err := checkBatchInput(content, req.Endpoint, mode)
+	content.Close()
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "invalid_batch_input", http.StatusBadRequest)
+	}
+
+	if len(token.Models) == 0 || !slices.Contains(token.Models, input.model) {
+		return nil, openai.ErrorWrapperWithMessage(
+			fmt.Sprintf("token (%s[%d]) has no permission to use model: %s", token.Name, token.ID, input.model),
+			"model_not_allowed",
+			http.StatusForbidden,
+		)
+	}
+	channel, err := model.CacheGetRandomSatisfiedChannelWithFilter(input.model, func(ch *model.Channel) bool {
+		_, ok := getBatchAdaptor(ch)
+		return ok
+	})
+	if err != nil {
+		return nil, openai.ErrorWrapperWithMessage(input.model+" is not available for batches", "model_not_available", http.StatusServiceUnavailable)
+	}
+	batchAdaptor, _ := getBatchAdaptor(channel)
+
+	batch := &model.Batch{
+		ID:               "batch_" + random.GetUUID(),
+		GroupID:          group.ID,
+		TokenID:          token.ID,
+		TokenName:        token.Name,
+		ChannelID:        channel.ID,
+		Model:            input.model,
+		Endpoint:         req.Endpoint,
+		CompletionWindow: req.CompletionWindow,
+		InputFileID:      inputFile.ID,
+		Metadata:         req.Metadata,
+	}
+	m := newBatchMeta(channel, batch)
+
+	price, _, ok := billingprice.GetModelBatchPrice(m.OriginModelName, m.ActualModelName)
+	if !ok {
+		return nil, openai.ErrorWrapper(fmt.Errorf("model price not found: %s", m.OriginModelName), "model_price_not_found", http.StatusInternalServerError)
+	}
+	ok, _, err = preCheckGroupBalance(ctx, &PreCheckGroupBalanceReq{
+		PromptTokens: input.promptTokens,
+		MaxTokens:    input.maxTokens,
+		Price:        price,
+	}, m)
+	if err != nil {
+		return nil, openai.ErrorWrapper(
+			fmt.Errorf("get group (%s) balance failed", group.ID),
+			"get_group_quota_failed",
+			http.StatusInternalServerError,
+		)
+	}
+	if !ok {
+		return nil, openai.ErrorWrapper(errors.New("group balance is not enough"), "insufficient_group_balance", http.StatusForbidden)
+	}
+
+	content, err = filestore.Default.Get(ctx, inputFile.StorageKey)
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "get_file_failed", http.StatusInternalServerError)
+	}
+	defer content.Close()
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(convertBatchInput(content, pw, m.ActualModelName))
+	}()
+	upstreamFileID, err := batchAdaptor.UploadFile(ctx, m, inputFile.Filename, pr)
+	pr.Close()
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "upload_file_failed", http.StatusBadGateway)
+	}
+
+	upstream, err := batchAdaptor.CreateBatch(ctx, m, &relaymodel.BatchRequest{
+		InputFileID:      upstreamFileID,
+		Endpoint:         req.Endpoint,
+		CompletionWindow: req.CompletionWindow,
+		Metadata:         req.Metadata,
+	})
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "create_batch_failed", http.StatusBadGateway)
+	}
+	batch.UpstreamID = upstream.ID
+	applyUpstreamBatch(batch, upstream)
+
+	if err := model.CreateBatch(batch); err != nil {
+		return nil, openai.ErrorWrapper(err, "create_batch_failed", http.StatusInternalServerError)
+	}
+	return batch, nil
+}
+
+// errBatchChannelUnavailable is returned if the channel of the batch is deleted or no longer supports batches
+var errBatchChannelUnavailable = errors.New("the channel of the batch is unavailable")
+
+func getBatchChannel(batch *model.Batch) (*model.Channel, adaptor.Batch, error) {
+	channel, err := model.GetChannelByID(batch.ChannelID, false)
+	if err != nil {
+		var notFound model.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, nil, fmt.Errorf("%w: channel %d is not found", errBatchChannelUnavailable, batch.ChannelID)
+		}
+		return nil, nil, err
+	}
+	batchAdaptor, ok := getBatchAdaptor(channel)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: channel %d does not support batches", errBatchChannelUnavailable, channel.ID)
+	}
+	return channel, batchAdaptor, nil
+}
+
+// failBatch fails a batch whose upstream batch can no longer be reached, there are no results to collect or bill
+func failBatch(batch *model.Batch, reason error) error {
+	batch.Status = model.BatchStatusFailed
+	batch.FailedAt = time.Now().Unix()
+	batch.Errors = map[string]any{
+		"object": "list",
+		"data": []map[string]any{
+			{"code": "channel_unavailable", "message": reason.Error()},
+		},
+	}
+	if err := model.UpdateBatchUpstreamStatus(batch); err != nil {
+		return err
+	}
+	ok, err := model.CollectBatch(batch)
+	if err != nil {
+		return err
+	}
+	batch.Collected = ok
+	return nil
+}
+
+func CancelBatch(ctx context.Context, batch *model.Batch) error {
+	if batch.IsTerminal() {
+		return fmt.Errorf("batch is already %s", batch.Status)
+	}
+	channel, batchAdaptor, err := getBatchChannel(batch)
+	if err != nil {
+		return err
+	}
+	upstream, err := batchAdaptor.CancelBatch(ctx, newBatchMeta(channel, batch), batch.UpstreamID)
+	if err != nil {
+		return err
+	}
+	applyUpstreamBatch(batch, upstream)
+	return model.UpdateBatchUpstreamStatus(batch)
+}
+
+// SyncBatch syncs the status of the upstream batch, the results are collected and billed once it is terminal
+func SyncBatch(ctx context.Context, batch *model.Batch) error {
+	if batch.Collected {
+		return nil
+	}
+	channel, batchAdaptor, err := getBatchChannel(batch)
+	if errors.Is(err, errBatchChannelUnavailable) {
+		return failBatch(batch, err)
+	}
+	if err != nil {
+		return err
+	}
+	m := newBatchMeta(channel, batch)
+	upstream, err := batchAdaptor.RetrieveBatch(ctx, m, batch.UpstreamID)
+	if err != nil {
+		return err
+	}
+	applyUpstreamBatch(batch, upstream)
+	if err := model.UpdateBatchUpstreamStatus(batch); err != nil {
+		return err
+	}
+	if !batch.IsTerminal() {
+		return nil
+	}
+	return collectBatch(ctx, batchAdaptor, m, batch)
+}
+
+// batchOutputCounter counts the bytes and sums the usage of the output lines written to it
+type batchOutputCounter struct {
+	buf   bytes.Buffer
+	usage relaymodel.Usage
+	bytes int64
+}
+
+func (w *batchOutputCounter) Write(p []byte) (int, error) {
+	w.bytes += int64(len(p))
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		w.add(w.buf.Next(i + 1))
+	}
+	return len(p), nil
+}
+
+func (w *batchOutputCounter) add(line []byte) {
+	var output relaymodel.BatchOutput
+	if err := json.Unmarshal(line, &output); err != nil {
+		return
+	}
+	if output.Response == nil || output.Response.Body.Usage == nil {
+		return
+	}
+	w.usage.PromptTokens += output.Response.Body.Usage.PromptTokens
+	w.usage.CompletionTokens += output.Response.Body.Usage.CompletionTokens
+}
+
+func (w *batchOutputCounter) finish() {
+	if w.buf.Len() > 0 {
+		w.add(w.buf.Bytes())
+		w.buf.Reset()
+	}
+	w.usage.TotalTokens = w.usage.PromptTokens + w.usage.CompletionTokens
+}
+
+// storeBatchResult copies a result file of the upstream batch to the file store
+func storeBatchResult(ctx context.Context, batchAdaptor adaptor.Batch, m *meta.Meta, batch *model.Batch, upstreamFileID string, name string) (*model.File, *relaymodel.Usage, error) {
+	content, err := batchAdaptor.GetFileContent(ctx, m, upstreamFileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer content.Close()
+
+	id := "file-" + random.GetUUID()
+	file := &model.File{
+		ID:         id,
+		GroupID:    batch.GroupID,
+		TokenID:    batch.TokenID,
+		Filename:   batch.ID + "_" + name + ".jsonl",
+		Purpose:    model.FilePurposeBatchOutput,
+		StorageKey: model.FileStorageKey(batch.GroupID, id),
+	}
+	var counter batchOutputCounter
+	if err := filestore.Default.Put(ctx, file.StorageKey, io.TeeReader(content, &counter), -1); err != nil {
+		return nil, nil, err
+	}
+	counter.finish()
+	file.Bytes = counter.bytes
+	if err := model.CreateFile(file); err != nil {
+		_ = filestore.Default.Delete(ctx, file.StorageKey)
+		return nil, nil, err
+	}
+	return file, &counter.usage, nil
+}
+
+func deleteFile(ctx context.Context, file *model.File) error {
+	if err := model.DeleteGroupFileByID(file.GroupID, file.ID); err != nil {
+		return err
+	}
+	return filestore.Default.Delete(ctx, file.StorageKey)
+}
+
+func collectBatch(ctx context.Context, batchAdaptor adaptor.Batch, m *meta.Meta, batch *model.Batch) error {
+	log := middleware.NewLogger()
+
+	price, completionPrice, ok := billingprice.GetModelBatchPrice(m.OriginModelName, m.ActualModelName)
+	if !ok {
+		return fmt.Errorf("model price not found: %s", m.OriginModelName)
+	}
+	_, postGroupConsumer, err := balance.Default.GetGroupRemainBalance(ctx, batch.GroupID)
+	if err != nil {
+		return fmt.Errorf("get group (%s) balance failed: %w", batch.GroupID, err)
+	}
+
+	var files []*model.File
+	deleteFiles := func() {
+		for _, file := range files {
+			if err := deleteFile(ctx, file); err != nil {
+				log.Errorf("failed to delete file %s: %v", file.ID, err)
+			}
+		}
+	}
+	var usage *relaymodel.Usage
+	if batch.UpstreamOutputFileID != "" {
+		file, outputUsage, err := storeBatchResult(ctx, batchAdaptor, m, batch, batch.UpstreamOutputFileID, "output")
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		batch.OutputFileID = file.ID
+		usage = outputUsage
+		batch.PromptTokens = usage.PromptTokens
+		batch.CompletionTokens = usage.CompletionTokens
+	}
+	if batch.UpstreamErrorFileID != "" {
+		file, _, err := storeBatchResult(ctx, batchAdaptor, m, batch, batch.UpstreamErrorFileID, "error")
+		if err != nil {
+			// the output file is stored again by the next sync
+			deleteFiles()
+			return err
+		}
+		files = append(files, file)
+		batch.ErrorFileID = file.ID
+	}
+
+	ok, err = model.CollectBatch(batch)
+	if err != nil || !ok {
+		// the results have been collected by another replica
+		deleteFiles()
+		return err
+	}
+	batch.Collected = true
+
+	if usage == nil {
+		return nil
+	}
+	ConsumeWaitGroup.Add(1)
+	go postConsumeAmount(context.Background(),
+		&ConsumeWaitGroup,
+		postGroupConsumer,
+		http.StatusOK,
+		batchEndpoint,
+		usage,
+		m,
+		price,
+		completionPrice,
+		"",
+		nil,
+	)
+	return nil
+}
+
+func SyncBatches(ctx context.Context, wg *sync.WaitGroup, frequency time.Duration) {
+	defer wg.Done()
+
+	log := middleware.NewLogger()
+	ticker := time.NewTicker(frequency)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			batches, err := model.GetUncollectedBatches(100)
+			if err != nil {
+				log.Error("failed to get uncollected batches: " + err.Error())
+				continue
+			}
+			for _, batch := range batches {
+				if err := SyncBatch(ctx, batch); err != nil {
+					log.Errorf("failed to sync batch %s: %v", batch.ID, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	json "github.com/json-iterator/go"
+	"github.com/labring/sealos/service/aiproxy/common"
+	"github.com/labring/sealos/service/aiproxy/common/config"
+	"github.com/labring/sealos/service/aiproxy/common/conv"
+	"github.com/labring/sealos/service/aiproxy/relay/meta"
+	"github.com/labring/sealos/service/aiproxy/relay/model"
+	"github.com/labring/sealos/service/aiproxy/relay/relaymode"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// response cache for deterministic requests, such as embeddings and
+// completions with temperature 0, keyed on the model and the normalized request body
+
+const responseCacheKeyPrefix = "response_cache:"
+
+type responseCacheEntry struct {
+	ContentType string       `json:"content_type"`
+	Body        string       `json:"body"`
+	Usage       *model.Usage `json:"usage"`
+}
+
+// memoryResponseCache is used when redis is not enabled,
+// the total size of the cached bodies is limited by config.ResponseCacheMemoryMaxSize
+type memoryResponseCache struct {
+	cache *gocache.Cache
+	size  atomic.Int64
+}
+
+func newMemoryResponseCache() *memoryResponseCache {
+	m := &memoryResponseCache{
+		cache: gocache.New(time.Hour, time.Minute),
+	}
+	m.cache.OnEvicted(func(_ string, value any) {
+		m.size.Add(-int64(len(value.([]byte))))
+	})
+	return m
+}
+
+func (m *memoryResponseCache) get(key string) ([]byte, bool) {
+	value, ok := m.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return value.([]byte), true
+}
+
+func (m *memoryResponseCache) set(key string, data []byte, ttl time.Duration) {
+	size := int64(len(data))
+	if m.size.Add(size) > config.ResponseCacheMemoryMaxSize {
+		m.size.Add(-size)
+		return
+	}
+	// expired items are replaced by set without eviction, delete first to keep the size accurate
+	m.cache.Delete(key)
+	m.cache.Set(key, data, ttl)
+}
+
+var memoryCache = newMemoryResponseCache()
+
+// isResponseCacheable reports whether the response of the request is deterministic
+// and the group or the token has enabled the response cache
+func isResponseCacheable(meta *meta.Meta, request *model.GeneralOpenAIRequest) bool {
+	if meta.IsChannelTest || config.GetResponseCacheTTL() <= 0 {
+		return false
+	}
+	if !meta.Group.ResponseCache && !meta.Token.ResponseCache {
+		return false
+	}
+	if request.Stream {
+		return false
+	}
+	switch meta.Mode {
+	case relaymode.Embeddings:
+		return true
+	case relaymode.ChatCompletions, relaymode.Completions:
+		return request.Temperature != nil && *request.Temperature == 0 && request.N <= 1
+	default:
+		return false
+	}
+}
+
+// responseCacheKey hashes the model and the normalized request body,
+// the keys of the body are sorted and the fields that do not affect the response are dropped
+func responseCacheKey(meta *meta.Meta, req *http.Request) (string, error) {
+	reqMap := make(map[string]any)
+	err := common.UnmarshalBodyReusable(req, &reqMap)
+	if err != nil {
+		return "", err
+	}
+	delete(reqMap, "user")
+	delete(reqMap, "model")
+	body, err := json.ConfigCompatibleWithStandardLibrary.Marshal(reqMap)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(conv.StringToBytes(meta.OriginModelName))
+	h.Write([]byte{0})
+	h.Write(conv.StringToBytes(strconv.Itoa(meta.Mode)))
+	h.Write([]byte{0})
+	h.Write(body)
+	return responseCacheKeyPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func getResponseCache(ctx context.Context, log *logrus.Entry, key string) (*responseCacheEntry, bool) {
+	var data []byte
+	if common.RedisEnabled {
+		var err error
+		data, err = common.RDB.Get(ctx, key).Bytes()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) {
+				log.Error("get response cache failed: " + err.Error())
+			}
+			return nil, false
+		}
+	} else {
+		var ok bool
+		data, ok = memoryCache.get(key)
+		if !ok {
+			return nil, false
+		}
+	}
+	var entry responseCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.Error("unmarshal response cache failed: " + err.Error())
+		return nil, false
+	}
+	return &entry, true
+}
+
+func setResponseCache(ctx context.Context, log *logrus.Entry, key string, entry *responseCacheEntry) {
+	if int64(len(entry.Body)) > config.GetResponseCacheMaxSize() {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Error("marshal response cache failed: " + err.Error())
+		return
+	}
+	ttl := time.Duration(config.GetResponseCacheTTL()) * time.Second
+	if common.RedisEnabled {
+		err := common.RDB.Set(ctx, key, data, ttl).Err()
+		if err != nil {
+			log.Error("set response cache failed: " + err.Error())
+		}
+		return
+	}
+	memoryCache.set(key, data, ttl)
+}
+
+func writeCachedResponse(c *gin.Context, entry *responseCacheEntry) {
+	contentType := entry.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("X-Aiproxy-Cache", "hit")
+	c.Status(http.StatusOK)
+	_, _ = c.Writer.WriteString(entry.Body)
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/labring/sealos/service/aiproxy/common"
@@ -64,6 +68,62 @@ func preCheckGroupBalance(ctx context.Context, req *PreCheckGroupBalanceReq, met
 	return true, postGroupConsumer, nil
 }
 
+type tokenRateLimitReservations []*middleware.TokenRateLimitReservation
+
+// Settle corrects the estimated tokens reserved on admission to the actual usage
+func (r tokenRateLimitReservations) Settle(ctx context.Context, usage *relaymodel.Usage) {
+	var tokens int64
+	if usage != nil {
+		tokens = int64(usage.TotalTokens)
+		if tokens == 0 {
+			tokens = int64(usage.PromptTokens + usage.CompletionTokens)
+		}
+	}
+	for _, reservation := range r {
+		reservation.Settle(ctx, tokens)
+	}
+}
+
+// preCheckTokenRateLimit reserves the estimated tokens in the group, token and model tpm windows
+func preCheckTokenRateLimit(c *gin.Context, req *PreCheckGroupBalanceReq, meta *meta.Meta) (tokenRateLimitReservations, *relaymodel.ErrorWithStatusCode) {
+	if meta.IsChannelTest {
+		return nil, nil
+	}
+	ctx := c.Request.Context()
+	estimatedTokens := int64(req.PromptTokens + req.MaxTokens)
+
+	type limit struct {
+		key    string
+		name   string
+		maxTPM int64
+	}
+	limits := []limit{
+		{key: "group_tpm:" + meta.Group.ID, name: fmt.Sprintf("group (%s)", meta.Group.ID), maxTPM: meta.Group.TPM},
+		{key: "token_tpm:" + strconv.Itoa(meta.Token.ID), name: fmt.Sprintf("token (%s[%d])", meta.Token.Name, meta.Token.ID), maxTPM: meta.Token.TPM},
+	}
+	if modelConfig, ok := model.CacheGetModelConfig(meta.OriginModelName); ok {
+		limits = append(limits, limit{key: "model_tpm:" + meta.OriginModelName, name: "model " + meta.OriginModelName, maxTPM: modelConfig.TPM})
+	}
+
+	reservations := make(tokenRateLimitReservations, 0, len(limits))
+	for _, l := range limits {
+		reservation, ok, retryAfter := middleware.ForceTokenRateLimit(ctx, l.key, l.maxTPM, estimatedTokens, time.Minute)
+		if !ok {
+			reservations.Settle(ctx, nil)
+			middleware.SetRetryAfter(c, retryAfter)
+			return nil, openai.ErrorWrapperWithMessage(
+				fmt.Sprintf("%s has exceeded the tokens per minute limit: %d, please retry after %ds", l.name, l.maxTPM, int(math.Ceil(retryAfter.Seconds()))),
+				middleware.ErrCodeRateLimitExceeded,
+				http.StatusTooManyRequests,
+			)
+		}
+		if reservation != nil {
+			reservations = append(reservations, reservation)
+		}
+	}
+	return reservations, nil
+}
+
 func postConsumeAmount(
 	ctx context.Context,
 	consumeWaitGroup *sync.WaitGroup,
@@ -101,6 +161,7 @@ func postConsumeAmount(
 			endpoint,
 			content,
 			meta.Mode,
+			meta.CacheHit,
 			requestDetail,
 		)
 		if err != nil {
@@ -161,6 +222,7 @@ func postConsumeAmount(
 		endpoint,
 		content,
 		meta.Mode,
+		meta.CacheHit,
 		requestDetail,
 	)
 	if err != nil {
@@ -34,10 +34,11 @@ func RerankHelper(meta *meta.Meta, c *gin.Context) *relaymodel.ErrorWithStatusCo
 
 	meta.PromptTokens = rerankPromptTokens(rerankRequest)
 
-	ok, postGroupConsumer, err := preCheckGroupBalance(ctx, &PreCheckGroupBalanceReq{
+	preCheckReq := &PreCheckGroupBalanceReq{
 		PromptTokens: meta.PromptTokens,
 		Price:        price,
-	}, meta)
+	}
+	ok, postGroupConsumer, err := preCheckGroupBalance(ctx, preCheckReq, meta)
 	if err != nil {
 		log.Errorf("get group (%s) balance failed: %v", meta.Group.ID, err)
 		return openai.ErrorWrapper(
@@ -55,7 +56,13 @@ func RerankHelper(meta *meta.Meta, c *gin.Context) *relaymodel.ErrorWithStatusCo
 		return openai.ErrorWrapper(fmt.Errorf("invalid channel type: %d", meta.Channel.Type), "invalid_channel_type", http.StatusBadRequest)
 	}
 
+	tokenRateLimitReservations, rateLimitErr := preCheckTokenRateLimit(c, preCheckReq, meta)
+	if rateLimitErr != nil {
+		return rateLimitErr
+	}
+
 	usage, detail, respErr := DoHelper(adaptor, c, meta)
+	tokenRateLimitReservations.Settle(context.Background(), usage)
 	if respErr != nil {
 		if detail != nil {
 			log.Errorf("do rerank failed: %s\nrequest detail:\n%s\nresponse detail:\n%s", respErr, detail.RequestBody, detail.ResponseBody)
@@ -29,10 +29,11 @@ func RelaySTTHelper(meta *meta.Meta, c *gin.Context) *relaymodel.ErrorWithStatus
 		return openai.ErrorWrapper(fmt.Errorf("model price not found: %s", meta.OriginModelName), "model_price_not_found", http.StatusInternalServerError)
 	}
 
-	ok, postGroupConsumer, err := preCheckGroupBalance(ctx, &PreCheckGroupBalanceReq{
+	preCheckReq := &PreCheckGroupBalanceReq{
 		PromptTokens: meta.PromptTokens,
 		Price:        price,
-	}, meta)
+	}
+	ok, postGroupConsumer, err := preCheckGroupBalance(ctx, preCheckReq, meta)
 	if err != nil {
 		log.Errorf("get group (%s) balance failed: %v", meta.Group.ID, err)
 		return openai.ErrorWrapper(
@@ -45,7 +46,13 @@ func RelaySTTHelper(meta *meta.Meta, c *gin.Context) *relaymodel.ErrorWithStatus
 		return openai.ErrorWrapper(errors.New("group balance is not enough"), "insufficient_group_balance", http.StatusForbidden)
 	}
 
+	tokenRateLimitReservations, rateLimitErr := preCheckTokenRateLimit(c, preCheckReq, meta)
+	if rateLimitErr != nil {
+		return rateLimitErr
+	}
+
 	usage, detail, respErr := DoHelper(adaptor, c, meta)
+	tokenRateLimitReservations.Settle(context.Background(), usage)
 	if respErr != nil {
 		if detail != nil {
 			log.Errorf("do stt failed: %s\nrequest detail:\n%s\nresponse detail:\n%s", respErr, detail.RequestBody, detail.ResponseBody)
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/labring/sealos/service/aiproxy/common/config"
 	"github.com/labring/sealos/service/aiproxy/middleware"
 	"github.com/labring/sealos/service/aiproxy/relay/adaptor/openai"
 	"github.com/labring/sealos/service/aiproxy/relay/channeltype"
@@ -14,6 +15,7 @@ import (
 	"github.com/labring/sealos/service/aiproxy/relay/model"
 	billingprice "github.com/labring/sealos/service/aiproxy/relay/price"
 	"github.com/labring/sealos/service/aiproxy/relay/utils"
+	"github.com/shopspring/decimal"
 )
 
 func RelayTextHelper(meta *meta.Meta, c *gin.Context) *model.ErrorWithStatusCode {
@@ -34,11 +36,12 @@ func RelayTextHelper(meta *meta.Meta, c *gin.Context) *model.ErrorWithStatusCode
 	// pre-consume balance
 	promptTokens := openai.GetPromptTokens(meta, textRequest)
 	meta.PromptTokens = promptTokens
-	ok, postGroupConsumer, err := preCheckGroupBalance(ctx, &PreCheckGroupBalanceReq{
+	preCheckReq := &PreCheckGroupBalanceReq{
 		PromptTokens: promptTokens,
 		MaxTokens:    textRequest.MaxTokens,
 		Price:        price,
-	}, meta)
+	}
+	ok, postGroupConsumer, err := preCheckGroupBalance(ctx, preCheckReq, meta)
 	if err != nil {
 		log.Errorf("get group (%s) balance failed: %v", meta.Group.ID, err)
 		return openai.ErrorWrapper(
@@ -51,13 +54,49 @@ func RelayTextHelper(meta *meta.Meta, c *gin.Context) *model.ErrorWithStatusCode
 		return openai.ErrorWrapper(errors.New("group balance is not enough"), "insufficient_group_balance", http.StatusForbidden)
 	}
 
+	var cacheKey string
+	if isResponseCacheable(meta, textRequest) {
+		cacheKey, err = responseCacheKey(meta, c.Request)
+		if err != nil {
+			log.Errorf("get response cache key failed: %s", err.Error())
+		}
+	}
+	if cacheKey != "" {
+		if entry, ok := getResponseCache(ctx, log, cacheKey); ok {
+			meta.CacheHit = true
+			writeCachedResponse(c, entry)
+			// cache hits are billed at a ratio of the model price, 0 means free
+			ratio := decimal.NewFromFloat(config.GetResponseCachePriceRatio())
+			ConsumeWaitGroup.Add(1)
+			go postConsumeAmount(context.Background(),
+				&ConsumeWaitGroup,
+				postGroupConsumer,
+				http.StatusOK,
+				c.Request.URL.Path,
+				entry.Usage,
+				meta,
+				decimal.NewFromFloat(price).Mul(ratio).InexactFloat64(),
+				decimal.NewFromFloat(completionPrice).Mul(ratio).InexactFloat64(),
+				"",
+				nil,
+			)
+			return nil
+		}
+	}
+
 	adaptor, ok := channeltype.GetAdaptor(meta.Channel.Type)
 	if !ok {
 		return openai.ErrorWrapper(fmt.Errorf("invalid channel type: %d", meta.Channel.Type), "invalid_channel_type", http.StatusBadRequest)
 	}
 
+	tokenRateLimitReservations, rateLimitErr := preCheckTokenRateLimit(c, preCheckReq, meta)
+	if rateLimitErr != nil {
+		return rateLimitErr
+	}
+
 	// do response
 	usage, detail, respErr := DoHelper(adaptor, c, meta)
+	tokenRateLimitReservations.Settle(context.Background(), usage)
 	if respErr != nil {
 		if detail != nil {
 			log.Errorf("do text failed: %s\nrequest detail:\n%s\nresponse detail:\n%s", respErr, detail.RequestBody, detail.ResponseBody)
@@ -79,6 +118,14 @@ func RelayTextHelper(meta *meta.Meta, c *gin.Context) *model.ErrorWithStatusCode
 		)
 		return respErr
 	}
+	if cacheKey != "" {
+		setResponseCache(ctx, log, cacheKey, &responseCacheEntry{
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Body:        detail.ResponseBody,
+			Usage:       usage,
+		})
+	}
+
 	// post-consume amount
 	ConsumeWaitGroup.Add(1)
 	go postConsumeAmount(context.Background(),
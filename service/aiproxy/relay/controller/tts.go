@@ -36,10 +36,11 @@ func RelayTTSHelper(meta *meta.Meta, c *gin.Context) *relaymodel.ErrorWithStatus
 	}
 	meta.PromptTokens = openai.CountTokenText(ttsRequest.Input, meta.ActualModelName)
 
-	ok, postGroupConsumer, err := preCheckGroupBalance(ctx, &PreCheckGroupBalanceReq{
+	preCheckReq := &PreCheckGroupBalanceReq{
 		PromptTokens: meta.PromptTokens,
 		Price:        price,
-	}, meta)
+	}
+	ok, postGroupConsumer, err := preCheckGroupBalance(ctx, preCheckReq, meta)
 	if err != nil {
 		log.Errorf("get group (%s) balance failed: %v", meta.Group.ID, err)
 		return openai.ErrorWrapper(
@@ -52,7 +53,13 @@ func RelayTTSHelper(meta *meta.Meta, c *gin.Context) *relaymodel.ErrorWithStatus
 		return openai.ErrorWrapper(errors.New("group balance is not enough"), "insufficient_group_balance", http.StatusForbidden)
 	}
 
+	tokenRateLimitReservations, rateLimitErr := preCheckTokenRateLimit(c, preCheckReq, meta)
+	if rateLimitErr != nil {
+		return rateLimitErr
+	}
+
 	usage, detail, respErr := DoHelper(adaptor, c, meta)
+	tokenRateLimitReservations.Settle(context.Background(), usage)
 	if respErr != nil {
 		if detail != nil {
 			log.Errorf("do tts failed: %s\nrequest detail:\n%s\nresponse detail:\n%s", respErr, detail.RequestBody, detail.ResponseBody)
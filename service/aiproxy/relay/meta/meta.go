@@ -32,6 +32,8 @@ type Meta struct {
 	Mode            int
 	PromptTokens    int
 	IsChannelTest   bool
+	// the response is served from the response cache
+	CacheHit bool
 }
 
 type Option func(meta *Meta)
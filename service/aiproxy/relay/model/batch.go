@@ -0,0 +1,44 @@
+package model
+
+// https://platform.openai.com/docs/api-reference/batch
+
+type BatchRequest struct {
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+}
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type BatchResponse struct {
+	Errors        any                `json:"errors"`
+	ID            string             `json:"id"`
+	Status        string             `json:"status"`
+	OutputFileID  string             `json:"output_file_id"`
+	ErrorFileID   string             `json:"error_file_id"`
+	RequestCounts BatchRequestCounts `json:"request_counts"`
+	InProgressAt  int64              `json:"in_progress_at"`
+	ExpiresAt     int64              `json:"expires_at"`
+	FinalizingAt  int64              `json:"finalizing_at"`
+	CompletedAt   int64              `json:"completed_at"`
+	FailedAt      int64              `json:"failed_at"`
+	ExpiredAt     int64              `json:"expired_at"`
+	CancellingAt  int64              `json:"cancelling_at"`
+	CancelledAt   int64              `json:"cancelled_at"`
+}
+
+// BatchOutput is a line of the output file of a batch
+type BatchOutput struct {
+	Response *struct {
+		Body struct {
+			Usage *Usage `json:"usage"`
+		} `json:"body"`
+		StatusCode int `json:"status_code"`
+	} `json:"response"`
+	CustomID string `json:"custom_id"`
+}
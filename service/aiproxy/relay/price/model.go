@@ -34,3 +34,31 @@ func getModelPrice(modelName string) (float64, float64, bool) {
 	}
 	return modelConfig.InputPrice, modelConfig.OutputPrice, true
 }
+
+// GetModelBatchPrice returns the prices of the batch api, falling back to the normal prices when they are not set
+func GetModelBatchPrice(mapedName string, reqModel string) (float64, float64, bool) {
+	if !config.GetBillingEnabled() {
+		return 0, 0, true
+	}
+	price, completionPrice, ok := getModelBatchPrice(mapedName)
+	if !ok && reqModel != "" {
+		price, completionPrice, ok = getModelBatchPrice(reqModel)
+	}
+	return price, completionPrice, ok
+}
+
+func getModelBatchPrice(modelName string) (float64, float64, bool) {
+	modelConfig, ok := model.CacheGetModelConfig(modelName)
+	if !ok {
+		return 0, 0, false
+	}
+	price := modelConfig.BatchInputPrice
+	if price == 0 {
+		price = modelConfig.InputPrice
+	}
+	completionPrice := modelConfig.BatchOutputPrice
+	if completionPrice == 0 {
+		completionPrice = modelConfig.OutputPrice
+	}
+	return price, completionPrice, true
+}
@@ -12,4 +12,8 @@ const (
 	AudioTranscription
 	AudioTranslation
 	Rerank
+	// https://docs.anthropic.com/en/api/messages
+	Anthropic
+	// https://platform.openai.com/docs/api-reference/batch
+	Batches
 )
@@ -24,6 +24,10 @@ func GetByPath(path string) int {
 		return AudioTranslation
 	case strings.HasPrefix(path, "/v1/rerank"):
 		return Rerank
+	case strings.HasPrefix(path, "/v1/messages"):
+		return Anthropic
+	case strings.HasPrefix(path, "/v1/batches"):
+		return Batches
 	default:
 		return Unknown
 	}
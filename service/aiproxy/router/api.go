@@ -46,6 +46,9 @@ func SetAPIRouter(router *gin.Engine) {
 			groupRoute.DELETE("/:id", controller.DeleteGroup)
 			groupRoute.POST("/:id/status", controller.UpdateGroupStatus)
 			groupRoute.POST("/:id/qpm", controller.UpdateGroupQPM)
+			groupRoute.POST("/:id/tpm", controller.UpdateGroupTPM)
+			groupRoute.POST("/:id/concurrency", controller.UpdateGroupConcurrency)
+			groupRoute.POST("/:id/response_cache", controller.UpdateGroupResponseCache)
 		}
 
 		optionRoute := apiRouter.Group("/option")
@@ -107,11 +110,13 @@ func SetAPIRouter(router *gin.Engine) {
 			logsRoute.GET("/", controller.GetLogs)
 			logsRoute.DELETE("/", controller.DeleteHistoryLogs)
 			logsRoute.GET("/search", controller.SearchLogs)
+			logsRoute.GET("/export", controller.ExportLogs)
 			logsRoute.GET("/consume_error", controller.SearchConsumeError)
 		}
 		logRoute := apiRouter.Group("/log")
 		{
 			logRoute.GET("/:group/search", controller.SearchGroupLogs)
+			logRoute.GET("/:group/export", controller.ExportGroupLogs)
 			logRoute.GET("/:group", controller.GetGroupLogs)
 		}
 
@@ -23,6 +23,23 @@ func SetRelayRouter(router *gin.Engine) {
 		dashboardRouter.GET("/billing/subscription", controller.GetSubscription)
 		dashboardRouter.GET("/billing/usage", controller.GetUsage)
 	}
+	filesRouter := router.Group("/v1/files")
+	filesRouter.Use(middleware.TokenAuth)
+	{
+		filesRouter.GET("", controller.ListFiles)
+		filesRouter.POST("", controller.UploadFile)
+		filesRouter.GET("/:id", controller.GetFile)
+		filesRouter.DELETE("/:id", controller.DeleteFile)
+		filesRouter.GET("/:id/content", controller.GetFileContent)
+	}
+	batchesRouter := router.Group("/v1/batches")
+	batchesRouter.Use(middleware.TokenAuth)
+	{
+		batchesRouter.POST("", controller.CreateBatch)
+		batchesRouter.GET("", controller.ListBatches)
+		batchesRouter.GET("/:id", controller.GetBatch)
+		batchesRouter.POST("/:id/cancel", controller.CancelBatch)
+	}
 	relayV1Router := router.Group("/v1")
 	relayV1Router.Use(middleware.TokenAuth, middleware.Distribute)
 	{
@@ -38,11 +55,7 @@ func SetRelayRouter(router *gin.Engine) {
 		relayV1Router.POST("/audio/translations", controller.Relay)
 		relayV1Router.POST("/audio/speech", controller.Relay)
 		relayV1Router.POST("/rerank", controller.Relay)
-		relayV1Router.GET("/files", controller.RelayNotImplemented)
-		relayV1Router.POST("/files", controller.RelayNotImplemented)
-		relayV1Router.DELETE("/files/:id", controller.RelayNotImplemented)
-		relayV1Router.GET("/files/:id", controller.RelayNotImplemented)
-		relayV1Router.GET("/files/:id/content", controller.RelayNotImplemented)
+		relayV1Router.POST("/messages", controller.Relay)
 		relayV1Router.POST("/fine_tuning/jobs", controller.RelayNotImplemented)
 		relayV1Router.GET("/fine_tuning/jobs", controller.RelayNotImplemented)
 		relayV1Router.GET("/fine_tuning/jobs/:id", controller.RelayNotImplemented)